@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/intothevoid/kramerbot/api/middleware"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
+)
+
+// categoriesResponse is returned by every category endpoint.
+func categoriesResponse(user *models.WebUser) map[string]interface{} {
+	subs := user.Categories
+	if subs == nil {
+		subs = []models.CategorySub{}
+	}
+	return map[string]interface{}{"categories": subs, "available": scrapers.OzbCategories}
+}
+
+// ListCategories returns the authenticated user's category subscriptions and
+// the known OzBargain categories.
+func (h *Handler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil {
+		jsonError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	user, err := h.WebUserDB.GetWebUserByID(claims.UserID)
+	if err != nil || user == nil {
+		jsonError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	jsonOK(w, categoriesResponse(user))
+}
+
+// AddCategory subscribes the user to a category or tag, replacing the vote
+// threshold if already subscribed, and syncs to the bot.
+func (h *Handler) AddCategory(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil {
+		jsonError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req models.CategorySub
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Category = scrapers.CanonicalCategory(req.Category)
+	if req.Category == "" {
+		jsonError(w, http.StatusBadRequest, "category cannot be empty")
+		return
+	}
+	if req.MinVotes < 0 {
+		jsonError(w, http.StatusBadRequest, "min_votes cannot be negative")
+		return
+	}
+
+	user, err := h.WebUserDB.GetWebUserByID(claims.UserID)
+	if err != nil || user == nil {
+		jsonError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	i := slices.IndexFunc(user.Categories, func(c models.CategorySub) bool { return strings.EqualFold(c.Category, req.Category) })
+	if i >= 0 {
+		user.Categories[i] = req
+	} else {
+		user.Categories = append(user.Categories, req)
+	}
+
+	if err := h.WebUserDB.UpdateWebUser(user); err != nil {
+		h.Logger.Error("failed to save category", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	h.syncTelegramPrefs(user)
+
+	jsonOK(w, categoriesResponse(user))
+}
+
+// RemoveCategory ends a category subscription and syncs to the bot.
+func (h *Handler) RemoveCategory(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil {
+		jsonError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	category := scrapers.CanonicalCategory(chi.URLParam(r, "category"))
+	if category == "" {
+		jsonError(w, http.StatusBadRequest, "category param is required")
+		return
+	}
+
+	user, err := h.WebUserDB.GetWebUserByID(claims.UserID)
+	if err != nil || user == nil {
+		jsonError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	user.Categories = slices.DeleteFunc(user.Categories, func(c models.CategorySub) bool {
+		return strings.EqualFold(c.Category, category)
+	})
+
+	if err := h.WebUserDB.UpdateWebUser(user); err != nil {
+		h.Logger.Error("failed to remove category", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	h.syncTelegramPrefs(user)
+
+	jsonOK(w, categoriesResponse(user))
+}
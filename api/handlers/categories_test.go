@@ -0,0 +1,74 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/intothevoid/kramerbot/api/handlers"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/persist/sqlite"
+)
+
+func serveCategories(h *handlers.Handler, user *models.WebUser, method, target, body string) []models.CategorySub {
+	r := chi.NewRouter()
+	r.Get("/user/categories", h.ListCategories)
+	r.Post("/user/categories", h.AddCategory)
+	r.Delete("/user/categories/{category}", h.RemoveCategory)
+	w := serveWith(r, user, method, target, body)
+	if w.Code != http.StatusOK {
+		return nil
+	}
+	var envelope struct {
+		Data struct {
+			Categories []models.CategorySub `json:"categories"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &envelope)
+	return envelope.Data.Categories
+}
+
+// TestCategories verifies subscriptions are added, updated, removed and synced to the linked chat.
+func TestCategories(t *testing.T) {
+	h, user := newUserHandler(t)
+	db := h.WebUserDB.(*sqlite.SQLiteWrapper)
+	h.BotDB = db
+	chatID := int64(77)
+	user.TelegramChatID = &chatID
+	if err := db.UpdateWebUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddUser(&models.UserData{ChatID: chatID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if subs := serveCategories(h, user, http.MethodGet, "/user/categories", ""); subs == nil || len(subs) != 0 {
+		t.Fatalf("new user categories = %v, want empty", subs)
+	}
+	serveCategories(h, user, http.MethodPost, "/user/categories", `{"category":"gaming","min_votes":50}`)
+	serveCategories(h, user, http.MethodPost, "/user/categories", `{"category":"lego"}`)
+	subs := serveCategories(h, user, http.MethodPost, "/user/categories", `{"category":"GAMING","min_votes":10}`)
+	want := []models.CategorySub{{Category: "Gaming", MinVotes: 10}, {Category: "lego"}}
+	if len(subs) != 2 || subs[0] != want[0] || subs[1] != want[1] {
+		t.Fatalf("categories = %v, want %v", subs, want)
+	}
+
+	botUser, err := db.GetUser(chatID)
+	if err != nil || len(botUser.Categories) != 2 {
+		t.Errorf("bot user categories = %v, %v; want synced", botUser, err)
+	}
+
+	subs = serveCategories(h, user, http.MethodDelete, "/user/categories/gaming", "")
+	if len(subs) != 1 || subs[0].Category != "lego" {
+		t.Errorf("categories after delete = %v, want lego", subs)
+	}
+
+	for _, body := range []string{`{"category":"  "}`, `{"category":"Travel","min_votes":-1}`, `not json`} {
+		r := chi.NewRouter()
+		r.Post("/user/categories", h.AddCategory)
+		if w := serveWith(r, user, http.MethodPost, "/user/categories", body); w.Code != http.StatusBadRequest {
+			t.Errorf("POST %s = %d, want 400", body, w.Code)
+		}
+	}
+}
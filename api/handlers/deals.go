@@ -1,73 +1,52 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/persist"
 	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
 )
 
-// GetOzbDeals returns OzBargain deals from the scraper's in-memory cache.
-// Query params: type=good|super|all (default: all), limit (default 50), offset (default 0).
-func (h *Handler) GetOzbDeals(w http.ResponseWriter, r *http.Request) {
-	dealType := r.URL.Query().Get("type")
+// GetSourceDeals returns deals from a single source, read from the deal history
+// when available and from the source's in-memory cache otherwise. Expired and
+// out of stock deals are left out.
+// URL param: source (e.g. ozbargain, amazon).
+// Query params: type=super|reg|good|daily|weekly|all (default: all), limit (default 50), offset (default 0).
+func (h *Handler) GetSourceDeals(w http.ResponseWriter, r *http.Request) {
 	limit := queryInt(r, "limit", 50)
 	offset := queryInt(r, "offset", 0)
 
-	if h.OzbScraper == nil {
-		jsonOK(w, map[string]interface{}{"deals": []interface{}{}, "total": 0})
+	src := h.Sources.Get(chi.URLParam(r, "source"))
+	if src == nil {
+		jsonError(w, http.StatusNotFound, "unknown deal source")
 		return
 	}
 
-	var filtered []interface{}
-	for i := range h.OzbScraper.Deals {
-		d := h.OzbScraper.Deals[i]
-		switch dealType {
-		case "good":
-			if d.DealType == int(scrapers.OZB_GOOD) {
-				filtered = append(filtered, d)
-			}
-		case "super":
-			if d.DealType == int(scrapers.OZB_SUPER) {
-				filtered = append(filtered, d)
-			}
-		default:
-			filtered = append(filtered, d)
-		}
-	}
-
-	total := len(filtered)
-	filtered = paginate(filtered, offset, limit)
-	jsonOK(w, map[string]interface{}{"deals": filtered, "total": total})
-}
+	dealType, filterByType := scrapers.DealTypeFromName(r.URL.Query().Get("type"))
 
-// GetAmazonDeals returns Amazon deals from the scraper's in-memory cache.
-// Query params: type=daily|weekly|all (default: all), limit, offset.
-func (h *Handler) GetAmazonDeals(w http.ResponseWriter, r *http.Request) {
-	dealType := r.URL.Query().Get("type")
-	limit := queryInt(r, "limit", 50)
-	offset := queryInt(r, "offset", 0)
-
-	if h.CCCScraper == nil {
-		jsonOK(w, map[string]interface{}{"deals": []interface{}{}, "total": 0})
+	if h.DealDB != nil {
+		q := models.DealQuery{Source: src.Name(), Active: true, Limit: limit, Offset: offset}
+		if filterByType {
+			q.DealType = int(dealType)
+		}
+		h.queryDeals(w, q)
 		return
 	}
 
-	var filtered []interface{}
-	for i := range h.CCCScraper.Deals {
-		d := h.CCCScraper.Deals[i]
-		switch dealType {
-		case "daily":
-			if d.DealType == int(scrapers.AMZ_DAILY) {
-				filtered = append(filtered, d)
-			}
-		case "weekly":
-			if d.DealType == int(scrapers.AMZ_WEEKLY) {
-				filtered = append(filtered, d)
-			}
-		default:
-			filtered = append(filtered, d)
+	now := time.Now()
+	filtered := []models.Deal{}
+	for _, d := range src.GetDeals() {
+		if (filterByType && d.DealType != int(dealType)) || d.IsExpired(now) {
+			continue
 		}
+		filtered = append(filtered, d)
 	}
 
 	total := len(filtered)
@@ -75,20 +54,22 @@ func (h *Handler) GetAmazonDeals(w http.ResponseWriter, r *http.Request) {
 	jsonOK(w, map[string]interface{}{"deals": filtered, "total": total})
 }
 
-// GetAllDeals returns a combined OzBargain + Amazon deal feed.
+// GetAllDeals returns a combined feed of every registered source, leaving out
+// expired and out of stock deals.
 func (h *Handler) GetAllDeals(w http.ResponseWriter, r *http.Request) {
 	limit := queryInt(r, "limit", 100)
 	offset := queryInt(r, "offset", 0)
 
-	var combined []interface{}
-	if h.OzbScraper != nil {
-		for i := range h.OzbScraper.Deals {
-			combined = append(combined, h.OzbScraper.Deals[i])
-		}
+	if h.DealDB != nil {
+		h.queryDeals(w, models.DealQuery{Active: true, Limit: limit, Offset: offset})
+		return
 	}
-	if h.CCCScraper != nil {
-		for i := range h.CCCScraper.Deals {
-			combined = append(combined, h.CCCScraper.Deals[i])
+
+	now := time.Now()
+	combined := []models.Deal{}
+	for _, d := range h.Sources.AllDeals() {
+		if !d.IsExpired(now) {
+			combined = append(combined, d)
 		}
 	}
 
@@ -97,6 +78,112 @@ func (h *Handler) GetAllDeals(w http.ResponseWriter, r *http.Request) {
 	jsonOK(w, map[string]interface{}{"deals": combined, "total": total})
 }
 
+// GetDealHistory returns a stored deal along with its vote snapshots.
+// URL params: source, id.
+func (h *Handler) GetDealHistory(w http.ResponseWriter, r *http.Request) {
+	if h.DealDB == nil {
+		jsonError(w, http.StatusServiceUnavailable, "deal history is unavailable")
+		return
+	}
+
+	source, id := chi.URLParam(r, "source"), chi.URLParam(r, "id")
+	deal, err := h.DealDB.GetDeal(source, id)
+	if err != nil {
+		h.Logger.Error("get deal failed", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "failed to load deal")
+		return
+	}
+	if deal == nil {
+		jsonError(w, http.StatusNotFound, "deal not found")
+		return
+	}
+
+	history, err := h.DealDB.GetVoteHistory(source, id)
+	if err != nil {
+		h.Logger.Error("get vote history failed", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "failed to load deal history")
+		return
+	}
+	jsonOK(w, map[string]interface{}{"deal": deal, "votes": history})
+}
+
+// SearchDeals runs a full-text search over the deal history.
+// Query params: q (required), source, from/to (RFC 3339 or YYYY-MM-DD), limit (default 20, max 100), cursor.
+func (h *Handler) SearchDeals(w http.ResponseWriter, r *http.Request) {
+	if h.DealDB == nil {
+		jsonError(w, http.StatusServiceUnavailable, "deal search is unavailable")
+		return
+	}
+
+	params := r.URL.Query()
+	search := models.DealSearch{
+		Query:  strings.TrimSpace(params.Get("q")),
+		Source: params.Get("source"),
+		Limit:  queryInt(r, "limit", 20),
+		Cursor: params.Get("cursor"),
+	}
+	if search.Query == "" {
+		jsonError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	if search.Source != "" && h.Sources.Get(search.Source) == nil {
+		jsonError(w, http.StatusBadRequest, "unknown deal source")
+		return
+	}
+
+	var err error
+	if search.From, err = queryDate(params.Get("from"), false); err != nil {
+		jsonError(w, http.StatusBadRequest, "from must be RFC 3339 or YYYY-MM-DD")
+		return
+	}
+	if search.To, err = queryDate(params.Get("to"), true); err != nil {
+		jsonError(w, http.StatusBadRequest, "to must be RFC 3339 or YYYY-MM-DD")
+		return
+	}
+
+	deals, next, err := h.DealDB.SearchDeals(search)
+	if errors.Is(err, persist.ErrInvalidCursor) {
+		jsonError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+	if err != nil {
+		h.Logger.Error("search deals failed", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "failed to search deals")
+		return
+	}
+	jsonOK(w, map[string]interface{}{"deals": deals, "next_cursor": next})
+}
+
+// queryDate parses an RFC 3339 timestamp or a plain date. A plain date used as
+// an upper bound covers the whole day.
+func queryDate(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t, nil
+}
+
+// queryDeals writes a page of stored deals in the same shape as the cache-backed handlers.
+func (h *Handler) queryDeals(w http.ResponseWriter, q models.DealQuery) {
+	deals, total, err := h.DealDB.QueryDeals(q)
+	if err != nil {
+		h.Logger.Error("query deals failed", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "failed to load deals")
+		return
+	}
+	jsonOK(w, map[string]interface{}{"deals": deals, "total": total})
+}
+
 func queryInt(r *http.Request, param string, defaultVal int) int {
 	s := r.URL.Query().Get(param)
 	if s == "" {
@@ -109,9 +196,9 @@ func queryInt(r *http.Request, param string, defaultVal int) int {
 	return v
 }
 
-func paginate(items []interface{}, offset, limit int) []interface{} {
+func paginate(items []models.Deal, offset, limit int) []models.Deal {
 	if offset >= len(items) {
-		return []interface{}{}
+		return []models.Deal{}
 	}
 	end := offset + limit
 	if end > len(items) {
@@ -4,11 +4,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/intothevoid/kramerbot/api/handlers"
 	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/persist/sqlite"
 	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
 )
 
 // buildOzbScraper returns a pre-seeded OzBargain scraper with one REG and one SUPER deal.
@@ -31,6 +36,33 @@ func buildAmazonScraper() *scrapers.CamCamCamScraper {
 	return s
 }
 
+// buildRegistry registers the pre-seeded OzBargain and Amazon scrapers.
+func buildRegistry(t *testing.T) *scrapers.Registry {
+	t.Helper()
+	r := scrapers.NewRegistry()
+	if err := r.Register(buildOzbScraper()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(buildAmazonScraper()); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// serveDeals routes a request through the deal endpoints so URL params are populated.
+func serveDeals(h *handlers.Handler, target string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Get("/deals", h.GetAllDeals)
+	r.Get("/deals/search", h.SearchDeals)
+	r.Get("/deals/{source}", h.GetSourceDeals)
+	r.Get("/deals/{source}/{id}/history", h.GetDealHistory)
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
 func getDealsFromResponse(t *testing.T, body []byte) []interface{} {
 	t.Helper()
 	var envelope struct {
@@ -48,11 +80,9 @@ func getDealsFromResponse(t *testing.T, body []byte) []interface{} {
 // TestGetOzbDeals_SuperFilter verifies that type=super returns only OZB_SUPER deals.
 // This would have caught Bug 1 (DealType always OZB_REG in scraper cache).
 func TestGetOzbDeals_SuperFilter(t *testing.T) {
-	h := &handlers.Handler{OzbScraper: buildOzbScraper()}
+	h := &handlers.Handler{Sources: buildRegistry(t)}
 
-	req := httptest.NewRequest(http.MethodGet, "/deals/ozbargain?type=super", nil)
-	w := httptest.NewRecorder()
-	h.GetOzbDeals(w, req)
+	w := serveDeals(h, "/deals/ozbargain?type=super")
 
 	deals := getDealsFromResponse(t, w.Body.Bytes())
 	if len(deals) != 1 {
@@ -62,11 +92,9 @@ func TestGetOzbDeals_SuperFilter(t *testing.T) {
 
 // TestGetOzbDeals_AllFilter verifies that no type param returns all deals.
 func TestGetOzbDeals_AllFilter(t *testing.T) {
-	h := &handlers.Handler{OzbScraper: buildOzbScraper()}
+	h := &handlers.Handler{Sources: buildRegistry(t)}
 
-	req := httptest.NewRequest(http.MethodGet, "/deals/ozbargain", nil)
-	w := httptest.NewRecorder()
-	h.GetOzbDeals(w, req)
+	w := serveDeals(h, "/deals/ozbargain")
 
 	deals := getDealsFromResponse(t, w.Body.Bytes())
 	if len(deals) != 2 {
@@ -76,11 +104,9 @@ func TestGetOzbDeals_AllFilter(t *testing.T) {
 
 // TestGetAmazonDeals_DailyFilter verifies type=daily returns only AMZ_DAILY deals.
 func TestGetAmazonDeals_DailyFilter(t *testing.T) {
-	h := &handlers.Handler{CCCScraper: buildAmazonScraper()}
+	h := &handlers.Handler{Sources: buildRegistry(t)}
 
-	req := httptest.NewRequest(http.MethodGet, "/deals/amazon?type=daily", nil)
-	w := httptest.NewRecorder()
-	h.GetAmazonDeals(w, req)
+	w := serveDeals(h, "/deals/amazon?type=daily")
 
 	deals := getDealsFromResponse(t, w.Body.Bytes())
 	if len(deals) != 1 {
@@ -91,14 +117,168 @@ func TestGetAmazonDeals_DailyFilter(t *testing.T) {
 // TestGetAmazonDeals_WeeklyFilter verifies type=weekly returns only AMZ_WEEKLY deals.
 // This would catch Bug 2 if daily deals leaked into the weekly response.
 func TestGetAmazonDeals_WeeklyFilter(t *testing.T) {
-	h := &handlers.Handler{CCCScraper: buildAmazonScraper()}
+	h := &handlers.Handler{Sources: buildRegistry(t)}
 
-	req := httptest.NewRequest(http.MethodGet, "/deals/amazon?type=weekly", nil)
-	w := httptest.NewRecorder()
-	h.GetAmazonDeals(w, req)
+	w := serveDeals(h, "/deals/amazon?type=weekly")
 
 	deals := getDealsFromResponse(t, w.Body.Bytes())
 	if len(deals) != 1 {
 		t.Errorf("type=weekly: expected 1 deal, got %d", len(deals))
 	}
 }
+
+// TestGetSourceDeals_UnknownSource verifies an unregistered source returns 404.
+func TestGetSourceDeals_UnknownSource(t *testing.T) {
+	h := &handlers.Handler{Sources: buildRegistry(t)}
+
+	w := serveDeals(h, "/deals/ebay")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown source: expected 404, got %d", w.Code)
+	}
+}
+
+// TestGetAllDeals_CombinesSources verifies the combined feed includes every registered source.
+func TestGetAllDeals_CombinesSources(t *testing.T) {
+	h := &handlers.Handler{Sources: buildRegistry(t)}
+
+	w := serveDeals(h, "/deals")
+	deals := getDealsFromResponse(t, w.Body.Bytes())
+	if len(deals) != 4 {
+		t.Errorf("combined feed: expected 4 deals, got %d", len(deals))
+	}
+}
+
+// newDealDB opens a throwaway SQLite deal history.
+func newDealDB(t *testing.T) *sqlite.SQLiteWrapper {
+	t.Helper()
+	db, err := sqlite.NewSQLiteWrapper(filepath.Join(t.TempDir(), "deals.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to open deal db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestGetSourceDeals_ReadsHistory verifies stored deals are served even when the
+// scraper cache no longer holds them.
+func TestGetSourceDeals_ReadsHistory(t *testing.T) {
+	db := newDealDB(t)
+	stored := []models.Deal{
+		{Id: "old", Source: scrapers.SOURCE_OZBARGAIN, Title: "Evicted Deal", Upvotes: "7", DealType: int(scrapers.OZB_REG)},
+		{Id: "2", Source: scrapers.SOURCE_OZBARGAIN, Title: "Top Deal", Upvotes: "30", DealType: int(scrapers.OZB_SUPER)},
+	}
+	if err := db.UpsertDeals(stored, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	h := &handlers.Handler{Sources: buildRegistry(t), DealDB: db, Logger: zap.NewNop()}
+
+	w := serveDeals(h, "/deals/ozbargain?type=reg")
+	deals := getDealsFromResponse(t, w.Body.Bytes())
+	if len(deals) != 1 || deals[0].(map[string]interface{})["id"] != "old" {
+		t.Errorf("type=reg: expected the stored deal, got %v", deals)
+	}
+}
+
+// TestGetDeals_ExcludesExpired verifies expired and out of stock deals are left
+// out of the feeds, whether they come from the deal history or the scraper cache.
+func TestGetDeals_ExcludesExpired(t *testing.T) {
+	db := newDealDB(t)
+	stored := []models.Deal{
+		{Id: "1", Source: scrapers.SOURCE_OZBARGAIN, Title: "Regular Deal", Upvotes: "3"},
+		{Id: "2", Source: scrapers.SOURCE_OZBARGAIN, Title: "Top Deal", Upvotes: "30", Expiry: models.DealOutOfStock},
+		{Id: "3", Source: scrapers.SOURCE_OZBARGAIN, Title: "Ended", ExpiresAt: time.Now().Add(-time.Hour)},
+	}
+	if err := db.UpsertDeals(stored, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	ozb := buildOzbScraper()
+	ozb.Deals[1].Expiry = models.DealExpired
+	sources := scrapers.NewRegistry()
+	if err := sources.Register(ozb); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, h := range []*handlers.Handler{
+		{Sources: sources, DealDB: db, Logger: zap.NewNop()},
+		{Sources: sources},
+	} {
+		for _, target := range []string{"/deals", "/deals/ozbargain"} {
+			deals := getDealsFromResponse(t, serveDeals(h, target).Body.Bytes())
+			if len(deals) != 1 || deals[0].(map[string]interface{})["id"] != "1" {
+				t.Errorf("%s (history %t): expected only deal 1, got %v", target, h.DealDB != nil, deals)
+			}
+		}
+	}
+}
+
+// TestGetDealHistory verifies the vote snapshots of a stored deal are returned.
+func TestGetDealHistory(t *testing.T) {
+	db := newDealDB(t)
+	start := time.Now().Add(-time.Hour)
+	for i, votes := range []string{"3", "9"} {
+		deal := models.Deal{Id: "1", Source: scrapers.SOURCE_OZBARGAIN, Title: "Deal", Upvotes: votes}
+		if err := db.UpsertDeals([]models.Deal{deal}, start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &handlers.Handler{Sources: buildRegistry(t), DealDB: db, Logger: zap.NewNop()}
+
+	w := serveDeals(h, "/deals/ozbargain/1/history")
+	var envelope struct {
+		Data struct {
+			Votes []models.VoteSnapshot `json:"votes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(envelope.Data.Votes) != 2 || envelope.Data.Votes[1].Votes != 9 {
+		t.Errorf("expected 2 snapshots ending at 9 votes, got %+v", envelope.Data.Votes)
+	}
+
+	w = serveDeals(h, "/deals/ozbargain/missing/history")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing deal: expected 404, got %d", w.Code)
+	}
+}
+
+// TestSearchDeals verifies the search endpoint's validation and result envelope.
+func TestSearchDeals(t *testing.T) {
+	db := newDealDB(t)
+	stored := []models.Deal{
+		{Id: "1", Source: scrapers.SOURCE_OZBARGAIN, Title: "Cheap OLED TV"},
+		{Id: "2", Source: scrapers.SOURCE_OZBARGAIN, Title: "Robot Vacuum"},
+	}
+	if err := db.UpsertDeals(stored, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	h := &handlers.Handler{Sources: buildRegistry(t), DealDB: db, Logger: zap.NewNop()}
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantDeals  int
+	}{
+		{"match", "/deals/search?q=oled", http.StatusOK, 1},
+		{"source filter", "/deals/search?q=oled&source=amazon", http.StatusOK, 0},
+		{"missing q", "/deals/search", http.StatusBadRequest, 0},
+		{"unknown source", "/deals/search?q=oled&source=ebay", http.StatusBadRequest, 0},
+		{"bad date", "/deals/search?q=oled&from=yesterday", http.StatusBadRequest, 0},
+		{"bad cursor", "/deals/search?q=oled&cursor=%21%21", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveDeals(h, tt.target)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				if deals := getDealsFromResponse(t, w.Body.Bytes()); len(deals) != tt.wantDeals {
+					t.Errorf("expected %d deals, got %d", tt.wantDeals, len(deals))
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
 	"github.com/intothevoid/kramerbot/persist"
 	"github.com/intothevoid/kramerbot/scrapers"
 	"github.com/intothevoid/kramerbot/util"
@@ -14,14 +15,17 @@ import (
 
 // Handler holds the shared dependencies for all HTTP handlers.
 type Handler struct {
-	WebUserDB  persist.WebUserDBIF
-	BotDB      persist.DatabaseIF  // for syncing prefs/keywords to bot's Telegram user store
-	OzbScraper *scrapers.OzBargainScraper
-	CCCScraper *scrapers.CamCamCamScraper
-	Config     *util.Config
-	Logger     *zap.Logger
-	JWTSecret  []byte
-	EmailSvc   *util.EmailService
+	WebUserDB persist.WebUserDBIF
+	BotDB     persist.DatabaseIF // for syncing prefs/keywords to bot's Telegram user store
+	Sources   *scrapers.Registry // every registered deal source
+	DealDB    persist.DealDBIF   // stored deal history; nil falls back to the in-memory caches
+	WebhookDB persist.WebhookDBIF
+	OutboxDB  persist.OutboxDBIF // Telegram send queue, for monitoring; may be nil
+	Config    *util.Config
+	Logger    *zap.Logger
+	JWTSecret []byte
+	EmailSvc  *util.EmailService
+	Recorder  *notify.Recorder // notifications recorded in test mode; nil otherwise
 }
 
 // APIResponse is the standard JSON envelope returned by all endpoints.
@@ -64,6 +68,10 @@ func (h *Handler) syncTelegramPrefs(webUser *models.WebUser) {
 	botUser.AmzDaily = webUser.AmzDaily
 	botUser.AmzWeekly = webUser.AmzWeekly
 	botUser.Keywords = webUser.Keywords
+	botUser.Categories = webUser.Categories
+	botUser.TopDeal = webUser.TopDeal
+	botUser.OzbTrending = webUser.OzbTrending
+	botUser.ExpiryNotices = webUser.ExpiryNotices
 	if err := h.BotDB.UpdateUser(botUser); err != nil {
 		h.Logger.Warn("failed to sync prefs to Telegram user", zap.Error(err))
 	}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// OutboxStats reports the depth of the Telegram send queue. It responds 503
+// if the queue cannot be read so monitors can alert on it.
+func (h *Handler) OutboxStats(w http.ResponseWriter, r *http.Request) {
+	if h.OutboxDB == nil {
+		jsonError(w, http.StatusNotFound, "outbox not available")
+		return
+	}
+	stats, err := h.OutboxDB.OutboxStats()
+	if err != nil {
+		h.Logger.Error("failed to read outbox stats", zap.Error(err))
+		jsonError(w, http.StatusServiceUnavailable, "outbox unavailable")
+		return
+	}
+	jsonOK(w, stats)
+}
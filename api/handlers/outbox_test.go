@@ -0,0 +1,32 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/intothevoid/kramerbot/api/handlers"
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+func TestOutboxStats(t *testing.T) {
+	db := newDealDB(t)
+	for _, chatID := range []int64{1, 2} {
+		if err := db.EnqueueOutbox(&models.OutboxMessage{ChatID: chatID, Text: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &handlers.Handler{OutboxDB: db, Logger: zap.NewNop()}
+
+	w := httptest.NewRecorder()
+	h.OutboxStats(w, httptest.NewRequest(http.MethodGet, "/healthz/outbox", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var stats models.OutboxStats
+	decodeData(t, w, &stats)
+	if stats.Pending != 2 || stats.Due != 2 || stats.Dead != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
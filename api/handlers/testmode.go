@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/intothevoid/kramerbot/api/middleware"
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+// TestModeNotifications returns the latest notifications test mode recorded
+// instead of sending, newest first. Recordings name every recipient, so only
+// web accounts linked to a bot admin's Telegram chat may read them.
+// Query params: limit (default 100).
+func (h *Handler) TestModeNotifications(w http.ResponseWriter, r *http.Request) {
+	if h.Recorder == nil {
+		jsonError(w, http.StatusNotFound, "test mode is not active")
+		return
+	}
+	if !h.isBotAdmin(r) {
+		jsonError(w, http.StatusForbidden, "admin only")
+		return
+	}
+	jsonOK(w, h.Recorder.Recent(queryInt(r, "limit", 100)))
+}
+
+// isBotAdmin reports whether the request's web account is linked to the
+// Telegram chat of a bot admin: listed in the telegram.admins config, or
+// given the admin role in the users table.
+func (h *Handler) isBotAdmin(r *http.Request) bool {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil || h.WebUserDB == nil {
+		return false
+	}
+	webUser, err := h.WebUserDB.GetWebUserByID(claims.UserID)
+	if err != nil || webUser == nil || webUser.TelegramChatID == nil {
+		return false
+	}
+	if h.Config != nil && slices.Contains(h.Config.Telegram.Admins, *webUser.TelegramChatID) {
+		return true
+	}
+	if h.BotDB == nil {
+		return false
+	}
+	botUser, err := h.BotDB.GetUser(*webUser.TelegramChatID)
+	if err != nil {
+		h.Logger.Warn("failed to look up Telegram user", zap.Error(err))
+		return false
+	}
+	return botUser != nil && botUser.Role == models.RoleAdmin
+}
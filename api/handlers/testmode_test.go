@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/intothevoid/kramerbot/api/handlers"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	"github.com/intothevoid/kramerbot/util"
+	"go.uber.org/zap"
+)
+
+// newTestModeHandler returns a handler with a web user linked to Telegram
+// chat 9, whose bot user has the given role.
+func newTestModeHandler(t *testing.T, role string) (*handlers.Handler, *models.WebUser) {
+	t.Helper()
+	db := newDealDB(t)
+	chatID := int64(9)
+	user := &models.WebUser{ID: "user-1", Email: "kramer@example.com", PasswordHash: "x"}
+	if err := db.CreateWebUser(user); err != nil {
+		t.Fatal(err)
+	}
+	user.TelegramChatID = &chatID
+	if err := db.UpdateWebUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddUser(&models.UserData{ChatID: chatID, Username: "kramer", Role: role}); err != nil {
+		t.Fatal(err)
+	}
+	return &handlers.Handler{WebUserDB: db, BotDB: db, Logger: zap.NewNop()}, user
+}
+
+func serveTestMode(h *handlers.Handler, user *models.WebUser, target string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Get("/api/v1/testmode/notifications", h.TestModeNotifications)
+	return serveWith(r, user, http.MethodGet, target, "")
+}
+
+func TestTestModeNotifications(t *testing.T) {
+	h, user := newTestModeHandler(t, models.RoleAdmin)
+	w := serveTestMode(h, user, "/api/v1/testmode/notifications")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 outside test mode, got %d", w.Code)
+	}
+
+	rec, err := notify.NewRecorder("", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		rec.Record(notify.Recording{Channel: notify.ChannelTelegram, ChatID: 9, DealID: id})
+	}
+	h.Recorder = rec
+
+	w = serveTestMode(h, user, "/api/v1/testmode/notifications?limit=2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var got []notify.Recording
+	decodeData(t, w, &got)
+	if len(got) != 2 || got[0].DealID != "3" || got[1].DealID != "2" {
+		t.Errorf("unexpected recordings %+v", got)
+	}
+}
+
+// TestTestModeNotifications_AdminOnly verifies that other users' recordings
+// are not shown to web users who aren't bot admins.
+func TestTestModeNotifications_AdminOnly(t *testing.T) {
+	rec, err := notify.NewRecorder("", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Record(notify.Recording{Channel: notify.ChannelTelegram, ChatID: 42, Username: "newman", DealID: "1"})
+
+	h, user := newTestModeHandler(t, "")
+	h.Recorder = rec
+	if w := serveTestMode(h, user, "/api/v1/testmode/notifications"); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: expected 403, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Admins listed in the config need no role
+	h.Config = util.DefaultConfig()
+	h.Config.Telegram.Admins = []int64{9}
+	if w := serveTestMode(h, user, "/api/v1/testmode/notifications"); w.Code != http.StatusOK {
+		t.Errorf("config admin: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	unlinked, _ := newUserHandler(t)
+	unlinked.Recorder = rec
+	if w := serveTestMode(unlinked, user, "/api/v1/testmode/notifications"); w.Code != http.StatusForbidden {
+		t.Errorf("no Telegram link: expected 403, got %d", w.Code)
+	}
+}
@@ -3,10 +3,14 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/intothevoid/kramerbot/api/middleware"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	"github.com/intothevoid/kramerbot/watch"
 	"go.uber.org/zap"
 )
 
@@ -29,11 +33,28 @@ func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
 }
 
 type preferencesRequest struct {
-	OzbGood      bool `json:"ozb_good"`
-	OzbSuper     bool `json:"ozb_super"`
-	AmzDaily     bool `json:"amz_daily"`
-	AmzWeekly    bool `json:"amz_weekly"`
-	EmailSummary bool `json:"email_summary"`
+	OzbGood      bool      `json:"ozb_good"`
+	OzbSuper     bool      `json:"ozb_super"`
+	AmzDaily     bool      `json:"amz_daily"`
+	AmzWeekly    bool      `json:"amz_weekly"`
+	EmailSummary bool      `json:"email_summary"`
+	Channels     *[]string `json:"channels,omitempty"` // left unchanged when omitted
+
+	TopDeal       *models.TopDealRule `json:"top_deal,omitempty"`       // left unchanged when omitted
+	OzbTrending   *bool               `json:"ozb_trending,omitempty"`   // left unchanged when omitted
+	ExpiryNotices *bool               `json:"expiry_notices,omitempty"` // left unchanged when omitted
+}
+
+// validateChannels checks requested delivery channels.
+func validateChannels(req *preferencesRequest) string {
+	if req.Channels != nil {
+		for _, ch := range *req.Channels {
+			if !notify.ValidChannel(ch) {
+				return "unknown channel: " + ch
+			}
+		}
+	}
+	return ""
 }
 
 // UpdatePreferences saves the user's deal notification toggles and syncs them
@@ -50,6 +71,16 @@ func (h *Handler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
+	if msg := validateChannels(&req); msg != "" {
+		jsonError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if req.TopDeal != nil {
+		if err := req.TopDeal.Validate(); err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	user, err := h.WebUserDB.GetWebUserByID(claims.UserID)
 	if err != nil || user == nil {
@@ -63,6 +94,18 @@ func (h *Handler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
 	user.AmzDaily = req.AmzDaily
 	user.AmzWeekly = req.AmzWeekly
 	user.EmailSummary = req.EmailSummary
+	if req.Channels != nil {
+		user.Channels = slices.Compact(slices.Sorted(slices.Values(*req.Channels)))
+	}
+	if req.TopDeal != nil {
+		user.TopDeal = *req.TopDeal
+	}
+	if req.OzbTrending != nil {
+		user.OzbTrending = *req.OzbTrending
+	}
+	if req.ExpiryNotices != nil {
+		user.ExpiryNotices = *req.ExpiryNotices
+	}
 
 	if err := h.WebUserDB.UpdateWebUser(user); err != nil {
 		h.Logger.Error("failed to save preferences", zap.Error(err))
@@ -114,12 +157,20 @@ func (h *Handler) AddKeyword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	kw := strings.TrimSpace(strings.ToLower(req.Keyword))
+	kw := strings.TrimSpace(req.Keyword)
 	if kw == "" {
 		jsonError(w, http.StatusBadRequest, "keyword cannot be empty")
 		return
 	}
 
+	// Keywords are watch expressions; store them in canonical form.
+	expr, err := watch.Parse(kw)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "invalid keyword: "+err.Error())
+		return
+	}
+	kw = expr.String()
+
 	user, err := h.WebUserDB.GetWebUserByID(claims.UserID)
 	if err != nil || user == nil {
 		jsonError(w, http.StatusNotFound, "user not found")
@@ -128,7 +179,7 @@ func (h *Handler) AddKeyword(w http.ResponseWriter, r *http.Request) {
 
 	// Deduplicate.
 	for _, existing := range user.Keywords {
-		if existing == kw {
+		if watch.Canonical(existing) == kw {
 			jsonOK(w, map[string]interface{}{"keywords": user.Keywords})
 			return
 		}
@@ -155,7 +206,7 @@ func (h *Handler) RemoveKeyword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	kw := strings.ToLower(chi.URLParam(r, "keyword"))
+	kw := watch.Canonical(chi.URLParam(r, "keyword"))
 	if kw == "" {
 		jsonError(w, http.StatusBadRequest, "keyword param is required")
 		return
@@ -169,7 +220,7 @@ func (h *Handler) RemoveKeyword(w http.ResponseWriter, r *http.Request) {
 
 	filtered := user.Keywords[:0]
 	for _, k := range user.Keywords {
-		if k != kw {
+		if watch.Canonical(k) != kw {
 			filtered = append(filtered, k)
 		}
 	}
@@ -0,0 +1,208 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/intothevoid/kramerbot/api/handlers"
+	"github.com/intothevoid/kramerbot/api/middleware"
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+// newUserHandler returns a handler backed by a throwaway database holding one web user.
+func newUserHandler(t *testing.T) (*handlers.Handler, *models.WebUser) {
+	t.Helper()
+	db := newDealDB(t)
+	user := &models.WebUser{ID: "user-1", Email: "kramer@example.com", PasswordHash: "x"}
+	if err := db.CreateWebUser(user); err != nil {
+		t.Fatal(err)
+	}
+	return &handlers.Handler{WebUserDB: db, Logger: zap.NewNop()}, user
+}
+
+// serveAsUser routes a request through the user endpoints as the given user.
+func serveAsUser(h *handlers.Handler, user *models.WebUser, method, target, body string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Post("/user/keywords", h.AddKeyword)
+	r.Delete("/user/keywords/{keyword}", h.RemoveKeyword)
+	r.Put("/user/preferences", h.UpdatePreferences)
+	return serveWith(r, user, method, target, body)
+}
+
+// serveWith sends a request through r, authenticated as the given user.
+func serveWith(r http.Handler, user *models.WebUser, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	claims := &middleware.JWTClaims{UserID: user.ID, Email: user.Email}
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ClaimsKey, claims))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func keywordsFromResponse(t *testing.T, body []byte) []string {
+	t.Helper()
+	var envelope struct {
+		Data struct {
+			Keywords []string `json:"keywords"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v\nbody: %s", err, body)
+	}
+	return envelope.Data.Keywords
+}
+
+// TestAddKeyword_Expression verifies expressions are validated and stored in canonical form.
+func TestAddKeyword_Expression(t *testing.T) {
+	h, user := newUserHandler(t)
+
+	w := serveAsUser(h, user, http.MethodPost, "/user/keywords", `{"keyword":"\"Samsung\" AND ssd -refurbished"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	kws := keywordsFromResponse(t, w.Body.Bytes())
+	if len(kws) != 1 || kws[0] != `samsung AND ssd AND NOT refurbished` {
+		t.Errorf("unexpected keywords %q", kws)
+	}
+
+	// The same expression written differently is a duplicate
+	w = serveAsUser(h, user, http.MethodPost, "/user/keywords", `{"keyword":"samsung ssd NOT refurbished"}`)
+	if kws := keywordsFromResponse(t, w.Body.Bytes()); len(kws) != 1 {
+		t.Errorf("duplicate expression added: %q", kws)
+	}
+}
+
+// TestAddKeyword_SyntaxError verifies invalid expressions are rejected with the error position.
+func TestAddKeyword_SyntaxError(t *testing.T) {
+	h, user := newUserHandler(t)
+
+	tests := []struct {
+		keyword string
+		wantMsg string
+	}{
+		{`(ps5 OR playstation`, "position 1"},
+		{`ps5 OR`, "position 7"},
+		{`-refurbished`, "matches every deal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyword, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"keyword": tt.keyword})
+			w := serveAsUser(h, user, http.MethodPost, "/user/keywords", string(body))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantMsg) {
+				t.Errorf("expected error mentioning %q, got %s", tt.wantMsg, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestRemoveKeyword_Canonical verifies keywords can be removed using any equivalent form.
+func TestRemoveKeyword_Canonical(t *testing.T) {
+	h, user := newUserHandler(t)
+	serveAsUser(h, user, http.MethodPost, "/user/keywords", `{"keyword":"ps5 OR xbox"}`)
+
+	w := serveAsUser(h, user, http.MethodDelete, "/user/keywords/PS5%20OR%20Xbox", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if kws := keywordsFromResponse(t, w.Body.Bytes()); len(kws) != 0 {
+		t.Errorf("keyword not removed: %q", kws)
+	}
+}
+
+// TestUpdatePreferences_Channels verifies delivery channels are validated and
+// left untouched by clients that do not send them.
+func TestUpdatePreferences_Channels(t *testing.T) {
+	h, user := newUserHandler(t)
+
+	w := serveAsUser(h, user, http.MethodPut, "/user/preferences",
+		`{"ozb_good":true,"channels":["webhook","telegram"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// An older client only sends the subscription toggles
+	serveAsUser(h, user, http.MethodPut, "/user/preferences", `{"ozb_good":false}`)
+	got, _ := h.WebUserDB.GetWebUserByID(user.ID)
+	if strings.Join(got.Channels, ",") != "telegram,webhook" {
+		t.Errorf("got channels %q", got.Channels)
+	}
+
+	if w := serveAsUser(h, user, http.MethodPut, "/user/preferences", `{"channels":["carrier-pigeon"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown channel, got %d", w.Code)
+	}
+}
+
+// TestUpdatePreferences_TopDeal verifies the top deal rule is validated and
+// kept when a request does not send one.
+func TestUpdatePreferences_TopDeal(t *testing.T) {
+	h, user := newUserHandler(t)
+
+	w := serveAsUser(h, user, http.MethodPut, "/user/preferences",
+		`{"ozb_super":true,"top_deal":{"min_votes":10,"max_age_hours":6,"min_votes_per_hour":2.5}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	serveAsUser(h, user, http.MethodPut, "/user/preferences", `{"ozb_super":true}`)
+	got, _ := h.WebUserDB.GetWebUserByID(user.ID)
+	if want := (models.TopDealRule{MinVotes: 10, MaxAgeHours: 6, MinVotesPerHour: 2.5}); got.TopDeal != want {
+		t.Errorf("got top deal rule %+v, want %+v", got.TopDeal, want)
+	}
+
+	for _, body := range []string{
+		`{"top_deal":{"min_votes":-1}}`,
+		`{"top_deal":{"max_age_hours":500}}`,
+		`{"top_deal":{"min_votes_per_hour":-2}}`,
+	} {
+		if w := serveAsUser(h, user, http.MethodPut, "/user/preferences", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+// TestUpdatePreferences_Trending verifies the trending subscription is only
+// changed by requests that send it.
+func TestUpdatePreferences_Trending(t *testing.T) {
+	h, user := newUserHandler(t)
+
+	w := serveAsUser(h, user, http.MethodPut, "/user/preferences", `{"ozb_trending":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	serveAsUser(h, user, http.MethodPut, "/user/preferences", `{"ozb_good":true}`)
+	if got, _ := h.WebUserDB.GetWebUserByID(user.ID); !got.OzbTrending {
+		t.Error("ozb_trending turned off by a request without it")
+	}
+
+	serveAsUser(h, user, http.MethodPut, "/user/preferences", `{"ozb_trending":false}`)
+	if got, _ := h.WebUserDB.GetWebUserByID(user.ID); got.OzbTrending {
+		t.Error("ozb_trending not turned off")
+	}
+}
+
+func TestUpdatePreferences_ExpiryNotices(t *testing.T) {
+	h, user := newUserHandler(t)
+
+	w := serveAsUser(h, user, http.MethodPut, "/user/preferences", `{"expiry_notices":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	serveAsUser(h, user, http.MethodPut, "/user/preferences", `{"ozb_good":true}`)
+	if got, _ := h.WebUserDB.GetWebUserByID(user.ID); !got.ExpiryNotices {
+		t.Error("expiry_notices turned off by a request without it")
+	}
+
+	serveAsUser(h, user, http.MethodPut, "/user/preferences", `{"expiry_notices":false}`)
+	if got, _ := h.WebUserDB.GetWebUserByID(user.ID); got.ExpiryNotices {
+		t.Error("expiry_notices not turned off")
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/intothevoid/kramerbot/api/middleware"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	"go.uber.org/zap"
+)
+
+// maxWebhooksPerUser caps how many endpoints a single account can register.
+const maxWebhooksPerUser = 5
+
+type webhookRequest struct {
+	URL string `json:"url"`
+}
+
+// ListWebhooks returns the authenticated user's webhooks (without secrets).
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil {
+		jsonError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	hooks, err := h.WebhookDB.ListWebhooks(claims.UserID)
+	if err != nil {
+		h.Logger.Error("failed to list webhooks", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+	jsonOK(w, map[string]interface{}{"webhooks": hooks})
+}
+
+// CreateWebhook registers a webhook endpoint. The signing secret is only
+// returned in this response.
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil {
+		jsonError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		jsonError(w, http.StatusBadRequest, "url must be an http(s) URL")
+		return
+	}
+	if !h.allowPrivateWebhooks() {
+		if err := notify.CheckWebhookHost(r.Context(), u.Hostname()); errors.Is(err, notify.ErrPrivateAddress) {
+			jsonError(w, http.StatusBadRequest, "url must point to a public address")
+			return
+		} else if err != nil {
+			jsonError(w, http.StatusBadRequest, "url host could not be resolved")
+			return
+		}
+	}
+
+	existing, err := h.WebhookDB.ListWebhooks(claims.UserID)
+	if err != nil {
+		h.Logger.Error("failed to list webhooks", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if len(existing) >= maxWebhooksPerUser {
+		jsonError(w, http.StatusBadRequest, "webhook limit reached ("+strconv.Itoa(maxWebhooksPerUser)+")")
+		return
+	}
+
+	hook := &models.Webhook{
+		ID:     uuid.New().String(),
+		UserID: claims.UserID,
+		URL:    req.URL,
+		Secret: notify.NewSecret(),
+	}
+	if err := h.WebhookDB.CreateWebhook(hook); err != nil {
+		h.Logger.Error("failed to create webhook", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	jsonCreated(w, hook)
+}
+
+// DeleteWebhook removes one of the authenticated user's webhooks.
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil {
+		jsonError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	found, err := h.WebhookDB.DeleteWebhook(claims.UserID, chi.URLParam(r, "id"))
+	if err != nil {
+		h.Logger.Error("failed to delete webhook", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !found {
+		jsonError(w, http.StatusNotFound, "webhook not found")
+		return
+	}
+	jsonOK(w, map[string]string{"message": "webhook deleted"})
+}
+
+// ListWebhookDeliveries returns a webhook's recent delivery attempts, newest first.
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	hook := h.ownWebhook(w, r)
+	if hook == nil {
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	deliveries, err := h.WebhookDB.ListWebhookDeliveries(hook.ID, limit)
+	if err != nil {
+		h.Logger.Error("failed to list webhook deliveries", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	jsonOK(w, map[string]interface{}{"deliveries": deliveries})
+}
+
+// TestWebhook sends a single signed test payload to a webhook and returns the
+// logged attempt.
+func (h *Handler) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	hook := h.ownWebhook(w, r)
+	if hook == nil {
+		return
+	}
+
+	sender := notify.NewWebhook(h.WebhookDB, h.Logger)
+	sender.MaxAttempts = 1
+	sender.AllowPrivate = h.allowPrivateWebhooks()
+	deal := &models.Deal{
+		Id:       "test",
+		Source:   "kramerbot",
+		Title:    "Test deal from KramerBot",
+		Url:      "https://www.ozbargain.com.au",
+		PostedAt: time.Now().UTC(),
+	}
+	delivery, err := sender.Deliver(hook, notify.EventTest, deal)
+	if delivery == nil {
+		h.Logger.Error("failed to send test webhook", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	jsonOK(w, delivery)
+}
+
+// allowPrivateWebhooks reports whether webhooks may target private addresses.
+func (h *Handler) allowPrivateWebhooks() bool {
+	return h.Config != nil && h.Config.API.AllowPrivateWebhooks
+}
+
+// ownWebhook looks up the {id} webhook of the authenticated user, writing an
+// error response and returning nil if there is none.
+func (h *Handler) ownWebhook(w http.ResponseWriter, r *http.Request) *models.Webhook {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil {
+		jsonError(w, http.StatusUnauthorized, "unauthorized")
+		return nil
+	}
+
+	hooks, err := h.WebhookDB.ListWebhooks(claims.UserID)
+	if err != nil {
+		h.Logger.Error("failed to list webhooks", zap.Error(err))
+		jsonError(w, http.StatusInternalServerError, "internal error")
+		return nil
+	}
+	id := chi.URLParam(r, "id")
+	for _, hook := range hooks {
+		if hook.ID == id {
+			return hook
+		}
+	}
+	jsonError(w, http.StatusNotFound, "webhook not found")
+	return nil
+}
@@ -0,0 +1,172 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/intothevoid/kramerbot/api/handlers"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	"github.com/intothevoid/kramerbot/util"
+	"go.uber.org/zap"
+)
+
+// newWebhookHandler returns a handler with webhook routes backed by a throwaway
+// database. Private webhook addresses are allowed so tests can use local receivers.
+func newWebhookHandler(t *testing.T) (*handlers.Handler, *models.WebUser) {
+	t.Helper()
+	db := newDealDB(t)
+	user := &models.WebUser{ID: "user-1", Email: "kramer@example.com", PasswordHash: "x"}
+	if err := db.CreateWebUser(user); err != nil {
+		t.Fatal(err)
+	}
+	cfg := util.DefaultConfig()
+	cfg.API.AllowPrivateWebhooks = true
+	return &handlers.Handler{WebUserDB: db, WebhookDB: db, Config: cfg, Logger: zap.NewNop()}, user
+}
+
+func serveWebhooks(h *handlers.Handler, user *models.WebUser, method, target, body string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Get("/user/webhooks", h.ListWebhooks)
+	r.Post("/user/webhooks", h.CreateWebhook)
+	r.Delete("/user/webhooks/{id}", h.DeleteWebhook)
+	r.Get("/user/webhooks/{id}/deliveries", h.ListWebhookDeliveries)
+	r.Post("/user/webhooks/{id}/test", h.TestWebhook)
+	return serveWith(r, user, method, target, body)
+}
+
+func decodeData(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	envelope := struct {
+		Data interface{} `json:"data"`
+	}{Data: v}
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v\nbody: %s", err, w.Body.String())
+	}
+}
+
+// TestWebhooks_Lifecycle creates a webhook, sends a signed test delivery to an
+// httptest receiver, reads the delivery log and deletes the webhook.
+func TestWebhooks_Lifecycle(t *testing.T) {
+	h, user := newWebhookHandler(t)
+
+	var gotSig, gotTS string
+	var gotBody []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig, gotTS = r.Header.Get(notify.HeaderSignature), r.Header.Get(notify.HeaderTimestamp)
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer receiver.Close()
+
+	w := serveWebhooks(h, user, http.MethodPost, "/user/webhooks", `{"url":"`+receiver.URL+`"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.Webhook
+	decodeData(t, w, &created)
+	if created.ID == "" || created.Secret == "" {
+		t.Fatalf("created webhook missing id or secret: %+v", created)
+	}
+
+	// Secrets are not listed
+	w = serveWebhooks(h, user, http.MethodGet, "/user/webhooks", "")
+	var list struct {
+		Webhooks []models.Webhook `json:"webhooks"`
+	}
+	decodeData(t, w, &list)
+	if len(list.Webhooks) != 1 || list.Webhooks[0].Secret != "" {
+		t.Errorf("unexpected list %+v", list.Webhooks)
+	}
+
+	w = serveWebhooks(h, user, http.MethodPost, "/user/webhooks/"+created.ID+"/test", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("test: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	ts, _ := strconv.ParseInt(gotTS, 10, 64)
+	if gotSig == "" || gotSig != notify.Sign(created.Secret, ts, gotBody) {
+		t.Errorf("test delivery signature %q does not verify", gotSig)
+	}
+
+	w = serveWebhooks(h, user, http.MethodGet, "/user/webhooks/"+created.ID+"/deliveries", "")
+	var log struct {
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+	}
+	decodeData(t, w, &log)
+	if len(log.Deliveries) != 1 || log.Deliveries[0].StatusCode != 200 || log.Deliveries[0].Event != notify.EventTest {
+		t.Errorf("unexpected delivery log %+v", log.Deliveries)
+	}
+
+	if w := serveWebhooks(h, user, http.MethodDelete, "/user/webhooks/"+created.ID, ""); w.Code != http.StatusOK {
+		t.Errorf("delete: expected 200, got %d", w.Code)
+	}
+	if w := serveWebhooks(h, user, http.MethodDelete, "/user/webhooks/"+created.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("second delete: expected 404, got %d", w.Code)
+	}
+}
+
+// TestWebhooks_Ownership verifies users cannot see or touch each other's webhooks.
+func TestWebhooks_Ownership(t *testing.T) {
+	h, user := newWebhookHandler(t)
+	other := &models.WebUser{ID: "user-2", Email: "newman@example.com", PasswordHash: "x"}
+	if err := h.WebUserDB.CreateWebUser(other); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serveWebhooks(h, user, http.MethodPost, "/user/webhooks", `{"url":"https://example.com/hook"}`)
+	var created models.Webhook
+	decodeData(t, w, &created)
+
+	for _, tt := range []struct{ method, target string }{
+		{http.MethodGet, "/user/webhooks/" + created.ID + "/deliveries"},
+		{http.MethodPost, "/user/webhooks/" + created.ID + "/test"},
+		{http.MethodDelete, "/user/webhooks/" + created.ID},
+	} {
+		if w := serveWebhooks(h, other, tt.method, tt.target, ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s %s as other user: expected 404, got %d", tt.method, tt.target, w.Code)
+		}
+	}
+}
+
+func TestCreateWebhook_Validation(t *testing.T) {
+	h, user := newWebhookHandler(t)
+
+	for _, body := range []string{`{"url":"ftp://example.com"}`, `{"url":"not a url"}`, `{}`} {
+		if w := serveWebhooks(h, user, http.MethodPost, "/user/webhooks", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		serveWebhooks(h, user, http.MethodPost, "/user/webhooks", `{"url":"https://example.com/hook"}`)
+	}
+	if w := serveWebhooks(h, user, http.MethodPost, "/user/webhooks", `{"url":"https://example.com/hook"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("over the limit: expected 400, got %d", w.Code)
+	}
+}
+
+func TestCreateWebhook_PrivateAddress(t *testing.T) {
+	h, user := newWebhookHandler(t)
+	h.Config.API.AllowPrivateWebhooks = false
+
+	for _, url := range []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.1.20/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+	} {
+		if w := serveWebhooks(h, user, http.MethodPost, "/user/webhooks", `{"url":"`+url+`"}`); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, w.Code)
+		}
+	}
+	if w := serveWebhooks(h, user, http.MethodPost, "/user/webhooks", `{"url":"https://93.184.215.14/hook"}`); w.Code != http.StatusCreated {
+		t.Errorf("public address: expected 201, got %d: %s", w.Code, w.Body)
+	}
+}
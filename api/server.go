@@ -14,6 +14,7 @@ import (
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/intothevoid/kramerbot/api/handlers"
 	"github.com/intothevoid/kramerbot/api/middleware"
+	"github.com/intothevoid/kramerbot/notify"
 	"github.com/intothevoid/kramerbot/persist"
 	sqlite_persist "github.com/intothevoid/kramerbot/persist/sqlite"
 	"github.com/intothevoid/kramerbot/scrapers"
@@ -28,14 +29,17 @@ type Server struct {
 }
 
 // NewServer constructs the Chi router, registers all routes, and returns a ready-to-run Server.
+// telegramUpdates, if not nil, serves the Telegram webhook at telegram.webhook.path.
+// recorder, if not nil, holds the notifications recorded in test mode.
 func NewServer(
 	cfg *util.Config,
 	db persist.DatabaseIF,
-	ozbScraper *scrapers.OzBargainScraper,
-	cccScraper *scrapers.CamCamCamScraper,
+	sources *scrapers.Registry,
 	logger *zap.Logger,
 	staticFiles fs.FS,
 	emailSvc *util.EmailService,
+	telegramUpdates http.Handler,
+	recorder *notify.Recorder,
 ) (*Server, error) {
 	// Resolve JWT secret — prefer env var over a generated fallback.
 	jwtSecret := os.Getenv("JWT_SECRET")
@@ -55,15 +59,28 @@ func NewServer(
 		}
 	}
 
+	// Deal history is optional — without it the deal routes serve the scraper caches.
+	dealDB, _ := db.(persist.DealDBIF)
+
+	webhookDB, ok := db.(persist.WebhookDBIF)
+	if !ok {
+		return nil, fmt.Errorf("database driver does not implement WebhookDBIF")
+	}
+
+	outboxDB, _ := db.(persist.OutboxDBIF)
+
 	h := &handlers.Handler{
-		WebUserDB:  webUserDB,
-		BotDB:      db,
-		OzbScraper: ozbScraper,
-		CCCScraper: cccScraper,
-		Config:     cfg,
-		Logger:     logger,
-		JWTSecret:  []byte(jwtSecret),
-		EmailSvc:   emailSvc,
+		WebUserDB: webUserDB,
+		BotDB:     db,
+		Sources:   sources,
+		DealDB:    dealDB,
+		WebhookDB: webhookDB,
+		OutboxDB:  outboxDB,
+		Config:    cfg,
+		Logger:    logger,
+		JWTSecret: []byte(jwtSecret),
+		EmailSvc:  emailSvc,
+		Recorder:  recorder,
 	}
 
 	r := chi.NewRouter()
@@ -93,25 +110,47 @@ func NewServer(
 		r.Get("/keywords", h.ListKeywords)
 		r.Post("/keywords", h.AddKeyword)
 		r.Delete("/keywords/{keyword}", h.RemoveKeyword)
+		r.Get("/categories", h.ListCategories)
+		r.Post("/categories", h.AddCategory)
+		r.Delete("/categories/{category}", h.RemoveCategory)
 		r.Post("/telegram/link", h.GenerateTelegramLink)
 		r.Get("/telegram/status", h.GetTelegramStatus)
 		r.Delete("/telegram/link", h.UnlinkTelegram)
+		r.Get("/webhooks", h.ListWebhooks)
+		r.Post("/webhooks", h.CreateWebhook)
+		r.Delete("/webhooks/{id}", h.DeleteWebhook)
+		r.Get("/webhooks/{id}/deliveries", h.ListWebhookDeliveries)
+		r.Post("/webhooks/{id}/test", h.TestWebhook)
 	})
 
 	// Deal feed (requires auth)
 	r.Route("/api/v1/deals", func(r chi.Router) {
 		r.Use(middleware.JWTAuth([]byte(jwtSecret)))
-		r.Get("/ozbargain", h.GetOzbDeals)
-		r.Get("/amazon", h.GetAmazonDeals)
 		r.Get("/", h.GetAllDeals)
+		r.Get("/search", h.SearchDeals)
+		r.Get("/{source}", h.GetSourceDeals)
+		r.Get("/{source}/{id}/history", h.GetDealHistory)
 	})
 
+	// Notifications recorded instead of sent in test mode (requires auth; bot admins only)
+	if recorder != nil {
+		r.With(middleware.JWTAuth([]byte(jwtSecret))).Get("/api/v1/testmode/notifications", h.TestModeNotifications)
+	}
+
 	// Health check (public)
 	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("ok"))
 	})
 
+	// Telegram send queue depth (public, for monitoring)
+	r.Get("/healthz/outbox", h.OutboxStats)
+
+	// Telegram webhook (public, checked against the webhook's secret token)
+	if telegramUpdates != nil {
+		r.Method(http.MethodPost, cfg.Telegram.Webhook.Path, telegramUpdates)
+	}
+
 	// Serve React SPA — catch-all, must be last.
 	// Any path not matched above falls through to index.html so React Router works.
 	if staticFiles != nil {
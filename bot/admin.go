@@ -0,0 +1,317 @@
+package bot
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
+)
+
+// Commands only bot admins can use, in a private chat with the bot.
+var adminCommands = map[string]bool{
+	"announce": true, "broadcast": true, "stats": true,
+	"user": true, "ban": true, "unban": true, "scrape": true,
+}
+
+// isBotAdmin reports whether a chat belongs to a bot admin: listed in the
+// telegram.admins config, or given the admin role in the users table.
+func (k *KramerBot) isBotAdmin(chatID int64) bool {
+	if k.Config != nil && slices.Contains(k.Config.Telegram.Admins, chatID) {
+		return true
+	}
+	if k.DataWriter == nil {
+		return false
+	}
+	user, err := k.DataWriter.GetUser(chatID)
+	return err == nil && user != nil && user.Role == models.RoleAdmin
+}
+
+// isBanned reports whether an admin banned a chat.
+func (k *KramerBot) isBanned(chatID int64) bool {
+	if k.UserStore == nil {
+		return false
+	}
+	user := k.UserStore.GetUser(chatID)
+	return user != nil && user.Banned
+}
+
+// botStats is a summary of the bot's users for /stats.
+type botStats struct {
+	chats, groups             int
+	active, inactive, banned  int
+	ozbGood, ozbSuper         int
+	ozbTrending               int
+	expiryNotices             int
+	amzDaily, amzWeekly       int
+	keywords, watchers        int
+	dealsToday                int
+	dealsBySource             map[string]int
+	outboxPending, outboxDead int
+}
+
+// countUsers fills in the user counts of the stats. Subscriptions are only
+// counted for chats that are sent deals.
+func (s *botStats) countUsers(users map[int64]*models.UserData) {
+	for chatID, u := range users {
+		if u == nil {
+			continue
+		}
+		s.chats++
+		if chatID < 0 {
+			s.groups++
+		}
+		switch {
+		case u.Banned:
+			s.banned++
+			continue
+		case u.Inactive:
+			s.inactive++
+			continue
+		}
+		s.active++
+		if u.OzbGood {
+			s.ozbGood++
+		}
+		if u.OzbSuper {
+			s.ozbSuper++
+		}
+		if u.OzbTrending {
+			s.ozbTrending++
+		}
+		if u.AmzDaily {
+			s.amzDaily++
+		}
+		if u.AmzWeekly {
+			s.amzWeekly++
+		}
+		if u.ExpiryNotices {
+			s.expiryNotices++
+		}
+		if len(u.Keywords) > 0 {
+			s.watchers++
+			s.keywords += len(u.Keywords)
+		}
+	}
+}
+
+func (s *botStats) String() string {
+	var b strings.Builder
+	b.WriteString("📊 KramerBot stats\n\n")
+	fmt.Fprintf(&b, "Chats: %d (%d people, %d groups)\n", s.chats, s.chats-s.groups, s.groups)
+	fmt.Fprintf(&b, "Active: %d · Inactive: %d · Banned: %d\n\n", s.active, s.inactive, s.banned)
+	b.WriteString("Active subscriptions\n")
+	fmt.Fprintf(&b, "OzBargain regular: %d\nOzBargain top: %d\nOzBargain trending: %d\nAmazon daily: %d\nAmazon weekly: %d\n",
+		s.ozbGood, s.ozbSuper, s.ozbTrending, s.amzDaily, s.amzWeekly)
+	fmt.Fprintf(&b, "Expired deal notices: %d\n", s.expiryNotices)
+	fmt.Fprintf(&b, "Keywords: %d, watched by %d chats\n\n", s.keywords, s.watchers)
+	fmt.Fprintf(&b, "Deals today: %d", s.dealsToday)
+	if len(s.dealsBySource) > 0 {
+		var parts []string
+		for _, source := range slices.Sorted(maps.Keys(s.dealsBySource)) {
+			parts = append(parts, fmt.Sprintf("%s %d", source, s.dealsBySource[source]))
+		}
+		fmt.Fprintf(&b, " (%s)", strings.Join(parts, ", "))
+	}
+	fmt.Fprintf(&b, "\nOutbox: %d pending, %d dead", s.outboxPending, s.outboxDead)
+	return b.String()
+}
+
+// startOfToday is midnight in the daily summary's timezone.
+func (k *KramerBot) startOfToday() time.Time {
+	loc := time.UTC
+	if k.Config != nil && k.Config.API.SummaryTimezone != "" {
+		if l, err := time.LoadLocation(k.Config.API.SummaryTimezone); err == nil {
+			loc = l
+		}
+	}
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+}
+
+// ShowStats sends an admin the number of users, their active subscriptions
+// and the deals found today.
+func (k *KramerBot) ShowStats(chat *tgbotapi.Chat) {
+	stats := botStats{dealsBySource: make(map[string]int)}
+	stats.countUsers(k.UserStore.GetAllUsers())
+
+	if k.DealDB != nil {
+		since := k.startOfToday()
+		for _, src := range k.Sources.Sources() {
+			_, n, err := k.DealDB.QueryDeals(models.DealQuery{Source: src.Name(), Since: since, Limit: 1})
+			if err != nil {
+				k.Logger.Error("Failed to count today's deals", zap.String("source", src.Name()), zap.Error(err))
+				continue
+			}
+			stats.dealsBySource[src.Name()] = n
+			stats.dealsToday += n
+		}
+	}
+	if k.Outbox != nil {
+		if depth, err := k.Outbox.Depth(); err == nil {
+			stats.outboxPending, stats.outboxDead = depth.Pending, depth.Dead
+		}
+	}
+	k.SendMessage(chat.ID, stats.String())
+}
+
+// findUser looks up a user by chat ID or username, with or without the @.
+func (k *KramerBot) findUser(arg string) *models.UserData {
+	arg = strings.TrimSpace(arg)
+	if chatID, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		user, err := k.DataWriter.GetUser(chatID)
+		if err != nil {
+			return nil
+		}
+		return user
+	}
+
+	name := strings.TrimPrefix(arg, "@")
+	for _, u := range k.UserStore.GetAllUsers() {
+		if name != "" && strings.EqualFold(u.Username, name) {
+			user, err := k.DataWriter.GetUser(u.ChatID)
+			if err != nil {
+				return nil
+			}
+			return user
+		}
+	}
+	return nil
+}
+
+// userDetails describes a user for /user.
+func userDetails(u *models.UserData, account *models.WebUser) string {
+	status := "active"
+	switch {
+	case u.Banned:
+		status = "banned"
+	case u.Inactive:
+		status = "inactive (blocked the bot)"
+	}
+	role := u.Role
+	if role == "" {
+		role = "user"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "👤 %s (%d)\n", u.Username, u.ChatID)
+	fmt.Fprintf(&b, "Status: %s\nRole: %s\n", status, role)
+	fmt.Fprintf(&b, "OzBargain regular: %t\nOzBargain top: %t\nOzBargain trending: %t\nAmazon daily: %t\nAmazon weekly: %t\n",
+		u.OzbGood, u.OzbSuper, u.OzbTrending, u.AmzDaily, u.AmzWeekly)
+	fmt.Fprintf(&b, "Expired deal notices: %t\n", u.ExpiryNotices)
+	fmt.Fprintf(&b, "Keywords: %s", strings.Join(u.Keywords, ", "))
+	if len(u.MutedStores) > 0 {
+		fmt.Fprintf(&b, "\nMuted stores: %s", strings.Join(u.MutedStores, ", "))
+	}
+	if len(u.Categories) > 0 {
+		fmt.Fprintf(&b, "\nCategories: %s", formatCategorySubs(u.Categories, ", "))
+	}
+	if account != nil {
+		fmt.Fprintf(&b, "\nWeb account: %s", account.Email)
+	}
+	return b.String()
+}
+
+// ShowUser sends an admin the details of a user.
+func (k *KramerBot) ShowUser(chat *tgbotapi.Chat, arg string) {
+	if strings.TrimSpace(arg) == "" {
+		k.SendMessage(chat.ID, "Usage: /user <chat id or username>")
+		return
+	}
+	user := k.findUser(arg)
+	if user == nil {
+		k.SendMessage(chat.ID, fmt.Sprintf("No user '%s'.", arg))
+		return
+	}
+
+	var account *models.WebUser
+	if k.WebUserDB != nil {
+		account, _ = k.WebUserDB.GetWebUserByTelegramChatID(user.ChatID)
+	}
+	k.SendMessage(chat.ID, userDetails(user, account))
+}
+
+// SetBanned bans or unbans a user. Banned users are sent nothing and the bot
+// ignores their messages.
+func (k *KramerBot) SetBanned(chat *tgbotapi.Chat, arg string, banned bool) {
+	command := "/unban"
+	if banned {
+		command = "/ban"
+	}
+	if strings.TrimSpace(arg) == "" {
+		k.SendMessage(chat.ID, fmt.Sprintf("Usage: %s <chat id or username>", command))
+		return
+	}
+	user := k.findUser(arg)
+	if user == nil {
+		k.SendMessage(chat.ID, fmt.Sprintf("No user '%s'.", arg))
+		return
+	}
+	if banned && k.isBotAdmin(user.ChatID) {
+		k.SendMessage(chat.ID, "Admins cannot be banned.")
+		return
+	}
+
+	user.Banned = banned
+	if err := k.UpdateUser(user); err != nil {
+		k.Logger.Error("Failed to update ban", zap.Int64("chatID", user.ChatID), zap.Error(err))
+		k.SendMessage(chat.ID, "Sorry, the user could not be updated.")
+		return
+	}
+	k.UserStore.SetUser(user.ChatID, user)
+	k.Logger.Info("Changed ban", zap.Int64("chatID", user.ChatID), zap.Bool("banned", banned), zap.Int64("by", chat.ID))
+
+	if banned {
+		k.SendMessage(chat.ID, fmt.Sprintf("🚫 Banned %s (%d).", user.Username, user.ChatID))
+	} else {
+		k.SendMessage(chat.ID, fmt.Sprintf("✅ Unbanned %s (%d).", user.Username, user.ChatID))
+	}
+}
+
+// ScrapeNow scrapes every source, or the named one, straight away and sends
+// the new deals as usual. Arguments: now [source].
+func (k *KramerBot) ScrapeNow(chat *tgbotapi.Chat, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] != "now" || len(fields) > 2 {
+		k.SendMessage(chat.ID, "Usage: /scrape now [source]")
+		return
+	}
+	sources := k.Sources.Sources()
+	if len(fields) == 2 {
+		src := k.Sources.Get(strings.ToLower(fields[1]))
+		if src == nil {
+			k.SendMessage(chat.ID, fmt.Sprintf("Unknown source '%s'.", fields[1]))
+			return
+		}
+		sources = []scrapers.Source{src}
+	}
+
+	k.SendMessage(chat.ID, "⏳ Scraping...")
+	go func() {
+		var lines []string
+		for _, src := range sources {
+			start := time.Now()
+			if err := k.processSource(src); err != nil {
+				lines = append(lines, fmt.Sprintf("❌ %s: %s", src.DisplayName(), err))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("✅ %s: %d deals in %s", src.DisplayName(), len(src.GetDeals()),
+				time.Since(start).Round(time.Millisecond)))
+		}
+		k.SendMessage(chat.ID, "Scrape finished\n\n"+strings.Join(lines, "\n"))
+	}()
+}
+
+// sourceLock returns the lock held while a source is processed, so a
+// /scrape now does not overlap the source's scheduled scrape.
+func (k *KramerBot) sourceLock(name string) *sync.Mutex {
+	mu, _ := k.scrapeLocks.LoadOrStore(name, &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
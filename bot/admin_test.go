@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+)
+
+func TestBotStats(t *testing.T) {
+	users := map[int64]*models.UserData{
+		1:    {ChatID: 1, OzbGood: true, Keywords: []string{"ssd", "ps5"}},
+		2:    {ChatID: 2, OzbSuper: true, OzbTrending: true, AmzDaily: true, ExpiryNotices: true},
+		3:    {ChatID: 3, OzbGood: true, Inactive: true},
+		4:    {ChatID: 4, AmzWeekly: true, Banned: true},
+		-100: {ChatID: -100, OzbSuper: true, Keywords: []string{"lego"}},
+	}
+	s := botStats{dealsToday: 12, dealsBySource: map[string]int{"ozbargain": 10, "amazon": 2}, outboxPending: 3}
+	s.countUsers(users)
+
+	want := `📊 KramerBot stats
+
+Chats: 5 (4 people, 1 groups)
+Active: 3 · Inactive: 1 · Banned: 1
+
+Active subscriptions
+OzBargain regular: 1
+OzBargain top: 2
+OzBargain trending: 1
+Amazon daily: 1
+Amazon weekly: 0
+Expired deal notices: 1
+Keywords: 3, watched by 2 chats
+
+Deals today: 12 (amazon 2, ozbargain 10)
+Outbox: 3 pending, 0 dead`
+	if got := s.String(); got != want {
+		t.Errorf("stats =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUserDetails(t *testing.T) {
+	u := &models.UserData{ChatID: 7, Username: "kramer", Banned: true, OzbSuper: true, Keywords: []string{"ssd"}, MutedStores: []string{"Kogan"}}
+	got := userDetails(u, &models.WebUser{Email: "kramer@example.com"})
+	for _, want := range []string{"kramer (7)", "Status: banned", "Role: user", "OzBargain top: true", "Keywords: ssd", "Muted stores: Kogan", "Web account: kramer@example.com"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("userDetails() = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestBroadcastRecipients(t *testing.T) {
+	users := map[int64]*models.UserData{
+		3:  {ChatID: 3},
+		1:  {ChatID: 1},
+		2:  {ChatID: 2, Inactive: true},
+		4:  {ChatID: 4, Banned: true},
+		-5: {ChatID: -5},
+	}
+	got := broadcastRecipients(users)
+	if len(got) != 3 || got[0] != -5 || got[1] != 1 || got[2] != 3 {
+		t.Errorf("broadcastRecipients() = %v, want [-5 1 3]", got)
+	}
+}
+
+func TestBroadcastTracker(t *testing.T) {
+	var tr broadcastTracker
+	batch := tr.start(42, 3)
+	other := tr.start(43, 1)
+	if batch == other {
+		t.Fatal("batch IDs repeat")
+	}
+
+	if r := tr.done(batch, nil); r != nil {
+		t.Fatalf("report after 1 of 3 deliveries: %v", r)
+	}
+	if r := tr.done("unknown", nil); r != nil {
+		t.Fatalf("report for an unknown batch: %v", r)
+	}
+	if r := tr.done(batch, tgbotapi.Error{Message: "Forbidden: bot was blocked by the user"}); r != nil {
+		t.Fatalf("report after 2 of 3 deliveries: %v", r)
+	}
+	r := tr.done(batch, errors.New("connection reset"))
+	if r == nil {
+		t.Fatal("no report after the last delivery")
+	}
+	if r.adminChatID != 42 || r.sent != 1 || r.total != 3 {
+		t.Errorf("report = %+v", r)
+	}
+	if got := r.String(); !strings.HasPrefix(got, "📬 Broadcast delivered to 1 of 3 chats") ||
+		!strings.HasSuffix(got, "Failed: blocked 1, transient 1") {
+		t.Errorf("report text = %q", got)
+	}
+	if r := tr.done(batch, nil); r != nil {
+		t.Error("finished broadcast still tracked")
+	}
+}
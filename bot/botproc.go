@@ -8,34 +8,65 @@ import (
 )
 
 func (k *KramerBot) BotProc(updates tgbotapi.UpdatesChannel) {
-	var announceMode bool = false
-
 	// keep watching updates channel
 	for update := range updates {
+		if update.CallbackQuery != nil {
+			k.HandleCallback(update.CallbackQuery)
+			continue
+		}
+		if update.InlineQuery != nil {
+			if !k.isBanned(int64(update.InlineQuery.From.ID)) {
+				k.HandleInlineQuery(update.InlineQuery)
+			}
+			continue
+		}
 		if update.Message == nil {
 			continue
 		}
 
 		k.Logger.Info("Received message", zap.String("text", update.Message.Text), zap.Int64("chatID", update.Message.Chat.ID))
 
-		if announceMode {
-			if k.verifyAdminPassword(update.Message.Text) {
-				k.MakeAnnouncement(update.Message.Chat, update.Message.Text)
-			} else {
-				k.SendMessage(update.Message.Chat.ID, "⛔ Admin password incorrect.")
-			}
+		if k.isBanned(update.Message.Chat.ID) {
+			continue
+		}
+
+		// The group was upgraded to a supergroup, which has a new chat ID
+		if update.Message.MigrateToChatID != 0 {
+			k.migrateChat(update.Message.Chat.ID, update.Message.MigrateToChatID)
+			continue
+		}
 
-			announceMode = false
+		// Answers to a multi-step command such as /announce
+		if !update.Message.IsCommand() && k.continueDialog(update.Message) {
 			continue
 		}
 
 		// Check if the message is a command
 		if update.Message.IsCommand() {
+			if !k.addressedToBot(update.Message) {
+				continue // for another bot in the group
+			}
 			command := update.Message.Command()
 			args := update.Message.CommandArguments()
 
 			k.Logger.Info("Received command", zap.String("command", command), zap.String("args", args), zap.Int64("chatID", update.Message.Chat.ID))
 
+			if adminCommands[command] && (!update.Message.Chat.IsPrivate() || !k.isBotAdmin(update.Message.Chat.ID)) {
+				k.SendMessage(update.Message.Chat.ID, "⛔ This command is only for bot admins, in a private chat with the bot.")
+				continue
+			}
+			if configCommands[command] && !k.canConfigure(update.Message.Chat, update.Message.From) {
+				k.SendMessage(update.Message.Chat.ID, "⛔ Only group admins can change this group's deal subscriptions.")
+				continue
+			}
+
+			// A new command abandons any dialog in progress
+			if command == "cancel" {
+				k.CancelDialog(update.Message)
+				continue
+			}
+			k.dialogs.clear(messageDialogKey(update.Message))
+
 			switch command {
 			case "start", "register":
 				if args != "" {
@@ -48,24 +79,61 @@ func (k *KramerBot) BotProc(updates tgbotapi.UpdatesChannel) {
 			case "help":
 				k.Help(update.Message.Chat)
 				continue
+			case "settings":
+				k.ShowSettings(update.Message.Chat)
+				continue
 			case "preferences", "status":
 				k.ShowPreferences(update.Message.Chat)
 				continue
+			case "search":
+				k.SearchDeals(update.Message.Chat, args)
+				continue
+			case "top":
+				k.TopDeals(update.Message.Chat, args)
+				continue
+			case "latest":
+				k.LatestDeals(update.Message.Chat, args)
+				continue
+			case "saved":
+				k.ShowSavedDeals(update.Message.Chat)
+				continue
+			case "unmutestore":
+				k.UnmuteStore(update.Message.Chat, args)
+				continue
 			case "listkeywords":
 				k.ListKeywords(update.Message.Chat)
 				continue
 			case "addkeyword":
-				k.AddKeyword(update.Message.Chat, args)
+				if strings.TrimSpace(args) == "" {
+					k.StartAddKeyword(update.Message)
+				} else {
+					k.AddKeyword(update.Message.Chat, args)
+				}
 				continue
 			case "removekeyword":
 				k.RemoveKeyword(update.Message.Chat, args)
 				continue
+			case "categories":
+				k.ListCategories(update.Message.Chat)
+				continue
+			case "addcategory":
+				k.AddCategory(update.Message.Chat, args)
+				continue
+			case "removecategory":
+				k.RemoveCategory(update.Message.Chat, args)
+				continue
 			case "ozbgood":
 				k.ToggleOzbGood(update.Message.Chat)
 				continue
 			case "ozbsuper":
 				k.ToggleOzbSuper(update.Message.Chat)
 				continue
+			case "ozbtrending":
+				k.ToggleOzbTrending(update.Message.Chat)
+				continue
+			case "expirynotices":
+				k.ToggleExpiryNotices(update.Message.Chat)
+				continue
 			case "amzdaily":
 				k.ToggleAmzDaily(update.Message.Chat)
 				continue
@@ -75,27 +143,35 @@ func (k *KramerBot) BotProc(updates tgbotapi.UpdatesChannel) {
 			case "test":
 				k.SendTestMessage(update.Message.Chat)
 				continue
-			case "announce": // Admin command
-				if !announceMode {
-					k.SendMessage(update.Message.Chat.ID, "Enter admin password and announcement in format password:announcement")
-				}
-				announceMode = !announceMode // Toggle announce mode
+			case "broadcast", "announce": // Admin commands
+				k.StartBroadcast(update.Message)
+				continue
+			case "stats":
+				k.ShowStats(update.Message.Chat)
+				continue
+			case "user":
+				k.ShowUser(update.Message.Chat, args)
+				continue
+			case "ban":
+				k.SetBanned(update.Message.Chat, args, true)
+				continue
+			case "unban":
+				k.SetBanned(update.Message.Chat, args, false)
+				continue
+			case "scrape":
+				k.ScrapeNow(update.Message.Chat, args)
 				continue
 			default:
-				// Unknown command - show help banner
-				k.Help(update.Message.Chat)
+				// Unknown command - show help banner, unless it's group chatter
+				if !isGroup(update.Message.Chat) {
+					k.Help(update.Message.Chat)
+				}
 				continue
 			}
 		}
 
-		// If it's not a command and not in announce mode, treat as potential announcement password/message
-		if announceMode {
-			if k.verifyAdminPassword(update.Message.Text) {
-				k.MakeAnnouncement(update.Message.Chat, update.Message.Text)
-			} else {
-				k.SendMessage(update.Message.Chat.ID, "⛔ Admin password incorrect or invalid format.")
-			}
-			announceMode = false // Reset announce mode after attempt
+		// Groups only talk to the bot with commands
+		if isGroup(update.Message.Chat) {
 			continue
 		}
 
@@ -104,13 +180,39 @@ func (k *KramerBot) BotProc(updates tgbotapi.UpdatesChannel) {
 	}
 }
 
-// Verify pass for administrative function
-func (k *KramerBot) verifyAdminPassword(message string) bool {
-	messages := strings.Split(message, ":")
-	if len(messages) == 2 {
-		if strings.EqualFold(strings.ToLower(messages[0]), strings.ToLower(k.GetAdminPass())) {
-			return true
-		}
+// HandleCallback handles a tap on an inline keyboard button, routing it by the
+// prefix of its callback data.
+func (k *KramerBot) HandleCallback(query *tgbotapi.CallbackQuery) {
+	k.Logger.Info("Received callback", zap.String("data", query.Data), zap.Int("from", query.From.ID))
+
+	if query.Message != nil && k.isBanned(query.Message.Chat.ID) {
+		k.answerCallback(query, "")
+		return
+	}
+
+	if query.Message != nil && !strings.HasPrefix(query.Data, pagePrefix) && !k.canConfigure(query.Message.Chat, query.From) {
+		k.answerCallback(query, "Only group admins can change this group's deal subscriptions.")
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(query.Data, settingsPrefix):
+		k.handleSettingsCallback(query, strings.TrimPrefix(query.Data, settingsPrefix))
+	case strings.HasPrefix(query.Data, pagePrefix):
+		k.handlePageCallback(query, strings.TrimPrefix(query.Data, pagePrefix))
+	case strings.HasPrefix(query.Data, dialogPrefix):
+		k.handleDialogCallback(query, strings.TrimPrefix(query.Data, dialogPrefix))
+	case isDealAction(query.Data):
+		k.handleDealAction(query)
+	default:
+		k.answerCallback(query, "")
+	}
+}
+
+// answerCallback stops the button's loading spinner, showing text as a
+// notification if it is not empty.
+func (k *KramerBot) answerCallback(query *tgbotapi.CallbackQuery, text string) {
+	if _, err := k.BotApi.AnswerCallbackQuery(tgbotapi.NewCallback(query.ID, text)); err != nil {
+		k.Logger.Warn("Failed to answer callback query", zap.String("id", query.ID), zap.Error(err))
 	}
-	return false
 }
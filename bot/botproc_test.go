@@ -1,72 +1,42 @@
 package bot
 
 import (
+	"path/filepath"
 	"testing"
 
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/intothevoid/kramerbot/models"
-	persist "github.com/intothevoid/kramerbot/persist"
-	"github.com/intothevoid/kramerbot/pipup"
-	"github.com/intothevoid/kramerbot/scrapers"
+	sqlite_persist "github.com/intothevoid/kramerbot/persist/sqlite"
 	"github.com/intothevoid/kramerbot/util"
 	"go.uber.org/zap"
 )
 
-func TestKramerBot_verifyAdminPassword(t *testing.T) {
-	type fields struct {
-		Token      string
-		Logger     *zap.Logger
-		BotApi     *tgbotapi.BotAPI
-		OzbScraper *scrapers.OzBargainScraper
-		CCCScraper *scrapers.CamCamCamScraper
-		UserStore  *models.UserStore
-		DataWriter persist.DatabaseIF
-		Pipup      *pipup.Pipup
-		Config     *util.Config
+func TestKramerBot_isBotAdmin(t *testing.T) {
+	db, err := sqlite_persist.NewSQLiteWrapper(filepath.Join(t.TempDir(), "admin_test.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
 	}
-	type args struct {
-		message string
+	t.Cleanup(func() { db.Close() })
+	for _, u := range []*models.UserData{{ChatID: 2, Role: models.RoleAdmin}, {ChatID: 3}} {
+		if err := db.AddUser(u); err != nil {
+			t.Fatal(err)
+		}
 	}
+	k := &KramerBot{DataWriter: db, Config: &util.Config{Telegram: util.TelegramConfig{Admins: []int64{1}}}}
+
 	tests := []struct {
 		name   string
-		fields fields
-		args   args
+		chatID int64
 		want   bool
 	}{
-		{
-			name: "test1",
-			fields: fields{
-				Token:      "test",
-				Logger:     nil,
-				BotApi:     nil,
-				OzbScraper: nil,
-				CCCScraper: nil,
-				UserStore:  nil,
-				DataWriter: nil,
-				Pipup:      nil,
-				Config:     nil,
-			},
-			args: args{
-				message: "testpassword:this is a test announcement",
-			},
-			want: false,
-		},
+		{"admin in config", 1, true},
+		{"admin role", 2, true},
+		{"user", 3, false},
+		{"unknown chat", 4, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			k := &KramerBot{
-				Token:      tt.fields.Token,
-				Logger:     tt.fields.Logger,
-				BotApi:     tt.fields.BotApi,
-				OzbScraper: tt.fields.OzbScraper,
-				CCCScraper: tt.fields.CCCScraper,
-				UserStore:  tt.fields.UserStore,
-				DataWriter: tt.fields.DataWriter,
-				Pipup:      tt.fields.Pipup,
-				Config:     tt.fields.Config,
-			}
-			if got := k.verifyAdminPassword(tt.args.message); got != tt.want {
-				t.Errorf("KramerBot.verifyAdminPassword() = %v, want %v", got, tt.want)
+			if got := k.isBotAdmin(tt.chatID); got != tt.want {
+				t.Errorf("KramerBot.isBotAdmin() = %v, want %v", got, tt.want)
 			}
 		})
 	}
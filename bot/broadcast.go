@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+// broadcastReport counts the deliveries of one broadcast.
+type broadcastReport struct {
+	adminChatID int64 // where the delivery report is sent
+	total       int
+	sent        int
+	failed      map[string]int // failed deliveries by kind of Telegram error
+	started     time.Time
+}
+
+// finished reports whether every message of the broadcast was delivered or
+// given up on.
+func (r *broadcastReport) finished() bool {
+	failed := 0
+	for _, n := range r.failed {
+		failed += n
+	}
+	return r.sent+failed >= r.total
+}
+
+// String is the delivery report sent to the admin.
+func (r *broadcastReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📬 Broadcast delivered to %d of %d chats in %s.", r.sent, r.total,
+		time.Since(r.started).Round(time.Second))
+	if len(r.failed) > 0 {
+		var reasons []string
+		for _, kind := range slices.Sorted(maps.Keys(r.failed)) {
+			reasons = append(reasons, fmt.Sprintf("%s %d", kind, r.failed[kind]))
+		}
+		fmt.Fprintf(&b, "\nFailed: %s", strings.Join(reasons, ", "))
+	}
+	return b.String()
+}
+
+// broadcastTracker follows the broadcasts being delivered, by batch ID. The
+// zero value is ready to use. Broadcasts still being delivered when the bot
+// restarts are delivered without a report.
+type broadcastTracker struct {
+	mu      sync.Mutex
+	next    int
+	reports map[string]*broadcastReport
+}
+
+// start begins tracking a broadcast to total chats and returns its batch ID.
+func (t *broadcastTracker) start(adminChatID int64, total int) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.reports == nil {
+		t.reports = make(map[string]*broadcastReport)
+	}
+	t.next++
+	batch := strconv.FormatInt(time.Now().Unix(), 36) + "-" + strconv.Itoa(t.next)
+	t.reports[batch] = &broadcastReport{adminChatID: adminChatID, total: total, failed: make(map[string]int), started: time.Now()}
+	return batch
+}
+
+// done records the outcome of one message of a broadcast. Once every message
+// is accounted for it returns the finished report and stops tracking it.
+func (t *broadcastTracker) done(batch string, err error) *broadcastReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := t.reports[batch]
+	if r == nil {
+		return nil
+	}
+	if err == nil {
+		r.sent++
+	} else {
+		kind, _ := ClassifyTelegramError(err)
+		r.failed[kind.String()]++
+	}
+	if !r.finished() {
+		return nil
+	}
+	delete(t.reports, batch)
+	return r
+}
+
+// announcementText formats an announcement as it is sent to users.
+func announcementText(message string) string {
+	return fmt.Sprintf(`📢 Kramerbot Announcement 📢 %s`, message)
+}
+
+// broadcastRecipients are the chats an announcement is sent to: every user
+// and group that is neither inactive nor banned.
+func broadcastRecipients(users map[int64]*models.UserData) []int64 {
+	var chatIDs []int64
+	for chatID, user := range users {
+		if user != nil && !user.Inactive && !user.Banned {
+			chatIDs = append(chatIDs, chatID)
+		}
+	}
+	slices.Sort(chatIDs)
+	return chatIDs
+}
+
+// Broadcast sends an announcement to every active chat through the outbox and
+// reports back to the admin once all of them were delivered or given up on.
+func (k *KramerBot) Broadcast(admin *tgbotapi.Chat, message string) {
+	recipients := broadcastRecipients(k.UserStore.GetAllUsers())
+	if len(recipients) == 0 {
+		k.SendMessage(admin.ID, "There is nobody to send the announcement to.")
+		return
+	}
+	text := announcementText(message)
+
+	batch := k.broadcasts.start(admin.ID, len(recipients))
+	k.Logger.Info("Broadcasting announcement", zap.String("batch", batch), zap.Int("chats", len(recipients)))
+	k.SendMessage(admin.ID, fmt.Sprintf("Announcement queued for %d chats. You will get a delivery report when it has been sent.", len(recipients)))
+
+	for _, chatID := range recipients {
+		if k.Outbox == nil {
+			err := k.sendOutboxMessage(models.OutboxMessage{ChatID: chatID, Text: text, Batch: batch})
+			k.broadcastDelivered(batch, err)
+			continue
+		}
+		if err := k.Outbox.EnqueueBatch(batch, chatID, text, ""); err != nil {
+			k.Logger.Error("Failed to queue announcement", zap.Int64("chat_id", chatID), zap.Error(err))
+			k.broadcastDelivered(batch, err)
+		}
+	}
+}
+
+// broadcastDelivered records the outcome of one message of a broadcast and
+// sends the delivery report when it was the last.
+func (k *KramerBot) broadcastDelivered(batch string, err error) {
+	if batch == "" {
+		return
+	}
+	if r := k.broadcasts.done(batch, err); r != nil {
+		k.Logger.Info("Broadcast finished", zap.String("batch", batch), zap.Int("sent", r.sent), zap.Int("total", r.total))
+		k.SendMessage(r.adminChatID, r.String())
+	}
+}
+
+// outboxSent is called by the outbox after Telegram accepts a message.
+func (k *KramerBot) outboxSent(m models.OutboxMessage) {
+	k.broadcastDelivered(m.Batch, nil)
+}
+
+// outboxDead is called by the outbox after it gives up on a message.
+func (k *KramerBot) outboxDead(m models.OutboxMessage, err error) {
+	k.handleUndeliverable(m, err)
+	k.broadcastDelivered(m.Batch, err)
+}
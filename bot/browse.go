@@ -0,0 +1,264 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
+)
+
+const (
+	pagePrefix     = "page:"   // callback data: pagePrefix + token + ":" + page
+	pageSize       = 5         // deals per page
+	maxResults     = 50        // deals kept per result set
+	resultsTTL     = time.Hour // how long the pages of a result can be browsed
+	defaultTopAge  = 24        // hours, /top without an argument
+	maxTopAge      = 24 * 7    // hours
+	defaultLatestN = 10
+)
+
+// resultSet is a command's deals, kept so its pages can be browsed.
+type resultSet struct {
+	title   string
+	deals   []models.Deal
+	created time.Time
+}
+
+// resultPages holds recent result sets by token. The zero value is ready to use.
+type resultPages struct {
+	mu   sync.Mutex
+	next uint64
+	sets map[string]*resultSet
+}
+
+// add stores a result set and returns its token, dropping expired sets.
+func (p *resultPages) add(rs *resultSet) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sets == nil {
+		p.sets = make(map[string]*resultSet)
+	}
+	for token, s := range p.sets {
+		if time.Since(s.created) > resultsTTL {
+			delete(p.sets, token)
+		}
+	}
+	p.next++
+	token := strconv.FormatUint(p.next, 36)
+	p.sets[token] = rs
+	return token
+}
+
+// get returns the result set for token, or nil if it expired.
+func (p *resultPages) get(token string) *resultSet {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rs := p.sets[token]
+	if rs == nil || time.Since(rs.created) > resultsTTL {
+		return nil
+	}
+	return rs
+}
+
+// SearchDeals sends the cached deals matching a query, best ranked first.
+func (k *KramerBot) SearchDeals(chat *tgbotapi.Chat, query string) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		k.SendMessage(chat.ID, "Please provide something to search for. Usage: /search <query>\n\n"+keywordSyntaxHelp)
+		return
+	}
+	deals := searchCachedDeals(k.Sources.AllDeals(), query, time.Now(), maxResults)
+	k.sendResults(chat.ID, fmt.Sprintf("🔎 Deals matching '%s'", query), deals)
+}
+
+// TopDeals sends the OzBargain top deals posted in the last few hours, most
+// upvoted first. Stored deals are used when available, as they carry the
+// expiry found when re-checking sent deals.
+func (k *KramerBot) TopDeals(chat *tgbotapi.Chat, args string) {
+	hours := defaultTopAge
+	if args = strings.TrimSpace(args); args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 || n > maxTopAge {
+			k.SendMessage(chat.ID, fmt.Sprintf("Usage: /top [hours], where hours is between 1 and %d.", maxTopAge))
+			return
+		}
+		hours = n
+	}
+	maxAge := time.Duration(hours) * time.Hour
+	deals := k.Sources.AllDeals()
+	if k.DealDB != nil {
+		stored, _, err := k.DealDB.QueryDeals(models.DealQuery{
+			Source: scrapers.SOURCE_OZBARGAIN,
+			Since:  time.Now().Add(-maxAge),
+			Active: true,
+		})
+		if err != nil {
+			k.Logger.Error("Failed to load top deals", zap.Error(err))
+		} else {
+			deals = stored
+		}
+	}
+	deals = topOzbDeals(deals, maxAge)
+	k.sendResults(chat.ID, fmt.Sprintf("🔥 OzBargain top deals, last %dh", hours), deals)
+}
+
+// LatestDeals sends the newest deals, optionally from a single source.
+// Arguments: [n] [source], in either order.
+func (k *KramerBot) LatestDeals(chat *tgbotapi.Chat, args string) {
+	n := defaultLatestN
+	var src scrapers.Source
+	for _, arg := range strings.Fields(args) {
+		if v, err := strconv.Atoi(arg); err == nil && v > 0 {
+			n = min(v, maxResults)
+		} else if src = k.Sources.Get(strings.ToLower(arg)); src == nil {
+			var names []string
+			for _, s := range k.Sources.Sources() {
+				names = append(names, s.Name())
+			}
+			k.SendMessage(chat.ID, fmt.Sprintf("Unknown source '%s'. Usage: /latest [n] [source], where source is one of: %s",
+				arg, strings.Join(names, ", ")))
+			return
+		}
+	}
+
+	title := "🆕 Latest deals"
+	var deals []models.Deal
+	if src != nil {
+		title = "🆕 Latest " + src.DisplayName() + " deals"
+		deals = src.GetDeals()
+	} else {
+		deals = k.Sources.AllDeals()
+	}
+	k.sendResults(chat.ID, title, latestDeals(deals, n))
+}
+
+// topOzbDeals returns the OzBargain deals posted within maxAge with the
+// default top deal votes, most upvoted first. Votes are checked here rather
+// than the stored deal type, which only marks deals under a day old as top.
+func topOzbDeals(deals []models.Deal, maxAge time.Duration) []models.Deal {
+	now := time.Now()
+	rule := models.TopDealRule{MinVotes: models.DefaultTopDealRule.MinVotes, MaxAgeHours: int(maxAge.Hours())}
+	top := []models.Deal{}
+	for _, d := range uniqueDeals(deals) {
+		if d.Source == scrapers.SOURCE_OZBARGAIN && !d.PostedAt.IsZero() && rule.Matches(&d, now) && !d.IsExpired(now) {
+			top = append(top, d)
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool {
+		return top[i].Votes() > top[j].Votes()
+	})
+	if len(top) > maxResults {
+		top = top[:maxResults]
+	}
+	return top
+}
+
+// latestDeals returns the n most recently posted deals. Deals without a
+// posting time keep their cache order, after the ones with one.
+func latestDeals(deals []models.Deal, n int) []models.Deal {
+	latest := uniqueDeals(deals)
+	sort.SliceStable(latest, func(i, j int) bool {
+		return latest[i].PostedAt.After(latest[j].PostedAt)
+	})
+	if len(latest) > n {
+		latest = latest[:n]
+	}
+	return latest
+}
+
+// uniqueDeals drops repeats of a deal cached under more than one deal type.
+func uniqueDeals(deals []models.Deal) []models.Deal {
+	seen := make(map[string]bool)
+	unique := []models.Deal{}
+	for _, d := range deals {
+		if key := d.Source + ":" + d.Id; !seen[key] {
+			seen[key] = true
+			unique = append(unique, d)
+		}
+	}
+	return unique
+}
+
+// sendResults sends the first page of a command's deals.
+func (k *KramerBot) sendResults(chatID int64, title string, deals []models.Deal) {
+	if len(deals) == 0 {
+		k.SendMessage(chatID, title+"\n\nNo deals found.")
+		return
+	}
+
+	token := k.pages.add(&resultSet{title: title, deals: deals, created: time.Now()})
+	text, buttons := renderPage(title, deals, token, 0)
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.DisableWebPagePreview = true
+	if len(buttons) > 0 {
+		msg.ReplyMarkup = inlineKeyboard(buttons)
+	}
+	if _, err := k.BotApi.Send(msg); err != nil {
+		k.Logger.Error("Failed to send results", zap.Int64("chatID", chatID), zap.Error(err))
+	}
+}
+
+// handlePageCallback shows another page of a result in place.
+func (k *KramerBot) handlePageCallback(query *tgbotapi.CallbackQuery, data string) {
+	token, pageArg, _ := strings.Cut(data, ":")
+	page, err := strconv.Atoi(pageArg)
+	rs := k.pages.get(token)
+	if query.Message == nil || err != nil || rs == nil {
+		k.answerCallback(query, "These results have expired. Please run the command again.")
+		return
+	}
+
+	text, buttons := renderPage(rs.title, rs.deals, token, page)
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, text)
+	edit.ParseMode = tgbotapi.ModeHTML
+	edit.DisableWebPagePreview = true
+	if len(buttons) > 0 {
+		keyboard := inlineKeyboard(buttons)
+		edit.ReplyMarkup = &keyboard
+	}
+	if _, err := k.BotApi.Send(edit); err != nil && !strings.Contains(err.Error(), "message is not modified") {
+		k.Logger.Error("Failed to show results page", zap.Int64("chatID", query.Message.Chat.ID), zap.Error(err))
+	}
+	k.answerCallback(query, "")
+}
+
+// renderPage formats one page of deals, with buttons to the pages either side.
+func renderPage(title string, deals []models.Deal, token string, page int) (string, [][]models.InlineButton) {
+	pages := (len(deals) + pageSize - 1) / pageSize
+	page = max(min(page, pages-1), 0)
+
+	var b strings.Builder
+	b.WriteString(html.EscapeString(title))
+	if pages > 1 {
+		fmt.Fprintf(&b, " (page %d/%d)", page+1, pages)
+	}
+	b.WriteString("\n")
+	for i := page * pageSize; i < min((page+1)*pageSize, len(deals)); i++ {
+		d := &deals[i]
+		fmt.Fprintf(&b, "\n%d. <a href=\"%s\">%s</a>", i+1, html.EscapeString(d.Url), html.EscapeString(d.Title))
+		if detail := dealDetail(d); detail != "" {
+			b.WriteString(" " + html.EscapeString(detail))
+		}
+	}
+
+	var nav []models.InlineButton
+	if page > 0 {
+		nav = append(nav, models.InlineButton{Text: "◀ Prev", Data: fmt.Sprintf("%s%s:%d", pagePrefix, token, page-1)})
+	}
+	if page < pages-1 {
+		nav = append(nav, models.InlineButton{Text: "Next ▶", Data: fmt.Sprintf("%s%s:%d", pagePrefix, token, page+1)})
+	}
+	if len(nav) == 0 {
+		return b.String(), nil
+	}
+	return b.String(), [][]models.InlineButton{nav}
+}
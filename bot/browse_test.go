@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+)
+
+func dealIDs(deals []models.Deal) string {
+	ids := []string{}
+	for _, d := range deals {
+		ids = append(ids, d.Id)
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestTopOzbDeals(t *testing.T) {
+	now := time.Now()
+	deals := []models.Deal{
+		{Source: "ozbargain", Id: "a", DealType: int(scrapers.OZB_SUPER), Upvotes: "30", PostedAt: now.Add(-2 * time.Hour)},
+		{Source: "ozbargain", Id: "b", DealType: int(scrapers.OZB_SUPER), Upvotes: "90", PostedAt: now.Add(-20 * time.Hour)},
+		{Source: "ozbargain", Id: "c", DealType: int(scrapers.OZB_REG), Upvotes: "200", PostedAt: now.Add(-30 * time.Hour)}, // re-scraped after a day
+		{Source: "ozbargain", Id: "d", DealType: int(scrapers.OZB_REG), Upvotes: "10", PostedAt: now.Add(-time.Hour)},
+		{Source: "amazon", Id: "e", DealType: int(scrapers.AMZ_DAILY), Upvotes: "500", PostedAt: now.Add(-time.Hour)},
+		{Source: "ozbargain", Id: "f", DealType: int(scrapers.OZB_SUPER), Upvotes: "999"}, // no posting time
+		{Source: "ozbargain", Id: "g", DealType: int(scrapers.OZB_SUPER), Upvotes: "400", PostedAt: now.Add(-time.Hour), Expiry: models.DealExpired},
+		{Source: "ozbargain", Id: "h", DealType: int(scrapers.OZB_SUPER), Upvotes: "300", PostedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)},
+		{Source: "ozbargain", Id: "i", DealType: int(scrapers.OZB_REG), Upvotes: "150", PostedAt: now.Add(-100 * time.Hour)},
+	}
+	tests := []struct {
+		maxAge time.Duration
+		want   string
+	}{
+		{24 * time.Hour, "[b a]"},
+		{3 * time.Hour, "[a]"},
+		{48 * time.Hour, "[c b a]"},
+		{168 * time.Hour, "[c i b a]"},
+	}
+	for _, tt := range tests {
+		if got := dealIDs(topOzbDeals(deals, tt.maxAge)); got != tt.want {
+			t.Errorf("topOzbDeals(%v) = %s, want %s", tt.maxAge, got, tt.want)
+		}
+	}
+}
+
+func TestLatestDeals(t *testing.T) {
+	now := time.Now()
+	deals := []models.Deal{
+		{Source: "amazon", Id: "x"},
+		{Source: "ozbargain", Id: "old", PostedAt: now.Add(-time.Hour)},
+		{Source: "ozbargain", Id: "new", PostedAt: now},
+		{Source: "amazon", Id: "y"},
+		{Source: "amazon", Id: "x"}, // weekly copy of a daily deal
+	}
+	if got := dealIDs(latestDeals(deals, 10)); got != "[new old x y]" {
+		t.Errorf("latestDeals(10) = %s", got)
+	}
+	if got := dealIDs(latestDeals(deals, 2)); got != "[new old]" {
+		t.Errorf("latestDeals(2) = %s", got)
+	}
+}
+
+func TestRenderPage(t *testing.T) {
+	var deals []models.Deal
+	for i := 1; i <= 12; i++ {
+		deals = append(deals, models.Deal{Id: fmt.Sprint(i), Title: fmt.Sprintf("Deal <%d>", i), Url: "https://example.com", Upvotes: "5"})
+	}
+
+	tests := []struct {
+		page      int
+		wantFirst string
+		wantNav   string
+	}{
+		{0, "1. ", "page:t:1"},
+		{1, "6. ", "page:t:0 page:t:2"},
+		{2, "11. ", "page:t:1"},
+		{9, "11. ", "page:t:1"}, // past the end shows the last page
+	}
+	for _, tt := range tests {
+		text, buttons := renderPage("🔥 Top", deals, "t", tt.page)
+		lines := strings.Split(text, "\n")
+		if !strings.HasPrefix(lines[2], tt.wantFirst) {
+			t.Errorf("page %d starts with %q, want %q", tt.page, lines[2], tt.wantFirst)
+		}
+		if got := buttonData(buttons); got != tt.wantNav {
+			t.Errorf("page %d buttons = %q, want %q", tt.page, got, tt.wantNav)
+		}
+	}
+
+	text, buttons := renderPage("One", deals[:1], "t", 0)
+	want := "One\n\n1. <a href=\"https://example.com\">Deal &lt;1&gt;</a> 🔺5"
+	if text != want || buttons != nil {
+		t.Errorf("single page = %q %v, want %q without buttons", text, buttons, want)
+	}
+}
+
+func TestResultPages(t *testing.T) {
+	var p resultPages
+	fresh := p.add(&resultSet{title: "fresh", created: time.Now()})
+	stale := p.add(&resultSet{title: "stale", created: time.Now().Add(-2 * resultsTTL)})
+	if fresh == stale {
+		t.Fatal("tokens repeat")
+	}
+	if rs := p.get(fresh); rs == nil || rs.title != "fresh" {
+		t.Errorf("get(fresh) = %+v", rs)
+	}
+	if rs := p.get(stale); rs != nil {
+		t.Errorf("get(stale) = %+v, want expired", rs)
+	}
+	p.add(&resultSet{created: time.Now()})
+	if len(p.sets) != 2 {
+		t.Errorf("%d result sets kept, want the expired one dropped", len(p.sets))
+	}
+}
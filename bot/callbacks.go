@@ -8,38 +8,38 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/intothevoid/kramerbot/models"
-	"github.com/intothevoid/kramerbot/scrapers"
 	"github.com/intothevoid/kramerbot/util"
+	"github.com/intothevoid/kramerbot/watch"
 )
 
 // welcomeMessage returns the standard welcome text including the web app URL.
-func (k *KramerBot) welcomeMessage(firstName string) string {
+func (k *KramerBot) welcomeMessage(name string) string {
 	webURL := "http://localhost:8080"
 	if k.Config != nil && k.Config.API.WebURL != "" {
 		webURL = k.Config.API.WebURL
 	}
-	return fmt.Sprintf("👋 Welcome to KramerBot - Aussie Deals, %s!\n\nManage your deal preferences and subscriptions at:\n%s", firstName, webURL)
+	return fmt.Sprintf("👋 Welcome to KramerBot - Aussie Deals, %s!\n\nManage your deal preferences and subscriptions at:\n%s\n\nOr change your subscriptions right here with /settings.", name, webURL)
 }
 
 // Help sends the welcome message with the web app URL.
 func (k *KramerBot) Help(chat *tgbotapi.Chat) {
-	k.SendMessage(chat.ID, k.welcomeMessage(chat.FirstName))
+	k.SendMessage(chat.ID, k.welcomeMessage(greetingName(chat)))
 }
 
-// RegisterUser adds a new user or shows the welcome message for an existing user.
+// RegisterUser adds a new user or group, or shows the welcome message for an
+// existing one.
 func (k *KramerBot) RegisterUser(chat *tgbotapi.Chat) {
+	name := chatName(chat)
 	user, err := k.DataWriter.GetUser(chat.ID)
 	if err != nil || user == nil {
 		newUser := &models.UserData{
 			ChatID:    chat.ID,
-			Username:  chat.UserName,
+			Username:  name,
 			OzbGood:   false, // no subscriptions until user opts-in via the web UI
 			OzbSuper:  false,
 			AmzDaily:  false,
 			AmzWeekly: false,
 			Keywords:  []string{},
-			OzbSent:   []string{},
-			AmzSent:   []string{},
 		}
 		if err := k.DataWriter.AddUser(newUser); err != nil {
 			k.Logger.Error("Failed to add new user", zap.Int64("chatID", chat.ID), zap.Error(err))
@@ -47,16 +47,19 @@ func (k *KramerBot) RegisterUser(chat *tgbotapi.Chat) {
 			return
 		}
 		k.UserStore.SetUser(chat.ID, newUser)
-		k.Logger.Info("Registered new user", zap.String("username", chat.UserName), zap.Int64("chatID", chat.ID))
-	} else {
-		if user.Username != chat.UserName {
-			user.Username = chat.UserName
-			k.UpdateUser(user)
-			k.UserStore.SetUser(chat.ID, user)
-			k.Logger.Info("Updated username", zap.String("username", chat.UserName), zap.Int64("chatID", chat.ID))
+		k.Logger.Info("Registered new user", zap.String("username", name), zap.Int64("chatID", chat.ID))
+	} else if user.Username != name || user.Inactive {
+		if user.Inactive {
+			// Back after blocking the bot; deals resume
+			k.Logger.Info("Reactivated user", zap.Int64("chatID", chat.ID))
 		}
+		user.Username = name
+		user.Inactive = false
+		k.UpdateUser(user)
+		k.UserStore.SetUser(chat.ID, user)
+		k.Logger.Info("Updated user", zap.String("username", name), zap.Int64("chatID", chat.ID))
 	}
-	k.SendMessage(chat.ID, k.welcomeMessage(chat.FirstName))
+	k.SendMessage(chat.ID, k.welcomeMessage(greetingName(chat)))
 }
 
 // ShowPreferences displays the user's current notification settings
@@ -69,11 +72,21 @@ func (k *KramerBot) ShowPreferences(chat *tgbotapi.Chat) {
 
 	prefsText := fmt.Sprintf("Your current preferences:\n"+
 		"OzBargain Regular Deals (all deals): %t\n"+
-		"OzBargain Top Deals (25+ votes in 24h): %t\n"+
+		"OzBargain Top Deals (%s): %t\n"+
+		"OzBargain Trending Deals: %t\n"+
 		"Amazon Daily Deals: %t\n"+
 		"Amazon Weekly Deals: %t\n"+
+		"Expired Deal Notices: %t\n"+
 		"Watched Keywords: %d",
-		user.OzbGood, user.OzbSuper, user.AmzDaily, user.AmzWeekly, len(user.Keywords))
+		user.OzbGood, user.TopDeal, user.OzbSuper, user.OzbTrending, user.AmzDaily, user.AmzWeekly, user.ExpiryNotices,
+		len(user.Keywords))
+
+	if len(user.MutedStores) > 0 {
+		prefsText += "\nMuted Stores: " + strings.Join(user.MutedStores, ", ")
+	}
+	if len(user.Categories) > 0 {
+		prefsText += "\nCategories: " + formatCategorySubs(user.Categories, ", ")
+	}
 
 	k.SendMessage(chat.ID, prefsText)
 	k.ListKeywords(chat) // Also list the keywords
@@ -113,8 +126,7 @@ func (k *KramerBot) ToggleOzbGood(chat *tgbotapi.Chat) {
 	}
 
 	user.OzbGood = !user.OzbGood
-	k.UpdateUser(user)                 // Update DB
-	k.UserStore.SetUser(chat.ID, user) // Update memory
+	k.savePreferences(user) // Update DB, memory and the linked web account
 
 	k.SendMessage(chat.ID, fmt.Sprintf("OzBargain Regular Deals (all deals) notifications set to: %t", user.OzbGood))
 	k.ShowPreferences(chat)
@@ -128,10 +140,9 @@ func (k *KramerBot) ToggleOzbSuper(chat *tgbotapi.Chat) {
 	}
 
 	user.OzbSuper = !user.OzbSuper
-	k.UpdateUser(user)                 // Update DB
-	k.UserStore.SetUser(chat.ID, user) // Update memory
+	k.savePreferences(user) // Update DB, memory and the linked web account
 
-	k.SendMessage(chat.ID, fmt.Sprintf("OzBargain Top Deals (25+ votes in 24h) notifications set to: %t", user.OzbSuper))
+	k.SendMessage(chat.ID, fmt.Sprintf("OzBargain Top Deals (%s) notifications set to: %t", user.TopDeal, user.OzbSuper))
 	k.ShowPreferences(chat)
 }
 
@@ -149,12 +160,11 @@ func (k *KramerBot) ToggleAmzDaily(chat *tgbotapi.Chat) {
 		zap.Bool("newValue", !user.AmzDaily))
 
 	user.AmzDaily = !user.AmzDaily
-	if err := k.UpdateUser(user); err != nil {
+	if err := k.savePreferences(user); err != nil {
 		k.Logger.Error("Failed to update user", zap.Error(err))
 		k.SendMessage(chat.ID, "Sorry, there was an error updating your preferences. Please try again later.")
 		return
 	}
-	k.UserStore.SetUser(chat.ID, user) // Update memory
 
 	k.Logger.Debug("Successfully updated AmzDaily preference",
 		zap.Int64("chatID", chat.ID),
@@ -172,13 +182,18 @@ func (k *KramerBot) ToggleAmzWeekly(chat *tgbotapi.Chat) {
 	}
 
 	user.AmzWeekly = !user.AmzWeekly
-	k.UpdateUser(user)                 // Update DB
-	k.UserStore.SetUser(chat.ID, user) // Update memory
+	k.savePreferences(user) // Update DB, memory and the linked web account
 
 	k.SendMessage(chat.ID, fmt.Sprintf("Amazon Weekly Deals notifications set to: %t", user.AmzWeekly))
 	k.ShowPreferences(chat)
 }
 
+// Short guide to watch expressions, shown with /addkeyword errors
+const keywordSyntaxHelp = `Keywords match whole words. Examples:
+- ssd* (also matches SSDs)
+- "samsung" AND ssd -refurbished
+- (ps5 OR "playstation 5") -controller`
+
 // AddKeyword adds a keyword to the user's watch list
 func (k *KramerBot) AddKeyword(chat *tgbotapi.Chat, keyword string) {
 	user, err := k.getUserData(chat.ID)
@@ -186,23 +201,30 @@ func (k *KramerBot) AddKeyword(chat *tgbotapi.Chat, keyword string) {
 		return
 	}
 
-	keyword = strings.TrimSpace(strings.ToLower(keyword))
+	keyword = strings.TrimSpace(keyword)
 	if keyword == "" {
-		k.SendMessage(chat.ID, "Please provide a keyword to add. Usage: /addkeyword <keyword>")
+		k.SendMessage(chat.ID, "Please provide a keyword to add. Usage: /addkeyword <keyword>\n\n"+keywordSyntaxHelp)
+		return
+	}
+
+	// Validate the watch expression and store it in canonical form
+	expr, err := watch.Parse(keyword)
+	if err != nil {
+		k.SendMessage(chat.ID, fmt.Sprintf("Invalid keyword '%s': %s\n\n%s", keyword, err, keywordSyntaxHelp))
 		return
 	}
+	keyword = expr.String()
 
 	// Check if keyword already exists
 	for _, existingKeyword := range user.Keywords {
-		if existingKeyword == keyword {
+		if watch.Canonical(existingKeyword) == keyword {
 			k.SendMessage(chat.ID, fmt.Sprintf("Keyword '%s' is already in your watch list.", keyword))
 			return
 		}
 	}
 
 	user.Keywords = append(user.Keywords, keyword)
-	k.UpdateUser(user)                 // Update DB
-	k.UserStore.SetUser(chat.ID, user) // Update memory
+	k.savePreferences(user) // Update DB, memory and the linked web account
 
 	k.SendMessage(chat.ID, fmt.Sprintf("Keyword '%s' added to your watch list.", keyword))
 	k.ListKeywords(chat)
@@ -215,7 +237,7 @@ func (k *KramerBot) RemoveKeyword(chat *tgbotapi.Chat, keywordToRemove string) {
 		return
 	}
 
-	keywordToRemove = strings.TrimSpace(strings.ToLower(keywordToRemove))
+	keywordToRemove = strings.TrimSpace(keywordToRemove)
 	if keywordToRemove == "" {
 		k.SendMessage(chat.ID, "Please provide a keyword to remove. Usage: /removekeyword <keyword>")
 		return
@@ -223,8 +245,9 @@ func (k *KramerBot) RemoveKeyword(chat *tgbotapi.Chat, keywordToRemove string) {
 
 	found := false
 	var updatedKeywords []string
+	canonical := watch.Canonical(keywordToRemove)
 	for _, existingKeyword := range user.Keywords {
-		if existingKeyword != keywordToRemove {
+		if watch.Canonical(existingKeyword) != canonical {
 			updatedKeywords = append(updatedKeywords, existingKeyword)
 		} else {
 			found = true
@@ -237,8 +260,7 @@ func (k *KramerBot) RemoveKeyword(chat *tgbotapi.Chat, keywordToRemove string) {
 	}
 
 	user.Keywords = updatedKeywords
-	k.UpdateUser(user)                 // Update DB
-	k.UserStore.SetUser(chat.ID, user) // Update memory
+	k.savePreferences(user) // Update DB, memory and the linked web account
 
 	k.SendMessage(chat.ID, fmt.Sprintf("Keyword '%s' removed from your watch list.", keywordToRemove))
 	k.ListKeywords(chat)
@@ -254,162 +276,3 @@ func (k *KramerBot) SendTestMessage(chat *tgbotapi.Chat) {
 	k.Logger.Debug(fmt.Sprintf("Sending deal %s to user %s", shortenedTitle, chat.FirstName))
 	k.SendHTMLMessage(chat.ID, formattedDeal)
 }
-
-// Make an announcement to all users i.e. important messages, updates etc.
-// Note: This is an admin function and will need KRAMERBOT_ADMIN_PASSWORD
-func (k *KramerBot) MakeAnnouncement(chat *tgbotapi.Chat, announcement string) {
-	// Extract message
-	messages := strings.Split(announcement, ":")
-	var message string
-	if len(messages) == 2 {
-		message = messages[1]
-	}
-
-	formattedAnnouncement := fmt.Sprintf(`📢 Kramerbot Announcement 📢 %s`, message)
-
-	for _, user := range k.UserStore.Users {
-		k.Logger.Debug(fmt.Sprintf("Sending announcement %s to user %s", message, user.Username))
-		k.SendMessage(user.ChatID, formattedAnnouncement)
-	}
-
-	k.SendMessage(chat.ID, "Announcement was sent to all users.")
-}
-
-// Send OZB good deal message to user
-func (k *KramerBot) SendOzbGoodDeal(user *models.UserData, deal *models.OzBargainDeal) error {
-	shortenedTitle := util.ShortenString(deal.Title, 30) + "..."
-	formattedDeal := fmt.Sprintf(`🟠🔥<a href="%s" target="_blank">%s</a>🔺%s`, deal.Url, shortenedTitle, deal.Upvotes)
-	textDeal := fmt.Sprintf(`🟠🔥 %s 🔺%s`, shortenedTitle, deal.Upvotes)
-
-	k.Logger.Debug(fmt.Sprintf("Sending good deal %s to user %s", shortenedTitle, user.Username))
-	if err := k.SendHTMLMessage(user.ChatID, formattedDeal); err != nil {
-		return fmt.Errorf("failed to send HTML message: %w", err)
-	}
-
-	// Send android notification if username is set
-	if k.Pipup != nil && strings.EqualFold(user.Username, k.Pipup.Username) {
-		if err := k.Pipup.SendMediaMessage(textDeal, "Kramerbot"); err != nil {
-			return fmt.Errorf("failed to send pipup message: %w", err)
-		}
-	}
-
-	// Mark deal as sent
-	user.OzbSent = append(user.OzbSent, deal.Id)
-	if err := k.UpdateUser(user); err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
-	}
-	return nil
-}
-
-// Send OZB super deal to user
-func (k *KramerBot) SendOzbSuperDeal(user *models.UserData, deal *models.OzBargainDeal) error {
-	shortenedTitle := util.ShortenString(deal.Title, 30) + "..."
-	formattedDeal := fmt.Sprintf(`🟠🔥<a href="%s" target="_blank">%s</a>🔺%s`, deal.Url, shortenedTitle, deal.Upvotes)
-	textDeal := fmt.Sprintf(`🟠🔥 %s 🔺%s`, shortenedTitle, deal.Upvotes)
-
-	k.Logger.Debug(fmt.Sprintf("Sending super deal %s to user %s", shortenedTitle, user.Username))
-	if err := k.SendHTMLMessage(user.ChatID, formattedDeal); err != nil {
-		return fmt.Errorf("failed to send HTML message: %w", err)
-	}
-
-	// Send android notification if username is set
-	if k.Pipup != nil && strings.EqualFold(user.Username, k.Pipup.Username) {
-		if err := k.Pipup.SendMediaMessage(textDeal, "Kramerbot"); err != nil {
-			return fmt.Errorf("failed to send pipup message: %w", err)
-		}
-	}
-
-	// Mark deal as sent
-	user.OzbSent = append(user.OzbSent, deal.Id)
-	if err := k.UpdateUser(user); err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
-	}
-	return nil
-}
-
-func (k *KramerBot) SendAmzDeal(user *models.UserData, deal *models.CamCamCamDeal) error {
-	dealType := ""
-
-	// Get deal type
-	if deal.DealType == int(scrapers.AMZ_DAILY) {
-		dealType = "top daily deal"
-	}
-	if deal.DealType == int(scrapers.AMZ_WEEKLY) {
-		dealType = "top weekly deal"
-	}
-
-	shortenedTitle := util.ShortenString(deal.Title, 30) + "..."
-	formattedDeal := fmt.Sprintf(`🅰️<a href="%s" target="_blank">%s</a> - %s`, deal.Url, shortenedTitle, k.CCCScraper.GetDealDropString(deal))
-	textDeal := fmt.Sprintf(`🅰️ %s`, shortenedTitle)
-
-	k.Logger.Debug(fmt.Sprintf("Sending Amazon %s deal %s to user %s", dealType, shortenedTitle, user.Username))
-	if err := k.SendHTMLMessage(user.ChatID, formattedDeal); err != nil {
-		return fmt.Errorf("failed to send HTML message: %w", err)
-	}
-
-	// Send android notification if username is set
-	if k.Pipup != nil && strings.EqualFold(user.Username, k.Pipup.Username) {
-		if err := k.Pipup.SendMediaMessage(textDeal, "Kramerbot"); err != nil {
-			return fmt.Errorf("failed to send pipup message: %w", err)
-		}
-	}
-
-	// Mark deal as sent
-	user.AmzSent = append(user.AmzSent, deal.Id)
-	if err := k.UpdateUser(user); err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
-	}
-	return nil
-}
-
-// Send OZB watched deal to user
-func (k *KramerBot) SendOzbWatchedDeal(user *models.UserData, deal *models.OzBargainDeal) error {
-	shortenedTitle := util.ShortenString(deal.Title, 30) + "..."
-	formattedDeal := fmt.Sprintf(`🟠👀<a href="%s" target="_blank">%s</a>🔺%s`, deal.Url, shortenedTitle, deal.Upvotes)
-	textDeal := fmt.Sprintf(`🟠👀 %s 🔺%s`, shortenedTitle, deal.Upvotes)
-
-	k.Logger.Debug(fmt.Sprintf("Sending watched Ozbargain deal %s to user %s", shortenedTitle, user.Username))
-	if err := k.SendHTMLMessage(user.ChatID, formattedDeal); err != nil {
-		return fmt.Errorf("failed to send HTML message: %w", err)
-	}
-
-	// Send android notification if username is set
-	if k.Pipup != nil && strings.EqualFold(user.Username, k.Pipup.Username) {
-		if err := k.Pipup.SendMediaMessage(textDeal, "Kramerbot"); err != nil {
-			return fmt.Errorf("failed to send pipup message: %w", err)
-		}
-	}
-
-	// Mark deal as sent
-	user.OzbSent = append(user.OzbSent, deal.Id)
-	if err := k.UpdateUser(user); err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
-	}
-	return nil
-}
-
-// Send AMZ watched deal to user
-func (k *KramerBot) SendAmzWatchedDeal(user *models.UserData, deal *models.CamCamCamDeal) error {
-	shortenedTitle := util.ShortenString(deal.Title, 30) + "..."
-	formattedDeal := fmt.Sprintf(`🅰️👀<a href="%s" target="_blank">%s</a> - %s`, deal.Url, shortenedTitle, k.CCCScraper.GetDealDropString(deal))
-	textDeal := fmt.Sprintf(`🅰️👀 %s`, shortenedTitle)
-
-	k.Logger.Debug(fmt.Sprintf("Sending watched Amazon deal %s to user %s", shortenedTitle, user.Username))
-	if err := k.SendHTMLMessage(user.ChatID, formattedDeal); err != nil {
-		return fmt.Errorf("failed to send HTML message: %w", err)
-	}
-
-	// Send android notification if username is set
-	if k.Pipup != nil && strings.EqualFold(user.Username, k.Pipup.Username) {
-		if err := k.Pipup.SendMediaMessage(textDeal, "Kramerbot"); err != nil {
-			return fmt.Errorf("failed to send pipup message: %w", err)
-		}
-	}
-
-	// Mark deal as sent
-	user.AmzSent = append(user.AmzSent, deal.Id)
-	if err := k.UpdateUser(user); err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
-	}
-	return nil
-}
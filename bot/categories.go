@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
+)
+
+const addCategoryUsage = "Usage: /addcategory <category or tag> [min votes]\nFor example: /addcategory Gaming 50"
+
+// categorySubscribed reports whether one of the user's category
+// subscriptions covers the deal.
+func categorySubscribed(user *models.UserData, deal *models.Deal) bool {
+	return slices.ContainsFunc(user.Categories, func(c models.CategorySub) bool { return c.Matches(deal) })
+}
+
+// formatCategorySubs lists category subscriptions with their vote thresholds.
+func formatCategorySubs(subs []models.CategorySub, sep string) string {
+	parts := make([]string, len(subs))
+	for i, c := range subs {
+		parts[i] = c.Category
+		if c.MinVotes > 0 {
+			parts[i] += fmt.Sprintf(" (%d+ votes)", c.MinVotes)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// parseCategoryArgs splits "/addcategory" arguments into a category and an
+// optional trailing vote threshold.
+func parseCategoryArgs(args string) (models.CategorySub, error) {
+	fields := strings.Fields(args)
+	sub := models.CategorySub{}
+	if n := len(fields); n > 1 {
+		if votes, err := strconv.Atoi(fields[n-1]); err == nil {
+			if votes < 0 {
+				return sub, fmt.Errorf("the vote threshold can't be negative")
+			}
+			sub.MinVotes = votes
+			fields = fields[:n-1]
+		}
+	}
+	sub.Category = scrapers.CanonicalCategory(strings.Join(fields, " "))
+	if sub.Category == "" {
+		return sub, fmt.Errorf("no category given")
+	}
+	return sub, nil
+}
+
+// ListCategories shows the user's category subscriptions and the categories
+// they can subscribe to.
+func (k *KramerBot) ListCategories(chat *tgbotapi.Chat) {
+	user, err := k.getUserData(chat.ID)
+	if err != nil {
+		return
+	}
+
+	text := "You are not subscribed to any categories."
+	if len(user.Categories) > 0 {
+		text = "Your categories:\n- " + formatCategorySubs(user.Categories, "\n- ")
+	}
+	text += "\n\nOzBargain categories: " + strings.Join(scrapers.OzbCategories, ", ") +
+		"\nTags such as lego or nintendo-switch work too.\n\n" + addCategoryUsage
+	k.SendMessage(chat.ID, text)
+}
+
+// AddCategory subscribes the user to a category or tag, or changes the vote
+// threshold of an existing subscription.
+func (k *KramerBot) AddCategory(chat *tgbotapi.Chat, args string) {
+	user, err := k.getUserData(chat.ID)
+	if err != nil {
+		return
+	}
+
+	sub, err := parseCategoryArgs(args)
+	if err != nil {
+		k.SendMessage(chat.ID, fmt.Sprintf("Could not add the category: %s.\n\n%s", err, addCategoryUsage))
+		return
+	}
+
+	i := slices.IndexFunc(user.Categories, func(c models.CategorySub) bool { return strings.EqualFold(c.Category, sub.Category) })
+	if i >= 0 {
+		user.Categories[i] = sub
+	} else {
+		user.Categories = append(user.Categories, sub)
+	}
+	if err := k.savePreferences(user); err != nil {
+		k.Logger.Error("Failed to add category", zap.Int64("chatID", chat.ID), zap.Error(err))
+		k.SendMessage(chat.ID, "Sorry, the category could not be added. Please try again later.")
+		return
+	}
+
+	k.SendMessage(chat.ID, fmt.Sprintf("Subscribed to %s.", formatCategorySubs([]models.CategorySub{sub}, "")))
+}
+
+// RemoveCategory ends a category subscription.
+func (k *KramerBot) RemoveCategory(chat *tgbotapi.Chat, category string) {
+	user, err := k.getUserData(chat.ID)
+	if err != nil {
+		return
+	}
+
+	category = scrapers.CanonicalCategory(category)
+	if category == "" {
+		k.SendMessage(chat.ID, "Please provide a category to remove. Usage: /removecategory <category or tag>")
+		return
+	}
+
+	i := slices.IndexFunc(user.Categories, func(c models.CategorySub) bool { return strings.EqualFold(c.Category, category) })
+	if i < 0 {
+		k.SendMessage(chat.ID, fmt.Sprintf("You are not subscribed to '%s'.", category))
+		return
+	}
+	user.Categories = slices.Delete(user.Categories, i, i+1)
+	if err := k.savePreferences(user); err != nil {
+		k.Logger.Error("Failed to remove category", zap.Int64("chatID", chat.ID), zap.Error(err))
+		k.SendMessage(chat.ID, "Sorry, the category could not be removed. Please try again later.")
+		return
+	}
+
+	k.SendMessage(chat.ID, fmt.Sprintf("Unsubscribed from %s.", category))
+}
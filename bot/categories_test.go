@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+func TestParseCategoryArgs(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    models.CategorySub
+		wantErr bool
+	}{
+		{"gaming", models.CategorySub{Category: "Gaming"}, false},
+		{"electrical  &  electronics 50", models.CategorySub{Category: "Electrical & Electronics", MinVotes: 50}, false},
+		{"nintendo-switch 0", models.CategorySub{Category: "nintendo-switch"}, false},
+		{"50", models.CategorySub{Category: "50"}, false}, // a lone number is a tag
+		{"travel -5", models.CategorySub{}, true},
+		{"   ", models.CategorySub{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseCategoryArgs(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCategoryArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseCategoryArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCategorySubs(t *testing.T) {
+	subs := []models.CategorySub{{Category: "Gaming", MinVotes: 50}, {Category: "lego"}}
+	if got := formatCategorySubs(subs, ", "); got != "Gaming (50+ votes), lego" {
+		t.Errorf("formatCategorySubs() = %q", got)
+	}
+}
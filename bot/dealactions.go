@@ -0,0 +1,243 @@
+package bot
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"slices"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"github.com/intothevoid/kramerbot/util"
+	"github.com/intothevoid/kramerbot/watch"
+	"go.uber.org/zap"
+)
+
+// Callback data of the buttons under deal notifications: an action prefix
+// followed by its argument.
+const (
+	muteKeywordPrefix = "mute:"    // hash of the keyword to remove
+	saveDealPrefix    = "save:"    // source:deal id
+	muteStorePrefix   = "nostore:" // store name
+	stopTopDealsData  = "stoptop"
+	stopTrendingData  = "stoptrending"
+)
+
+// Telegram rejects buttons whose callback data is longer than this.
+const maxCallbackData = 64
+
+// Number of saved deals listed by /saved
+const savedDealsLimit = 20
+
+// dealButtons returns the action buttons shown under a deal sent to a user.
+// Buttons whose callback data would be too long for Telegram are left out.
+func (k *KramerBot) dealButtons(r *notify.Recipient, e notify.Event) [][]models.InlineButton {
+	deal, user := e.Deal, r.User
+	if k.channelID != 0 && user.ChatID == k.channelID {
+		return nil // channel readers cannot change the bot's settings
+	}
+	var buttons [][]models.InlineButton
+
+	var top []models.InlineButton
+	if data := saveDealPrefix + deal.Source + ":" + deal.Id; k.SavedDB != nil && len(data) <= maxCallbackData {
+		top = append(top, models.InlineButton{Text: "⭐ Save deal", Data: data})
+	}
+	if e.Kind == notify.Watched {
+		if kw := matchedKeyword(user, deal); kw != "" {
+			top = append(top, models.InlineButton{Text: "🔇 Mute this keyword", Data: muteKeywordPrefix + keywordHash(kw)})
+		}
+	}
+	if len(top) > 0 {
+		buttons = append(buttons, top)
+	}
+
+	if store := deal.Store(); store != "" && len(muteStorePrefix+store) <= maxCallbackData {
+		buttons = append(buttons, []models.InlineButton{{
+			Text: "🚫 Not interested in " + util.ShortenString(store, 24),
+			Data: muteStorePrefix + store,
+		}})
+	}
+	if e.Kind == notify.Subscribed && user.OzbSuper && deal.Source == scrapers.SOURCE_OZBARGAIN &&
+		user.TopDeal.Matches(deal, time.Now()) {
+		buttons = append(buttons, []models.InlineButton{{Text: "⏹ Stop OzB top deals", Data: stopTopDealsData}})
+	}
+	if e.Kind == notify.Trending {
+		buttons = append(buttons, []models.InlineButton{{Text: "⏹ Stop trending deals", Data: stopTrendingData}})
+	}
+	return buttons
+}
+
+// matchedKeyword returns the first of the user's keywords that matches the
+// deal's title, or "".
+func matchedKeyword(user *models.UserData, deal *models.Deal) string {
+	text := watch.NewText(deal.Title)
+	for _, kw := range user.Keywords {
+		if watch.ParseOrLiteral(kw).Match(text) {
+			return kw
+		}
+	}
+	return ""
+}
+
+// keywordHash identifies a keyword in callback data, which is too short to
+// hold long watch expressions.
+func keywordHash(keyword string) string {
+	h := fnv.New32a()
+	h.Write([]byte(watch.Canonical(keyword)))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// isDealAction reports whether callback data came from a deal's buttons.
+func isDealAction(data string) bool {
+	return data == stopTopDealsData || data == stopTrendingData || strings.HasPrefix(data, muteKeywordPrefix) ||
+		strings.HasPrefix(data, saveDealPrefix) || strings.HasPrefix(data, muteStorePrefix)
+}
+
+// handleDealAction applies a tapped deal button and confirms it in a toast.
+func (k *KramerBot) handleDealAction(query *tgbotapi.CallbackQuery) {
+	if query.Message == nil {
+		k.answerCallback(query, "")
+		return
+	}
+	reply, err := k.dealAction(query.Message.Chat.ID, query.Data)
+	if err != nil {
+		k.Logger.Error("Failed to apply deal action", zap.Int64("chatID", query.Message.Chat.ID),
+			zap.String("data", query.Data), zap.Error(err))
+		reply = "Sorry, that didn't work. Please try again later."
+	}
+	k.answerCallback(query, reply)
+}
+
+// dealAction applies the action in a deal button's callback data for a chat
+// and returns the confirmation to show.
+func (k *KramerBot) dealAction(chatID int64, data string) (string, error) {
+	user, err := k.DataWriter.GetUser(chatID)
+	if err != nil || user == nil {
+		return "Could not find your user data. Have you registered using /start ?", nil
+	}
+
+	switch {
+	case strings.HasPrefix(data, muteKeywordPrefix):
+		hash := strings.TrimPrefix(data, muteKeywordPrefix)
+		i := slices.IndexFunc(user.Keywords, func(kw string) bool { return keywordHash(kw) == hash })
+		if i < 0 {
+			return "That keyword is no longer in your watch list.", nil
+		}
+		keyword := user.Keywords[i]
+		user.Keywords = slices.Delete(user.Keywords, i, i+1)
+		if err := k.savePreferences(user); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Muted keyword '%s'.", keyword), nil
+
+	case strings.HasPrefix(data, saveDealPrefix):
+		source, dealID, ok := strings.Cut(strings.TrimPrefix(data, saveDealPrefix), ":")
+		if !ok || k.SavedDB == nil {
+			return "", fmt.Errorf("cannot save deal %q", data)
+		}
+		saved, err := k.SavedDB.SaveDeal(chatID, source, dealID)
+		if err != nil {
+			return "", err
+		}
+		if !saved {
+			return "You already saved this deal. See /saved", nil
+		}
+		return "Deal saved. See /saved", nil
+
+	case strings.HasPrefix(data, muteStorePrefix):
+		store := strings.TrimPrefix(data, muteStorePrefix)
+		if !storeMuted(user, store) {
+			user.MutedStores = append(user.MutedStores, store)
+			if err := k.savePreferences(user); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("You won't be sent deals from %s. Undo with /unmutestore %s", store, store), nil
+
+	case data == stopTopDealsData:
+		if user.OzbSuper {
+			user.OzbSuper = false
+			if err := k.savePreferences(user); err != nil {
+				return "", err
+			}
+		}
+		return "OzBargain top deals turned off. Turn them back on with /settings", nil
+
+	case data == stopTrendingData:
+		if user.OzbTrending {
+			user.OzbTrending = false
+			if err := k.savePreferences(user); err != nil {
+				return "", err
+			}
+		}
+		return "Trending deals turned off. Turn them back on with /settings", nil
+	}
+	return "", fmt.Errorf("unknown deal action %q", data)
+}
+
+// storeMuted reports whether the user muted a store.
+func storeMuted(user *models.UserData, store string) bool {
+	return store != "" && slices.ContainsFunc(user.MutedStores, func(s string) bool {
+		return strings.EqualFold(s, store)
+	})
+}
+
+// UnmuteStore lets deals from a muted store through again.
+func (k *KramerBot) UnmuteStore(chat *tgbotapi.Chat, store string) {
+	user, err := k.getUserData(chat.ID)
+	if err != nil {
+		return
+	}
+
+	store = strings.TrimSpace(store)
+	if store == "" {
+		if len(user.MutedStores) == 0 {
+			k.SendMessage(chat.ID, "You have not muted any stores.")
+		} else {
+			k.SendMessage(chat.ID, "Usage: /unmutestore <store>\n\nMuted stores:\n- "+strings.Join(user.MutedStores, "\n- "))
+		}
+		return
+	}
+
+	i := slices.IndexFunc(user.MutedStores, func(s string) bool { return strings.EqualFold(s, store) })
+	if i < 0 {
+		k.SendMessage(chat.ID, fmt.Sprintf("Store '%s' is not muted.", store))
+		return
+	}
+	user.MutedStores = slices.Delete(user.MutedStores, i, i+1)
+	if err := k.savePreferences(user); err != nil {
+		k.Logger.Error("Failed to unmute store", zap.Int64("chatID", chat.ID), zap.Error(err))
+		k.SendMessage(chat.ID, fmt.Sprintf("Sorry, %s could not be unmuted. Please try again later.", store))
+		return
+	}
+
+	k.SendMessage(chat.ID, fmt.Sprintf("Deals from %s will be sent again.", store))
+}
+
+// ShowSavedDeals lists the deals the user saved from notifications.
+func (k *KramerBot) ShowSavedDeals(chat *tgbotapi.Chat) {
+	if k.SavedDB == nil {
+		return
+	}
+	deals, err := k.SavedDB.SavedDeals(chat.ID, savedDealsLimit)
+	if err != nil {
+		k.Logger.Error("Failed to load saved deals", zap.Int64("chatID", chat.ID), zap.Error(err))
+		k.SendMessage(chat.ID, "Sorry, your saved deals could not be loaded. Please try again later.")
+		return
+	}
+	if len(deals) == 0 {
+		k.SendMessage(chat.ID, "You have no saved deals. Tap ⭐ Save deal under a deal to keep it here.")
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("⭐ Your saved deals\n")
+	for _, d := range deals {
+		fmt.Fprintf(&b, "\n• <a href=\"%s\">%s</a>", html.EscapeString(d.Url), html.EscapeString(d.Title))
+	}
+	k.SendHTMLMessage(chat.ID, b.String())
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	"github.com/intothevoid/kramerbot/persist"
+	sqlite_persist "github.com/intothevoid/kramerbot/persist/sqlite"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
+)
+
+// buttonData flattens button rows to their callback data, one row per line.
+func buttonData(buttons [][]models.InlineButton) string {
+	var rows []string
+	for _, row := range buttons {
+		var data []string
+		for _, b := range row {
+			data = append(data, b.Data)
+		}
+		rows = append(rows, strings.Join(data, " "))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// savedDB stands in for a saved deal store that is never called.
+type savedDB struct{ persist.SavedDealDBIF }
+
+func TestKramerBot_dealButtons(t *testing.T) {
+	k := &KramerBot{SavedDB: savedDB{}}
+	user := &models.UserData{ChatID: 1, OzbSuper: true, Keywords: []string{"ssd"}}
+	top := &models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Id: "42", Title: "Samsung SSD 2TB @ Amazon AU", Upvotes: "30", DealType: int(scrapers.OZB_SUPER)}
+
+	tests := []struct {
+		name string
+		deal *models.Deal
+		kind notify.Kind
+		want string
+	}{
+		{"top deal", top, notify.Subscribed, "save:ozbargain:42\nnostore:Amazon AU\nstoptop"},
+		{"watched", top, notify.Watched, "save:ozbargain:42 mute:" + keywordHash("ssd") + "\nnostore:Amazon AU"},
+		{"trending", top, notify.Trending, "save:ozbargain:42\nnostore:Amazon AU\nstoptrending"},
+		{"no store", &models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Id: "7", Title: "Free coffee"}, notify.Subscribed, "save:ozbargain:7"},
+		{"id too long", &models.Deal{Source: scrapers.SOURCE_AMAZON, Id: strings.Repeat("x", 60), Title: "Kindle"}, notify.Subscribed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buttonData(k.dealButtons(&notify.Recipient{User: user}, notify.Event{Deal: tt.deal, Kind: tt.kind}))
+			if got != tt.want {
+				t.Errorf("dealButtons() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKramerBot_dealAction(t *testing.T) {
+	db, err := sqlite_persist.NewSQLiteWrapper(filepath.Join(t.TempDir(), "actions_test.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.AddUser(&models.UserData{ChatID: 1, OzbSuper: true, OzbTrending: true, Keywords: []string{"ssd", "ps5"}}); err != nil {
+		t.Fatal(err)
+	}
+	deal := models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Id: "42", Title: "Samsung SSD @ Amazon AU", Url: "https://example.com/42"}
+	if err := db.UpsertDeals([]models.Deal{deal}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	k := &KramerBot{Logger: zap.NewNop(), DataWriter: db, SavedDB: db,
+		UserStore: &models.UserStore{Users: map[int64]*models.UserData{}}}
+
+	steps := []struct {
+		data      string
+		wantReply string
+		wantErr   bool
+	}{
+		{"mute:" + keywordHash("ssd"), "Muted keyword 'ssd'.", false},
+		{"mute:" + keywordHash("ssd"), "That keyword is no longer in your watch list.", false},
+		{"save:ozbargain:42", "Deal saved. See /saved", false},
+		{"save:ozbargain:42", "You already saved this deal. See /saved", false},
+		{"nostore:Amazon AU", "You won't be sent deals from Amazon AU. Undo with /unmutestore Amazon AU", false},
+		{"nostore:amazon au", "You won't be sent deals from amazon au. Undo with /unmutestore amazon au", false},
+		{"stoptop", "OzBargain top deals turned off. Turn them back on with /settings", false},
+		{"stoptrending", "Trending deals turned off. Turn them back on with /settings", false},
+		{"save:bad", "", true},
+		{"nope:1", "", true},
+	}
+	for _, s := range steps {
+		reply, err := k.dealAction(1, s.data)
+		if reply != s.wantReply || (err != nil) != s.wantErr {
+			t.Errorf("dealAction(%q) = %q, %v; want %q (error %v)", s.data, reply, err, s.wantReply, s.wantErr)
+		}
+	}
+
+	user, _ := db.GetUser(1)
+	if got := fmt.Sprint(user.Keywords, user.MutedStores, user.OzbSuper, user.OzbTrending); got != "[ps5] [Amazon AU] false false" {
+		t.Errorf("user after actions = %s, want [ps5] [Amazon AU] false false", got)
+	}
+	if saved, _ := db.SavedDeals(1, 10); len(saved) != 1 || saved[0].Id != "42" {
+		t.Errorf("saved deals = %+v", saved)
+	}
+
+	if reply, _ := k.dealAction(2, "stoptop"); !strings.Contains(reply, "/start") {
+		t.Errorf("dealAction() for an unknown user = %q", reply)
+	}
+}
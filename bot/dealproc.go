@@ -2,65 +2,65 @@ package bot
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
 	"github.com/intothevoid/kramerbot/scrapers"
 	"go.uber.org/zap"
 )
 
-// Process deals returned by the scraper, check deal type and notify user
-// if they are subscribed to a particular deal type
+// Process deals returned by every registered source, check deal type and notify
+// user if they are subscribed to a particular deal type
 func (k *KramerBot) StartProcessing() {
-	// Begin timed processing and scraping
-	go func() {
-		ozbTick := time.NewTicker(time.Minute * time.Duration(k.OzbScraper.ScrapeInterval))
-		for range ozbTick.C {
-			if err := k.processOzbargainDeals(); err != nil {
-				k.Logger.Error("Error processing Ozbargain deals", zap.Error(err))
-			}
-		}
-	}()
-
-	go func() {
-		amzTick := time.NewTicker(time.Minute * time.Duration(k.CCCScraper.ScrapeInterval))
-		for range amzTick.C {
-			if err := k.processCCCDeals(); err != nil {
-				k.Logger.Error("Error processing CCC deals", zap.Error(err))
+	// Begin timed processing and scraping, one ticker per source
+	for _, src := range k.Sources.Sources() {
+		go func(src scrapers.Source) {
+			tick := time.NewTicker(src.Interval())
+			for range tick.C {
+				if err := k.processSource(src); err != nil {
+					k.Logger.Error("Error processing deals", zap.String("source", src.Name()), zap.Error(err))
+				}
 			}
-		}
-	}()
+		}(src)
+	}
 }
 
-func (k *KramerBot) processOzbargainDeals() error {
-	// Add nil checks for k.OzbScraper
-	if k.OzbScraper == nil {
-		return fmt.Errorf("OzbScraper is nil")
+// processSource scrapes a single source and sends every new deal to the users
+// that are subscribed to its deal type or watching a matching keyword.
+func (k *KramerBot) processSource(src scrapers.Source) error {
+	if src == nil {
+		return fmt.Errorf("source is nil")
 	}
+	mu := k.sourceLock(src.Name())
+	mu.Lock()
+	defer mu.Unlock()
 
-	err := k.OzbScraper.Scrape()
+	err := src.Scrape()
 	if err != nil {
 		return fmt.Errorf("error scraping deals: %w", err)
 	}
 
-	// Load deals from OzBargain
-	deals := k.OzbScraper.GetData()
+	// Load deals from the source
+	deals := src.GetDeals()
 	if deals == nil {
 		return fmt.Errorf("no deals returned from scraper")
 	}
 
 	// Strip duplicates by using a map indexed by deal id
-	uniqueDeals := make(map[string]models.OzBargainDeal)
+	uniqueDeals := make(map[string]models.Deal)
 	for i := range deals {
 		deal := deals[i]
 		if deal.Id == "" {
-			k.Logger.Warn("Skipping deal with empty ID")
+			k.Logger.Warn("Skipping deal with empty ID", zap.String("source", src.Name()))
 			continue
 		}
 		uniqueDeals[deal.Id] = deal
 	}
 
+	// Persist the scrape so deal history survives restarts
+	k.storeDeals(src, uniqueDeals)
+
 	// Load store
 	if err := k.LoadUserStore(); err != nil {
 		return fmt.Errorf("error loading user store: %w", err)
@@ -72,185 +72,70 @@ func (k *KramerBot) processOzbargainDeals() error {
 		return fmt.Errorf("no users found in UserStore")
 	}
 
-	// Pre-process user keywords into maps for efficient lookups
-	userKeywordMaps := make(map[int64]map[string]bool)
-	for chatID, user := range userdata {
-		if user == nil {
-			k.Logger.Warn("Skipping nil user", zap.Int64("chat_id", chatID))
-			continue
-		}
-		keywordMap := make(map[string]bool)
-		for _, keyword := range user.Keywords {
-			// Only add non-empty keywords
-			if trimmed := strings.TrimSpace(keyword); trimmed != "" {
-				keywordMap[strings.ToLower(trimmed)] = true
-			}
-		}
-		userKeywordMaps[chatID] = keywordMap
-	}
+	// The matcher is only recompiled when someone's keywords have changed
+	matcher := k.watches.get(userdata)
+	accounts := k.linkedAccounts()
+	dispatcher := k.dispatcher()
 
 	for _, deal := range uniqueDeals {
-		k.Logger.Debug("Ozbargain deal", zap.Any("deal", deal))
-
-		// Pre-process deal title once
-		dealTitleLower := strings.ToLower(deal.Title)
-
-		// Go through all registered users and check deals they are subscribed to
-		for chatID, user := range userdata {
-			if user == nil {
-				k.Logger.Warn("Skipping nil user", zap.Int64("chat_id", chatID))
-				continue
-			}
-
-			// Check deal type subscriptions
-			if user.OzbGood && !OzbDealSent(user, &deal) {
-				// User is subscribed to all OzBargain deals (regular + top).
-				if err := k.SendOzbGoodDeal(user, &deal); err != nil {
-					k.Logger.Error("Failed to send OZB regular deal",
+		k.Logger.Debug("Processing deal", zap.String("source", src.Name()), zap.Any("deal", deal))
+
+		subscribers, trending, watchers := k.dealRecipients(&deal, userdata, matcher)
+		send := func(users []*models.UserData, kind notify.Kind) {
+			for _, user := range users {
+				recipient := &notify.Recipient{User: user, Account: accounts[user.ChatID]}
+				if err := dispatcher.Dispatch(recipient, notify.Event{Deal: &deal, Kind: kind}); err != nil {
+					k.Logger.Error("Failed to send deal",
+						zap.String("source", deal.Source),
 						zap.String("deal_id", deal.Id),
 						zap.Int64("user_id", user.ChatID),
+						zap.Stringer("kind", kind),
 						zap.Error(err))
 				}
 			}
-
-			if user.OzbSuper && !user.OzbGood && deal.DealType == int(scrapers.OZB_SUPER) && !OzbDealSent(user, &deal) {
-				// User is subscribed to top deals only (25+ votes within 24h).
-				// Skip if OzbGood is also on — Regular is a superset so the deal is already sent.
-				if err := k.SendOzbSuperDeal(user, &deal); err != nil {
-					k.Logger.Error("Failed to send OZB top deal",
-						zap.String("deal_id", deal.Id),
-						zap.Int64("user_id", user.ChatID),
-						zap.Error(err))
-				}
-			}
-
-			// Check for watched keywords using pre-processed map
-			keywordMap := userKeywordMaps[chatID]
-			if len(keywordMap) > 0 {
-				// Check if any keyword is in the deal title
-				for keyword := range keywordMap {
-					if strings.Contains(dealTitleLower, keyword) && !OzbDealSent(user, &deal) {
-						// Deal contains keyword, notify user
-						if err := k.SendOzbWatchedDeal(user, &deal); err != nil {
-							k.Logger.Error("Failed to send OZB watched deal",
-								zap.String("deal_id", deal.Id),
-								zap.Int64("user_id", user.ChatID),
-								zap.String("keyword", keyword),
-								zap.Error(err))
-						}
-						break // Break after first match
-					}
-				}
-			}
 		}
+		send(subscribers, notify.Subscribed)
+		send(trending, notify.Trending)
+		send(watchers, notify.Watched)
+		k.broadcastDeal(dispatcher, &deal)
 	}
 	return nil
 }
 
-func (k *KramerBot) processCCCDeals() error {
-	if k.CCCScraper == nil {
-		return fmt.Errorf("CCCScraper is nil")
-	}
-
-	err := k.CCCScraper.Scrape()
-	if err != nil {
-		return fmt.Errorf("error scraping deals: %w", err)
-	}
-
-	// Load deals from OzBargain
-	deals := k.CCCScraper.GetData()
-	if deals == nil {
-		return fmt.Errorf("no deals returned from scraper")
+// storeDeals upserts scraped deals into the deal history, logging any failure.
+// Test mode does not write to the database.
+func (k *KramerBot) storeDeals(src scrapers.Source, deals map[string]models.Deal) {
+	if k.DealDB == nil || k.Recorder != nil {
+		return
 	}
-
-	// Strip duplicates by using a map indexed by deal id
-	uniqueDeals := make(map[string]models.CamCamCamDeal)
+	batch := make([]models.Deal, 0, len(deals))
 	for _, deal := range deals {
-		uniqueDeals[deal.Id] = deal
-	}
-
-	// Load store
-	if err := k.LoadUserStore(); err != nil {
-		return fmt.Errorf("error loading user store: %w", err)
+		batch = append(batch, deal)
 	}
-
-	// Get a thread-safe copy of all users
-	userdata := k.UserStore.GetAllUsers()
-	if userdata == nil {
-		return fmt.Errorf("no users found in UserStore")
+	if err := k.DealDB.UpsertDeals(batch, time.Now()); err != nil {
+		k.Logger.Error("Failed to store deals", zap.String("source", src.Name()), zap.Error(err))
 	}
+}
 
-	// Pre-process user keywords into maps for efficient lookups
-	userKeywordMaps := make(map[int64]map[string]bool)
-	for chatID, user := range userdata {
-		keywordMap := make(map[string]bool)
-		for _, keyword := range user.Keywords {
-			// Only add non-empty keywords
-			if trimmed := strings.TrimSpace(keyword); trimmed != "" {
-				keywordMap[strings.ToLower(trimmed)] = true
-			}
-		}
-		userKeywordMaps[chatID] = keywordMap
-	}
-
-	// Get price drop target from configuration
-	priceDropTarget := k.Config.Scrapers.Amazon.TargetPriceDrop
-
-	for _, deal := range uniqueDeals {
-		k.Logger.Debug("Amazon deal", zap.Any("deal", deal))
-
-		// Pre-process deal title once
-		dealTitleLower := strings.ToLower(deal.Title)
-
-		// Check if percentage drop meets target
-		priceDropTargetMet := k.CCCScraper.IsTargetDropGreater(&deal, priceDropTarget)
-
-		// Go through all registered users and check deals they are subscribed to
-		for chatID, user := range userdata {
-			if user == nil {
-				k.Logger.Warn("Skipping nil user", zap.Int64("chat_id", chatID))
-				continue
-			}
-
-			if user.AmzDaily && priceDropTargetMet && deal.DealType == int(scrapers.AMZ_DAILY) && !AmzDealSent(user, &deal) {
-				// User is subscribed to AMZ daily deals, notify user
-				if err := k.SendAmzDeal(user, &deal); err != nil {
-					k.Logger.Error("Failed to send AMZ daily deal",
-						zap.String("deal_id", deal.Id),
-						zap.Int64("user_id", user.ChatID),
-						zap.Error(err))
-				}
-			}
-
-			if user.AmzWeekly && priceDropTargetMet && deal.DealType == int(scrapers.AMZ_WEEKLY) && !AmzDealSent(user, &deal) {
-				// User is subscribed to AMZ weekly deals, notify user
-				if err := k.SendAmzDeal(user, &deal); err != nil {
-					k.Logger.Error("Failed to send AMZ weekly deal",
-						zap.String("deal_id", deal.Id),
-						zap.Int64("user_id", user.ChatID),
-						zap.Error(err))
-				}
-			}
+// subscribedTo reports whether the user's deal type subscriptions cover the deal
+func (k *KramerBot) subscribedTo(user *models.UserData, deal *models.Deal) bool {
+	switch scrapers.DealType(deal.DealType) {
+	case scrapers.OZB_REG, scrapers.OZB_GOOD, scrapers.OZB_SUPER:
+		// Regular (all deals) is a superset of top deals. Whether a deal is
+		// a top deal depends on the user's rule, not its site-wide type.
+		return user.OzbGood || (user.OzbSuper && user.TopDeal.Matches(deal, time.Now()))
+	case scrapers.AMZ_DAILY:
+		return user.AmzDaily && deal.DropPercent >= k.priceDropTarget()
+	case scrapers.AMZ_WEEKLY:
+		return user.AmzWeekly && deal.DropPercent >= k.priceDropTarget()
+	}
+	return false
+}
 
-			// Check for watched keywords using pre-processed map
-			keywordMap := userKeywordMaps[chatID]
-			if len(keywordMap) > 0 {
-				// Check if any keyword is in the deal title
-				for keyword := range keywordMap {
-					if strings.Contains(dealTitleLower, keyword) && !AmzDealSent(user, &deal) {
-						// Deal contains keyword, notify user
-						if err := k.SendAmzWatchedDeal(user, &deal); err != nil {
-							k.Logger.Error("Failed to send AMZ watched deal",
-								zap.String("deal_id", deal.Id),
-								zap.Int64("user_id", user.ChatID),
-								zap.String("keyword", keyword),
-								zap.Error(err))
-						}
-						break // Break after first match
-					}
-				}
-			}
-		}
+// Get price drop target from configuration
+func (k *KramerBot) priceDropTarget() int {
+	if k.Config == nil {
+		return 0
 	}
-	return nil
+	return k.Config.Scrapers.Amazon.TargetPriceDrop
 }
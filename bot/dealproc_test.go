@@ -3,7 +3,6 @@ package bot
 import (
 	"testing"
 
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/intothevoid/kramerbot/models"
 	"github.com/intothevoid/kramerbot/persist"
 	"github.com/intothevoid/kramerbot/pipup"
@@ -48,13 +47,12 @@ func (m *mockDatabase) Ping() error {
 	return nil
 }
 
-func TestKramerBot_processOzbargainDeals(t *testing.T) {
+func TestKramerBot_processSource(t *testing.T) {
 	type fields struct {
 		Token      string
 		Logger     *zap.Logger
-		BotApi     *tgbotapi.BotAPI
-		OzbScraper *scrapers.OzBargainScraper
-		CCCScraper *scrapers.CamCamCamScraper
+		BotApi     TelegramClient
+		Source     scrapers.Source
 		UserStore  *models.UserStore
 		DataWriter persist.DatabaseIF
 		Pipup      *pipup.Pipup
@@ -68,9 +66,9 @@ func TestKramerBot_processOzbargainDeals(t *testing.T) {
 		{
 			name: "nil scraper",
 			fields: fields{
-				Logger:     zap.NewNop(),
-				OzbScraper: nil,
-				UserStore:  &models.UserStore{Users: make(map[int64]*models.UserData)},
+				Logger:    zap.NewNop(),
+				Source:    nil,
+				UserStore: &models.UserStore{Users: make(map[int64]*models.UserData)},
 			},
 			wantErr: true,
 		},
@@ -78,7 +76,7 @@ func TestKramerBot_processOzbargainDeals(t *testing.T) {
 			name: "successful processing",
 			fields: fields{
 				Logger: zap.NewNop(),
-				OzbScraper: &scrapers.OzBargainScraper{
+				Source: &scrapers.OzBargainScraper{
 					Logger:          zap.NewNop(),
 					ScrapeInterval:  10,
 					MaxDealsToStore: 50,
@@ -115,7 +113,6 @@ func TestKramerBot_processOzbargainDeals(t *testing.T) {
 							Username: "testuser",
 							OzbGood:  true,
 							Keywords: []string{"test", "nintendo"},
-							OzbSent:  []string{}, // Empty sent deals to verify multiple matches
 						},
 						456: {
 							ChatID:   456,
@@ -141,7 +138,7 @@ func TestKramerBot_processOzbargainDeals(t *testing.T) {
 			name: "empty user store",
 			fields: fields{
 				Logger: zap.NewNop(),
-				OzbScraper: &scrapers.OzBargainScraper{
+				Source: &scrapers.OzBargainScraper{
 					Logger:          zap.NewNop(),
 					ScrapeInterval:  10,
 					MaxDealsToStore: 50,
@@ -173,19 +170,59 @@ func TestKramerBot_processOzbargainDeals(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			sources := scrapers.NewRegistry()
+			if tt.fields.Source != nil {
+				sources.Register(tt.fields.Source)
+			}
 			k := &KramerBot{
 				Token:      tt.fields.Token,
 				Logger:     tt.fields.Logger,
 				BotApi:     tt.fields.BotApi,
-				OzbScraper: tt.fields.OzbScraper,
-				CCCScraper: tt.fields.CCCScraper,
+				Sources:    sources,
 				UserStore:  tt.fields.UserStore,
 				DataWriter: tt.fields.DataWriter,
 				Pipup:      tt.fields.Pipup,
 				Config:     tt.fields.Config,
 			}
-			if err := k.processOzbargainDeals(); (err != nil) != tt.wantErr {
-				t.Errorf("KramerBot.processOzbargainDeals() error = %v, wantErr %v", err, tt.wantErr)
+			if err := k.processSource(tt.fields.Source); (err != nil) != tt.wantErr {
+				t.Errorf("KramerBot.processSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKramerBot_subscribedTo(t *testing.T) {
+	k := &KramerBot{
+		Config: &util.Config{
+			Scrapers: util.ScrapersConfig{
+				Amazon: util.AmazonConfig{TargetPriceDrop: 20},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		user models.UserData
+		deal models.Deal
+		want bool
+	}{
+		{"regular deal, all deals on", models.UserData{OzbGood: true}, models.Deal{DealType: int(scrapers.OZB_REG)}, true},
+		{"regular deal, top deals only", models.UserData{OzbSuper: true}, models.Deal{DealType: int(scrapers.OZB_REG)}, false},
+		{"top deal, top deals only", models.UserData{OzbSuper: true}, models.Deal{DealType: int(scrapers.OZB_SUPER), Upvotes: "30", DealAge: "2h"}, true},
+		{"top deal, all deals on", models.UserData{OzbGood: true}, models.Deal{DealType: int(scrapers.OZB_SUPER)}, true},
+		{"own rule, enough votes", models.UserData{OzbSuper: true, TopDeal: models.TopDealRule{MinVotes: 10, MaxAgeHours: 6}}, models.Deal{DealType: int(scrapers.OZB_REG), Upvotes: "12", DealAge: "2h"}, true},
+		{"own rule, too old", models.UserData{OzbSuper: true, TopDeal: models.TopDealRule{MinVotes: 10, MaxAgeHours: 6}}, models.Deal{DealType: int(scrapers.OZB_GOOD), Upvotes: "40", DealAge: "8h"}, false},
+		{"own rule, too slow", models.UserData{OzbSuper: true, TopDeal: models.TopDealRule{MinVotesPerHour: 10}}, models.Deal{DealType: int(scrapers.OZB_SUPER), Upvotes: "30", DealAge: "5h"}, false},
+		{"own rule, fast enough", models.UserData{OzbSuper: true, TopDeal: models.TopDealRule{MinVotesPerHour: 10}}, models.Deal{DealType: int(scrapers.OZB_SUPER), Upvotes: "30", DealAge: "2h"}, true},
+		{"amazon daily meets target", models.UserData{AmzDaily: true}, models.Deal{DealType: int(scrapers.AMZ_DAILY), DropPercent: 25}, true},
+		{"amazon daily below target", models.UserData{AmzDaily: true}, models.Deal{DealType: int(scrapers.AMZ_DAILY), DropPercent: 5}, false},
+		{"amazon weekly, daily only", models.UserData{AmzDaily: true}, models.Deal{DealType: int(scrapers.AMZ_WEEKLY), DropPercent: 25}, false},
+		{"unknown deal type", models.UserData{OzbGood: true, AmzDaily: true}, models.Deal{DealType: int(scrapers.UNKNOWN)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := k.subscribedTo(&tt.user, &tt.deal); got != tt.want {
+				t.Errorf("KramerBot.subscribedTo() = %v, want %v", got, tt.want)
 			}
 		})
 	}
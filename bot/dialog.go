@@ -0,0 +1,254 @@
+package bot
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/watch"
+	"go.uber.org/zap"
+)
+
+// How long a multi-step command waits for the next answer
+const dialogTTL = 5 * time.Minute
+
+// Callback data of the buttons shown during a dialog is dialogPrefix followed
+// by one of the dialog actions.
+const (
+	dialogPrefix = "dialog:"
+	actionSend   = "send"
+	actionEdit   = "edit"
+	actionSkip   = "skip"
+	actionCancel = "cancel"
+)
+
+// Steps of the multi-step commands. A step names the answer the bot is
+// waiting for.
+const (
+	stepBroadcastText    = "broadcast_text"
+	stepBroadcastConfirm = "broadcast_confirm"
+	stepKeyword          = "keyword"
+	stepKeywordExclude   = "keyword_exclude"
+)
+
+// dialogKey identifies a conversation with one user in one chat, so group
+// members do not answer each other's questions.
+type dialogKey struct {
+	chatID int64
+	userID int
+}
+
+// dialog is a chat's place in a multi-step command.
+type dialog struct {
+	step    string
+	values  map[string]string // answers given so far
+	expires time.Time
+}
+
+// dialogStore holds the dialogs in progress. Dialogs left unanswered expire
+// after dialogTTL. The zero value is ready to use.
+type dialogStore struct {
+	mu      sync.Mutex
+	dialogs map[dialogKey]dialog
+}
+
+// get returns the dialog in progress for key, if it has not expired.
+func (s *dialogStore) get(key dialogKey) (dialog, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.dialogs[key]
+	if !ok || time.Now().After(d.expires) {
+		delete(s.dialogs, key)
+		return dialog{}, false
+	}
+	d.values = maps.Clone(d.values)
+	return d, true
+}
+
+// set moves key's dialog to step, keeping its answers and the given values,
+// and restarts its timeout. It starts a dialog if there is none.
+func (s *dialogStore) set(key dialogKey, step string, values map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.dialogs == nil {
+		s.dialogs = make(map[dialogKey]dialog)
+	}
+	for k, d := range s.dialogs {
+		if now.After(d.expires) {
+			delete(s.dialogs, k)
+		}
+	}
+
+	d := s.dialogs[key]
+	if d.values == nil {
+		d.values = make(map[string]string)
+	}
+	maps.Copy(d.values, values)
+	d.step = step
+	d.expires = now.Add(dialogTTL)
+	s.dialogs[key] = d
+}
+
+// clear ends key's dialog, reporting whether there was one.
+func (s *dialogStore) clear(key dialogKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.dialogs[key]
+	delete(s.dialogs, key)
+	return ok && time.Now().Before(d.expires)
+}
+
+// messageDialogKey is the dialog key of a message's sender and chat.
+func messageDialogKey(msg *tgbotapi.Message) dialogKey {
+	key := dialogKey{chatID: msg.Chat.ID}
+	if msg.From != nil {
+		key.userID = msg.From.ID
+	}
+	return key
+}
+
+// continueDialog passes a message to the sender's dialog in progress,
+// reporting whether there was one.
+func (k *KramerBot) continueDialog(msg *tgbotapi.Message) bool {
+	key := messageDialogKey(msg)
+	d, ok := k.dialogs.get(key)
+	if !ok {
+		return false
+	}
+	text := strings.TrimSpace(msg.Text)
+
+	switch d.step {
+	case stepBroadcastText, stepBroadcastConfirm:
+		// A new message while confirming replaces the announcement
+		if text == "" {
+			k.SendMessage(key.chatID, "Please send the announcement as text.")
+			return true
+		}
+		k.dialogs.set(key, stepBroadcastConfirm, map[string]string{"text": text})
+		k.previewBroadcast(key.chatID, text)
+
+	case stepKeyword:
+		if _, err := watch.Parse(text); err != nil {
+			k.SendMessage(key.chatID, fmt.Sprintf("Invalid keyword '%s': %s\n\nPlease try again, or send /cancel to stop.", text, err))
+			return true
+		}
+		k.dialogs.set(key, stepKeywordExclude, map[string]string{"keyword": text})
+		k.sendWithButtons(key.chatID, "🚫 Send any words deals must not contain (e.g. refurbished used), or tap Skip.",
+			[][]models.InlineButton{{{Text: "Skip", Data: dialogPrefix + actionSkip}}})
+
+	case stepKeywordExclude:
+		k.dialogs.clear(key)
+		k.AddKeyword(msg.Chat, excludeWords(d.values["keyword"], text))
+
+	default:
+		k.dialogs.clear(key)
+		return false
+	}
+	return true
+}
+
+// handleDialogCallback handles the buttons shown during a dialog.
+func (k *KramerBot) handleDialogCallback(query *tgbotapi.CallbackQuery, action string) {
+	if query.Message == nil {
+		k.answerCallback(query, "")
+		return
+	}
+	key := dialogKey{chatID: query.Message.Chat.ID, userID: query.From.ID}
+	d, ok := k.dialogs.get(key)
+	if !ok {
+		k.closeDialogMessage(query, "This has expired.")
+		k.answerCallback(query, "")
+		return
+	}
+
+	switch {
+	case action == actionCancel:
+		k.dialogs.clear(key)
+		k.closeDialogMessage(query, "Cancelled.")
+
+	case d.step == stepBroadcastConfirm && action == actionSend:
+		k.dialogs.clear(key)
+		k.closeDialogMessage(query, "📢 Sending:\n\n"+announcementText(d.values["text"]))
+		k.Broadcast(query.Message.Chat, d.values["text"])
+
+	case d.step == stepBroadcastConfirm && action == actionEdit:
+		k.dialogs.set(key, stepBroadcastText, nil)
+		k.closeDialogMessage(query, "✏️ Send the new announcement.")
+
+	case d.step == stepKeywordExclude && action == actionSkip:
+		k.dialogs.clear(key)
+		k.closeDialogMessage(query, "No words excluded.")
+		k.AddKeyword(query.Message.Chat, d.values["keyword"])
+	}
+	k.answerCallback(query, "")
+}
+
+// StartBroadcast begins the /broadcast dialog: announcement, preview,
+// confirmation. Only admins get this far.
+func (k *KramerBot) StartBroadcast(msg *tgbotapi.Message) {
+	k.dialogs.set(messageDialogKey(msg), stepBroadcastText, nil)
+	k.SendMessage(msg.Chat.ID, "✏️ Send the announcement. Send /cancel to stop.")
+}
+
+// StartAddKeyword begins the /addkeyword dialog, which asks for a keyword and
+// then for words to exclude.
+func (k *KramerBot) StartAddKeyword(msg *tgbotapi.Message) {
+	if _, err := k.getUserData(msg.Chat.ID); err != nil {
+		return // Error message already sent by getUserData
+	}
+	k.dialogs.set(messageDialogKey(msg), stepKeyword, nil)
+	k.SendMessage(msg.Chat.ID, "🔎 Send the keyword to watch. Send /cancel to stop.\n\n"+keywordSyntaxHelp)
+}
+
+// CancelDialog ends the sender's dialog in progress.
+func (k *KramerBot) CancelDialog(msg *tgbotapi.Message) {
+	if k.dialogs.clear(messageDialogKey(msg)) {
+		k.SendMessage(msg.Chat.ID, "Cancelled.")
+	} else {
+		k.SendMessage(msg.Chat.ID, "Nothing to cancel.")
+	}
+}
+
+// previewBroadcast shows an announcement as it will be sent, with buttons to
+// send, edit or cancel it.
+func (k *KramerBot) previewBroadcast(chatID int64, text string) {
+	recipients := len(broadcastRecipients(k.UserStore.GetAllUsers()))
+	k.sendWithButtons(chatID, "Preview:\n\n"+announcementText(text),
+		[][]models.InlineButton{{
+			{Text: fmt.Sprintf("✅ Send to %d chats", recipients), Data: dialogPrefix + actionSend},
+			{Text: "✏️ Edit", Data: dialogPrefix + actionEdit},
+			{Text: "❌ Cancel", Data: dialogPrefix + actionCancel},
+		}})
+}
+
+// excludeWords adds a NOT term to a keyword for each of the words.
+func excludeWords(keyword, words string) string {
+	expr := "(" + keyword + ")"
+	for _, w := range strings.Fields(words) {
+		expr += " -" + strings.TrimPrefix(w, "-")
+	}
+	return expr
+}
+
+// sendWithButtons sends a plain text message with rows of inline buttons.
+func (k *KramerBot) sendWithButtons(chatID int64, text string, buttons [][]models.InlineButton) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = inlineKeyboard(buttons)
+	if _, err := k.BotApi.Send(msg); err != nil {
+		k.Logger.Error("Failed to send message", zap.Int64("chatID", chatID), zap.Error(err))
+	}
+}
+
+// closeDialogMessage replaces the text of a message with buttons, removing
+// the buttons.
+func (k *KramerBot) closeDialogMessage(query *tgbotapi.CallbackQuery, text string) {
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, text)
+	if _, err := k.BotApi.Send(edit); err != nil && !strings.Contains(err.Error(), "message is not modified") {
+		k.Logger.Warn("Failed to update dialog message", zap.Int64("chatID", query.Message.Chat.ID), zap.Error(err))
+	}
+}
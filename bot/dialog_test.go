@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/watch"
+)
+
+func TestDialogStore(t *testing.T) {
+	var s dialogStore
+	alice := dialogKey{chatID: -1, userID: 1}
+	bob := dialogKey{chatID: -1, userID: 2}
+
+	if _, ok := s.get(alice); ok {
+		t.Fatal("dialog found before one was started")
+	}
+
+	s.set(alice, stepKeyword, nil)
+	s.set(alice, stepKeywordExclude, map[string]string{"keyword": "ssd"})
+	d, ok := s.get(alice)
+	if !ok || d.step != stepKeywordExclude || d.values["keyword"] != "ssd" {
+		t.Fatalf("get(alice) = %+v, %v", d, ok)
+	}
+	d.values["keyword"] = "changed"
+	if d, _ := s.get(alice); d.values["keyword"] != "ssd" {
+		t.Error("changing a returned dialog changed the stored one")
+	}
+	if _, ok := s.get(bob); ok {
+		t.Error("another member of the chat shares the dialog")
+	}
+
+	if !s.clear(alice) {
+		t.Error("clear() = false for a dialog in progress")
+	}
+	if s.clear(alice) {
+		t.Error("clear() = true after the dialog ended")
+	}
+
+	// Dialogs expire after dialogTTL
+	s.set(bob, stepBroadcastText, nil)
+	d = s.dialogs[bob]
+	d.expires = time.Now().Add(-time.Second)
+	s.dialogs[bob] = d
+	if _, ok := s.get(bob); ok {
+		t.Error("expired dialog still in progress")
+	}
+}
+
+func TestExcludeWords(t *testing.T) {
+	tests := []struct {
+		keyword, words, want string
+	}{
+		{"ssd", "", "ssd"},
+		{"ssd", "used refurbished", "ssd AND NOT used AND NOT refurbished"},
+		{"ps5 OR xbox", "-controller", "(ps5 OR xbox) AND NOT controller"},
+	}
+	for _, tt := range tests {
+		if got := watch.Canonical(excludeWords(tt.keyword, tt.words)); got != tt.want {
+			t.Errorf("excludeWords(%q, %q) = %q, want %q", tt.keyword, tt.words, got, tt.want)
+		}
+	}
+}
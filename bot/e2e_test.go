@@ -0,0 +1,285 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	"github.com/intothevoid/kramerbot/outbox"
+	sqlite_persist "github.com/intothevoid/kramerbot/persist/sqlite"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"github.com/intothevoid/kramerbot/util"
+	"go.uber.org/zap"
+)
+
+// fixedSource is a deal source that always returns the same deals.
+type fixedSource struct {
+	deals []models.Deal
+}
+
+func (s *fixedSource) Name() string            { return scrapers.SOURCE_OZBARGAIN }
+func (s *fixedSource) DisplayName() string     { return "OzBargain" }
+func (s *fixedSource) Scrape() error           { return nil }
+func (s *fixedSource) AutoScrape()             {}
+func (s *fixedSource) Interval() time.Duration { return time.Hour }
+func (s *fixedSource) GetDeals() []models.Deal { return s.deals }
+
+// e2eBot is a bot wired to a temporary database and the fake Bot API,
+// receiving updates by long polling and sending deals through the outbox.
+type e2eBot struct {
+	*KramerBot
+	api *fakeBotAPI
+	db  *sqlite_persist.SQLiteWrapper
+}
+
+func newE2EBot(t *testing.T, src scrapers.Source) *e2eBot {
+	t.Helper()
+	db, err := sqlite_persist.NewSQLiteWrapper(filepath.Join(t.TempDir(), "e2e_test.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	fake := newFakeBotAPI(t)
+	client := fake.client(t)
+
+	config := util.DefaultConfig()
+	config.Outbox.ChatIntervalMs = 0
+	sources := scrapers.NewRegistry()
+	if src != nil {
+		sources.Register(src)
+	}
+	k := &KramerBot{
+		Logger:     zap.NewNop(),
+		BotApi:     client,
+		Username:   client.Self.UserName,
+		Sources:    sources,
+		DataWriter: db,
+		WebUserDB:  db,
+		DealDB:     db,
+		Webhooks:   notify.NewWebhook(db, zap.NewNop()),
+		SentDB:     db,
+		SavedDB:    db,
+		Config:     config,
+	}
+	k.Outbox = outbox.New(db, k.sendOutboxMessage, config.Outbox, k.Logger)
+	k.Outbox.OnSent = k.outboxSent
+	k.Outbox.OnDead = k.outboxDead
+	if err := k.LoadUserStore(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go k.Outbox.Run(ctx)
+
+	updates, err := k.receiveUpdates()
+	if err != nil {
+		t.Fatalf("Failed to receive updates: %v", err)
+	}
+	go k.BotProc(updates)
+
+	return &e2eBot{KramerBot: k, api: fake, db: db}
+}
+
+func TestE2E_Start(t *testing.T) {
+	b := newE2EBot(t, nil)
+
+	b.api.sendText(100, "jerry", "/start")
+
+	msgs := b.api.waitForMessages(t, 100, 1)
+	if !strings.Contains(msgs[0].Text, "jerry") {
+		t.Errorf("welcome message %q does not greet the user", msgs[0].Text)
+	}
+	user, err := b.db.GetUser(100)
+	if err != nil {
+		t.Fatalf("user not registered: %v", err)
+	}
+	if user.Username != "jerry" || user.OzbGood || user.OzbSuper {
+		t.Errorf("registered user = %+v, want jerry without subscriptions", user)
+	}
+	// The library removes a webhook by setting an empty one
+	if b.api.callCount("setWebhook") != 1 {
+		t.Error("webhook not removed before polling")
+	}
+}
+
+func TestE2E_TelegramLink(t *testing.T) {
+	b := newE2EBot(t, nil)
+	token := "link-token"
+	expires := time.Now().Add(time.Hour)
+	web := &models.WebUser{ID: "web-1", Email: "jerry@example.com", PasswordHash: "x", OzbSuper: true, Keywords: []string{"lego"}}
+	if err := b.db.CreateWebUser(web); err != nil {
+		t.Fatal(err)
+	}
+	web.LinkToken, web.LinkTokenExpires = &token, &expires
+	if err := b.db.UpdateWebUser(web); err != nil {
+		t.Fatal(err)
+	}
+
+	b.api.sendText(100, "jerry", "/start "+token)
+
+	msgs := b.api.waitForMessages(t, 100, 2)
+	if last := msgs[len(msgs)-1].Text; !strings.Contains(last, "now linked") {
+		t.Errorf("last message = %q, want the link confirmation", last)
+	}
+	linked, err := b.db.GetWebUserByID("web-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked.TelegramChatID == nil || *linked.TelegramChatID != 100 {
+		t.Errorf("web user chat ID = %v, want 100", linked.TelegramChatID)
+	}
+	if linked.LinkToken != nil {
+		t.Error("link token not cleared")
+	}
+	user, err := b.db.GetUser(100)
+	if err != nil {
+		t.Fatalf("user not registered: %v", err)
+	}
+	if !user.OzbSuper || len(user.Keywords) != 1 || user.Keywords[0] != "lego" {
+		t.Errorf("web preferences not synced: %+v", user)
+	}
+}
+
+func TestE2E_DealDelivery(t *testing.T) {
+	src := &fixedSource{deals: []models.Deal{{
+		Source: scrapers.SOURCE_OZBARGAIN, Id: "42", Title: "Seinfeld DVD box set @ JB Hi-Fi",
+		Url: "https://www.ozbargain.com.au/node/42", Upvotes: "12", DealType: int(scrapers.OZB_REG),
+	}}}
+	b := newE2EBot(t, src)
+
+	b.api.sendText(100, "jerry", "/start")
+	b.api.waitForMessages(t, 100, 1)
+	b.api.sendText(100, "jerry", "/ozbgood")
+	replies := len(b.api.waitForMessages(t, 100, 3))
+
+	if err := b.processSource(src); err != nil {
+		t.Fatalf("processSource() error = %v", err)
+	}
+
+	msgs := b.api.waitForMessages(t, 100, replies+1)
+	deal := msgs[replies]
+	if !strings.Contains(deal.Text, "Seinfeld DVD box set") || deal.ParseMode != "HTML" {
+		t.Errorf("deal message = %+v", deal)
+	}
+	if len(deal.Buttons) == 0 {
+		t.Error("deal message has no buttons")
+	}
+
+	// The deal is only sent once
+	if err := b.processSource(src); err != nil {
+		t.Fatalf("processSource() error = %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := len(b.api.messages(100)); n != replies+1 {
+		t.Errorf("got %d messages after scraping again, want %d", n, replies+1)
+	}
+}
+
+// expiringSource is a fixed source whose deals can be marked expired.
+type expiringSource struct {
+	fixedSource
+	mu      sync.Mutex
+	expiry  map[string]string
+	checked int
+}
+
+func (s *expiringSource) CheckExpiry(deal models.Deal) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checked++
+	return s.expiry[deal.Id], nil
+}
+
+func (s *expiringSource) expire(id, expiry string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expiry[id] = expiry
+}
+
+func TestE2E_ExpiryNotice(t *testing.T) {
+	src := &expiringSource{
+		fixedSource: fixedSource{deals: []models.Deal{{
+			Source: scrapers.SOURCE_OZBARGAIN, Id: "42", Title: "Seinfeld DVD box set @ JB Hi-Fi",
+			Url: "https://www.ozbargain.com.au/node/42", Upvotes: "12", DealType: int(scrapers.OZB_REG),
+		}}},
+		expiry: make(map[string]string),
+	}
+	b := newE2EBot(t, src)
+
+	// Jerry wants expiry notices, George doesn't
+	b.api.sendText(100, "jerry", "/start")
+	b.api.waitForMessages(t, 100, 1)
+	b.api.sendText(100, "jerry", "/ozbgood")
+	b.api.waitForMessages(t, 100, 3)
+	b.api.sendText(100, "jerry", "/expirynotices")
+	b.api.waitForMessages(t, 100, 5)
+	b.api.sendText(200, "george", "/start")
+	b.api.waitForMessages(t, 200, 1)
+	b.api.sendText(200, "george", "/ozbgood")
+	b.api.waitForMessages(t, 200, 3)
+	time.Sleep(200 * time.Millisecond)
+	jerry, george := len(b.api.messages(100))+1, len(b.api.messages(200))+1
+
+	if err := b.processSource(src); err != nil {
+		t.Fatalf("processSource() error = %v", err)
+	}
+	b.api.waitForMessages(t, 100, jerry)
+	b.api.waitForMessages(t, 200, george)
+
+	// Still available: no notice
+	b.checkSentDeals(time.Now())
+	src.expire("42", models.DealOutOfStock)
+	b.checkSentDeals(time.Now())
+
+	msgs := b.api.waitForMessages(t, 100, jerry+1)
+	notice := msgs[jerry]
+	if !strings.Contains(notice.Text, "Seinfeld DVD box set") || !strings.Contains(notice.Text, "is out of stock") ||
+		notice.ParseMode != "HTML" {
+		t.Errorf("expiry notice = %+v", notice)
+	}
+	if deal, err := b.db.GetDeal(scrapers.SOURCE_OZBARGAIN, "42"); err != nil || deal == nil || deal.Expiry != models.DealOutOfStock {
+		t.Errorf("stored deal = %+v, %v, want out of stock", deal, err)
+	}
+
+	// The notice goes out once and the deal isn't checked again
+	b.checkSentDeals(time.Now())
+	time.Sleep(200 * time.Millisecond)
+	if n := len(b.api.messages(100)); n != jerry+1 {
+		t.Errorf("jerry got %d messages, want %d", n, jerry+1)
+	}
+	if n := len(b.api.messages(200)); n != george {
+		t.Errorf("george got %d messages without expiry notices on, want %d", n, george)
+	}
+	if src.checked != 2 {
+		t.Errorf("deal checked %d times, want 2", src.checked)
+	}
+}
+
+func TestE2E_InlineQueryBanned(t *testing.T) {
+	b := newE2EBot(t, &fixedSource{})
+	b.UserStore.SetUser(100, &models.UserData{ChatID: 100, Username: "newman", Banned: true})
+
+	b.api.inject(tgbotapi.Update{InlineQuery: &tgbotapi.InlineQuery{ID: "1", From: &tgbotapi.User{ID: 100}, Query: "ssd"}})
+	b.api.inject(tgbotapi.Update{InlineQuery: &tgbotapi.InlineQuery{ID: "2", From: &tgbotapi.User{ID: 200}, Query: "ssd"}})
+
+	// Updates are handled in order, so once the second query is answered the
+	// first has been dropped
+	deadline := time.Now().Add(5 * time.Second)
+	for b.api.callCount("answerInlineQuery") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("inline query from a user in good standing not answered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := b.api.callCount("answerInlineQuery"); n != 1 {
+		t.Errorf("answered %d inline queries, want 1", n)
+	}
+}
@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	"github.com/intothevoid/kramerbot/outbox"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
+)
+
+// Most deals re-checked on their source per run. Deals left over are checked
+// on the next run, so the source isn't hit with every sent deal at once.
+const maxExpiryChecks = 50
+
+// Pause between expiry checks
+var expiryCheckDelay = time.Second
+
+// checkExpiries re-checks the deals sent over Telegram every configured
+// interval, until ctx is done.
+func (k *KramerBot) checkExpiries(ctx context.Context) {
+	cfg := k.Config.Scrapers.ExpiryCheck
+	if cfg.Interval <= 0 || k.DealDB == nil || k.SentDB == nil {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(cfg.Interval) * time.Minute)
+	defer ticker.Stop()
+	for {
+		k.checkSentDeals(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkSentDeals asks the sources of recently sent deals whether they have
+// expired, records any expiry and sends the notices of expired deals. Deals
+// not checked for longest go first, so every deal gets its turn even when
+// more than maxExpiryChecks are pending.
+func (k *KramerBot) checkSentDeals(now time.Time) {
+	since := now.AddDate(0, 0, -k.Config.Scrapers.ExpiryCheck.Days)
+	deals, err := k.DealDB.ExpiryNoticePending(since)
+	if err != nil {
+		k.Logger.Error("Failed to load sent deals", zap.Error(err))
+		return
+	}
+
+	checked := 0
+	for i := range deals {
+		deal := &deals[i]
+		if !deal.IsExpired(now) {
+			checker, ok := k.Sources.Get(deal.Source).(scrapers.ExpiryChecker)
+			if !ok || checked == maxExpiryChecks {
+				continue
+			}
+			if checked > 0 {
+				time.Sleep(expiryCheckDelay)
+			}
+			checked++
+
+			expiry, err := checker.CheckExpiry(*deal)
+			// Failed checks count too, so a broken page doesn't hold up the rest
+			if err := k.DealDB.MarkExpiryChecked(deal.Source, deal.Id, now); err != nil {
+				k.Logger.Error("Failed to record expiry check", zap.Error(err))
+			}
+			if err != nil {
+				k.Logger.Warn("Failed to check deal expiry",
+					zap.String("source", deal.Source), zap.String("deal_id", deal.Id), zap.Error(err))
+				continue
+			}
+			if expiry == "" {
+				continue
+			}
+			if _, err := k.DealDB.SetDealExpiry(deal.Source, deal.Id, expiry, now); err != nil {
+				k.Logger.Error("Failed to record deal expiry", zap.Error(err))
+				continue
+			}
+			deal.Expiry = expiry
+		}
+		k.sendExpiryNotices(deal)
+	}
+}
+
+// sendExpiryNotices tells the chats a deal was sent to over Telegram, if they
+// want expiry notices, that it has expired. Each deal's notices go out once.
+func (k *KramerBot) sendExpiryNotices(deal *models.Deal) {
+	chats, err := k.SentDB.SentChats(deal.Source, deal.Id, notify.ChannelTelegram)
+	if err != nil {
+		k.Logger.Error("Failed to load deal recipients", zap.Error(err))
+		return
+	}
+	text := expiryNotice(deal)
+	for _, chatID := range chats {
+		user := k.UserStore.GetUser(chatID)
+		if user == nil || !user.ExpiryNotices || user.Inactive || user.Banned {
+			continue
+		}
+		if err := k.queueMessage(chatID, text, outbox.ParseModeHTML); err != nil {
+			k.Logger.Error("Failed to send expiry notice",
+				zap.String("deal_id", deal.Id), zap.Int64("user_id", chatID), zap.Error(err))
+		}
+	}
+	if err := k.DealDB.MarkExpiryNoticeSent(deal.Source, deal.Id); err != nil {
+		k.Logger.Error("Failed to record expiry notice", zap.Error(err))
+	}
+}
+
+// expiryNotice is the message telling a user that a deal has expired.
+func expiryNotice(deal *models.Deal) string {
+	state := "has expired"
+	if deal.Expiry == models.DealOutOfStock {
+		state = "is out of stock"
+	}
+	return fmt.Sprintf(`⌛ <a href="%s">%s</a> %s`, html.EscapeString(deal.Url), html.EscapeString(deal.Title), state)
+}
+
+// ToggleExpiryNotices toggles the ExpiryNotices preference
+func (k *KramerBot) ToggleExpiryNotices(chat *tgbotapi.Chat) {
+	user, err := k.getUserData(chat.ID)
+	if err != nil {
+		return
+	}
+
+	user.ExpiryNotices = !user.ExpiryNotices
+	k.savePreferences(user) // Update DB, memory and the linked web account
+
+	k.SendMessage(chat.ID, fmt.Sprintf("Expired deal notices set to: %t", user.ExpiryNotices))
+	k.ShowPreferences(chat)
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	sqlite_persist "github.com/intothevoid/kramerbot/persist/sqlite"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"github.com/intothevoid/kramerbot/util"
+	"go.uber.org/zap"
+)
+
+// checkedSource is an OzBargain source that records the deals it is asked to
+// check and reports the deals in expired as expired.
+type checkedSource struct {
+	fixedSource
+	expired map[string]bool
+	checked []string
+}
+
+func (s *checkedSource) CheckExpiry(deal models.Deal) (string, error) {
+	s.checked = append(s.checked, deal.Id)
+	if s.expired[deal.Id] {
+		return models.DealExpired, nil
+	}
+	return "", nil
+}
+
+// newExpiryBot returns a bot whose database holds n deals sent to chat 1 over
+// Telegram, deal i sent i minutes before now.
+func newExpiryBot(t *testing.T, src scrapers.Source, n int, now time.Time) (*KramerBot, *sqlite_persist.SQLiteWrapper) {
+	t.Helper()
+	db, err := sqlite_persist.NewSQLiteWrapper(filepath.Join(t.TempDir(), "expiry_test.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	deals := make([]models.Deal, n)
+	for i := range deals {
+		deals[i] = models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Id: fmt.Sprint(i), Title: fmt.Sprintf("Deal %d", i)}
+	}
+	if err := db.UpsertDeals(deals, now); err != nil {
+		t.Fatal(err)
+	}
+	for i, d := range deals {
+		if err := db.MarkDealSent(1, d.Source, d.Id, "telegram", now.Add(-time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sources := scrapers.NewRegistry()
+	if err := sources.Register(src); err != nil {
+		t.Fatal(err)
+	}
+	k := &KramerBot{
+		Logger:    zap.NewNop(),
+		Config:    util.DefaultConfig(),
+		Sources:   sources,
+		DealDB:    db,
+		SentDB:    db,
+		UserStore: &models.UserStore{Users: map[int64]*models.UserData{}},
+	}
+	return k, db
+}
+
+func TestKramerBot_checkSentDeals(t *testing.T) {
+	delay := expiryCheckDelay
+	expiryCheckDelay = 0
+	t.Cleanup(func() { expiryCheckDelay = delay })
+
+	const pending = maxExpiryChecks + 10
+	now := time.Now().Truncate(time.Second)
+	expired := fmt.Sprint(pending - 1) // sent first, so checked in the first run
+	src := &checkedSource{expired: map[string]bool{expired: true}}
+	k, db := newExpiryBot(t, src, pending, now)
+
+	// The first run checks as many deals as allowed
+	k.checkSentDeals(now)
+	if len(src.checked) != maxExpiryChecks {
+		t.Fatalf("first run checked %d deals, want %d", len(src.checked), maxExpiryChecks)
+	}
+	first := make(map[string]bool)
+	for _, id := range src.checked {
+		first[id] = true
+	}
+	if deal, err := db.GetDeal(scrapers.SOURCE_OZBARGAIN, expired); err != nil || deal == nil || deal.Expiry != models.DealExpired {
+		t.Errorf("deal %s = %+v, %v, want expired", expired, deal, err)
+	}
+
+	// The next run starts with the deals the first run didn't get to
+	src.checked = nil
+	k.checkSentDeals(now.Add(time.Hour))
+	if len(src.checked) != maxExpiryChecks {
+		t.Fatalf("second run checked %d deals, want %d", len(src.checked), maxExpiryChecks)
+	}
+	for _, id := range src.checked[:pending-maxExpiryChecks] {
+		if first[id] {
+			t.Errorf("second run checked deal %s again before the deals never checked", id)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, id := range src.checked {
+		seen[id] = true
+		if id == expired {
+			t.Errorf("deal %s checked again after its expiry notice", id)
+		}
+	}
+	for i := range pending {
+		if id := fmt.Sprint(i); !first[id] && !seen[id] {
+			t.Errorf("deal %s not checked in two runs", id)
+		}
+	}
+}
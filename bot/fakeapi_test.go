@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// The longest a getUpdates call waits for an update, so polling stops soon
+// after a test ends.
+const fakePollWait = 100 * time.Millisecond
+
+// sentMessage is a message the bot sent or edited through the fake Bot API.
+type sentMessage struct {
+	Method    string // sendMessage or editMessageText
+	ChatID    int64
+	Text      string
+	ParseMode string
+	Buttons   [][]tgbotapi.InlineKeyboardButton
+}
+
+// fakeBotAPI is an in-process Telegram Bot API. It records the messages the
+// bot sends and serves injected updates to getUpdates.
+type fakeBotAPI struct {
+	server *httptest.Server
+	self   tgbotapi.User
+
+	mu       sync.Mutex
+	sent     []sentMessage
+	calls    map[string]int // API calls by method
+	updates  []tgbotapi.Update
+	nextID   int           // last update and message ID handed out
+	received chan struct{} // closed and replaced when an update is injected
+}
+
+func newFakeBotAPI(t *testing.T) *fakeBotAPI {
+	t.Helper()
+	f := &fakeBotAPI{
+		self:     tgbotapi.User{ID: 1, IsBot: true, FirstName: "Kramer", UserName: "kramerbot"},
+		calls:    make(map[string]int),
+		received: make(chan struct{}),
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// client returns a Bot API client whose requests go to the fake server.
+func (f *fakeBotAPI) client(t *testing.T) *tgbotapi.BotAPI {
+	t.Helper()
+	target, _ := url.Parse(f.server.URL)
+	api, err := tgbotapi.NewBotAPIWithClient("123:test", &http.Client{Transport: redirectTransport{target}})
+	if err != nil {
+		t.Fatalf("Failed to connect to the fake Bot API: %v", err)
+	}
+	t.Cleanup(api.StopReceivingUpdates)
+	return api
+}
+
+// redirectTransport sends every request to the fake server instead of
+// api.telegram.org.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = rt.target.Scheme
+	r.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// inject queues an update for the bot's next getUpdates call.
+func (f *fakeBotAPI) inject(update tgbotapi.Update) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	update.UpdateID = f.nextID
+	f.updates = append(f.updates, update)
+	close(f.received)
+	f.received = make(chan struct{})
+}
+
+// sendText injects a message from a user in a private chat, marking a
+// leading /command as a bot command.
+func (f *fakeBotAPI) sendText(chatID int64, username, text string) {
+	f.mu.Lock()
+	f.nextID++
+	messageID := f.nextID
+	f.mu.Unlock()
+
+	msg := &tgbotapi.Message{
+		MessageID: messageID,
+		From:      &tgbotapi.User{ID: int(chatID), UserName: username, FirstName: username},
+		Chat:      &tgbotapi.Chat{ID: chatID, Type: "private", UserName: username, FirstName: username},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		msg.Entities = &[]tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+	f.inject(tgbotapi.Update{Message: msg})
+}
+
+// messages returns the messages sent to a chat so far.
+func (f *fakeBotAPI) messages(chatID int64) []sentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var msgs []sentMessage
+	for _, m := range f.sent {
+		if m.ChatID == chatID {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs
+}
+
+// waitForMessages waits until n messages were sent to a chat and returns
+// them.
+func (f *fakeBotAPI) waitForMessages(t *testing.T, chatID int64, n int) []sentMessage {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		msgs := f.messages(chatID)
+		if len(msgs) >= n {
+			return msgs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d messages to chat %d, want %d: %+v", len(msgs), chatID, n, msgs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// callCount is the number of calls made to an API method.
+func (f *fakeBotAPI) callCount(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func (f *fakeBotAPI) serve(w http.ResponseWriter, r *http.Request) {
+	// Requests are POST /bot<token>/<method> with form values
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	if err := r.ParseForm(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+		return
+	}
+	f.mu.Lock()
+	f.calls[method]++
+	f.mu.Unlock()
+
+	switch method {
+	case "getMe":
+		writeAPIResult(w, f.self)
+	case "sendMessage", "editMessageText":
+		writeAPIResult(w, f.record(method, r.Form))
+	case "getUpdates":
+		writeAPIResult(w, f.pollUpdates(r))
+	case "answerCallbackQuery", "answerInlineQuery", "setWebhook":
+		writeAPIResult(w, true)
+	case "getChatAdministrators":
+		writeAPIResult(w, []tgbotapi.ChatMember{})
+	default:
+		writeAPIError(w, http.StatusNotFound, "Not Found: method not found")
+	}
+}
+
+// record stores a sent or edited message and returns it as Telegram would.
+func (f *fakeBotAPI) record(method string, form url.Values) tgbotapi.Message {
+	chatID, _ := strconv.ParseInt(form.Get("chat_id"), 10, 64)
+	m := sentMessage{Method: method, ChatID: chatID, Text: form.Get("text"), ParseMode: form.Get("parse_mode")}
+	if markup := form.Get("reply_markup"); markup != "" {
+		var keyboard tgbotapi.InlineKeyboardMarkup
+		if err := json.Unmarshal([]byte(markup), &keyboard); err == nil {
+			m.Buttons = keyboard.InlineKeyboard
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, m)
+	messageID, _ := strconv.Atoi(form.Get("message_id"))
+	if messageID == 0 {
+		f.nextID++
+		messageID = f.nextID
+	}
+	return tgbotapi.Message{
+		MessageID: messageID,
+		From:      &f.self,
+		Chat:      &tgbotapi.Chat{ID: chatID, Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      m.Text,
+	}
+}
+
+// pollUpdates returns the updates from the requested offset, waiting a
+// little for one if there are none.
+func (f *fakeBotAPI) pollUpdates(r *http.Request) []tgbotapi.Update {
+	offset, _ := strconv.Atoi(r.Form.Get("offset"))
+	timer := time.NewTimer(fakePollWait)
+	defer timer.Stop()
+	for {
+		f.mu.Lock()
+		var updates []tgbotapi.Update
+		for _, u := range f.updates {
+			if u.UpdateID >= offset {
+				updates = append(updates, u)
+			}
+		}
+		received := f.received
+		f.mu.Unlock()
+		if len(updates) > 0 {
+			return updates
+		}
+
+		select {
+		case <-received:
+		case <-timer.C:
+			return []tgbotapi.Update{}
+		case <-r.Context().Done():
+			return []tgbotapi.Update{}
+		}
+	}
+}
+
+func writeAPIResult(w http.ResponseWriter, result any) {
+	raw, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: raw})
+}
+
+func writeAPIError(w http.ResponseWriter, status int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: false, ErrorCode: status, Description: description})
+}
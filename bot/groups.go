@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
+)
+
+// Messages an admin posts anonymously on behalf of a group come from this
+// user. Only admins can post anonymously.
+const groupAnonymousBotID = 1087968824
+
+// How long a group's admin list is reused before it is fetched again
+const adminCacheTTL = time.Minute
+
+// Commands that change a chat's subscriptions. In groups only admins can use
+// them.
+var configCommands = map[string]bool{
+	"start": true, "register": true, "settings": true,
+	"addkeyword": true, "removekeyword": true, "unmutestore": true,
+	"addcategory": true, "removecategory": true,
+	"ozbgood": true, "ozbsuper": true, "ozbtrending": true, "amzdaily": true, "amzweekly": true,
+	"expirynotices": true,
+}
+
+// isGroup reports whether a chat is a group or supergroup.
+func isGroup(chat *tgbotapi.Chat) bool {
+	return chat != nil && (chat.IsGroup() || chat.IsSuperGroup())
+}
+
+// chatName is the name stored for a chat: the username of a person or public
+// group, or the title of a private group.
+func chatName(chat *tgbotapi.Chat) string {
+	if chat.UserName == "" && isGroup(chat) {
+		return chat.Title
+	}
+	return chat.UserName
+}
+
+// greetingName is the name used to greet a chat.
+func greetingName(chat *tgbotapi.Chat) string {
+	if isGroup(chat) {
+		return chat.Title
+	}
+	return chat.FirstName
+}
+
+// adminCache holds the user IDs of each group's admins. The zero value is
+// ready to use.
+type adminCache struct {
+	mu      sync.Mutex
+	entries map[int64]adminEntry
+}
+
+type adminEntry struct {
+	ids     map[int]bool
+	fetched time.Time
+}
+
+// get returns a group's cached admins, or nil if they are not cached.
+func (c *adminCache) get(chatID int64) map[int]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[chatID]
+	if !ok || time.Since(e.fetched) > adminCacheTTL {
+		return nil
+	}
+	return e.ids
+}
+
+// set caches a group's admins.
+func (c *adminCache) set(chatID int64, members []tgbotapi.ChatMember) {
+	ids := make(map[int]bool)
+	for _, m := range members {
+		if m.User != nil && (m.IsCreator() || m.IsAdministrator()) {
+			ids[m.User.ID] = true
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[int64]adminEntry)
+	}
+	c.entries[chatID] = adminEntry{ids: ids, fetched: time.Now()}
+}
+
+// canConfigure reports whether a user may change a chat's subscriptions:
+// anyone in their own chat, only admins in a group.
+func (k *KramerBot) canConfigure(chat *tgbotapi.Chat, from *tgbotapi.User) bool {
+	if !isGroup(chat) {
+		return true
+	}
+	if from == nil {
+		return false
+	}
+	if from.ID == groupAnonymousBotID {
+		return true
+	}
+
+	admins := k.admins.get(chat.ID)
+	if admins == nil {
+		members, err := k.BotApi.GetChatAdministrators(tgbotapi.ChatConfig{ChatID: chat.ID})
+		if err != nil {
+			k.Logger.Error("Failed to get chat administrators", zap.Int64("chatID", chat.ID), zap.Error(err))
+			return false
+		}
+		k.admins.set(chat.ID, members)
+		admins = k.admins.get(chat.ID)
+	}
+	return admins[from.ID]
+}
+
+// addressedToBot reports whether a command is meant for this bot. In groups
+// commands may name the bot they are for, e.g. /settings@kramerbot.
+func (k *KramerBot) addressedToBot(msg *tgbotapi.Message) bool {
+	_, name, found := strings.Cut(msg.CommandWithAt(), "@")
+	if !found || k.Username == "" {
+		return true
+	}
+	return strings.EqualFold(name, k.Username)
+}
+
+// migrateChat moves a group's subscriptions to its new chat ID after it was
+// upgraded to a supergroup.
+func (k *KramerBot) migrateChat(from, to int64) {
+	user, err := k.DataWriter.GetUser(from)
+	if err != nil || user == nil {
+		return
+	}
+	if existing, _ := k.DataWriter.GetUser(to); existing != nil {
+		return // already migrated
+	}
+
+	old := *user
+	user.ChatID = to
+	if err := k.DataWriter.AddUser(user); err != nil {
+		k.Logger.Error("Failed to migrate group", zap.Int64("from", from), zap.Int64("to", to), zap.Error(err))
+		return
+	}
+	if err := k.DataWriter.DeleteUser(&old); err != nil {
+		k.Logger.Warn("Failed to remove migrated group", zap.Int64("chatID", from), zap.Error(err))
+	}
+	if k.UserStore != nil {
+		k.UserStore.DeleteUser(from)
+		k.UserStore.SetUser(to, user)
+	}
+	k.Logger.Info("Migrated group to supergroup", zap.Int64("from", from), zap.Int64("to", to))
+}
+
+// resolveChannel looks up the chat ID of the channel OzBargain top deals are
+// broadcast to. The channel is configured as @username or a numeric chat ID.
+func (k *KramerBot) resolveChannel() {
+	channel := strings.TrimSpace(k.Config.Telegram.Channel)
+	if channel == "" {
+		return
+	}
+	if id, err := strconv.ParseInt(channel, 10, 64); err == nil {
+		k.channelID = id
+		return
+	}
+
+	if !strings.HasPrefix(channel, "@") {
+		channel = "@" + channel
+	}
+	chat, err := k.BotApi.GetChat(tgbotapi.ChatConfig{SuperGroupUsername: channel})
+	if err != nil {
+		k.Logger.Error("Failed to look up broadcast channel; top deals will not be broadcast",
+			zap.String("channel", channel), zap.Error(err))
+		return
+	}
+	k.channelID = chat.ID
+	k.Logger.Info("Broadcasting top deals to channel", zap.String("channel", channel), zap.Int64("chatID", chat.ID))
+}
+
+// broadcastDeal posts an OzBargain top deal to the configured channel, once.
+func (k *KramerBot) broadcastDeal(dispatcher *notify.Dispatcher, deal *models.Deal) {
+	if k.channelID == 0 || deal.Source != scrapers.SOURCE_OZBARGAIN ||
+		scrapers.DealType(deal.DealType) != scrapers.OZB_SUPER {
+		return
+	}
+	recipient := &notify.Recipient{
+		User:    &models.UserData{ChatID: k.channelID, Username: k.Config.Telegram.Channel},
+		Account: &models.WebUser{Channels: []string{notify.ChannelTelegram}},
+	}
+	if err := dispatcher.Dispatch(recipient, notify.Event{Deal: deal, Kind: notify.Subscribed}); err != nil {
+		k.Logger.Error("Failed to broadcast deal",
+			zap.String("deal_id", deal.Id),
+			zap.Int64("channel_id", k.channelID),
+			zap.Error(err))
+	}
+}
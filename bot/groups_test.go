@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"path/filepath"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	sqlite_persist "github.com/intothevoid/kramerbot/persist/sqlite"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"github.com/intothevoid/kramerbot/util"
+	"go.uber.org/zap"
+)
+
+func TestChatNames(t *testing.T) {
+	tests := []struct {
+		chat         tgbotapi.Chat
+		wantName     string
+		wantGreeting string
+	}{
+		{tgbotapi.Chat{Type: "private", UserName: "kramer", FirstName: "Cosmo"}, "kramer", "Cosmo"},
+		{tgbotapi.Chat{Type: "group", Title: "Deal Hunters"}, "Deal Hunters", "Deal Hunters"},
+		{tgbotapi.Chat{Type: "supergroup", Title: "Deal Hunters", UserName: "dealhunters"}, "dealhunters", "Deal Hunters"},
+	}
+	for _, tt := range tests {
+		if got := chatName(&tt.chat); got != tt.wantName {
+			t.Errorf("chatName(%s) = %q, want %q", tt.chat.Type, got, tt.wantName)
+		}
+		if got := greetingName(&tt.chat); got != tt.wantGreeting {
+			t.Errorf("greetingName(%s) = %q, want %q", tt.chat.Type, got, tt.wantGreeting)
+		}
+	}
+}
+
+func TestKramerBot_canConfigure(t *testing.T) {
+	k := &KramerBot{Logger: zap.NewNop()}
+	group := &tgbotapi.Chat{ID: -100, Type: "supergroup"}
+	k.admins.set(group.ID, []tgbotapi.ChatMember{
+		{User: &tgbotapi.User{ID: 1}, Status: "creator"},
+		{User: &tgbotapi.User{ID: 2}, Status: "administrator"},
+		{User: &tgbotapi.User{ID: 3}, Status: "member"},
+	})
+
+	tests := []struct {
+		name string
+		chat *tgbotapi.Chat
+		from *tgbotapi.User
+		want bool
+	}{
+		{"private chat", &tgbotapi.Chat{ID: 3, Type: "private"}, &tgbotapi.User{ID: 3}, true},
+		{"creator", group, &tgbotapi.User{ID: 1}, true},
+		{"admin", group, &tgbotapi.User{ID: 2}, true},
+		{"member", group, &tgbotapi.User{ID: 3}, false},
+		{"anonymous admin", group, &tgbotapi.User{ID: groupAnonymousBotID}, true},
+		{"no sender", group, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := k.canConfigure(tt.chat, tt.from); got != tt.want {
+				t.Errorf("canConfigure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKramerBot_addressedToBot(t *testing.T) {
+	k := &KramerBot{Username: "KramerBot"}
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"/settings", true},
+		{"/settings@kramerbot", true},
+		{"/settings@otherbot", false},
+	}
+	for _, tt := range tests {
+		msg := &tgbotapi.Message{Text: tt.text, Entities: &[]tgbotapi.MessageEntity{{Type: "bot_command", Length: len(tt.text)}}}
+		if got := k.addressedToBot(msg); got != tt.want {
+			t.Errorf("addressedToBot(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestKramerBot_migrateChat(t *testing.T) {
+	db, err := sqlite_persist.NewSQLiteWrapper(filepath.Join(t.TempDir(), "migrate_test.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.AddUser(&models.UserData{ChatID: -5, Username: "Deal Hunters", OzbSuper: true, Keywords: []string{"ssd"}}); err != nil {
+		t.Fatal(err)
+	}
+	k := &KramerBot{Logger: zap.NewNop(), DataWriter: db, UserStore: &models.UserStore{Users: map[int64]*models.UserData{}}}
+
+	k.migrateChat(-5, -1005)
+
+	if old, _ := db.GetUser(-5); old != nil {
+		t.Errorf("old group still registered: %+v", old)
+	}
+	user, err := db.GetUser(-1005)
+	if err != nil || user == nil {
+		t.Fatalf("migrated group not found: %v", err)
+	}
+	if !user.OzbSuper || len(user.Keywords) != 1 || user.Keywords[0] != "ssd" {
+		t.Errorf("migrated group lost its settings: %+v", user)
+	}
+	if k.UserStore.GetUser(-1005) == nil {
+		t.Error("user store not updated")
+	}
+}
+
+// htmlSender records the chats sent to and the buttons sent with each message.
+type htmlSender struct {
+	chats   []int64
+	buttons [][][]models.InlineButton
+}
+
+func (s *htmlSender) SendHTML(chatID int64, text string, buttons [][]models.InlineButton) error {
+	s.chats = append(s.chats, chatID)
+	s.buttons = append(s.buttons, buttons)
+	return nil
+}
+
+func TestKramerBot_broadcastDeal(t *testing.T) {
+	k := newSentBot(t)
+	k.Config = &util.Config{Telegram: util.TelegramConfig{Channel: "@kramerdeals"}}
+	k.channelID = -1001
+	k.SavedDB = savedDB{}
+	sender := &htmlSender{}
+	dispatcher := notify.NewDispatcher(k, zap.NewNop(), &notify.Telegram{Sender: sender, Buttons: k.dealButtons})
+
+	top := &models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Id: "1", Title: "SSD @ Amazon AU", DealType: int(scrapers.OZB_SUPER)}
+	regular := &models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Id: "2", Title: "Coffee", DealType: int(scrapers.OZB_REG)}
+	amazon := &models.Deal{Source: scrapers.SOURCE_AMAZON, Id: "3", Title: "Kindle", DealType: int(scrapers.AMZ_DAILY)}
+	for _, d := range []*models.Deal{top, regular, amazon, top} {
+		k.broadcastDeal(dispatcher, d)
+	}
+
+	if len(sender.chats) != 1 || sender.chats[0] != k.channelID {
+		t.Fatalf("sent to %v, want the top deal sent to the channel once", sender.chats)
+	}
+	if sender.buttons[0] != nil {
+		t.Errorf("channel post has buttons %v", sender.buttons[0])
+	}
+}
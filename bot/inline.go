@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/watch"
+	"go.uber.org/zap"
+)
+
+const (
+	inlineResultsLimit = 20 // results per inline query; Telegram allows 50
+	inlineCacheSeconds = 60 // how long Telegram may reuse an answer
+)
+
+// Deals without a posting time are ranked as if they were this old
+const unknownDealAge = 48 * time.Hour
+
+// HandleInlineQuery answers "@kramerbot <query>" from any chat with the
+// matching deals from the scrapers' caches.
+func (k *KramerBot) HandleInlineQuery(query *tgbotapi.InlineQuery) {
+	k.Logger.Info("Received inline query", zap.String("query", query.Query), zap.Int("from", query.From.ID))
+
+	deals := searchCachedDeals(k.Sources.AllDeals(), query.Query, time.Now(), inlineResultsLimit)
+	results := make([]interface{}, 0, len(deals))
+	for i := range deals {
+		results = append(results, k.inlineResult(&deals[i]))
+	}
+
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     inlineCacheSeconds,
+	}
+	if _, err := k.BotApi.AnswerInlineQuery(answer); err != nil {
+		k.Logger.Error("Failed to answer inline query", zap.String("query", query.Query), zap.Error(err))
+	}
+}
+
+// searchCachedDeals returns up to limit deals whose titles match query, best
+// ranked first. An empty query matches every deal.
+func searchCachedDeals(deals []models.Deal, query string, now time.Time, limit int) []models.Deal {
+	var expr watch.Expr
+	if query = strings.TrimSpace(query); query != "" {
+		expr = watch.ParseOrLiteral(query)
+	}
+
+	seen := make(map[string]bool)
+	matches := []models.Deal{}
+	for _, d := range deals {
+		// A deal can be cached under more than one deal type
+		key := d.Source + ":" + d.Id
+		if seen[key] {
+			continue
+		}
+		if expr != nil && !expr.Match(watch.NewText(d.Title)) {
+			continue
+		}
+		seen[key] = true
+		matches = append(matches, d)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return dealRank(&matches[i], now) > dealRank(&matches[j], now)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// dealRank scores a deal by its votes, decaying with age so fresh deals with
+// a few votes can outrank old ones with many.
+func dealRank(d *models.Deal, now time.Time) float64 {
+	age := unknownDealAge
+	if !d.PostedAt.IsZero() {
+		age = max(now.Sub(d.PostedAt), 0)
+	}
+	return float64(d.Votes()+1) / math.Pow(age.Hours()+2, 1.5)
+}
+
+// inlineResult renders a deal as an inline article that posts a link to it.
+func (k *KramerBot) inlineResult(d *models.Deal) tgbotapi.InlineQueryResultArticle {
+	h := fnv.New64a()
+	h.Write([]byte(d.Source + ":" + d.Id))
+
+	detail := dealDetail(d)
+	text := fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(d.Url), html.EscapeString(d.Title))
+	if detail != "" {
+		text += " " + html.EscapeString(detail)
+	}
+	article := tgbotapi.NewInlineQueryResultArticleHTML(fmt.Sprintf("%x", h.Sum64()), d.Title, text)
+	article.URL = d.Url
+	article.ThumbURL = d.Image
+
+	description := []string{}
+	if detail != "" {
+		description = append(description, detail)
+	}
+	if src := k.Sources.Get(d.Source); src != nil {
+		description = append(description, src.DisplayName())
+	}
+	article.Description = strings.Join(description, " · ")
+	return article
+}
+
+// dealDetail is a deal's votes, or its price drop for sources without voting.
+func dealDetail(d *models.Deal) string {
+	switch {
+	case d.Upvotes != "":
+		return "🔺" + d.Upvotes
+	case d.PriceDrop != "":
+		return d.PriceDrop
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"github.com/intothevoid/kramerbot/util"
+	"go.uber.org/zap"
+)
+
+func TestSearchCachedDeals(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	deals := []models.Deal{
+		{Source: "ozbargain", Id: "old", Title: "RTX 4070 Super @ Amazon AU", Upvotes: "120", PostedAt: now.Add(-72 * time.Hour)},
+		{Source: "ozbargain", Id: "fresh", Title: "Gigabyte RTX 4070 OC", Upvotes: "40", PostedAt: now.Add(-time.Hour)},
+		{Source: "ozbargain", Id: "new", Title: "MSI RTX 4070 Ventus", Upvotes: "2", PostedAt: now.Add(-10 * time.Minute)},
+		{Source: "amazon", Id: "a1", Title: "RTX 4070 Ti - down 20%", PriceDrop: "down 20%"},
+		{Source: "amazon", Id: "a1", Title: "RTX 4070 Ti - down 20%", PriceDrop: "down 20%"}, // also a weekly deal
+		{Source: "ozbargain", Id: "ssd", Title: "Samsung 990 Pro SSD", Upvotes: "300", PostedAt: now.Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  string
+	}{
+		// Three days old outweighs 120 votes; no posting time ranks last
+		{"votes and recency", "rtx 4070", 10, "[fresh new old a1]"},
+		{"limit", "rtx 4070", 2, "[fresh new]"},
+		{"watch syntax", "4070 -msi -amazon", 10, "[fresh a1]"},
+		{"empty query", "  ", 3, "[ssd fresh new]"},
+		{"no match", "ps5", 10, "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			for _, d := range searchCachedDeals(deals, tt.query, now, tt.limit) {
+				ids = append(ids, d.Id)
+			}
+			if got := fmt.Sprint(ids); got != tt.want {
+				t.Errorf("searchCachedDeals(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKramerBot_inlineResult(t *testing.T) {
+	sources := scrapers.NewRegistry()
+	sources.Register(scrapers.NewOzBargainScraper(util.OzBargainConfig{}, zap.NewNop()))
+	k := &KramerBot{Sources: sources}
+
+	deal := &models.Deal{Source: "ozbargain", Id: "1", Title: "Tea & Biscuits", Url: "https://example.com/1", Upvotes: "42"}
+	article := k.inlineResult(deal)
+
+	if article.Title != deal.Title || article.URL != deal.Url || article.Description != "🔺42 · OzBargain" {
+		t.Errorf("inlineResult() = %+v", article)
+	}
+	if got := fmt.Sprint(article.InputMessageContent); !strings.Contains(got, `<a href="https://example.com/1">Tea &amp; Biscuits</a> 🔺42`) {
+		t.Errorf("message content = %s", got)
+	}
+	if other := k.inlineResult(&models.Deal{Source: "amazon", Id: "1"}); other.ID == article.ID {
+		t.Error("deals from different sources share a result ID")
+	}
+}
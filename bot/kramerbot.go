@@ -7,11 +7,14 @@ package bot
 
 // imports
 import (
+	"context"
 	"os"
-	"time"
+	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	"github.com/intothevoid/kramerbot/outbox"
 	"github.com/intothevoid/kramerbot/persist"
 	sqlite_persist "github.com/intothevoid/kramerbot/persist/sqlite"
 	"github.com/intothevoid/kramerbot/pipup"
@@ -23,14 +26,34 @@ import (
 type KramerBot struct {
 	Token      string
 	Logger     *zap.Logger
-	BotApi     *tgbotapi.BotAPI
-	OzbScraper *scrapers.OzBargainScraper
-	CCCScraper *scrapers.CamCamCamScraper
+	BotApi     TelegramClient
+	Username   string             // the bot's Telegram username, without the @
+	Sources    *scrapers.Registry // every deal source the bot scrapes
 	UserStore  *models.UserStore
 	DataWriter persist.DatabaseIF
-	WebUserDB  persist.WebUserDBIF // web account store (set after NewBot)
+	WebUserDB  persist.WebUserDBIF   // web account store (set after NewBot)
+	DealDB     persist.DealDBIF      // scraped deal history
+	SentDB     persist.SentDealDBIF  // deals sent to each chat
+	SavedDB    persist.SavedDealDBIF // deals users saved from notifications
 	Pipup      *pipup.Pipup
+	EmailSvc   *util.EmailService // email deal channel (set before processing starts)
+	Notifier   *notify.Dispatcher // deal delivery; created on first use if nil
+	Outbox     *outbox.Outbox     // persistent, rate-limited Telegram send queue
+	Webhooks   *notify.Webhook    // persistent webhook delivery queue
+	Recorder   *notify.Recorder   // test mode: records deals instead of sending them
 	Config     *util.Config
+
+	watches      watchCache       // keyword matcher shared by the source goroutines
+	pages        resultPages      // /search, /top and /latest results being browsed
+	admins       adminCache       // group admins, who alone can change group settings
+	channelID    int64            // channel OzBargain top deals are broadcast to; 0 if none
+	dialogs      dialogStore      // multi-step commands waiting for an answer
+	broadcasts   broadcastTracker // announcements being delivered
+	scrapeLocks  sync.Map         // source name -> *sync.Mutex, so a source is not processed twice at once
+	notifierOnce sync.Once
+
+	webhookUpdates chan tgbotapi.Update // updates posted to the webhook endpoint
+	webhookOnce    sync.Once
 }
 
 // function to read token from environment variable
@@ -40,24 +63,23 @@ func (k *KramerBot) GetToken() string {
 	return token
 }
 
-// function to read admin password from environment variable
-func (k *KramerBot) GetAdminPass() string {
-	adminPass := os.Getenv("KRAMERBOT_ADMIN_PASS") // get the admin password
-	return adminPass
-}
-
 // get test mode from configuration
 func (k *KramerBot) getTestMode() bool {
 	return k.Config.TestMode
 }
 
 // function to create a new bot
-func (k *KramerBot) NewBot(ozbs *scrapers.OzBargainScraper, cccs *scrapers.CamCamCamScraper) {
+func (k *KramerBot) NewBot(sources *scrapers.Registry) {
 	// check test mode
 	testMode := k.getTestMode()
 	if testMode {
 		// TEST MODE
 		k.Logger.Info("****** TEST MODE IS NOW ACTIVE. Telegram not connected. ******")
+		recorder, err := notify.NewRecorder(k.Config.TestModeFile, k.Logger)
+		if err != nil {
+			k.Logger.Fatal("Failed to set up test mode recordings", zap.Error(err))
+		}
+		k.Recorder = recorder
 	} else {
 		// REGULAR MODE
 		// If user has forgotten to set the token
@@ -78,13 +100,14 @@ func (k *KramerBot) NewBot(ozbs *scrapers.OzBargainScraper, cccs *scrapers.CamCa
 		k.Logger.Info("Authorized on account", zap.String("username", bot.Self.UserName))
 
 		// Allocate bot
-		k.BotApi = &tgbotapi.BotAPI{}
 		k.BotApi = bot
+		k.Username = bot.Self.UserName
+
+		k.resolveChannel()
 	}
 
-	// Assign scrapers
-	k.OzbScraper = ozbs
-	k.CCCScraper = cccs
+	// Assign deal sources
+	k.Sources = sources
 
 	// Database Initialization (SQLite)
 	dbPath := os.Getenv("SQLITE_DB_PATH")
@@ -100,6 +123,14 @@ func (k *KramerBot) NewBot(ozbs *scrapers.OzBargainScraper, cccs *scrapers.CamCa
 		k.Logger.Fatal("Failed to initialize SQLite database", zap.String("path", dbPath), zap.Error(err))
 	}
 	k.DataWriter = dataWriter // Assign the wrapper which implements DatabaseIF
+	k.DealDB = dataWriter
+	k.SentDB = dataWriter
+	k.SavedDB = dataWriter
+	k.Outbox = outbox.New(dataWriter, k.sendOutboxMessage, k.Config.Outbox, k.Logger)
+	k.Outbox.OnSent = k.outboxSent
+	k.Outbox.OnDead = k.outboxDead
+	k.Webhooks = notify.NewWebhook(dataWriter, k.Logger)
+	k.Webhooks.AllowPrivate = k.Config.API.AllowPrivateWebhooks
 
 	// Check if the database connection is valid using Ping
 	if err := k.DataWriter.Ping(); err != nil {
@@ -122,16 +153,18 @@ func (k *KramerBot) StartBot() {
 		// log start receiving updates
 		k.Logger.Info("Start receiving updates")
 
-		// setup updates
-		u := tgbotapi.NewUpdate(0)
-		u.Timeout = 60
-
-		// get updates channel
-		updates, err := k.BotApi.GetUpdatesChan(u)
+		// get updates channel, from the webhook or long polling
+		updates, err := k.receiveUpdates()
 		if err != nil {
 			k.Logger.Fatal(err.Error())
 		}
 
+		// Send queued messages, including any left over from the last run
+		go k.Outbox.Run(context.Background())
+		go k.Webhooks.Run(context.Background())
+		go k.pruneSentDeals(context.Background())
+		go k.checkExpiries(context.Background())
+
 		// Start processing deals and scraping
 		// Run asyncronously to avoid blocking the main thread
 		go func() {
@@ -141,14 +174,12 @@ func (k *KramerBot) StartBot() {
 		// Start monitoring the bots updates channel
 		k.BotProc(updates)
 	} else {
-		testTick := time.NewTicker(time.Second * time.Duration(10))
-		count := 0
-		for range testTick.C {
-			// Test mode do nothing
-			// log tick count
-			count++
-			k.Logger.Info("test mode active", zap.Int("tick count", count))
-
-		}
+		// Scrape and match deals as usual; the dispatcher records the
+		// notifications instead of sending them. There are no Telegram
+		// updates to process.
+		k.Logger.Info("Test mode: recording notifications instead of sending them",
+			zap.String("file", k.Config.TestModeFile))
+		k.StartProcessing()
+		select {}
 	}
 }
@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	"go.uber.org/zap"
+)
+
+// dispatcher returns the deal dispatcher, creating it on first use with a
+// channel for every configured service. In test mode deals are recorded
+// instead of sent.
+func (k *KramerBot) dispatcher() *notify.Dispatcher {
+	k.notifierOnce.Do(func() {
+		if k.Notifier != nil {
+			return
+		}
+		var sender notify.HTMLSender = k
+		if k.Outbox != nil {
+			sender = k.Outbox
+		}
+		notifiers := []notify.Notifier{&notify.Telegram{Sender: sender, Buttons: k.dealButtons}}
+		if k.Webhooks != nil {
+			notifiers = append(notifiers, k.Webhooks)
+		}
+		if k.Pipup != nil {
+			notifiers = append(notifiers, &notify.Pipup{Client: k.Pipup})
+		}
+		if k.EmailSvc != nil && k.EmailSvc.Enabled() {
+			notifiers = append(notifiers, &notify.Email{Service: k.EmailSvc})
+		}
+
+		if k.Recorder != nil {
+			for i, n := range notifiers {
+				notifiers[i] = notify.DryRun(n, k.Recorder)
+			}
+			k.Notifier = notify.NewDispatcher(&testModeTracker{k: k}, k.Logger, notifiers...)
+			return
+		}
+		k.Notifier = notify.NewDispatcher(k, k.Logger, notifiers...)
+	})
+	return k.Notifier
+}
+
+// linkedAccounts maps Telegram chat IDs to their linked web accounts, whose
+// channel preferences decide where deals are delivered.
+func (k *KramerBot) linkedAccounts() map[int64]*models.WebUser {
+	accounts := make(map[int64]*models.WebUser)
+	if k.WebUserDB == nil {
+		return accounts
+	}
+	users, err := k.WebUserDB.GetLinkedWebUsers()
+	if err != nil {
+		k.Logger.Error("Failed to load linked web accounts", zap.Error(err))
+		return accounts
+	}
+	for _, u := range users {
+		accounts[*u.TelegramChatID] = u
+	}
+	return accounts
+}
@@ -0,0 +1,167 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+// Callback data of the /settings buttons is settingsPrefix followed by the
+// setting's key.
+const settingsPrefix = "settings:"
+
+// setting is a subscription that can be toggled from the /settings keyboard.
+type setting struct {
+	key   string
+	label string
+	value func(u *models.UserData) *bool
+}
+
+var settings = []setting{
+	{"ozb_good", "OzBargain regular deals", func(u *models.UserData) *bool { return &u.OzbGood }},
+	{"ozb_super", "OzBargain top deals", func(u *models.UserData) *bool { return &u.OzbSuper }},
+	{"ozb_trending", "OzBargain trending deals", func(u *models.UserData) *bool { return &u.OzbTrending }},
+	{"amz_daily", "Amazon daily deals", func(u *models.UserData) *bool { return &u.AmzDaily }},
+	{"amz_weekly", "Amazon weekly deals", func(u *models.UserData) *bool { return &u.AmzWeekly }},
+	{"expiry_notices", "Expired deal notices", func(u *models.UserData) *bool { return &u.ExpiryNotices }},
+}
+
+// findSetting returns the setting with the given key, or nil.
+func findSetting(key string) *setting {
+	for i := range settings {
+		if settings[i].key == key {
+			return &settings[i]
+		}
+	}
+	return nil
+}
+
+// settingsText is the text of the /settings message.
+func settingsText(user *models.UserData) string {
+	return fmt.Sprintf("⚙️ Your deal subscriptions\n\nTap a subscription to turn it on or off. "+
+		"You are watching %d keyword(s); use /addkeyword and /removekeyword to change them.", len(user.Keywords))
+}
+
+// settingsKeyboard has one button per setting showing whether it is on.
+func settingsKeyboard(user *models.UserData) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(settings))
+	for _, s := range settings {
+		mark := "❌"
+		if *s.value(user) {
+			mark = "✅"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(mark+" "+s.label, settingsPrefix+s.key),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// ShowSettings sends the user's subscriptions with buttons to toggle them.
+func (k *KramerBot) ShowSettings(chat *tgbotapi.Chat) {
+	user, err := k.getUserData(chat.ID)
+	if err != nil {
+		return // Error message already sent by getUserData
+	}
+
+	msg := tgbotapi.NewMessage(chat.ID, settingsText(user))
+	msg.ReplyMarkup = settingsKeyboard(user)
+	if _, err := k.BotApi.Send(msg); err != nil {
+		k.Logger.Error("Failed to send settings", zap.Int64("chatID", chat.ID), zap.Error(err))
+	}
+}
+
+// toggleSetting flips one of the user's subscriptions and saves it.
+func (k *KramerBot) toggleSetting(chatID int64, key string) (*models.UserData, error) {
+	s := findSetting(key)
+	if s == nil {
+		return nil, fmt.Errorf("unknown setting %q", key)
+	}
+	user, err := k.DataWriter.GetUser(chatID)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	v := s.value(user)
+	*v = !*v
+	if err := k.savePreferences(user); err != nil {
+		return nil, err
+	}
+	k.Logger.Info("Toggled setting", zap.Int64("chatID", chatID), zap.String("setting", key), zap.Bool("value", *v))
+	return user, nil
+}
+
+// handleSettingsCallback toggles the tapped setting and redraws the settings
+// message in place.
+func (k *KramerBot) handleSettingsCallback(query *tgbotapi.CallbackQuery, key string) {
+	s := findSetting(key)
+	if query.Message == nil || s == nil {
+		k.answerCallback(query, "")
+		return
+	}
+	chatID := query.Message.Chat.ID
+
+	user, err := k.toggleSetting(chatID, key)
+	if err != nil {
+		k.Logger.Error("Failed to toggle setting", zap.Int64("chatID", chatID), zap.String("setting", key), zap.Error(err))
+		k.answerCallback(query, "Sorry, that setting could not be changed. Have you registered using /start ?")
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatID, query.Message.MessageID, settingsText(user))
+	keyboard := settingsKeyboard(user)
+	edit.ReplyMarkup = &keyboard
+	if _, err := k.BotApi.Send(edit); err != nil && !strings.Contains(err.Error(), "message is not modified") {
+		k.Logger.Error("Failed to update settings message", zap.Int64("chatID", chatID), zap.Error(err))
+	}
+
+	state := "off"
+	if *s.value(user) {
+		state = "on"
+	}
+	k.answerCallback(query, fmt.Sprintf("%s turned %s", s.label, state))
+}
+
+// savePreferences stores a user's changed preferences and copies them to the
+// linked web account, if there is one.
+func (k *KramerBot) savePreferences(user *models.UserData) error {
+	if err := k.UpdateUser(user); err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	if k.UserStore != nil {
+		k.UserStore.SetUser(user.ChatID, user)
+	}
+	k.syncWebUser(user)
+	return nil
+}
+
+// syncWebUser updates the web account linked to a chat to match the bot's
+// preferences. It is best-effort; errors are logged.
+func (k *KramerBot) syncWebUser(user *models.UserData) {
+	if k.WebUserDB == nil {
+		return
+	}
+	webUser, err := k.WebUserDB.GetWebUserByTelegramChatID(user.ChatID)
+	if err != nil {
+		k.Logger.Warn("failed to look up linked web user", zap.Int64("chat_id", user.ChatID), zap.Error(err))
+		return
+	}
+	if webUser == nil {
+		return
+	}
+	webUser.OzbGood = user.OzbGood
+	webUser.OzbSuper = user.OzbSuper
+	webUser.AmzDaily = user.AmzDaily
+	webUser.AmzWeekly = user.AmzWeekly
+	webUser.Keywords = user.Keywords
+	webUser.Categories = user.Categories
+	webUser.TopDeal = user.TopDeal
+	webUser.OzbTrending = user.OzbTrending
+	webUser.ExpiryNotices = user.ExpiryNotices
+	if err := k.WebUserDB.UpdateWebUser(webUser); err != nil {
+		k.Logger.Warn("failed to sync prefs to web user", zap.Int64("chat_id", user.ChatID), zap.Error(err))
+	}
+}
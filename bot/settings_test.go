@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/intothevoid/kramerbot/models"
+	sqlite_persist "github.com/intothevoid/kramerbot/persist/sqlite"
+	"go.uber.org/zap"
+)
+
+func TestSettingsKeyboard(t *testing.T) {
+	user := &models.UserData{OzbSuper: true, OzbTrending: true, AmzWeekly: true}
+	keyboard := settingsKeyboard(user)
+
+	want := []struct{ text, data string }{
+		{"❌ OzBargain regular deals", "settings:ozb_good"},
+		{"✅ OzBargain top deals", "settings:ozb_super"},
+		{"✅ OzBargain trending deals", "settings:ozb_trending"},
+		{"❌ Amazon daily deals", "settings:amz_daily"},
+		{"✅ Amazon weekly deals", "settings:amz_weekly"},
+		{"❌ Expired deal notices", "settings:expiry_notices"},
+	}
+	if len(keyboard.InlineKeyboard) != len(want) {
+		t.Fatalf("keyboard has %d rows, want %d", len(keyboard.InlineKeyboard), len(want))
+	}
+	for i, w := range want {
+		b := keyboard.InlineKeyboard[i][0]
+		if b.Text != w.text || b.CallbackData == nil || *b.CallbackData != w.data {
+			t.Errorf("row %d = %q (%v), want %q (%s)", i, b.Text, b.CallbackData, w.text, w.data)
+		}
+	}
+}
+
+func TestKramerBot_toggleSetting(t *testing.T) {
+	db, err := sqlite_persist.NewSQLiteWrapper(filepath.Join(t.TempDir(), "settings_test.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	chatID := int64(42)
+	if err := db.AddUser(&models.UserData{ChatID: chatID, Keywords: []string{"ssd"}}); err != nil {
+		t.Fatal(err)
+	}
+	webUser := &models.WebUser{ID: "u1", Email: "kramer@example.com", PasswordHash: "x"}
+	if err := db.CreateWebUser(webUser); err != nil {
+		t.Fatal(err)
+	}
+	webUser.TelegramChatID = &chatID
+	if err := db.UpdateWebUser(webUser); err != nil {
+		t.Fatal(err)
+	}
+
+	k := &KramerBot{Logger: zap.NewNop(), DataWriter: db, WebUserDB: db,
+		UserStore: &models.UserStore{Users: map[int64]*models.UserData{}}}
+
+	user, err := k.toggleSetting(chatID, "ozb_super")
+	if err != nil {
+		t.Fatalf("toggleSetting() error = %v", err)
+	}
+	if !user.OzbSuper {
+		t.Error("OzbSuper not turned on")
+	}
+	if saved, _ := db.GetUser(chatID); !saved.OzbSuper {
+		t.Error("OzbSuper not saved")
+	}
+	if cached := k.UserStore.GetUser(chatID); cached == nil || !cached.OzbSuper {
+		t.Error("user store not updated")
+	}
+	if linked, _ := db.GetWebUserByID("u1"); !linked.OzbSuper || len(linked.Keywords) != 1 {
+		t.Errorf("web user not synced: %+v", linked)
+	}
+
+	if user, _ := k.toggleSetting(chatID, "ozb_super"); user.OzbSuper {
+		t.Error("OzbSuper not turned back off")
+	}
+	if linked, _ := db.GetWebUserByID("u1"); linked.OzbSuper {
+		t.Error("web user not synced after second toggle")
+	}
+
+	// The trending subscription is saved and synced the same way
+	k.toggleSetting(chatID, "ozb_trending")
+	saved, _ := db.GetUser(chatID)
+	linked, _ := db.GetWebUserByID("u1")
+	if !saved.OzbTrending || !linked.OzbTrending {
+		t.Errorf("OzbTrending saved %t, synced %t; want both", saved.OzbTrending, linked.OzbTrending)
+	}
+
+	if _, err := k.toggleSetting(chatID, "nope"); err == nil {
+		t.Error("expected an error for an unknown setting")
+	}
+	if _, err := k.toggleSetting(7, "ozb_good"); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+}
@@ -1,9 +1,13 @@
 package bot
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
 )
 
 // Create user data from parameters passed in
@@ -53,32 +57,91 @@ func (k *KramerBot) UpdateUser(userData *models.UserData) error {
 	return nil
 }
 
+// deactivateUser marks a user inactive after their chat rejected a message
+// for good, e.g. because they blocked the bot. They are skipped until they
+// send /start again.
+func (k *KramerBot) deactivateUser(chatID int64, reason TelegramErrorKind) {
+	user, err := k.DataWriter.GetUser(chatID)
+	if err != nil || user == nil {
+		k.Logger.Warn("Cannot deactivate unknown user", zap.Int64("chat_id", chatID), zap.Error(err))
+		return
+	}
+	if user.Inactive {
+		return
+	}
+	user.Inactive = true
+	if err := k.UpdateUser(user); err != nil {
+		k.Logger.Error("Failed to deactivate user", zap.Int64("chat_id", chatID), zap.Error(err))
+		return
+	}
+	if k.UserStore != nil {
+		k.UserStore.SetUser(chatID, user)
+	}
+	k.Logger.Info("Deactivated user", zap.Int64("chat_id", chatID), zap.Stringer("reason", reason))
+}
+
 // OzbDealSent checks if an OzBargain deal has already been sent to the user
-// by searching for the deal ID in the user's sent deals list
-func OzbDealSent(user *models.UserData, deal *models.OzBargainDeal) bool {
-	if user == nil || deal == nil {
+func (k *KramerBot) OzbDealSent(user *models.UserData, deal *models.OzBargainDeal) bool {
+	if deal == nil {
 		return false
 	}
+	return k.dealSent(user, scrapers.SOURCE_OZBARGAIN, deal.Id)
+}
 
-	// Use a map for O(1) lookup instead of slice iteration
-	sentDeals := make(map[string]bool)
-	for _, id := range user.OzbSent {
-		sentDeals[id] = true
+// AmzDealSent checks if an Amazon deal has already been sent to the user
+func (k *KramerBot) AmzDealSent(user *models.UserData, deal *models.CamCamCamDeal) bool {
+	if deal == nil {
+		return false
 	}
-	return sentDeals[deal.Id]
+	return k.dealSent(user, scrapers.SOURCE_AMAZON, deal.Id)
 }
 
-// AmzDealSent checks if an Amazon deal has already been sent to the user
-// by searching for the deal ID in the user's sent deals list
-func AmzDealSent(user *models.UserData, deal *models.CamCamCamDeal) bool {
-	if user == nil || deal == nil {
+// DealSent checks if a deal from any source has already been sent to the user
+func (k *KramerBot) DealSent(user *models.UserData, deal *models.Deal) bool {
+	if deal == nil {
+		return false
+	}
+	return k.dealSent(user, deal.Source, deal.Id)
+}
+
+func (k *KramerBot) dealSent(user *models.UserData, source, dealID string) bool {
+	if user == nil || k.SentDB == nil {
+		return false
+	}
+	sent, err := k.SentDB.DealSent(user.ChatID, source, dealID)
+	if err != nil {
+		k.Logger.Error("Failed to look up sent deal", zap.Int64("chat_id", user.ChatID),
+			zap.String("source", source), zap.String("deal_id", dealID), zap.Error(err))
 		return false
 	}
+	return sent
+}
+
+// MarkDealSent records that a deal was sent to the user over a channel
+func (k *KramerBot) MarkDealSent(user *models.UserData, deal *models.Deal, channel string) error {
+	if k.SentDB == nil {
+		return nil
+	}
+	return k.SentDB.MarkDealSent(user.ChatID, deal.Source, deal.Id, channel, time.Now())
+}
 
-	// Use a map for O(1) lookup instead of slice iteration
-	sentDeals := make(map[string]bool)
-	for _, id := range user.AmzSent {
-		sentDeals[id] = true
+// pruneSentDeals forgets sent deals older than the configured retention,
+// once at startup and then every few hours.
+func (k *KramerBot) pruneSentDeals(ctx context.Context) {
+	ttl := time.Duration(k.Config.SQLite.SentDealsTTLDays) * 24 * time.Hour
+	ticker := time.NewTicker(6 * time.Hour)
+	defer ticker.Stop()
+	for {
+		n, err := k.SentDB.PruneSentDeals(time.Now().Add(-ttl))
+		if err != nil {
+			k.Logger.Error("Failed to prune sent deals", zap.Error(err))
+		} else if n > 0 {
+			k.Logger.Info("Pruned sent deals", zap.Int64("deleted", n), zap.Duration("ttl", ttl))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
-	return sentDeals[deal.Id]
 }
@@ -1,73 +1,50 @@
 package bot
 
 import (
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/intothevoid/kramerbot/models"
+	sqlite_persist "github.com/intothevoid/kramerbot/persist/sqlite"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
 )
 
-func TestOzbDealSent(t *testing.T) {
-	type args struct {
-		user *models.UserData
-		deal *models.OzBargainDeal
+// newSentBot returns a bot whose sent deals are stored in a throwaway database,
+// with the given deals already sent to chat 1 over Telegram.
+func newSentBot(t *testing.T, sent ...models.Deal) *KramerBot {
+	t.Helper()
+	db, err := sqlite_persist.NewSQLiteWrapper(filepath.Join(t.TempDir(), "sent_test.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for _, d := range sent {
+		if err := db.MarkDealSent(1, d.Source, d.Id, "telegram", time.Now()); err != nil {
+			t.Fatal(err)
+		}
 	}
+	return &KramerBot{Logger: zap.NewNop(), SentDB: db}
+}
+
+func TestOzbDealSent(t *testing.T) {
+	k := newSentBot(t, models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Id: "123"})
 	tests := []struct {
 		name string
-		args args
+		user *models.UserData
+		deal *models.OzBargainDeal
 		want bool
 	}{
-		{
-			name: "Deal already sent",
-			args: args{
-				user: &models.UserData{
-					OzbSent: []string{"123"},
-				},
-				deal: &models.OzBargainDeal{
-					Id: "123",
-				},
-			},
-			want: true,
-		},
-		{
-			name: "Deal not sent",
-			args: args{
-				user: &models.UserData{
-					OzbSent: []string{"456"},
-				},
-				deal: &models.OzBargainDeal{
-					Id: "123",
-				},
-			},
-			want: false,
-		},
-		{
-			name: "Empty sent list",
-			args: args{
-				user: &models.UserData{
-					OzbSent: []string{},
-				},
-				deal: &models.OzBargainDeal{
-					Id: "123",
-				},
-			},
-			want: false,
-		},
-		{
-			name: "Multiple deals sent",
-			args: args{
-				user: &models.UserData{
-					OzbSent: []string{"123", "456", "789"},
-				},
-				deal: &models.OzBargainDeal{
-					Id: "456",
-				},
-			},
-			want: true,
-		},
+		{"deal sent", &models.UserData{ChatID: 1}, &models.OzBargainDeal{Id: "123"}, true},
+		{"deal not sent", &models.UserData{ChatID: 1}, &models.OzBargainDeal{Id: "456"}, false},
+		{"sent to another chat", &models.UserData{ChatID: 2}, &models.OzBargainDeal{Id: "123"}, false},
+		{"nil user", nil, &models.OzBargainDeal{Id: "123"}, false},
+		{"nil deal", &models.UserData{ChatID: 1}, nil, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := OzbDealSent(tt.args.user, tt.args.deal); got != tt.want {
+			if got := k.OzbDealSent(tt.user, tt.deal); got != tt.want {
 				t.Errorf("OzbDealSent() = %v, want %v", got, tt.want)
 			}
 		})
@@ -75,69 +52,69 @@ func TestOzbDealSent(t *testing.T) {
 }
 
 func TestAmzDealSent(t *testing.T) {
-	type args struct {
+	k := newSentBot(t, models.Deal{Source: scrapers.SOURCE_AMAZON, Id: "123"})
+	tests := []struct {
+		name string
 		user *models.UserData
 		deal *models.CamCamCamDeal
+		want bool
+	}{
+		{"deal sent", &models.UserData{ChatID: 1}, &models.CamCamCamDeal{Id: "123"}, true},
+		{"deal not sent", &models.UserData{ChatID: 1}, &models.CamCamCamDeal{Id: "456"}, false},
+		{"sent to another chat", &models.UserData{ChatID: 2}, &models.CamCamCamDeal{Id: "123"}, false},
+		{"nil deal", &models.UserData{ChatID: 1}, nil, false},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := k.AmzDealSent(tt.user, tt.deal); got != tt.want {
+				t.Errorf("AmzDealSent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDealSent(t *testing.T) {
+	k := newSentBot(t,
+		models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Id: "123"},
+		models.Deal{Source: scrapers.SOURCE_AMAZON, Id: "amz-1"},
+		models.Deal{Source: "ebay", Id: "999"},
+	)
+	user := &models.UserData{ChatID: 1}
+
 	tests := []struct {
 		name string
-		args args
+		deal *models.Deal
 		want bool
 	}{
-		{
-			name: "Deal already sent",
-			args: args{
-				user: &models.UserData{
-					AmzSent: []string{"123"},
-				},
-				deal: &models.CamCamCamDeal{
-					Id: "123",
-				},
-			},
-			want: true,
-		},
-		{
-			name: "Deal not sent",
-			args: args{
-				user: &models.UserData{
-					AmzSent: []string{"456"},
-				},
-				deal: &models.CamCamCamDeal{
-					Id: "123",
-				},
-			},
-			want: false,
-		},
-		{
-			name: "Empty sent list",
-			args: args{
-				user: &models.UserData{
-					AmzSent: []string{},
-				},
-				deal: &models.CamCamCamDeal{
-					Id: "123",
-				},
-			},
-			want: false,
-		},
-		{
-			name: "Multiple deals sent",
-			args: args{
-				user: &models.UserData{
-					AmzSent: []string{"123", "456", "789"},
-				},
-				deal: &models.CamCamCamDeal{
-					Id: "456",
-				},
-			},
-			want: true,
-		},
+		{"OzBargain deal sent", &models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Id: "123"}, true},
+		{"Amazon deal sent", &models.Deal{Source: scrapers.SOURCE_AMAZON, Id: "amz-1"}, true},
+		{"Amazon id does not match OzBargain deal", &models.Deal{Source: scrapers.SOURCE_AMAZON, Id: "123"}, false},
+		{"Other source", &models.Deal{Source: "ebay", Id: "999"}, true},
+		{"Other source does not collide", &models.Deal{Source: "ebay", Id: "123"}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := AmzDealSent(tt.args.user, tt.args.deal); got != tt.want {
-				t.Errorf("AmzDealSent() = %v, want %v", got, tt.want)
+			if got := k.DealSent(user, tt.deal); got != tt.want {
+				t.Errorf("DealSent() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestMarkDealSent(t *testing.T) {
+	k := newSentBot(t)
+	user := &models.UserData{ChatID: 1}
+	deal := &models.Deal{Source: scrapers.SOURCE_AMAZON, Id: "amz-2"}
+
+	for _, channel := range []string{"telegram", "email"} {
+		if err := k.MarkDealSent(user, deal, channel); err != nil {
+			t.Fatalf("MarkDealSent(%s) error = %v", channel, err)
+		}
+	}
+	if !k.DealSent(user, deal) {
+		t.Error("expected deal to be marked as sent")
+	}
+	if k.DealSent(user, &models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Id: "amz-2"}) {
+		t.Error("expected Amazon deal not to be recorded for OzBargain")
+	}
+}
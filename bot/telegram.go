@@ -1,13 +1,90 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"path/filepath"
+	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/outbox"
 )
 
+// TelegramClient is the part of the Telegram Bot API the bot uses.
+// *tgbotapi.BotAPI implements it; tests point one at a fake Bot API server.
+type TelegramClient interface {
+	// Sending messages and answering queries
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	AnswerCallbackQuery(config tgbotapi.CallbackConfig) (tgbotapi.APIResponse, error)
+	AnswerInlineQuery(config tgbotapi.InlineConfig) (tgbotapi.APIResponse, error)
+	MakeRequest(endpoint string, params url.Values) (tgbotapi.APIResponse, error)
+
+	// Looking up chats
+	GetChat(config tgbotapi.ChatConfig) (tgbotapi.Chat, error)
+	GetChatAdministrators(config tgbotapi.ChatConfig) ([]tgbotapi.ChatMember, error)
+
+	// Receiving updates by long polling
+	GetUpdatesChan(config tgbotapi.UpdateConfig) (tgbotapi.UpdatesChannel, error)
+	RemoveWebhook() (tgbotapi.APIResponse, error)
+}
+
+// TelegramErrorKind classifies a failed Bot API call by what should happen next.
+type TelegramErrorKind int
+
+const (
+	TelegramTransient    TelegramErrorKind = iota // network or server trouble; try again later
+	TelegramRateLimited                           // 429; wait for retry_after before sending anything
+	TelegramBlocked                               // 403; the user blocked the bot or deleted their account
+	TelegramChatNotFound                          // the chat no longer exists or the bot was never in it
+	TelegramBadRequest                            // the message itself was rejected
+)
+
+func (k TelegramErrorKind) String() string {
+	switch k {
+	case TelegramRateLimited:
+		return "rate limited"
+	case TelegramBlocked:
+		return "blocked"
+	case TelegramChatNotFound:
+		return "chat not found"
+	case TelegramBadRequest:
+		return "bad request"
+	}
+	return "transient"
+}
+
+// Permanent reports whether the chat can no longer be messaged at all.
+func (k TelegramErrorKind) Permanent() bool {
+	return k == TelegramBlocked || k == TelegramChatNotFound
+}
+
+// ClassifyTelegramError works out the kind of a Bot API error and, for rate
+// limits, how long Telegram asked us to wait. The Bot API reports errors as a
+// description prefixed with the HTTP status text, e.g. "Forbidden: bot was
+// blocked by the user".
+func ClassifyTelegramError(err error) (TelegramErrorKind, time.Duration) {
+	var apiErr tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return TelegramTransient, 0
+	}
+	msg := apiErr.Message
+	switch {
+	case apiErr.RetryAfter > 0 || strings.HasPrefix(msg, "Too Many Requests"):
+		return TelegramRateLimited, time.Duration(max(apiErr.RetryAfter, 1)) * time.Second
+	case strings.HasPrefix(msg, "Forbidden"):
+		return TelegramBlocked, 0
+	case strings.Contains(msg, "chat not found"), strings.Contains(msg, "group chat was upgraded"):
+		return TelegramChatNotFound, 0
+	case strings.HasPrefix(msg, "Bad Request"):
+		return TelegramBadRequest, 0
+	}
+	return TelegramTransient, 0
+}
+
 // send message to chat
 func (k *KramerBot) SendMessage(chatID int64, text string) error {
 	msg := tgbotapi.NewMessage(chatID, text)
@@ -29,6 +106,67 @@ func (k *KramerBot) SendHTMLMessage(chatID int64, text string) error {
 	return nil
 }
 
+// SendHTML sends an HTML message with optional inline buttons straight to
+// Telegram. The deal dispatcher uses it when there is no outbox.
+func (k *KramerBot) SendHTML(chatID int64, text string, buttons [][]models.InlineButton) error {
+	return k.sendOutboxMessage(models.OutboxMessage{ChatID: chatID, Text: text, ParseMode: outbox.ParseModeHTML, Buttons: buttons})
+}
+
+// sendOutboxMessage sends a message straight to Telegram. It is the outbox's
+// transport, so failures are returned as an *outbox.SendError saying how to
+// handle them.
+func (k *KramerBot) sendOutboxMessage(m models.OutboxMessage) error {
+	msg := tgbotapi.NewMessage(m.ChatID, m.Text)
+	msg.ParseMode = m.ParseMode
+	if len(m.Buttons) > 0 {
+		msg.ReplyMarkup = inlineKeyboard(m.Buttons)
+	}
+	_, err := k.BotApi.Send(msg)
+	if err == nil {
+		return nil
+	}
+
+	err = fmt.Errorf("failed to send message: %w", err)
+	switch kind, wait := ClassifyTelegramError(err); kind {
+	case TelegramRateLimited:
+		return &outbox.SendError{Err: err, RetryAfter: wait}
+	case TelegramBlocked, TelegramChatNotFound, TelegramBadRequest:
+		return &outbox.SendError{Err: err, Permanent: true}
+	}
+	return err
+}
+
+// inlineKeyboard converts rows of buttons to a Telegram inline keyboard.
+func inlineKeyboard(buttons [][]models.InlineButton) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(buttons))
+	for _, row := range buttons {
+		r := make([]tgbotapi.InlineKeyboardButton, 0, len(row))
+		for _, b := range row {
+			r = append(r, tgbotapi.NewInlineKeyboardButtonData(b.Text, b.Data))
+		}
+		rows = append(rows, r)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleUndeliverable is called with messages the outbox gave up on. If the
+// chat can no longer be messaged at all, its user is deactivated so deals stop
+// being queued for them.
+func (k *KramerBot) handleUndeliverable(m models.OutboxMessage, err error) {
+	if kind, _ := ClassifyTelegramError(err); kind.Permanent() {
+		k.deactivateUser(m.ChatID, kind)
+	}
+}
+
+// queueMessage sends a message through the outbox, so it is rate limited and
+// retried, falling back to sending it directly if there is no outbox.
+func (k *KramerBot) queueMessage(chatID int64, text, parseMode string) error {
+	if k.Outbox == nil {
+		return k.sendOutboxMessage(models.OutboxMessage{ChatID: chatID, Text: text, ParseMode: parseMode})
+	}
+	return k.Outbox.Enqueue(chatID, text, parseMode)
+}
+
 // send markdown message to chat
 func (k *KramerBot) SendMarkdownMessage(chatID int64, text string) error {
 	msg := tgbotapi.NewMessage(chatID, text)
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+func TestClassifyTelegramError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      TelegramErrorKind
+		wantAfter time.Duration
+	}{
+		{"retry after", tgbotapi.Error{Message: "Too Many Requests: retry after 7", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 7}}, TelegramRateLimited, 7 * time.Second},
+		{"too many requests", tgbotapi.Error{Message: "Too Many Requests: retry after 3"}, TelegramRateLimited, time.Second},
+		{"blocked", tgbotapi.Error{Message: "Forbidden: bot was blocked by the user"}, TelegramBlocked, 0},
+		{"deactivated", tgbotapi.Error{Message: "Forbidden: user is deactivated"}, TelegramBlocked, 0},
+		{"chat not found", tgbotapi.Error{Message: "Bad Request: chat not found"}, TelegramChatNotFound, 0},
+		{"group upgraded", tgbotapi.Error{Message: "Bad Request: group chat was upgraded to a supergroup chat"}, TelegramChatNotFound, 0},
+		{"bad request", tgbotapi.Error{Message: "Bad Request: can't parse entities"}, TelegramBadRequest, 0},
+		{"wrapped", fmt.Errorf("failed to send message: %w", tgbotapi.Error{Message: "Forbidden: bot was kicked from the group chat"}), TelegramBlocked, 0},
+		{"network", errors.New("connection reset by peer"), TelegramTransient, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, after := ClassifyTelegramError(tt.err)
+			if kind != tt.want || after != tt.wantAfter {
+				t.Errorf("ClassifyTelegramError() = %v, %v, want %v, %v", kind, after, tt.want, tt.wantAfter)
+			}
+		})
+	}
+}
+
+// userDB serves a single user and records updates to it.
+type userDB struct {
+	mockDatabase
+	user    *models.UserData
+	updates int
+}
+
+func (m *userDB) GetUser(chatID int64) (*models.UserData, error) {
+	if m.user == nil || m.user.ChatID != chatID {
+		return nil, errors.New("user not found")
+	}
+	u := *m.user
+	return &u, nil
+}
+
+func (m *userDB) UpdateUser(user *models.UserData) error {
+	m.user = user
+	m.updates++
+	return nil
+}
+
+func TestKramerBot_handleUndeliverable(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantInactive bool
+	}{
+		{"blocked", tgbotapi.Error{Message: "Forbidden: bot was blocked by the user"}, true},
+		{"chat not found", tgbotapi.Error{Message: "Bad Request: chat not found"}, true},
+		{"bad request", tgbotapi.Error{Message: "Bad Request: message is too long"}, false},
+		{"out of retries", errors.New("connection reset by peer"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &userDB{user: &models.UserData{ChatID: 1, OzbGood: true}}
+			k := &KramerBot{Logger: zap.NewNop(), DataWriter: db, UserStore: &models.UserStore{Users: map[int64]*models.UserData{}}}
+
+			k.handleUndeliverable(models.OutboxMessage{ChatID: 1}, tt.err)
+			k.handleUndeliverable(models.OutboxMessage{ChatID: 2}, tt.err) // unknown chat is ignored
+
+			if db.user.Inactive != tt.wantInactive {
+				t.Errorf("Inactive = %v, want %v", db.user.Inactive, tt.wantInactive)
+			}
+			if cached := k.UserStore.GetUser(1); tt.wantInactive && (cached == nil || !cached.Inactive) {
+				t.Errorf("user store not updated: %+v", cached)
+			}
+		})
+	}
+}
@@ -9,6 +9,10 @@ import (
 // If it is, the user's Telegram chat ID is written to the web_users record and
 // a confirmation message is sent. Otherwise the normal registration flow runs.
 func (k *KramerBot) HandleTelegramLink(chat *tgbotapi.Chat, token string) {
+	if isGroup(chat) {
+		k.SendMessage(chat.ID, "Web accounts can only be linked in a private chat with the bot.")
+		return
+	}
 	if k.WebUserDB == nil {
 		// API not initialised — fall back to normal registration.
 		k.RegisterUser(chat)
@@ -64,6 +68,10 @@ func (k *KramerBot) HandleTelegramLink(chat *tgbotapi.Chat, token string) {
 		botUser.AmzDaily = webUser.AmzDaily
 		botUser.AmzWeekly = webUser.AmzWeekly
 		botUser.Keywords = webUser.Keywords
+		botUser.Categories = webUser.Categories
+		botUser.TopDeal = webUser.TopDeal
+		botUser.OzbTrending = webUser.OzbTrending
+		botUser.ExpiryNotices = webUser.ExpiryNotices
 		if err := k.DataWriter.UpdateUser(botUser); err != nil {
 			k.Logger.Warn("failed to sync web prefs to bot user after link", zap.Error(err))
 		}
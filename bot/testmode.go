@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"sync"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+// testModeTracker tracks the deals recorded in test mode. Deals already sent
+// by the live bot are looked up in the sent deals table, but recordings are
+// only remembered in memory, so test mode never writes to the database.
+type testModeTracker struct {
+	k *KramerBot
+
+	mu       sync.Mutex
+	recorded map[int64]map[string]bool // chat ID -> "source:id"
+}
+
+func (t *testModeTracker) DealSent(user *models.UserData, deal *models.Deal) bool {
+	t.mu.Lock()
+	recorded := t.recorded[user.ChatID][deal.Source+":"+deal.Id]
+	t.mu.Unlock()
+	return recorded || t.k.DealSent(user, deal)
+}
+
+func (t *testModeTracker) MarkDealSent(user *models.UserData, deal *models.Deal, channel string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.recorded == nil {
+		t.recorded = make(map[int64]map[string]bool)
+	}
+	if t.recorded[user.ChatID] == nil {
+		t.recorded[user.ChatID] = make(map[string]bool)
+	}
+	t.recorded[user.ChatID][deal.Source+":"+deal.Id] = true
+	return nil
+}
@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/notify"
+	sqlite_persist "github.com/intothevoid/kramerbot/persist/sqlite"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"github.com/intothevoid/kramerbot/util"
+	"go.uber.org/zap"
+)
+
+func TestKramerBot_processSource_testMode(t *testing.T) {
+	db, err := sqlite_persist.NewSQLiteWrapper(filepath.Join(t.TempDir(), "testmode_test.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.AddUser(&models.UserData{ChatID: 1, Username: "jerry", OzbGood: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddUser(&models.UserData{ChatID: 2, Username: "elaine", Keywords: []string{"dvd"}}); err != nil {
+		t.Fatal(err)
+	}
+	recorder, err := notify.NewRecorder("", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := &fixedSource{deals: []models.Deal{{
+		Source: scrapers.SOURCE_OZBARGAIN, Id: "42", Title: "Seinfeld DVD box set", Url: "https://www.ozbargain.com.au/node/42", DealType: int(scrapers.OZB_REG),
+	}}}
+	sources := scrapers.NewRegistry()
+	sources.Register(src)
+	k := &KramerBot{
+		Logger:     zap.NewNop(),
+		Sources:    sources,
+		DataWriter: db,
+		DealDB:     db,
+		SentDB:     db,
+		Config:     util.DefaultConfig(),
+		Recorder:   recorder,
+	}
+
+	// Scraping twice records each deal once per user
+	for range 2 {
+		if err := k.processSource(src); err != nil {
+			t.Fatalf("processSource() error = %v", err)
+		}
+	}
+
+	recs := recorder.Recent(10)
+	kinds := map[int64]string{}
+	for _, r := range recs {
+		kinds[r.ChatID] = r.Kind
+	}
+	if len(recs) != 2 || kinds[1] != "subscribed" || kinds[2] != "watched" {
+		t.Errorf("recorded %+v, want the deal for both users", recs)
+	}
+	if sent, _ := db.DealSent(1, src.Name(), "42"); sent {
+		t.Error("test mode marked the deal sent in the database")
+	}
+	if _, n, _ := db.QueryDeals(models.DealQuery{Source: src.Name()}); n != 0 {
+		t.Errorf("test mode stored %d deals in the database", n)
+	}
+}
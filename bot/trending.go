@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+)
+
+// trending reports whether an OzBargain deal is gaining votes fast enough,
+// while still young, to be sent to trending subscribers.
+func (k *KramerBot) trending(deal *models.Deal) bool {
+	if k.Config == nil || deal.Source != scrapers.SOURCE_OZBARGAIN {
+		return false
+	}
+	t := k.Config.Scrapers.OzBargain.Trending
+	if t.MinVelocity <= 0 || deal.Velocity < t.MinVelocity || deal.Votes() < t.MinVotes {
+		return false
+	}
+	return t.MaxAgeHours == 0 || deal.Age(time.Now()) < time.Duration(t.MaxAgeHours)*time.Hour
+}
+
+// ToggleOzbTrending toggles the OzbTrending preference
+func (k *KramerBot) ToggleOzbTrending(chat *tgbotapi.Chat) {
+	user, err := k.getUserData(chat.ID)
+	if err != nil {
+		return
+	}
+
+	user.OzbTrending = !user.OzbTrending
+	k.savePreferences(user) // Update DB, memory and the linked web account
+
+	k.SendMessage(chat.ID, fmt.Sprintf("OzBargain Trending Deals notifications set to: %t", user.OzbTrending))
+	k.ShowPreferences(chat)
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/watch"
+)
+
+// watchCache holds the keyword matcher compiled from every user's keywords.
+// It is shared by the per-source processing goroutines and only rebuilt when
+// a keyword is added, removed or changed.
+type watchCache struct {
+	mu      sync.Mutex
+	sum     uint64
+	matcher *watch.Matcher
+}
+
+// get returns the matcher for users, recompiling it if their keywords changed.
+func (c *watchCache) get(users map[int64]*models.UserData) *watch.Matcher {
+	sum := keywordsFingerprint(users)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.matcher == nil || c.sum != sum {
+		c.matcher = compileWatches(users)
+		c.sum = sum
+	}
+	return c.matcher
+}
+
+// compileWatches builds a matcher from every user's non-empty keywords.
+func compileWatches(users map[int64]*models.UserData) *watch.Matcher {
+	m := watch.NewMatcher()
+	for chatID, user := range users {
+		if user == nil {
+			continue
+		}
+		for _, keyword := range user.Keywords {
+			if strings.TrimSpace(keyword) != "" {
+				m.Add(chatID, watch.ParseOrLiteral(keyword))
+			}
+		}
+	}
+	return m
+}
+
+// keywordsFingerprint hashes every user's keywords in chat ID order.
+func keywordsFingerprint(users map[int64]*models.UserData) uint64 {
+	ids := make([]int64, 0, len(users))
+	for chatID, user := range users {
+		if user != nil && len(user.Keywords) > 0 {
+			ids = append(ids, chatID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	h := fnv.New64a()
+	for _, chatID := range ids {
+		h.Write([]byte(strconv.FormatInt(chatID, 10)))
+		for _, keyword := range users[chatID].Keywords {
+			h.Write([]byte{0})
+			h.Write([]byte(keyword))
+		}
+		h.Write([]byte{'\n'})
+	}
+	return h.Sum64()
+}
+
+// dealRecipients returns the users a deal should be sent to: subscribers of its
+// deal type or category, trending subscribers if it is trending, and users watching a
+// keyword that matches its title. Inactive and banned users, and users who muted the
+// deal's store, are skipped. Whether a user was already sent the deal is left to the
+// dispatcher.
+func (k *KramerBot) dealRecipients(deal *models.Deal, users map[int64]*models.UserData,
+	matcher *watch.Matcher) (subscribers, trending, watchers []*models.UserData) {
+
+	watching := make(map[int64]bool)
+	for _, chatID := range matcher.Match(watch.NewText(deal.Title)) {
+		watching[chatID] = true
+	}
+	isTrending := k.trending(deal)
+
+	store := deal.Store()
+	for chatID, user := range users {
+		if user == nil || user.Inactive || user.Banned || storeMuted(user, store) {
+			continue
+		}
+		switch {
+		case k.subscribedTo(user, deal) || categorySubscribed(user, deal):
+			subscribers = append(subscribers, user)
+		case isTrending && user.OzbTrending:
+			trending = append(trending, user)
+		case watching[chatID]:
+			watchers = append(watchers, user)
+		}
+	}
+	return subscribers, trending, watchers
+}
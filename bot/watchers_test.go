@@ -0,0 +1,250 @@
+package bot
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"github.com/intothevoid/kramerbot/util"
+	"github.com/intothevoid/kramerbot/watch"
+)
+
+func chatIDs(users []*models.UserData) []int64 {
+	ids := make([]int64, len(users))
+	for i, u := range users {
+		ids[i] = u.ChatID
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func TestKramerBot_dealRecipients(t *testing.T) {
+	users := map[int64]*models.UserData{
+		1:  {ChatID: 1, OzbGood: true},
+		2:  {ChatID: 2, Keywords: []string{"ssd"}},
+		3:  {ChatID: 3, Keywords: []string{"ssd", "samsung"}},
+		4:  {ChatID: 4, Keywords: []string{"ssd"}, OzbGood: true},
+		5:  {ChatID: 5, Keywords: []string{"nvme -refurbished"}},
+		6:  {ChatID: 6, OzbSuper: true},
+		7:  nil,
+		8:  {ChatID: 8, OzbGood: true, Keywords: []string{"ssd"}, Inactive: true},
+		9:  {ChatID: 9, OzbGood: true, MutedStores: []string{"amazon au"}},
+		10: {ChatID: 10, Categories: []models.CategorySub{{Category: "Computing"}}},
+		11: {ChatID: 11, Categories: []models.CategorySub{{Category: "Computing", MinVotes: 50}}},
+		12: {ChatID: 12, Categories: []models.CategorySub{{Category: "Gaming"}, {Category: "SSD", MinVotes: 10}}},
+		13: {ChatID: 13, Categories: []models.CategorySub{{Category: "computing"}}, Keywords: []string{"ssd"}},
+		14: {ChatID: 14, OzbTrending: true},
+		15: {ChatID: 15, OzbTrending: true, Keywords: []string{"ssd"}},
+		16: {ChatID: 16, OzbTrending: true, OzbGood: true},
+	}
+	deal := &models.Deal{
+		Id:       "d1",
+		Source:   scrapers.SOURCE_OZBARGAIN,
+		Title:    "Samsung 990 Pro NVMe SSD 2TB @ Amazon AU",
+		DealType: int(scrapers.OZB_REG),
+		Upvotes:  "12",
+		Category: "Computing",
+		Tags:     []string{"ssd", "samsung"},
+		DealAge:  "1h0m0s",
+		Velocity: 12,
+	}
+
+	k := &KramerBot{Config: &util.Config{Scrapers: util.ScrapersConfig{OzBargain: util.OzBargainConfig{
+		Trending: util.TrendingConfig{MinVelocity: 10, MinVotes: 5, MaxAgeHours: 6},
+	}}}}
+	subscribers, trending, watchers := k.dealRecipients(deal, users, compileWatches(users))
+
+	// Subscribers take precedence, so users 4, 13 and 16 are only sent the
+	// deal once, and trending alerts take precedence over keywords for user
+	// 15. User 3 matches two keywords but is listed once. User 8 blocked the
+	// bot, user 9 muted the store and the deal has too few votes for user 11.
+	// User 12 subscribed to one of its tags.
+	if got := fmt.Sprint(chatIDs(subscribers)); got != "[1 4 10 12 13 16]" {
+		t.Errorf("subscribers = %s, want [1 4 10 12 13 16]", got)
+	}
+	if got := fmt.Sprint(chatIDs(trending)); got != "[14 15]" {
+		t.Errorf("trending = %s, want [14 15]", got)
+	}
+	if got := fmt.Sprint(chatIDs(watchers)); got != "[2 3 5]" {
+		t.Errorf("watchers = %s, want [2 3 5]", got)
+	}
+}
+
+func TestKramerBot_trending(t *testing.T) {
+	k := &KramerBot{Config: &util.Config{Scrapers: util.ScrapersConfig{OzBargain: util.OzBargainConfig{
+		Trending: util.TrendingConfig{MinVelocity: 10, MinVotes: 5, MaxAgeHours: 6},
+	}}}}
+	ozb := func(votes string, velocity float64, age string) *models.Deal {
+		return &models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Upvotes: votes, Velocity: velocity, DealAge: age}
+	}
+
+	tests := []struct {
+		name string
+		deal *models.Deal
+		want bool
+	}{
+		{"fast and young", ozb("8", 16, "30m0s"), true},
+		{"too slow", ozb("8", 6, "30m0s"), false},
+		{"too few votes", ozb("3", 12, "15m0s"), false},
+		{"too old", ozb("40", 15, "7h0m0s"), false},
+		{"unknown age", ozb("8", 16, ""), true},
+		{"other source", &models.Deal{Source: scrapers.SOURCE_AMAZON, Upvotes: "8", Velocity: 16}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := k.trending(tt.deal); got != tt.want {
+				t.Errorf("trending() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if (&KramerBot{Config: &util.Config{}}).trending(ozb("8", 16, "30m0s")) {
+		t.Error("trending() = true with trending alerts turned off")
+	}
+}
+
+func TestWatchCache_Get(t *testing.T) {
+	users := map[int64]*models.UserData{
+		1: {ChatID: 1, Keywords: []string{"ssd"}},
+	}
+	var c watchCache
+
+	first := c.get(users)
+	if c.get(users) != first {
+		t.Error("matcher rebuilt although keywords did not change")
+	}
+
+	// A reloaded store with the same keywords reuses the matcher
+	reloaded := map[int64]*models.UserData{
+		1: {ChatID: 1, Keywords: []string{"ssd"}, OzbGood: true},
+	}
+	if c.get(reloaded) != first {
+		t.Error("matcher rebuilt although keywords did not change")
+	}
+
+	users[1].Keywords = append(users[1].Keywords, "ps5")
+	second := c.get(users)
+	if second == first {
+		t.Fatal("matcher not rebuilt after keywords changed")
+	}
+	if got := second.Match(watch.NewText("PS5 Slim")); len(got) != 1 || got[0] != 1 {
+		t.Errorf("Match() = %v, want [1]", got)
+	}
+}
+
+// BenchmarkDealRecipients fans a batch of deals out to synthetic users. The scan
+// case is the previous deal loop: keywords compiled every cycle, every user's
+// keywords evaluated for every deal and their sent list (then a JSON array on
+// the user) rebuilt into a map on each check.
+func BenchmarkDealRecipients(b *testing.B) {
+	k := &KramerBot{}
+	deals := syntheticDeals(100)
+
+	for _, n := range []int{100, 1000, 10000} {
+		users := syntheticUsers(n)
+		sentLists := syntheticSentLists(users)
+
+		b.Run(fmt.Sprintf("scan/users=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				exprs := make(map[int64][]watch.Expr, len(users))
+				for chatID, user := range users {
+					for _, kw := range user.Keywords {
+						exprs[chatID] = append(exprs[chatID], watch.ParseOrLiteral(kw))
+					}
+				}
+				for d := range deals {
+					text := watch.NewText(deals[d].Title)
+					for chatID, user := range users {
+						sentDeals := make(map[string]bool)
+						for _, id := range sentLists[chatID] {
+							sentDeals[id] = true
+						}
+						if sentDeals[deals[d].Id] || k.subscribedTo(user, &deals[d]) {
+							continue
+						}
+						for _, e := range exprs[chatID] {
+							if e.Match(text) {
+								break
+							}
+						}
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("matcher/users=%d", n), func(b *testing.B) {
+			var cache watchCache
+			for i := 0; i < b.N; i++ {
+				matcher := cache.get(users)
+				for d := range deals {
+					k.dealRecipients(&deals[d], users, matcher)
+				}
+			}
+		})
+	}
+}
+
+var benchWords = []string{
+	"samsung", "ssd", "nvme", "oled", "sony", "ps5", "xbox", "switch", "nintendo", "lego",
+	"dyson", "iphone", "kindle", "monitor", "laptop", "coffee", "drill", "makita", "console",
+	"router", "camera", "bike", "gpu", "rtx", "ryzen", "keyboard", "chair", "speaker",
+}
+
+func benchWord(r *rand.Rand) string {
+	if r.Intn(8) == 0 {
+		return benchWords[r.Intn(len(benchWords))]
+	}
+	return fmt.Sprintf("model%d", r.Intn(5000))
+}
+
+// syntheticUsers creates n keyword-only users, each with three keywords.
+func syntheticUsers(n int) map[int64]*models.UserData {
+	r := rand.New(rand.NewSource(1))
+	users := make(map[int64]*models.UserData, n)
+	for id := int64(1); id <= int64(n); id++ {
+		user := &models.UserData{ChatID: id}
+		for j := 0; j < 3; j++ {
+			if r.Intn(2) == 0 {
+				user.Keywords = append(user.Keywords, benchWord(r))
+			} else {
+				user.Keywords = append(user.Keywords, benchWord(r)+" OR "+benchWord(r))
+			}
+		}
+		users[id] = user
+	}
+	return users
+}
+
+// syntheticSentLists gives every user 200 previously sent deal ids.
+func syntheticSentLists(users map[int64]*models.UserData) map[int64][]string {
+	r := rand.New(rand.NewSource(3))
+	lists := make(map[int64][]string, len(users))
+	for chatID := range users {
+		for j := 0; j < 200; j++ {
+			lists[chatID] = append(lists[chatID], fmt.Sprintf("%d", r.Intn(100000)))
+		}
+	}
+	return lists
+}
+
+func syntheticDeals(n int) []models.Deal {
+	r := rand.New(rand.NewSource(2))
+	deals := make([]models.Deal, n)
+	for i := range deals {
+		words := make([]string, 6+r.Intn(5))
+		for j := range words {
+			words[j] = benchWord(r)
+		}
+		deals[i] = models.Deal{
+			Id:       fmt.Sprintf("%d", 100000+i),
+			Source:   scrapers.SOURCE_OZBARGAIN,
+			Title:    strings.Join(words, " "),
+			DealType: int(scrapers.OZB_REG),
+		}
+	}
+	return deals
+}
@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"go.uber.org/zap"
+)
+
+// Header Telegram sends the webhook's secret token in
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Updates received through the webhook that wait for BotProc. When it is
+// full the endpoint answers 503 and Telegram retries later.
+const webhookQueueSize = 100
+
+// webhookEnabled reports whether updates come from the webhook rather than
+// long polling.
+func (k *KramerBot) webhookEnabled() bool {
+	return k.Config != nil && k.Config.Telegram.Webhook.Enabled
+}
+
+// webhookQueue is the channel the webhook endpoint passes updates to BotProc
+// through.
+func (k *KramerBot) webhookQueue() chan tgbotapi.Update {
+	k.webhookOnce.Do(func() {
+		k.webhookUpdates = make(chan tgbotapi.Update, webhookQueueSize)
+	})
+	return k.webhookUpdates
+}
+
+// WebhookHandler serves the endpoint Telegram posts updates to. Requests
+// without the configured secret token are refused.
+func (k *KramerBot) WebhookHandler() http.Handler {
+	updates := k.webhookQueue()
+	secret := []byte(k.Config.Telegram.Webhook.Secret)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := []byte(r.Header.Get(webhookSecretHeader))
+		if len(secret) == 0 || subtle.ConstantTimeCompare(token, secret) != 1 {
+			k.Logger.Warn("Refused Telegram webhook request without a valid secret token", zap.String("remote", r.RemoteAddr))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		var update tgbotapi.Update
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&update); err != nil {
+			k.Logger.Warn("Failed to decode Telegram update", zap.Error(err))
+			http.Error(w, "invalid update", http.StatusBadRequest)
+			return
+		}
+
+		// Don't hold Telegram's connection open while BotProc catches up
+		select {
+		case updates <- update:
+			w.WriteHeader(http.StatusOK)
+		default:
+			k.Logger.Warn("Telegram webhook queue is full", zap.Int("update_id", update.UpdateID))
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// webhookURL is the public URL Telegram posts updates to.
+func (k *KramerBot) webhookURL() string {
+	hook := k.Config.Telegram.Webhook
+	if hook.URL != "" {
+		return hook.URL
+	}
+	return strings.TrimRight(k.Config.API.WebURL, "/") + hook.Path
+}
+
+// setWebhook registers the webhook with Telegram. The library predates secret
+// tokens, so the request is made directly.
+func (k *KramerBot) setWebhook() error {
+	params := url.Values{}
+	params.Set("url", k.webhookURL())
+	params.Set("secret_token", k.Config.Telegram.Webhook.Secret)
+	if _, err := k.BotApi.MakeRequest("setWebhook", params); err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+	return nil
+}
+
+// receiveUpdates returns the channel of updates from Telegram: the webhook
+// endpoint's queue, or a long polling channel.
+func (k *KramerBot) receiveUpdates() (tgbotapi.UpdatesChannel, error) {
+	if k.webhookEnabled() {
+		if err := k.setWebhook(); err != nil {
+			return nil, err
+		}
+		k.Logger.Info("Receiving updates through webhook", zap.String("url", k.webhookURL()))
+		return k.webhookQueue(), nil
+	}
+
+	// Telegram refuses getUpdates while a webhook is set, e.g. one left
+	// over from running in webhook mode
+	if _, err := k.BotApi.RemoveWebhook(); err != nil {
+		k.Logger.Warn("Failed to remove webhook", zap.Error(err))
+	}
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	return k.BotApi.GetUpdatesChan(u)
+}
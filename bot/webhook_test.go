@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/intothevoid/kramerbot/util"
+	"go.uber.org/zap"
+)
+
+func newWebhookBot() *KramerBot {
+	return &KramerBot{
+		Logger: zap.NewNop(),
+		Config: &util.Config{
+			API:      util.APIConfig{WebURL: "https://kramer.example.com/"},
+			Telegram: util.TelegramConfig{Webhook: util.WebhookConfig{Enabled: true, Path: "/telegram/updates", Secret: "s3cret"}},
+		},
+	}
+}
+
+func TestKramerBot_WebhookHandler(t *testing.T) {
+	k := newWebhookBot()
+	handler := k.WebhookHandler()
+
+	tests := []struct {
+		name   string
+		secret string
+		body   string
+		want   int
+	}{
+		{"no secret", "", `{"update_id":1}`, http.StatusForbidden},
+		{"wrong secret", "guess", `{"update_id":1}`, http.StatusForbidden},
+		{"invalid update", "s3cret", `{`, http.StatusBadRequest},
+		{"update", "s3cret", `{"update_id":7,"message":{"message_id":1,"text":"/help","chat":{"id":42,"type":"private"}}}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/telegram/updates", strings.NewReader(tt.body))
+			if tt.secret != "" {
+				req.Header.Set(webhookSecretHeader, tt.secret)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	if n := len(k.webhookQueue()); n != 1 {
+		t.Fatalf("queued %d updates, want 1", n)
+	}
+	update := <-k.webhookQueue()
+	if update.UpdateID != 7 || update.Message == nil || update.Message.Chat.ID != 42 || update.Message.Text != "/help" {
+		t.Errorf("queued update = %+v", update)
+	}
+}
+
+// Test that a full queue is answered with 503 straight away, so Telegram
+// retries later instead of waiting on BotProc
+func TestKramerBot_WebhookHandler_QueueFull(t *testing.T) {
+	k := newWebhookBot()
+	handler := k.WebhookHandler()
+	for i := range webhookQueueSize {
+		k.webhookQueue() <- tgbotapi.Update{UpdateID: i}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/telegram/updates", strings.NewReader(`{"update_id":1000}`))
+	req.Header.Set(webhookSecretHeader, "s3cret")
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rec, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler blocked on a full queue")
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if n := len(k.webhookQueue()); n != webhookQueueSize {
+		t.Errorf("queued %d updates, want %d", n, webhookQueueSize)
+	}
+}
+
+func TestKramerBot_webhookURL(t *testing.T) {
+	k := newWebhookBot()
+	if got, want := k.webhookURL(), "https://kramer.example.com/telegram/updates"; got != want {
+		t.Errorf("webhookURL() = %q, want %q", got, want)
+	}
+	k.Config.Telegram.Webhook.URL = "https://ingress.example.com/tg"
+	if got, want := k.webhookURL(), "https://ingress.example.com/tg"; got != want {
+		t.Errorf("webhookURL() = %q, want %q", got, want)
+	}
+}
@@ -6,6 +6,9 @@ import type {
   AmazonDeal,
   TelegramLinkResponse,
   TelegramStatus,
+  TopDealRule,
+  Webhook,
+  WebhookDelivery,
   WebUser,
 } from '../types';
 
@@ -25,6 +28,10 @@ export async function updatePreferences(prefs: {
   amz_daily: boolean;
   amz_weekly: boolean;
   email_summary: boolean;
+  channels?: string[];
+  top_deal?: TopDealRule;
+  ozb_trending?: boolean;
+  expiry_notices?: boolean;
 }): Promise<WebUser> {
   const res = await api.put<APIResponse<WebUser>>('/user/preferences', prefs);
   return res.data.data!;
@@ -67,3 +74,29 @@ export async function getAmazonDeals(type?: string, limit = 50, offset = 0): Pro
   const res = await api.get<APIResponse<DealsPage<AmazonDeal>>>('/deals/amazon', { params });
   return res.data.data!;
 }
+
+export async function getWebhooks(): Promise<Webhook[]> {
+  const res = await api.get<APIResponse<{ webhooks: Webhook[] }>>('/user/webhooks');
+  return res.data.data?.webhooks ?? [];
+}
+
+export async function createWebhook(url: string): Promise<Webhook> {
+  const res = await api.post<APIResponse<Webhook>>('/user/webhooks', { url });
+  return res.data.data!;
+}
+
+export async function deleteWebhook(id: string): Promise<void> {
+  await api.delete(`/user/webhooks/${encodeURIComponent(id)}`);
+}
+
+export async function testWebhook(id: string): Promise<WebhookDelivery> {
+  const res = await api.post<APIResponse<WebhookDelivery>>(`/user/webhooks/${encodeURIComponent(id)}/test`);
+  return res.data.data!;
+}
+
+export async function getWebhookDeliveries(id: string): Promise<WebhookDelivery[]> {
+  const res = await api.get<APIResponse<{ deliveries: WebhookDelivery[] }>>(
+    `/user/webhooks/${encodeURIComponent(id)}/deliveries`,
+  );
+  return res.data.data?.deliveries ?? [];
+}
@@ -1,4 +1,4 @@
-import { ExternalLink, ThumbsUp, Clock } from 'lucide-react';
+import { ExternalLink, ThumbsUp, Clock, TrendingUp } from 'lucide-react';
 import type { OzbDeal, AmazonDeal } from '../types';
 
 function OzbBadge({ type }: { type: number }) {
@@ -45,6 +45,12 @@ export function OzbDealCard({ deal }: { deal: OzbDeal }) {
           <ThumbsUp className="h-3.5 w-3.5" />
           {deal.upvotes}
         </span>
+        {!!deal.velocity && (
+          <span className="flex items-center gap-1" title="Votes in the last hour">
+            <TrendingUp className="h-3.5 w-3.5" />
+            {deal.velocity}/h
+          </span>
+        )}
         <span className="flex items-center gap-1">
           <Clock className="h-3.5 w-3.5" />
           {deal.dealage || deal.time}
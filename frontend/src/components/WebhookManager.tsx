@@ -0,0 +1,111 @@
+import { useState } from 'react';
+import { Trash2, Send, Copy } from 'lucide-react';
+import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
+import { createWebhook, deleteWebhook, getWebhooks, testWebhook } from '../api/user';
+import { Spinner } from './Spinner';
+
+interface Props {
+  onMessage: (msg: string) => void;
+}
+
+export function WebhookManager({ onMessage }: Props) {
+  const qc = useQueryClient();
+  const [url, setUrl] = useState('');
+  const [secret, setSecret] = useState<string | null>(null);
+
+  const { data: hooks = [], isLoading } = useQuery({
+    queryKey: ['webhooks'],
+    queryFn: getWebhooks,
+  });
+
+  const apiError = (err: unknown, fallback: string) =>
+    (err as { response?: { data?: { error?: string } } })?.response?.data?.error ?? fallback;
+
+  const createMutation = useMutation({
+    mutationFn: createWebhook,
+    onSuccess: (hook) => {
+      setUrl('');
+      setSecret(hook.secret ?? null);
+      qc.invalidateQueries({ queryKey: ['webhooks'] });
+    },
+    onError: (err) => onMessage(apiError(err, 'Could not add webhook')),
+  });
+
+  const deleteMutation = useMutation({
+    mutationFn: deleteWebhook,
+    onSuccess: () => qc.invalidateQueries({ queryKey: ['webhooks'] }),
+  });
+
+  const testMutation = useMutation({
+    mutationFn: testWebhook,
+    onSuccess: (d) =>
+      onMessage(d.status_code && d.status_code < 300 ? `Test delivered (${d.status_code})` : `Test failed: ${d.error || d.status_code}`),
+    onError: (err) => onMessage(apiError(err, 'Could not send test')),
+  });
+
+  if (isLoading) return <Spinner size="sm" />;
+
+  return (
+    <div className="mt-2 space-y-2">
+      {hooks.map((h) => (
+        <div key={h.id} className="flex items-center gap-2 text-xs">
+          <span className="flex-1 truncate text-slate-600" title={h.url}>
+            {h.url}
+          </span>
+          <button
+            onClick={() => testMutation.mutate(h.id)}
+            disabled={testMutation.isPending}
+            title="Send test payload"
+            className="text-slate-400 hover:text-slate-700 disabled:opacity-50"
+          >
+            <Send className="h-3.5 w-3.5" />
+          </button>
+          <button
+            onClick={() => deleteMutation.mutate(h.id)}
+            disabled={deleteMutation.isPending}
+            title="Delete webhook"
+            className="disabled:opacity-50"
+            style={{ color: 'var(--kb-red)' }}
+          >
+            <Trash2 className="h-3.5 w-3.5" />
+          </button>
+        </div>
+      ))}
+
+      {secret && (
+        <div className="rounded-lg border border-amber-200 bg-amber-50 p-2 text-xs text-amber-900">
+          <p className="mb-1">Signing secret — copy it now, it won't be shown again:</p>
+          <div className="flex items-center gap-2">
+            <code className="flex-1 truncate">{secret}</code>
+            <button onClick={() => navigator.clipboard.writeText(secret)} title="Copy secret">
+              <Copy className="h-3.5 w-3.5" />
+            </button>
+          </div>
+        </div>
+      )}
+
+      <form
+        onSubmit={(e) => {
+          e.preventDefault();
+          if (url.trim()) createMutation.mutate(url.trim());
+        }}
+        className="flex gap-2"
+      >
+        <input
+          value={url}
+          onChange={(e) => setUrl(e.target.value)}
+          placeholder="https://example.com/deals"
+          className="input-field flex-1"
+        />
+        <button
+          type="submit"
+          disabled={createMutation.isPending}
+          className="btn-red px-3 text-xs"
+          style={{ borderRadius: '0.5rem' }}
+        >
+          Add
+        </button>
+      </form>
+    </div>
+  );
+}
@@ -12,6 +12,7 @@ import {
 } from '../api/user';
 import { OzbDealCard, AmazonDealCard } from '../components/DealCard';
 import { TelegramLinker } from '../components/TelegramLinker';
+import { WebhookManager } from '../components/WebhookManager';
 import { Spinner } from '../components/Spinner';
 import { Toast } from '../components/Toast';
 import { useToast } from '../hooks/useToast';
@@ -19,6 +20,9 @@ import type { WebUser } from '../types';
 
 type Tab = 'ozb-good' | 'ozb-super' | 'amz-daily' | 'amz-weekly';
 
+// Channels used when the user has not picked any (mirrors notify.DefaultChannels)
+const defaultChannels = ['telegram', 'pipup'];
+
 const tabs: { id: Tab; label: string }[] = [
   { id: 'ozb-good', label: '🛒 OzBargain Regular' },
   { id: 'ozb-super', label: '⚡ OzBargain Top' },
@@ -107,6 +111,10 @@ export default function Dashboard({ user, onSignOut }: Props) {
       qc.setQueryData(['keywords'], updated);
       showToast('Keyword added!');
     },
+    onError: (err) => {
+      const apiMsg = (err as { response?: { data?: { error?: string } } })?.response?.data?.error;
+      showToast(apiMsg ?? 'Could not add keyword');
+    },
   });
   const removeKw = useMutation({
     mutationFn: removeKeyword,
@@ -123,15 +131,43 @@ export default function Dashboard({ user, onSignOut }: Props) {
       qc.setQueryData(['profile'], updated);
       showToast('Preferences updated!');
     },
+    onError: (err) => {
+      const apiMsg = (err as { response?: { data?: { error?: string } } })?.response?.data?.error;
+      showToast(apiMsg ?? 'Could not update preferences');
+    },
   });
 
-  const handlePrefToggle = (key: 'ozb_good' | 'ozb_super' | 'amz_daily' | 'amz_weekly' | 'email_summary', value: boolean) => {
+  const handlePrefToggle = (
+    key: 'ozb_good' | 'ozb_super' | 'ozb_trending' | 'amz_daily' | 'amz_weekly' | 'email_summary' | 'expiry_notices',
+    value: boolean,
+  ) => {
     prefsMutation.mutate({
       ozb_good: key === 'ozb_good' ? value : profile.ozb_good ?? false,
       ozb_super: key === 'ozb_super' ? value : profile.ozb_super ?? false,
+      ozb_trending: key === 'ozb_trending' ? value : profile.ozb_trending ?? false,
       amz_daily: key === 'amz_daily' ? value : profile.amz_daily ?? false,
       amz_weekly: key === 'amz_weekly' ? value : profile.amz_weekly ?? false,
       email_summary: key === 'email_summary' ? value : profile.email_summary ?? false,
+      expiry_notices: key === 'expiry_notices' ? value : profile.expiry_notices ?? false,
+    });
+  };
+
+  const channels = profile.channels?.length ? profile.channels : defaultChannels;
+
+  const savePrefs = (extra: { channels?: string[] }) => {
+    prefsMutation.mutate({
+      ozb_good: profile.ozb_good ?? false,
+      ozb_super: profile.ozb_super ?? false,
+      amz_daily: profile.amz_daily ?? false,
+      amz_weekly: profile.amz_weekly ?? false,
+      email_summary: profile.email_summary ?? false,
+      ...extra,
+    });
+  };
+
+  const handleChannelToggle = (channel: string, value: boolean) => {
+    savePrefs({
+      channels: value ? [...channels, channel] : channels.filter((c) => c !== channel),
     });
   };
 
@@ -181,6 +217,12 @@ export default function Dashboard({ user, onSignOut }: Props) {
             onChange={(v) => handlePrefToggle('ozb_super', v)}
             disabled={prefsMutation.isPending}
           />
+          <ToggleRow
+            label="📈 OzBargain Trending (gaining votes fast)"
+            checked={profile.ozb_trending ?? false}
+            onChange={(v) => handlePrefToggle('ozb_trending', v)}
+            disabled={prefsMutation.isPending}
+          />
           <ToggleRow
             label="📅 Amazon Daily"
             checked={profile.amz_daily ?? false}
@@ -199,7 +241,47 @@ export default function Dashboard({ user, onSignOut }: Props) {
             onChange={(v) => handlePrefToggle('email_summary', v)}
             disabled={prefsMutation.isPending}
           />
+          <ToggleRow
+            label="⌛ Expired Deal Notices (Telegram)"
+            checked={profile.expiry_notices ?? false}
+            onChange={(v) => handlePrefToggle('expiry_notices', v)}
+            disabled={prefsMutation.isPending}
+          />
+        </div>
+      </div>
+
+      {/* Delivery channels */}
+      <div className="card">
+        <h3
+          className="serif mb-1 text-sm font-bold uppercase tracking-wide"
+          style={{ color: 'var(--kb-red)' }}
+        >
+          Delivery
+        </h3>
+        <p className="mb-3 text-xs text-slate-500">
+          Where matching deals are sent.
+        </p>
+        <div className="divide-y divide-slate-100">
+          <ToggleRow
+            label="✈️ Telegram"
+            checked={channels.includes('telegram')}
+            onChange={(v) => handleChannelToggle('telegram', v)}
+            disabled={prefsMutation.isPending}
+          />
+          <ToggleRow
+            label="📧 Email (each deal)"
+            checked={channels.includes('email')}
+            onChange={(v) => handleChannelToggle('email', v)}
+            disabled={prefsMutation.isPending}
+          />
+          <ToggleRow
+            label="🔗 Webhook"
+            checked={channels.includes('webhook')}
+            onChange={(v) => handleChannelToggle('webhook', v)}
+            disabled={prefsMutation.isPending}
+          />
         </div>
+        {channels.includes('webhook') && <WebhookManager onMessage={showToast} />}
       </div>
 
       {/* Keywords */}
@@ -220,7 +302,7 @@ export default function Dashboard({ user, onSignOut }: Props) {
           <input
             value={newKeyword}
             onChange={(e) => setNewKeyword(e.target.value)}
-            placeholder="e.g. iPad"
+            placeholder={'e.g. ipad OR "galaxy tab" -case'}
             className="input-field flex-1"
           />
           <button
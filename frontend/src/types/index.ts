@@ -10,10 +10,21 @@ export interface WebUser {
   amz_weekly?: boolean;
   email_summary?: boolean;
   keywords?: string[];
+  channels?: string[];
+  top_deal?: TopDealRule;
+  ozb_trending?: boolean;
+  expiry_notices?: boolean;
   created_at: string;
   updated_at: string;
 }
 
+// Zero min_votes or max_age_hours use the default of 25 votes in 24 hours
+export interface TopDealRule {
+  min_votes: number;
+  max_age_hours: number;
+  min_votes_per_hour?: number;
+}
+
 export interface OzbDeal {
   id: string;
   title: string;
@@ -22,6 +33,7 @@ export interface OzbDeal {
   upvotes: string;
   dealage: string;
   dealtype: number;
+  velocity?: number; // votes per hour over the last hour
 }
 
 export interface AmazonDeal {
@@ -59,3 +71,24 @@ export interface TelegramStatus {
   linked: boolean;
   telegram_username?: string;
 }
+
+export interface Webhook {
+  id: string;
+  url: string;
+  secret?: string; // only present right after creation
+  created_at: string;
+}
+
+export interface WebhookDelivery {
+  id: number;
+  webhook_id: string;
+  delivery_id: string;
+  event: string;
+  source: string;
+  deal_id: string;
+  attempt: number;
+  status_code?: number;
+  error?: string;
+  duration_ms: number;
+  created_at: string;
+}
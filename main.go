@@ -2,10 +2,9 @@ package main
 
 import (
 	"context"
+	"net/http"
 	"os"
 	"os/signal"
-	"sort"
-	"strconv"
 	"syscall"
 	"time"
 
@@ -56,26 +55,14 @@ func main() {
 		logger.Fatal("Cannot proceed without a bot token, is the TELEGRAM_BOT_TOKEN environment variable set?")
 	}
 
-	// Create Ozbargain scraper
-	ozbscraper := new(scrapers.OzBargainScraper)
-	ozbscraper.SID = scrapers.SID_OZBARGAIN
-	ozbscraper.Logger = logger
-	ozbscraper.BaseUrl = scrapers.URL_OZBARGAIN
-	ozbscraper.Deals = []models.OzBargainDeal{}
-	ozbscraper.ScrapeInterval = config.Scrapers.OzBargain.ScrapeInterval
-	ozbscraper.MaxDealsToStore = config.Scrapers.OzBargain.MaxStoredDeals
-
-	// Create CamelCamelCamel (Amazon) scraper
-	cccscraper := new(scrapers.CamCamCamScraper)
-	cccscraper.SID = scrapers.SID_CCC_AMAZON
-	cccscraper.Logger = logger
-	cccscraper.BaseUrl = config.Scrapers.Amazon.URLs
-	cccscraper.Deals = []models.CamCamCamDeal{}
-	cccscraper.ScrapeInterval = config.Scrapers.Amazon.ScrapeInterval
-	cccscraper.MaxDealsToStore = config.Scrapers.Amazon.MaxStoredDeals
+	// Register every deal source enabled in the configuration
+	sources, err := scrapers.NewRegistryFromConfig(config, logger)
+	if err != nil {
+		logger.Fatal("Failed to register deal sources", zap.Error(err))
+	}
 
 	// Initialise bot (creates DB connection internally)
-	k.NewBot(ozbscraper, cccscraper)
+	k.NewBot(sources)
 
 	// Wire the WebUserDB so the bot can resolve Telegram link tokens.
 	if sw, ok := k.DataWriter.(*sqlite_persist.SQLiteWrapper); ok {
@@ -84,9 +71,12 @@ func main() {
 		logger.Warn("DataWriter is not *SQLiteWrapper; Telegram linking will be unavailable")
 	}
 
+	// Email is used for deal notifications as well as account emails.
+	emailSvc := util.NewEmailService(config.SMTP)
+	k.EmailSvc = emailSvc
+
 	// Start the HTTP API server in the background (if enabled).
 	if config.API.Enabled {
-		emailSvc := util.NewEmailService(config.SMTP)
 		if emailSvc.Enabled() {
 			logger.Info("SMTP configured",
 				zap.String("host", config.SMTP.Host),
@@ -97,7 +87,13 @@ func main() {
 		} else {
 			logger.Warn("SMTP not configured — verification/reset links will be logged only (set SMTP_HOST to enable email)")
 		}
-		srv, err := api.NewServer(config, k.DataWriter, ozbscraper, cccscraper, logger, staticFiles, emailSvc)
+		// Telegram posts updates to the API server in webhook mode
+		var telegramUpdates http.Handler
+		if config.Telegram.Webhook.Enabled && !config.TestMode {
+			telegramUpdates = k.WebhookHandler()
+		}
+
+		srv, err := api.NewServer(config, k.DataWriter, sources, logger, staticFiles, emailSvc, telegramUpdates, k.Recorder)
 		if err != nil {
 			logger.Fatal("Failed to create API server", zap.Error(err))
 		}
@@ -115,7 +111,7 @@ func main() {
 					zap.String("timezone", config.API.SummaryTimezone), zap.Error(err))
 				loc = time.UTC
 			}
-			startDailySummaryScheduler(logger, k.WebUserDB, ozbscraper, cccscraper, emailSvc, loc)
+			startDailySummaryScheduler(logger, k.WebUserDB, k.DealDB, sources, emailSvc, loc)
 		}
 
 		// Graceful shutdown on SIGINT / SIGTERM — exits the whole process.
@@ -149,8 +145,8 @@ func main() {
 func startDailySummaryScheduler(
 	logger *zap.Logger,
 	webUserDB persist.WebUserDBIF,
-	ozbScraper *scrapers.OzBargainScraper,
-	cccScraper *scrapers.CamCamCamScraper,
+	dealDB persist.DealDBIF,
+	sources *scrapers.Registry,
 	emailSvc *util.EmailService,
 	loc *time.Location,
 ) {
@@ -163,7 +159,7 @@ func startDailySummaryScheduler(
 			}
 			logger.Info("Daily summary scheduled", zap.Time("next_run", next))
 			time.Sleep(time.Until(next))
-			sendDailySummaries(logger, webUserDB, ozbScraper, cccScraper, emailSvc)
+			sendDailySummaries(logger, webUserDB, dealDB, sources, emailSvc)
 		}
 	}()
 }
@@ -171,8 +167,8 @@ func startDailySummaryScheduler(
 func sendDailySummaries(
 	logger *zap.Logger,
 	webUserDB persist.WebUserDBIF,
-	ozbScraper *scrapers.OzBargainScraper,
-	cccScraper *scrapers.CamCamCamScraper,
+	dealDB persist.DealDBIF,
+	sources *scrapers.Registry,
 	emailSvc *util.EmailService,
 ) {
 	users, err := webUserDB.GetAllVerifiedWebUsers()
@@ -183,39 +179,28 @@ func sendDailySummaries(
 
 	const maxDealAge = 24 * time.Hour
 
-	// Collect OZB_SUPER deals posted within the last 24 hours, sorted by upvotes descending.
-	// GetDealAge re-parses PostedOn at call time, so it reflects current age not scrape-time age.
-	var ozbDeals []models.OzBargainDeal
-	for _, d := range ozbScraper.Deals {
-		if d.DealType == int(scrapers.OZB_SUPER) && ozbScraper.GetDealAge(d.PostedOn) <= maxDealAge {
-			ozbDeals = append(ozbDeals, d)
-		}
-	}
-	sort.Slice(ozbDeals, func(i, j int) bool {
-		vi, _ := strconv.Atoi(ozbDeals[i].Upvotes)
-		vj, _ := strconv.Atoi(ozbDeals[j].Upvotes)
-		return vi > vj
-	})
-
-	// Collect AMZ_DAILY deals published within the last 24 hours.
-	// gofeed sets Published as RFC1123Z or RFC1123; try both.
-	amzLayouts := []string{time.RFC1123Z, time.RFC1123}
-	cutoff := time.Now().Add(-maxDealAge)
-	var amzDeals []models.CamCamCamDeal
-	for _, d := range cccScraper.Deals {
-		if d.DealType != int(scrapers.AMZ_DAILY) {
-			continue
-		}
-		withinDay := false
-		for _, layout := range amzLayouts {
-			if t, err := time.Parse(layout, d.Published); err == nil {
-				withinDay = t.After(cutoff)
-				break
+	// One section per source: top deals posted within the last 24 hours that are still
+	// available, sorted by upvotes.
+	// Deals come from the stored history so a restart during the day loses nothing.
+	var sections []util.DigestSection
+	for _, src := range sources.Sources() {
+		deals := src.GetDeals()
+		if dealDB != nil {
+			stored, _, err := dealDB.QueryDeals(models.DealQuery{
+				Source: src.Name(),
+				Since:  time.Now().Add(-maxDealAge),
+				Active: true,
+			})
+			if err != nil {
+				logger.Error("daily summary: failed to load deals", zap.String("source", src.Name()), zap.Error(err))
+			} else {
+				deals = stored
 			}
 		}
-		if withinDay {
-			amzDeals = append(amzDeals, d)
-		}
+		sections = append(sections, util.DigestSection{
+			Title: "Top " + src.DisplayName() + " Deals",
+			Deals: scrapers.TopDeals(deals, maxDealAge),
+		})
 	}
 
 	sent := 0
@@ -223,7 +208,7 @@ func sendDailySummaries(
 		if !user.EmailSummary {
 			continue
 		}
-		if err := emailSvc.SendDailySummary(user.Email, ozbDeals, amzDeals); err != nil {
+		if err := emailSvc.SendDailySummary(user.Email, sections); err != nil {
 			logger.Error("daily summary: send failed", zap.String("email", user.Email), zap.Error(err))
 		} else {
 			sent++
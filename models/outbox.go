@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Outbox message states.
+const (
+	OutboxPending = "pending"
+	OutboxDead    = "dead" // gave up; kept for inspection
+)
+
+// OutboxMessage is a Telegram message waiting to be sent.
+type OutboxMessage struct {
+	ID            int64            `json:"id"`
+	ChatID        int64            `json:"chat_id"`
+	Text          string           `json:"text"`
+	ParseMode     string           `json:"parse_mode,omitempty"`
+	Buttons       [][]InlineButton `json:"buttons,omitempty"` // rows of inline buttons under the message
+	Batch         string           `json:"batch,omitempty"`   // ID shared by the messages of one broadcast
+	Status        string           `json:"status"`
+	Attempts      int              `json:"attempts"`
+	NextAttemptAt time.Time        `json:"next_attempt_at"`
+	LastError     string           `json:"last_error,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// InlineButton is a button under a Telegram message. Data is sent back to the
+// bot as a callback query when the button is tapped.
+type InlineButton struct {
+	Text string `json:"text"`
+	Data string `json:"data"`
+}
+
+// OutboxStats summarises the outbox for monitoring.
+type OutboxStats struct {
+	Pending int        `json:"pending"` // waiting to be sent, including scheduled retries
+	Due     int        `json:"due"`     // pending and ready to send now
+	Dead    int        `json:"dead"`
+	Oldest  *time.Time `json:"oldest_pending,omitempty"`
+}
@@ -1,5 +1,118 @@
 package models
 
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Deal is the source-agnostic deal model produced by every registered source.
+// JSON tags mirror the per-site types below so existing API clients keep working.
+type Deal struct {
+	Id          string    `json:"id"`
+	Source      string    `json:"source"` // registry name of the source, e.g. "ozbargain"
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"` // deal body text, empty if the source only lists titles
+	Url         string    `json:"url"`
+	PostedOn    string    `json:"time"`              // raw posted/published text as scraped
+	PostedAt    time.Time `json:"posted_at"`         // parsed PostedOn, zero if unknown
+	Upvotes     string    `json:"upvotes,omitempty"` // community votes, empty for sources without voting
+	DealAge     string    `json:"dealage,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	PriceDrop   string    `json:"price_drop,omitempty"` // human readable price drop, e.g. "down 5.00% ($15) to $300"
+	DropPercent int       `json:"drop_percent,omitempty"`
+	DealType    int       `json:"dealtype"`
+	FirstSeen   time.Time `json:"first_seen,omitzero"` // first scrape that returned the deal (stored deals only)
+	LastSeen    time.Time `json:"last_seen,omitzero"`  // latest scrape that returned the deal (stored deals only)
+
+	// Details from the deal page, for sources that enrich their listings
+	Price       *float64  `json:"price,omitempty"`        // nil if unknown, 0 for free deals
+	StoreDomain string    `json:"store_domain,omitempty"` // merchant domain, e.g. "jbhifi.com.au"
+	CouponCode  string    `json:"coupon_code,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`
+	Poster      string    `json:"poster,omitempty"` // username of the member who posted the deal
+
+	// Votes per hour over the last VelocityWindow, 0 if unknown
+	Velocity float64 `json:"velocity,omitempty"`
+
+	// Set once the source marks the deal as no longer available
+	Expiry    string    `json:"expiry,omitempty"`    // DealExpired or DealOutOfStock
+	ExpiredAt time.Time `json:"expired_at,omitzero"` // first time the expiry was seen (stored deals only)
+}
+
+// DealQuery filters stored deals. Zero values mean "no filter"; a zero Limit returns every match.
+type DealQuery struct {
+	Source   string
+	DealType int
+	Since    time.Time // only deals posted (or first seen) at or after this time
+	Active   bool      // leave out expired and out of stock deals
+	Limit    int
+	Offset   int
+}
+
+// DealSearch is a full-text search over stored deals. From/To bound the posting
+// (or first seen) time; Cursor continues a previous page.
+type DealSearch struct {
+	Query  string
+	Source string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Cursor string
+}
+
+// VoteSnapshot records a deal's vote count at a point in time.
+type VoteSnapshot struct {
+	Votes      int       `json:"votes"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Votes returns Upvotes as an integer, or 0 if the deal has no (valid) vote count.
+func (d *Deal) Votes() int {
+	v, err := strconv.Atoi(d.Upvotes)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// Age returns how long ago the deal was posted: from PostedAt, or the
+// scraped DealAge if the posting time is unknown. Returns 0 if neither is set.
+func (d *Deal) Age(now time.Time) time.Duration {
+	if !d.PostedAt.IsZero() {
+		return now.Sub(d.PostedAt)
+	}
+	age, err := time.ParseDuration(d.DealAge)
+	if err != nil {
+		return 0
+	}
+	return age
+}
+
+// Deal expiry states, as shown on the deal
+const (
+	DealExpired    = "expired"
+	DealOutOfStock = "out_of_stock"
+)
+
+// IsExpired reports whether the deal is no longer available: the source
+// marked it expired or out of stock, or its expiry time has passed.
+func (d *Deal) IsExpired(now time.Time) bool {
+	return d.Expiry != "" || (!d.ExpiresAt.IsZero() && !d.ExpiresAt.After(now))
+}
+
+// Store returns the store a deal is from, taken from the " @ Store" suffix
+// OzBargain titles end with, or "" if the title does not name one.
+func (d *Deal) Store() string {
+	i := strings.LastIndex(d.Title, " @ ")
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimSpace(d.Title[i+len(" @ "):])
+}
+
 // Ozbargain deal type
 type OzBargainDeal struct {
 	Id       string `json:"id"`
@@ -9,6 +122,30 @@ type OzBargainDeal struct {
 	Upvotes  string `json:"upvotes"`
 	DealAge  string `json:"dealage"`
 	DealType int    `json:"dealtype"`
+	Category string `json:"category,omitempty"` // from the listing, empty if not shown
+
+	// Filled in from the deal page when enrichment is enabled
+	Details *OzBargainDetails `json:"details,omitempty"`
+
+	// Votes per hour, from the votes seen by recent scrapes
+	Velocity float64 `json:"velocity,omitempty"`
+
+	// DealExpired or DealOutOfStock when the listing marks the deal so
+	Expiry string `json:"expiry,omitempty"`
+}
+
+// OzBargainDetails is what an OzBargain deal page adds to the listing.
+type OzBargainDetails struct {
+	Price       *float64  `json:"price,omitempty"` // first price in the title, 0 if the deal is free
+	StoreDomain string    `json:"store_domain,omitempty"`
+	CouponCode  string    `json:"coupon_code,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`
+	Poster      string    `json:"poster,omitempty"`
+	Thumbnail   string    `json:"thumbnail,omitempty"`
+	Expiry      string    `json:"expiry,omitempty"` // DealExpired or DealOutOfStock, as of FetchedAt
+	FetchedAt   time.Time `json:"fetched_at"`
 }
 
 // Camel Camel Camel deal type
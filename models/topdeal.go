@@ -0,0 +1,75 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TopDealRule decides which OzBargain deals count as top deals. Zero MinVotes
+// or MaxAgeHours fall back to DefaultTopDealRule.
+type TopDealRule struct {
+	MinVotes        int     `json:"min_votes" bson:"min_votes"`
+	MaxAgeHours     int     `json:"max_age_hours" bson:"max_age_hours"`
+	MinVotesPerHour float64 `json:"min_votes_per_hour,omitempty" bson:"min_votes_per_hour"` // 0 disables the check
+}
+
+// DefaultTopDealRule is the site-wide top deal rule: 25+ votes within 24 hours.
+var DefaultTopDealRule = TopDealRule{MinVotes: 25, MaxAgeHours: 24}
+
+// Longest MaxAgeHours a rule may have
+const MaxTopDealAgeHours = 7 * 24
+
+// OrDefault returns the rule with unset fields taken from DefaultTopDealRule.
+func (r TopDealRule) OrDefault() TopDealRule {
+	if r.MinVotes == 0 {
+		r.MinVotes = DefaultTopDealRule.MinVotes
+	}
+	if r.MaxAgeHours == 0 {
+		r.MaxAgeHours = DefaultTopDealRule.MaxAgeHours
+	}
+	return r
+}
+
+// Validate checks the rule's fields are in range.
+func (r TopDealRule) Validate() error {
+	if r.MinVotes < 0 {
+		return errors.New("min_votes cannot be negative")
+	}
+	if r.MaxAgeHours < 0 || r.MaxAgeHours > MaxTopDealAgeHours {
+		return fmt.Errorf("max_age_hours must be between 0 and %d", MaxTopDealAgeHours)
+	}
+	if r.MinVotesPerHour < 0 {
+		return errors.New("min_votes_per_hour cannot be negative")
+	}
+	return nil
+}
+
+// IsTop reports whether a deal with votes, posted age ago, is a top deal.
+// Votes per hour count the first hour as a whole hour, so a brand new deal
+// needs at least MinVotesPerHour votes.
+func (r TopDealRule) IsTop(votes int, age time.Duration) bool {
+	r = r.OrDefault()
+	if votes < r.MinVotes || age >= time.Duration(r.MaxAgeHours)*time.Hour {
+		return false
+	}
+	if r.MinVotesPerHour > 0 && float64(votes)/max(age.Hours(), 1) < r.MinVotesPerHour {
+		return false
+	}
+	return true
+}
+
+// Matches reports whether the deal is a top deal under the rule at now.
+func (r TopDealRule) Matches(deal *Deal, now time.Time) bool {
+	return r.IsTop(deal.Votes(), deal.Age(now))
+}
+
+// String describes the rule, e.g. "25+ votes in 24h".
+func (r TopDealRule) String() string {
+	r = r.OrDefault()
+	s := fmt.Sprintf("%d+ votes in %dh", r.MinVotes, r.MaxAgeHours)
+	if r.MinVotesPerHour > 0 {
+		s += fmt.Sprintf(", %g+ votes/hour", r.MinVotesPerHour)
+	}
+	return s
+}
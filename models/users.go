@@ -1,6 +1,9 @@
 package models
 
-import "sync"
+import (
+	"strings"
+	"sync"
+)
 
 // This package stores the model for user data
 
@@ -10,19 +13,60 @@ type UserStore struct {
 	mu    sync.RWMutex // Mutex to protect concurrent access to Users map
 }
 
+// RoleAdmin is the role of users who can use the bot's admin commands.
+const RoleAdmin = "admin"
+
 // User data model
 type UserData struct {
 	ChatID         int64    `bson:"chat_id"`         // Telegram chat ID
 	Username       string   `bson:"username"`        // Telegram username
 	OzbGood        bool     `bson:"ozb_good"`        // watch all OzBargain deals
-	OzbSuper       bool     `bson:"ozb_super"`       // watch top deals (25+ upvotes within 1 hour)
+	OzbSuper       bool     `bson:"ozb_super"`       // watch top deals, as decided by TopDeal
 	Keywords       []string `bson:"keywords"`        // list of keywords / deals to watch for
-	OzbSent        []string `bson:"ozb_sent"`        // comma separated list of ozb deals sent to user
 	AmzDaily       bool     `bson:"amz_daily"`       // watch top daily deals on amazon
 	AmzWeekly      bool     `bson:"amz_weekly"`      // watch top weekly deals on amazon
-	AmzSent        []string `bson:"amz_sent"`        // comma separated list of amz deals sent to user
+	Inactive       bool     `bson:"inactive"`        // blocked the bot or deleted the chat; no deals are sent
+	MutedStores    []string `bson:"muted_stores"`    // stores whose deals are never sent
+	Role           string   `bson:"role"`            // RoleAdmin for bot admins, otherwise empty
+	Banned         bool     `bson:"banned"`          // banned by an admin; ignored and sent nothing
 	UsernameChosen string   `bson:"username_chosen"` // username chosen by user on website
 	Password       string   `bson:"password"`        // password chosen by user on website
+
+	// OzBargain categories and tags subscribed to
+	Categories []CategorySub `bson:"categories"`
+
+	// What counts as an OzBargain top deal for OzbSuper
+	TopDeal TopDealRule `bson:"top_deal"`
+
+	// Watch OzBargain deals gaining votes quickly, before they become top deals
+	OzbTrending bool `bson:"ozb_trending"`
+
+	// Tell the user when a deal they were sent expires or goes out of stock
+	ExpiryNotices bool `bson:"expiry_notices"`
+}
+
+// CategorySub subscribes to OzBargain deals in a category, or with a tag,
+// once they have at least MinVotes upvotes.
+type CategorySub struct {
+	Category string `json:"category" bson:"category"`
+	MinVotes int    `json:"min_votes" bson:"min_votes"` // 0 sends every deal
+}
+
+// Matches reports whether the deal is in the subscribed category or tag and
+// has enough votes.
+func (c CategorySub) Matches(deal *Deal) bool {
+	if deal.Votes() < c.MinVotes {
+		return false
+	}
+	if strings.EqualFold(deal.Category, c.Category) {
+		return true
+	}
+	for _, tag := range deal.Tags {
+		if strings.EqualFold(tag, c.Category) {
+			return true
+		}
+	}
+	return false
 }
 
 // setters and getters for UserData
@@ -56,12 +100,6 @@ func (u *UserData) SetKeywords(keywords []string) {
 func (u *UserData) GetKeywords() []string {
 	return u.Keywords
 }
-func (u *UserData) SetOzbSent(ozbSent []string) {
-	u.OzbSent = ozbSent
-}
-func (u *UserData) GetOzbSent() []string {
-	return u.OzbSent
-}
 func (u *UserData) GetAmzDaily() bool {
 	return u.AmzDaily
 }
@@ -74,12 +112,6 @@ func (u *UserData) GetAmzWeekly() bool {
 func (u *UserData) SetAmzWeekly(amzWeekly bool) {
 	u.AmzWeekly = amzWeekly
 }
-func (u *UserData) SetAmzSent(amzSent []string) {
-	u.AmzSent = amzSent
-}
-func (u *UserData) GetAmzSent() []string {
-	return u.AmzSent
-}
 func (u *UserData) SetUsernameChosen(usernameChosen string) {
 	u.UsernameChosen = usernameChosen
 }
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// VelocityWindow is how far back a deal's vote velocity looks.
+const VelocityWindow = time.Hour
+
+// Shortest time a velocity is measured over, so a new deal's first few votes
+// don't read as hundreds per hour.
+const minVelocitySpan = 15 * time.Minute
+
+// VoteVelocity returns a deal's votes per hour over the last VelocityWindow,
+// rounded to one decimal place. history holds the deal's vote snapshots,
+// oldest first, each recorded when the vote count changed; votes is the
+// current count and age how long ago the deal was posted, 0 if unknown.
+// Deals younger than the window are measured from when they were posted.
+// Returns 0 if the history is too short to tell.
+func VoteVelocity(history []VoteSnapshot, votes int, age time.Duration, now time.Time) float64 {
+	baseline, from := 0, now.Add(-age)
+	if age <= 0 || age > VelocityWindow {
+		// Votes at the start of the window: the last snapshot before it, or
+		// the first one if the deal was only seen later
+		start := now.Add(-VelocityWindow)
+		i := len(history) - 1
+		for i >= 0 && history[i].RecordedAt.After(start) {
+			i--
+		}
+		switch {
+		case i >= 0:
+			baseline, from = history[i].Votes, start
+		case len(history) > 0:
+			baseline, from = history[0].Votes, history[0].RecordedAt
+		default:
+			return 0
+		}
+		if now.Sub(from) < minVelocitySpan {
+			return 0
+		}
+	}
+
+	hours := max(now.Sub(from), minVelocitySpan).Hours()
+	return math.Round(float64(max(votes-baseline, 0))/hours*10) / 10
+}
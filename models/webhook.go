@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Webhook is an endpoint that receives a signed JSON payload for every deal
+// delivered to its owner over the webhook channel.
+type Webhook struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"-"` // owning web user
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // HMAC key; only returned when the webhook is created
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one attempt at posting a payload to a webhook.
+type WebhookDelivery struct {
+	ID         int64     `json:"id"`
+	WebhookID  string    `json:"webhook_id"`
+	DeliveryID string    `json:"delivery_id"` // shared by every attempt of the same payload
+	Event      string    `json:"event"`
+	Source     string    `json:"source"`
+	DealID     string    `json:"deal_id"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"` // 0 if no response was received
+	Error      string    `json:"error,omitempty"`
+	Duration   int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// QueuedWebhook is a payload waiting to be delivered to a webhook. The
+// webhook's URL and secret are filled in when due deliveries are read.
+type QueuedWebhook struct {
+	ID            int64
+	WebhookID     string
+	URL           string
+	Secret        string
+	Payload       string // JSON body, sent unchanged on every attempt
+	Attempts      int    // attempts made so far
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+}
+
+// Succeeded reports whether the attempt got a 2xx response.
+func (d *WebhookDelivery) Succeeded() bool {
+	return d.StatusCode >= 200 && d.StatusCode <= 299
+}
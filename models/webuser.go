@@ -24,6 +24,15 @@ type WebUser struct {
 	AmzWeekly    bool     `json:"amz_weekly"`
 	EmailSummary bool     `json:"email_summary"`
 	Keywords     []string `json:"keywords"`
+	Channels     []string `json:"channels"` // enabled deal delivery channels; empty means the defaults
+	// OzBargain category and tag subscriptions
+	Categories []CategorySub `json:"categories"`
+	// What counts as an OzBargain top deal for ozb_super
+	TopDeal TopDealRule `json:"top_deal"`
+	// Notify about OzBargain deals gaining votes quickly
+	OzbTrending bool `json:"ozb_trending"`
+	// Notify when a deal sent over Telegram expires or goes out of stock
+	ExpiryNotices bool `json:"expiry_notices"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 }
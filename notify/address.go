@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// ErrPrivateAddress is returned for webhook hosts on loopback, private,
+// link-local or unspecified addresses, so users can't aim webhooks at the
+// server's own network.
+var ErrPrivateAddress = errors.New("webhook host is not a public address")
+
+// publicIP reports whether webhooks may be delivered to ip.
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast()
+}
+
+// CheckWebhookHost resolves a webhook URL's host and returns ErrPrivateAddress
+// if any of its addresses is not public.
+func CheckWebhookHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve webhook host: %w", err)
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, addr.IP)
+		}
+	}
+	return nil
+}
+
+// checkDialAddress refuses connections to addresses that are not public. It
+// runs after DNS resolution, so a host that passed CheckWebhookHost can't
+// change its records to reach the server's network later.
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+func TestCheckWebhookHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		private bool
+	}{
+		{"localhost", true},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.0.0.1", true},
+		{"172.16.5.4", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"0.0.0.0", true},
+		{"93.184.215.14", false},
+		{"2606:4700:4700::1111", false},
+	}
+	for _, tt := range tests {
+		err := CheckWebhookHost(context.Background(), tt.host)
+		if got := errors.Is(err, ErrPrivateAddress); got != tt.private || (!tt.private && err != nil) {
+			t.Errorf("CheckWebhookHost(%q) = %v, want private %v", tt.host, err, tt.private)
+		}
+	}
+}
+
+// The client from NewWebhook checks the address it connects to, so a saved
+// webhook can't reach the server's network after its DNS records change.
+func TestWebhook_RefusesPrivateAddress(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+	hook := &models.Webhook{ID: "h1", UserID: "u1", URL: srv.URL, Secret: "whsec_test"}
+
+	w := NewWebhook(&memWebhookDB{}, zap.NewNop())
+	w.BaseDelay = time.Millisecond
+	d, err := w.Deliver(hook, "watched", &models.Deal{Id: "1"})
+	if err == nil || d.Attempt != 1 {
+		t.Fatalf("Deliver() = %+v, %v, want one refused attempt", d, err)
+	}
+	if len(rc.requests) != 0 {
+		t.Errorf("receiver got %d requests", len(rc.requests))
+	}
+
+	w.AllowPrivate = true
+	if _, err := w.Deliver(hook, "watched", &models.Deal{Id: "1"}); err != nil {
+		t.Fatalf("Deliver() with AllowPrivate error = %v", err)
+	}
+	if len(rc.requests) != 1 {
+		t.Errorf("receiver got %d requests, want 1", len(rc.requests))
+	}
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/pipup"
+	"github.com/intothevoid/kramerbot/util"
+)
+
+// HTMLSender sends an HTML formatted Telegram message with optional rows of
+// inline buttons.
+type HTMLSender interface {
+	SendHTML(chatID int64, text string, buttons [][]models.InlineButton) error
+}
+
+// Telegram delivers deals as Telegram messages.
+type Telegram struct {
+	Sender HTMLSender
+	// Buttons, if set, returns the inline buttons shown under a deal.
+	Buttons func(r *Recipient, e Event) [][]models.InlineButton
+}
+
+func (t *Telegram) Name() string { return ChannelTelegram }
+
+func (t *Telegram) Notify(r *Recipient, e Event) error {
+	var buttons [][]models.InlineButton
+	if t.Buttons != nil {
+		buttons = t.Buttons(r, e)
+	}
+	return t.Sender.SendHTML(r.User.ChatID, FormatHTML(e), buttons)
+}
+
+// Email delivers deals to the verified email address of the linked web account.
+type Email struct {
+	Service *util.EmailService
+}
+
+func (m *Email) Name() string { return ChannelEmail }
+
+// Reachable returns ErrNoAddress unless the recipient has a verified email.
+func (m *Email) Reachable(r *Recipient) error {
+	if r.Account == nil || !r.Account.EmailVerified || r.Account.Email == "" {
+		return ErrNoAddress
+	}
+	return nil
+}
+
+func (m *Email) Notify(r *Recipient, e Event) error {
+	if err := m.Reachable(r); err != nil {
+		return err
+	}
+	prefix, _, detail := headline(e)
+	subject := "KramerBot: " + strings.Join(strings.Fields(e.Deal.Title), " ") // no line breaks in headers
+	body := fmt.Sprintf(`<p style="font-family:sans-serif">%s <a href="%s" style="color:#c0392b;font-weight:bold">%s</a> %s</p>`,
+		prefix, html.EscapeString(e.Deal.Url), html.EscapeString(e.Deal.Title), html.EscapeString(detail))
+	return m.Service.Send(r.Account.Email, subject, body)
+}
+
+// Pipup delivers deals to the Android TV of the configured Pipup user.
+type Pipup struct {
+	Client *pipup.Pipup
+}
+
+func (p *Pipup) Name() string { return ChannelPipup }
+
+// Reachable returns ErrNoAddress unless the recipient is the Pipup user.
+func (p *Pipup) Reachable(r *Recipient) error {
+	if !strings.EqualFold(r.User.Username, p.Client.Username) {
+		return ErrNoAddress
+	}
+	return nil
+}
+
+func (p *Pipup) Notify(r *Recipient, e Event) error {
+	if err := p.Reachable(r); err != nil {
+		return err
+	}
+	return p.Client.SendMediaMessage(FormatText(e), "Kramerbot")
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/pipup"
+	"github.com/intothevoid/kramerbot/scrapers"
+)
+
+type htmlRecorder struct {
+	chatID  int64
+	text    string
+	buttons [][]models.InlineButton
+}
+
+func (h *htmlRecorder) SendHTML(chatID int64, text string, buttons [][]models.InlineButton) error {
+	h.chatID, h.text, h.buttons = chatID, text, buttons
+	return nil
+}
+
+func TestTelegram_Notify(t *testing.T) {
+	rec := &htmlRecorder{}
+	n := &Telegram{Sender: rec}
+	deal := &models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Title: "Samsung 990 Pro 2TB SSD", Url: "https://example.com/1", Upvotes: "42"}
+
+	if err := n.Notify(&Recipient{User: &models.UserData{ChatID: 9}}, Event{Deal: deal, Kind: Watched}); err != nil {
+		t.Fatal(err)
+	}
+	want := `🟠👀<a href="https://example.com/1" target="_blank">Samsung 990 Pro 2TB SSD...</a>🔺42`
+	if rec.chatID != 9 || rec.text != want || rec.buttons != nil {
+		t.Errorf("sent %d %q %v, want 9 %q without buttons", rec.chatID, rec.text, rec.buttons, want)
+	}
+
+	n.Buttons = func(r *Recipient, e Event) [][]models.InlineButton {
+		return [][]models.InlineButton{{{Text: "Save deal", Data: "save:" + e.Deal.Source + ":" + e.Deal.Id}}}
+	}
+	deal.Id = "1"
+	if err := n.Notify(&Recipient{User: &models.UserData{ChatID: 9}}, Event{Deal: deal, Kind: Watched}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.buttons) != 1 || rec.buttons[0][0].Data != "save:ozbargain:1" {
+		t.Errorf("buttons = %+v, want a save button", rec.buttons)
+	}
+
+	deal.Velocity = 18.5
+	if err := n.Notify(&Recipient{User: &models.UserData{ChatID: 9}}, Event{Deal: deal, Kind: Trending}); err != nil {
+		t.Fatal(err)
+	}
+	want = `🟠📈<a href="https://example.com/1" target="_blank">Samsung 990 Pro 2TB SSD...</a>🔺42 (+18.5/h)`
+	if rec.text != want {
+		t.Errorf("sent %q, want %q", rec.text, want)
+	}
+}
+
+func TestEmailAndPipup_NoAddress(t *testing.T) {
+	deal := &models.Deal{Id: "1"}
+	unverified := &Recipient{User: &models.UserData{}, Account: &models.WebUser{Email: "a@example.com"}}
+	if err := (&Email{}).Notify(unverified, Event{Deal: deal}); !errors.Is(err, ErrNoAddress) {
+		t.Errorf("Email to unverified account: error = %v, want ErrNoAddress", err)
+	}
+
+	other := &Recipient{User: &models.UserData{Username: "someone"}}
+	p := &Pipup{Client: &pipup.Pipup{Username: "kramer"}}
+	if err := p.Notify(other, Event{Deal: deal}); !errors.Is(err, ErrNoAddress) {
+		t.Errorf("Pipup to another user: error = %v, want ErrNoAddress", err)
+	}
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+// SentTracker records which deals each user has been sent, and over which
+// channel. A deal counts as sent once any channel has delivered it.
+type SentTracker interface {
+	DealSent(user *models.UserData, deal *models.Deal) bool
+	MarkDealSent(user *models.UserData, deal *models.Deal, channel string) error
+}
+
+// Dispatcher sends deal events to every channel a recipient has enabled and
+// tracks sent deals, so a deal reaches each user at most once.
+type Dispatcher struct {
+	notifiers map[string]Notifier
+	tracker   SentTracker
+	logger    *zap.Logger
+}
+
+// NewDispatcher creates a dispatcher delivering through the given notifiers.
+func NewDispatcher(tracker SentTracker, logger *zap.Logger, notifiers ...Notifier) *Dispatcher {
+	d := &Dispatcher{
+		notifiers: make(map[string]Notifier),
+		tracker:   tracker,
+		logger:    logger,
+	}
+	for _, n := range notifiers {
+		d.Register(n)
+	}
+	return d
+}
+
+// Register adds a notifier, replacing any existing one for the same channel.
+func (d *Dispatcher) Register(n Notifier) {
+	d.notifiers[n.Name()] = n
+}
+
+// Dispatch sends the event to the recipient unless the deal was already sent.
+// Each channel that delivers the deal is recorded; failures on other channels
+// are returned joined together.
+func (d *Dispatcher) Dispatch(r *Recipient, e Event) error {
+	if r == nil || r.User == nil || e.Deal == nil {
+		return fmt.Errorf("invalid recipient or event")
+	}
+	if d.tracker.DealSent(r.User, e.Deal) {
+		return nil
+	}
+
+	var errs []error
+	for _, channel := range r.Channels() {
+		n, ok := d.notifiers[channel]
+		if !ok {
+			continue // channel not configured on this instance
+		}
+		err := n.Notify(r, e)
+		switch {
+		case errors.Is(err, ErrNoAddress):
+			continue
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+		default:
+			d.logger.Debug("Deal delivered",
+				zap.String("channel", channel),
+				zap.String("source", e.Deal.Source),
+				zap.String("deal_id", e.Deal.Id),
+				zap.Int64("chat_id", r.User.ChatID))
+			if err := d.tracker.MarkDealSent(r.User, e.Deal, channel); err != nil {
+				errs = append(errs, fmt.Errorf("failed to mark deal sent: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+// fakeNotifier records every event it is asked to deliver.
+type fakeNotifier struct {
+	name string
+	err  error
+	got  []int64
+}
+
+func (f *fakeNotifier) Name() string { return f.name }
+
+func (f *fakeNotifier) Notify(r *Recipient, e Event) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.got = append(f.got, r.User.ChatID)
+	return nil
+}
+
+// memTracker is an in-memory SentTracker keyed by chat, holding "id@channel".
+type memTracker map[int64][]string
+
+func (m memTracker) DealSent(user *models.UserData, deal *models.Deal) bool {
+	return slices.ContainsFunc(m[user.ChatID], func(key string) bool {
+		return strings.HasPrefix(key, deal.Id+"@")
+	})
+}
+
+func (m memTracker) MarkDealSent(user *models.UserData, deal *models.Deal, channel string) error {
+	m[user.ChatID] = append(m[user.ChatID], deal.Id+"@"+channel)
+	return nil
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	deal := &models.Deal{Id: "1", Title: "Cheap SSD"}
+	tests := []struct {
+		name         string
+		account      *models.WebUser
+		telegramErr  error
+		emailErr     error
+		wantTelegram int
+		wantEmail    int
+		wantErr      bool
+		wantSent     bool
+	}{
+		{"default channels", nil, nil, nil, 1, 0, false, true},
+		{"chosen channels", &models.WebUser{Channels: []string{ChannelEmail}}, nil, nil, 0, 1, false, true},
+		{"both channels", &models.WebUser{Channels: []string{ChannelTelegram, ChannelEmail}}, nil, nil, 1, 1, false, true},
+		{"one channel fails", &models.WebUser{Channels: []string{ChannelTelegram, ChannelEmail}}, errors.New("boom"), nil, 0, 1, true, true},
+		{"every channel fails", nil, errors.New("boom"), nil, 0, 0, true, false},
+		{"no address", &models.WebUser{Channels: []string{ChannelEmail}}, nil, ErrNoAddress, 0, 0, false, false},
+		{"unconfigured channel", &models.WebUser{Channels: []string{ChannelWebhook}}, nil, nil, 0, 0, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telegram := &fakeNotifier{name: ChannelTelegram, err: tt.telegramErr}
+			email := &fakeNotifier{name: ChannelEmail, err: tt.emailErr}
+			tracker := memTracker{}
+			d := NewDispatcher(tracker, zap.NewNop(), telegram, email)
+
+			r := &Recipient{User: &models.UserData{ChatID: 7}, Account: tt.account}
+			err := d.Dispatch(r, Event{Deal: deal})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Dispatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(telegram.got) != tt.wantTelegram || len(email.got) != tt.wantEmail {
+				t.Errorf("telegram got %d, email got %d; want %d, %d",
+					len(telegram.got), len(email.got), tt.wantTelegram, tt.wantEmail)
+			}
+			if sent := tracker.DealSent(r.User, deal); sent != tt.wantSent {
+				t.Errorf("deal sent = %v, want %v", sent, tt.wantSent)
+			}
+			// Each channel that delivered is recorded
+			if marked := len(tracker[r.User.ChatID]); marked != tt.wantTelegram+tt.wantEmail {
+				t.Errorf("recorded %d channels, want %d", marked, tt.wantTelegram+tt.wantEmail)
+			}
+		})
+	}
+}
+
+// A deal is only delivered once per user
+func TestDispatcher_SkipsSentDeals(t *testing.T) {
+	telegram := &fakeNotifier{name: ChannelTelegram}
+	d := NewDispatcher(memTracker{}, zap.NewNop(), telegram)
+	r := &Recipient{User: &models.UserData{ChatID: 7}}
+	deal := &models.Deal{Id: "1"}
+
+	for i := 0; i < 2; i++ {
+		if err := d.Dispatch(r, Event{Deal: deal, Kind: Watched}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(telegram.got) != 1 {
+		t.Errorf("deal delivered %d times, want 1", len(telegram.got))
+	}
+}
@@ -0,0 +1,129 @@
+// Package notify delivers deal events to users over their enabled channels
+// (Telegram, email, Pipup, webhook).
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"github.com/intothevoid/kramerbot/util"
+)
+
+// Channel names, as stored in a user's channel preferences
+const (
+	ChannelTelegram = "telegram"
+	ChannelEmail    = "email"
+	ChannelPipup    = "pipup"
+	ChannelWebhook  = "webhook"
+)
+
+// Channels lists every known channel name.
+var Channels = []string{ChannelTelegram, ChannelEmail, ChannelPipup, ChannelWebhook}
+
+// DefaultChannels are used for users who have not chosen any channels.
+var DefaultChannels = []string{ChannelTelegram, ChannelPipup}
+
+// ValidChannel reports whether name is a known channel.
+func ValidChannel(name string) bool {
+	return slices.Contains(Channels, name)
+}
+
+// ErrNoAddress is returned by a notifier when the recipient cannot be reached
+// on its channel (e.g. no verified email or webhook URL). The dispatcher skips
+// such channels silently.
+var ErrNoAddress = errors.New("recipient has no address for this channel")
+
+// Kind is the reason a deal is being sent.
+type Kind int
+
+const (
+	Subscribed Kind = iota // matches a deal type subscription
+	Watched                // matches a watched keyword
+	Trending               // gaining votes quickly, for trending subscribers
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Watched:
+		return "watched"
+	case Trending:
+		return "trending"
+	}
+	return "subscribed"
+}
+
+// Event is a deal being sent to a user.
+type Event struct {
+	Deal *models.Deal
+	Kind Kind
+}
+
+// Recipient is a bot user and, if linked, their web account.
+type Recipient struct {
+	User    *models.UserData
+	Account *models.WebUser // nil if the Telegram user has no linked web account
+}
+
+// Channels returns the recipient's enabled channels.
+func (r *Recipient) Channels() []string {
+	if r.Account != nil && len(r.Account.Channels) > 0 {
+		return r.Account.Channels
+	}
+	return DefaultChannels
+}
+
+// Notifier delivers events over a single channel.
+type Notifier interface {
+	// Name returns the channel name, one of the Channel constants.
+	Name() string
+	// Notify delivers the event, returning ErrNoAddress if the recipient
+	// cannot be reached on this channel.
+	Notify(r *Recipient, e Event) error
+}
+
+// Icon shown in front of deal notifications, per source
+var sourceIcons = map[string]string{
+	scrapers.SOURCE_OZBARGAIN: "🟠",
+	scrapers.SOURCE_AMAZON:    "🅰️",
+}
+
+// headline returns the icons, shortened title and vote/price detail of a deal.
+func headline(e Event) (prefix, title, detail string) {
+	icon, ok := sourceIcons[e.Deal.Source]
+	if !ok {
+		icon = "🛍️"
+	}
+	marker := "🔥"
+	switch e.Kind {
+	case Watched:
+		marker = "👀"
+	case Trending:
+		marker = "📈"
+	}
+
+	// Votes for community sites, price drop for price trackers
+	if e.Deal.Upvotes != "" {
+		detail = "🔺" + e.Deal.Upvotes
+		if e.Kind == Trending && e.Deal.Velocity > 0 {
+			detail += fmt.Sprintf(" (+%g/h)", e.Deal.Velocity)
+		}
+	} else if e.Deal.PriceDrop != "" {
+		detail = " - " + e.Deal.PriceDrop
+	}
+	return icon + marker, util.ShortenString(e.Deal.Title, 30) + "...", detail
+}
+
+// FormatHTML renders an event as a Telegram HTML message.
+func FormatHTML(e Event) string {
+	prefix, title, detail := headline(e)
+	return fmt.Sprintf(`%s<a href="%s" target="_blank">%s</a>%s`, prefix, e.Deal.Url, title, detail)
+}
+
+// FormatText renders an event as plain text.
+func FormatText(e Event) string {
+	prefix, title, detail := headline(e)
+	return fmt.Sprintf(`%s %s %s`, prefix, title, detail)
+}
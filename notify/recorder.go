@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+// How many recordings a Recorder keeps in memory for Recent
+const recentRecordings = 500
+
+// Recording is a notification recorded in test mode instead of being sent.
+type Recording struct {
+	Time     time.Time               `json:"time"`
+	Channel  string                  `json:"channel"`
+	ChatID   int64                   `json:"chat_id"`
+	Username string                  `json:"username"`
+	Kind     string                  `json:"kind"` // "subscribed", "watched" or "trending"
+	Source   string                  `json:"source"`
+	DealID   string                  `json:"deal_id"`
+	Title    string                  `json:"title"`
+	URL      string                  `json:"url"`
+	Text     string                  `json:"text"` // the message as it would have been sent
+	Buttons  [][]models.InlineButton `json:"buttons,omitempty"`
+}
+
+// Recorder writes recordings to the log and, optionally, a JSONL file, and
+// keeps the latest in memory.
+type Recorder struct {
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	file   *os.File // nil if recordings are not written to a file
+	recent []Recording
+}
+
+// NewRecorder returns a recorder appending to the JSONL file at path, which
+// is created if needed. An empty path only logs and keeps recordings in
+// memory.
+func NewRecorder(path string, logger *zap.Logger) (*Recorder, error) {
+	r := &Recorder{logger: logger}
+	if path == "" {
+		return r, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recordings directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recordings file: %w", err)
+	}
+	r.file = f
+	return r, nil
+}
+
+// Record stores a recording.
+func (r *Recorder) Record(rec Recording) {
+	r.logger.Info("Test mode notification",
+		zap.String("channel", rec.Channel),
+		zap.Int64("chat_id", rec.ChatID),
+		zap.String("kind", rec.Kind),
+		zap.String("source", rec.Source),
+		zap.String("deal_id", rec.DealID),
+		zap.String("title", rec.Title))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recent = append(r.recent, rec)
+	if len(r.recent) > recentRecordings {
+		r.recent = slices.Delete(r.recent, 0, len(r.recent)-recentRecordings)
+	}
+	if r.file == nil {
+		return
+	}
+	line, err := json.Marshal(rec)
+	if err == nil {
+		_, err = r.file.Write(append(line, '\n'))
+	}
+	if err != nil {
+		r.logger.Error("Failed to write test mode notification", zap.Error(err))
+	}
+}
+
+// Recent returns up to limit of the latest recordings, newest first.
+func (r *Recorder) Recent(limit int) []Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := min(max(limit, 0), len(r.recent))
+	recs := slices.Clone(r.recent[len(r.recent)-n:])
+	slices.Reverse(recs)
+	return recs
+}
+
+// Close closes the recordings file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+// reachability is implemented by notifiers that can only reach some
+// recipients.
+type reachability interface {
+	Reachable(r *Recipient) error
+}
+
+// dryRun records what a notifier would send instead of sending it.
+type dryRun struct {
+	notifier Notifier
+	recorder *Recorder
+}
+
+// DryRun wraps a notifier so that deliveries are recorded rather than sent.
+// Recipients the notifier cannot reach are skipped as usual.
+func DryRun(n Notifier, recorder *Recorder) Notifier {
+	return &dryRun{notifier: n, recorder: recorder}
+}
+
+func (d *dryRun) Name() string { return d.notifier.Name() }
+
+func (d *dryRun) Notify(r *Recipient, e Event) error {
+	if c, ok := d.notifier.(reachability); ok {
+		if err := c.Reachable(r); err != nil {
+			return err
+		}
+	}
+
+	rec := Recording{
+		Time:     time.Now(),
+		Channel:  d.notifier.Name(),
+		ChatID:   r.User.ChatID,
+		Username: r.User.Username,
+		Kind:     e.Kind.String(),
+		Source:   e.Deal.Source,
+		DealID:   e.Deal.Id,
+		Title:    e.Deal.Title,
+		URL:      e.Deal.Url,
+		Text:     FormatText(e),
+	}
+	if t, ok := d.notifier.(*Telegram); ok {
+		rec.Text = FormatHTML(e)
+		if t.Buttons != nil {
+			rec.Buttons = t.Buttons(r, e)
+		}
+	}
+	d.recorder.Record(rec)
+	return nil
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
+)
+
+func TestDryRun_Records(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recordings", "notifications.jsonl")
+	rec, err := NewRecorder(path, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := &htmlRecorder{}
+	telegram := DryRun(&Telegram{Sender: sender, Buttons: func(r *Recipient, e Event) [][]models.InlineButton {
+		return [][]models.InlineButton{{{Text: "Save deal", Data: "save:1"}}}
+	}}, rec)
+	email := DryRun(&Email{}, rec)
+
+	deal := &models.Deal{Source: scrapers.SOURCE_OZBARGAIN, Id: "1", Title: "Samsung 990 Pro 2TB SSD", Url: "https://example.com/1", Upvotes: "42"}
+	user := &Recipient{User: &models.UserData{ChatID: 9, Username: "kramer"}}
+
+	if err := telegram.Notify(user, Event{Deal: deal, Kind: Watched}); err != nil {
+		t.Fatal(err)
+	}
+	if err := email.Notify(user, Event{Deal: deal}); !errors.Is(err, ErrNoAddress) {
+		t.Errorf("Email without an account: error = %v, want ErrNoAddress", err)
+	}
+	if sender.text != "" {
+		t.Error("dry run sent the Telegram message")
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	recent := rec.Recent(10)
+	if len(recent) != 1 {
+		t.Fatalf("Recent() = %d recordings, want 1", len(recent))
+	}
+	got := recent[0]
+	if got.Channel != ChannelTelegram || got.ChatID != 9 || got.Kind != "watched" || got.DealID != "1" || len(got.Buttons) != 1 {
+		t.Errorf("recording = %+v", got)
+	}
+	if got.Text != FormatHTML(Event{Deal: deal, Kind: Watched}) {
+		t.Errorf("recorded text %q, want the Telegram message", got.Text)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []Recording
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Recording
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid JSONL line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, r)
+	}
+	if len(lines) != 1 || lines[0].DealID != "1" {
+		t.Errorf("file has %+v, want the one recording", lines)
+	}
+}
+
+func TestRecorder_Recent(t *testing.T) {
+	rec, err := NewRecorder("", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		rec.Record(Recording{DealID: id})
+	}
+	recent := rec.Recent(2)
+	if len(recent) != 2 || recent[0].DealID != "3" || recent[1].DealID != "2" {
+		t.Errorf("Recent(2) = %+v, want deals 3 and 2", recent)
+	}
+	if got := rec.Recent(0); len(got) != 0 {
+		t.Errorf("Recent(0) = %+v, want none", got)
+	}
+}
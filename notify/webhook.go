@@ -0,0 +1,365 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/persist"
+	"go.uber.org/zap"
+)
+
+// Headers sent with every webhook request. Receivers verify a request by
+// computing HMAC-SHA256 over "<timestamp>.<body>" with the webhook's secret
+// and comparing it with the signature header.
+const (
+	HeaderSignature = "X-KramerBot-Signature" // "sha256=" followed by the hex HMAC
+	HeaderTimestamp = "X-KramerBot-Timestamp" // unix seconds when the attempt was sent
+	HeaderEvent     = "X-KramerBot-Event"     // "subscribed", "watched", "trending" or "test"
+	HeaderDelivery  = "X-KramerBot-Delivery"  // same for every retry of a payload
+)
+
+// EventTest is the event name of the test payload sent from the dashboard.
+const EventTest = "test"
+
+// Sign returns the signature header value for a request body.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random webhook signing secret.
+func NewSecret() string {
+	return "whsec_" + randomHex(24)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b) //nolint:errcheck // never fails on supported platforms
+	return hex.EncodeToString(b)
+}
+
+// WebhookPayload is the JSON body posted to webhooks.
+type WebhookPayload struct {
+	ID     string       `json:"id"`    // delivery id, also sent as HeaderDelivery
+	Event  string       `json:"event"` // "subscribed", "watched", "trending" or "test"
+	Deal   *models.Deal `json:"deal"`
+	SentAt time.Time    `json:"sent_at"`
+}
+
+const (
+	webhookBatchSize    = 100         // queued deliveries read from the store per round
+	webhookPollInterval = time.Second // how often the queue is checked when nothing wakes Run
+)
+
+// Webhook posts signed deal payloads to every webhook of the recipient's web
+// account. Notify stores the payloads in the webhook queue and Run sends them,
+// so slow endpoints and retries do not hold up the deal loop and pending
+// retries survive a restart. Every attempt is recorded in the delivery log.
+type Webhook struct {
+	Store       persist.WebhookDBIF
+	Client      *http.Client
+	Logger      *zap.Logger
+	Workers     int           // deliveries sent at once
+	MaxAttempts int           // attempts per payload, including the first
+	BaseDelay   time.Duration // wait before the first retry, doubled for each further retry
+	MaxDelay    time.Duration // upper bound on a single wait
+	// Deliver to loopback and private network addresses too. The client made
+	// by NewWebhook refuses them otherwise.
+	AllowPrivate bool
+
+	wake chan struct{}
+}
+
+// NewWebhook returns a webhook channel with default retry settings: 5 attempts,
+// waiting 2s, 4s, 8s and 16s between them. Its client connects only to
+// public addresses unless AllowPrivate is set, and ignores proxy settings so
+// the address check sees the real destination.
+func NewWebhook(store persist.WebhookDBIF, logger *zap.Logger) *Webhook {
+	w := &Webhook{
+		Store:       store,
+		Logger:      logger,
+		Workers:     8,
+		MaxAttempts: 5,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    time.Minute,
+		wake:        make(chan struct{}, 1),
+	}
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			if w.AllowPrivate {
+				return nil
+			}
+			return checkDialAddress(network, address, c)
+		},
+	}
+	w.Client = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+	return w
+}
+
+func (w *Webhook) Name() string { return ChannelWebhook }
+
+// Reachable returns ErrNoAddress unless the recipient's web account has a
+// webhook.
+func (w *Webhook) Reachable(r *Recipient) error {
+	_, err := w.hooks(r)
+	return err
+}
+
+// hooks returns the recipient's webhooks, or ErrNoAddress if there are none.
+func (w *Webhook) hooks(r *Recipient) ([]*models.Webhook, error) {
+	if r.Account == nil {
+		return nil, ErrNoAddress
+	}
+	hooks, err := w.Store.ListWebhooks(r.Account.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(hooks) == 0 {
+		return nil, ErrNoAddress
+	}
+	return hooks, nil
+}
+
+// Notify queues the event for each of the recipient's webhooks.
+func (w *Webhook) Notify(r *Recipient, e Event) error {
+	hooks, err := w.hooks(r)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		body, err := json.Marshal(newPayload(e.Kind.String(), e.Deal))
+		if err != nil {
+			return fmt.Errorf("failed to marshal webhook payload: %w", err)
+		}
+		if err := w.Store.EnqueueWebhook(&models.QueuedWebhook{WebhookID: hook.ID, Payload: string(body)}); err != nil {
+			return err
+		}
+	}
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run sends queued deliveries, including any left over from the last run,
+// until ctx is done.
+func (w *Webhook) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		w.deliverQueued(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// deliverQueued makes one attempt at each due delivery, Workers at a time.
+// Failed attempts are rescheduled with exponential backoff until MaxAttempts
+// is reached.
+func (w *Webhook) deliverQueued(now time.Time) {
+	due, err := w.Store.DueWebhooks(now, webhookBatchSize)
+	if err != nil {
+		w.Logger.Error("Failed to load queued webhook deliveries", zap.Error(err))
+		return
+	}
+
+	jobs := make(chan models.QueuedWebhook)
+	var wg sync.WaitGroup
+	for range min(max(w.Workers, 1), len(due)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q := range jobs {
+				w.deliverOne(q, now)
+			}
+		}()
+	}
+	for _, q := range due {
+		jobs <- q
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// deliverOne makes the next attempt at a queued delivery and removes it from
+// the queue unless it should be retried.
+func (w *Webhook) deliverOne(q models.QueuedWebhook, now time.Time) {
+	var payload WebhookPayload
+	if err := json.Unmarshal([]byte(q.Payload), &payload); err != nil {
+		w.Logger.Error("Dropping unreadable webhook payload", zap.Int64("id", q.ID), zap.Error(err))
+		w.dequeue(q.ID)
+		return
+	}
+
+	hook := &models.Webhook{ID: q.WebhookID, URL: q.URL, Secret: q.Secret}
+	d, retryAfter, err := w.attempt(hook, payload, []byte(q.Payload), q.Attempts+1)
+	if d.Succeeded() {
+		w.dequeue(q.ID)
+		return
+	}
+	if d.Attempt >= max(w.MaxAttempts, 1) || !retryable(d) || errors.Is(err, ErrPrivateAddress) {
+		w.Logger.Warn("Webhook delivery failed",
+			zap.String("webhook_id", hook.ID),
+			zap.String("deal_id", d.DealID),
+			zap.Int("attempts", d.Attempt),
+			zap.Int("status_code", d.StatusCode),
+			zap.String("error", d.Error))
+		w.dequeue(q.ID)
+		return
+	}
+	if err := w.Store.RescheduleWebhook(q.ID, d.Attempt, now.Add(w.backoff(d.Attempt, retryAfter))); err != nil {
+		w.Logger.Error("Failed to reschedule webhook delivery", zap.Int64("id", q.ID), zap.Error(err))
+	}
+}
+
+func (w *Webhook) dequeue(id int64) {
+	if err := w.Store.DeleteQueuedWebhook(id); err != nil {
+		w.Logger.Error("Failed to remove queued webhook delivery", zap.Int64("id", id), zap.Error(err))
+	}
+}
+
+// backoff returns the wait after a payload's nth failed attempt: BaseDelay
+// doubled for each earlier failure, at least the server's Retry-After and at
+// most MaxDelay.
+func (w *Webhook) backoff(n int, retryAfter time.Duration) time.Duration {
+	wait := max(w.BaseDelay<<(n-1), retryAfter)
+	if w.MaxDelay > 0 {
+		wait = min(wait, w.MaxDelay)
+	}
+	return wait
+}
+
+// newPayload returns a payload with a fresh delivery ID.
+func newPayload(event string, deal *models.Deal) WebhookPayload {
+	return WebhookPayload{ID: randomHex(16), Event: event, Deal: deal, SentAt: time.Now().UTC()}
+}
+
+// Deliver posts a payload to one webhook straight away, retrying failed
+// attempts with exponential backoff. It returns the last attempt, and an error
+// if no attempt succeeded.
+func (w *Webhook) Deliver(hook *models.Webhook, event string, deal *models.Deal) (*models.WebhookDelivery, error) {
+	payload := newPayload(event, deal)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	attempts := max(w.MaxAttempts, 1)
+	var last *models.WebhookDelivery
+	for attempt := 1; ; attempt++ {
+		var retryAfter time.Duration
+		var err error
+		last, retryAfter, err = w.attempt(hook, payload, body, attempt)
+		if last.Succeeded() {
+			return last, nil
+		}
+		if attempt >= attempts || !retryable(last) || errors.Is(err, ErrPrivateAddress) {
+			break
+		}
+		time.Sleep(w.backoff(attempt, retryAfter))
+	}
+
+	if last.Error != "" {
+		return last, fmt.Errorf("webhook delivery failed after %d attempt(s): %s", last.Attempt, last.Error)
+	}
+	return last, fmt.Errorf("webhook delivery failed after %d attempt(s): status %d", last.Attempt, last.StatusCode)
+}
+
+// attempt sends one request and logs it, returning the server's Retry-After
+// hint if there was one and the error if the request could not be sent.
+func (w *Webhook) attempt(hook *models.Webhook, payload WebhookPayload, body []byte, n int) (*models.WebhookDelivery, time.Duration, error) {
+	d := &models.WebhookDelivery{
+		WebhookID:  hook.ID,
+		DeliveryID: payload.ID,
+		Event:      payload.Event,
+		Attempt:    n,
+	}
+	if payload.Deal != nil {
+		d.Source, d.DealID = payload.Deal.Source, payload.Deal.Id
+	}
+
+	var retryAfter time.Duration
+	start := time.Now()
+	resp, postErr := w.post(hook, payload, body)
+	d.Duration = time.Since(start).Milliseconds()
+	if postErr != nil {
+		d.Error = postErr.Error()
+	} else {
+		d.StatusCode = resp.StatusCode
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			retryAfter = time.Duration(secs) * time.Second
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) //nolint:errcheck // drain so the connection is reused
+		resp.Body.Close()
+	}
+
+	if err := w.Store.LogWebhookDelivery(d); err != nil {
+		w.Logger.Error("Failed to log webhook delivery", zap.String("webhook_id", hook.ID), zap.Error(err))
+	}
+	return d, retryAfter, postErr
+}
+
+func (w *Webhook) post(hook *models.Webhook, payload WebhookPayload, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KramerBot-Webhook")
+	req.Header.Set(HeaderEvent, payload.Event)
+	req.Header.Set(HeaderDelivery, payload.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, ts, body))
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// retryable reports whether a failed attempt may succeed if repeated: network
+// errors, timeouts, rate limiting and server errors.
+func retryable(d *models.WebhookDelivery) bool {
+	switch {
+	case d.StatusCode == 0:
+		return true
+	case d.StatusCode == http.StatusRequestTimeout, d.StatusCode == http.StatusTooManyRequests:
+		return true
+	}
+	return d.StatusCode >= 500
+}
@@ -0,0 +1,304 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+// memWebhookDB is an in-memory persist.WebhookDBIF.
+type memWebhookDB struct {
+	mu         sync.Mutex
+	hooks      []*models.Webhook
+	deliveries []models.WebhookDelivery
+	queue      []models.QueuedWebhook
+	nextID     int64
+}
+
+func (m *memWebhookDB) CreateWebhook(hook *models.Webhook) error {
+	m.hooks = append(m.hooks, hook)
+	return nil
+}
+
+func (m *memWebhookDB) ListWebhooks(userID string) ([]*models.Webhook, error) {
+	var out []*models.Webhook
+	for _, h := range m.hooks {
+		if h.UserID == userID {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (m *memWebhookDB) DeleteWebhook(userID, id string) (bool, error) { return false, nil }
+
+func (m *memWebhookDB) LogWebhookDelivery(d *models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, *d)
+	return nil
+}
+
+func (m *memWebhookDB) ListWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.WebhookDelivery(nil), m.deliveries...), nil
+}
+
+func (m *memWebhookDB) EnqueueWebhook(q *models.QueuedWebhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	q.ID, q.CreatedAt = m.nextID, time.Now()
+	q.NextAttemptAt = q.CreatedAt
+	m.queue = append(m.queue, *q)
+	return nil
+}
+
+func (m *memWebhookDB) DueWebhooks(now time.Time, limit int) ([]models.QueuedWebhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []models.QueuedWebhook
+	for _, q := range m.queue {
+		if q.NextAttemptAt.After(now) || len(due) == limit {
+			continue
+		}
+		for _, h := range m.hooks {
+			if h.ID == q.WebhookID {
+				q.URL, q.Secret = h.URL, h.Secret
+				due = append(due, q)
+			}
+		}
+	}
+	return due, nil
+}
+
+func (m *memWebhookDB) DeleteQueuedWebhook(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, q := range m.queue {
+		if q.ID == id {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *memWebhookDB) RescheduleWebhook(id int64, attempts int, next time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.queue {
+		if m.queue[i].ID == id {
+			m.queue[i].Attempts, m.queue[i].NextAttemptAt = attempts, next
+		}
+	}
+	return nil
+}
+
+// receiver is an httptest webhook endpoint that answers with the queued status
+// codes in turn (200 once they run out) and records every request.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+	times    []time.Time
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	rc.times = append(rc.times, time.Now())
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "1")
+	}
+	w.WriteHeader(status)
+}
+
+func newTestWebhook(t *testing.T, statuses ...int) (*Webhook, *memWebhookDB, *receiver, *models.Webhook) {
+	t.Helper()
+	rc := &receiver{statuses: statuses}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	store := &memWebhookDB{}
+	hook := &models.Webhook{ID: "h1", UserID: "u1", URL: srv.URL, Secret: "whsec_test"}
+	store.CreateWebhook(hook)
+
+	w := NewWebhook(store, zap.NewNop())
+	w.Client = srv.Client()
+	w.BaseDelay = 10 * time.Millisecond
+	w.MaxDelay = 50 * time.Millisecond
+	return w, store, rc, hook
+}
+
+func TestWebhook_SignsPayload(t *testing.T) {
+	w, store, rc, _ := newTestWebhook(t)
+	deal := &models.Deal{Id: "1", Source: "ozbargain", Title: "Cheap SSD"}
+	r := &Recipient{User: &models.UserData{ChatID: 1}, Account: &models.WebUser{ID: "u1"}}
+
+	if err := w.Notify(r, Event{Deal: deal, Kind: Watched}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if len(rc.requests) != 0 {
+		t.Fatal("Notify() delivered before the queue was run")
+	}
+	w.deliverQueued(time.Now())
+
+	if len(rc.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(rc.requests))
+	}
+	req, body := rc.requests[0], rc.bodies[0]
+	ts, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("bad timestamp header: %v", err)
+	}
+	if got, want := req.Header.Get(HeaderSignature), Sign("whsec_test", ts, body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if Sign("wrong secret", ts, body) == req.Header.Get(HeaderSignature) {
+		t.Error("signature does not depend on the secret")
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event != "watched" || payload.Deal.Id != "1" || payload.ID != req.Header.Get(HeaderDelivery) {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+
+	log, _ := store.ListWebhookDeliveries("h1", 10)
+	if len(log) != 1 || !log[0].Succeeded() || log[0].DealID != "1" || log[0].Attempt != 1 {
+		t.Errorf("unexpected delivery log %+v", log)
+	}
+}
+
+func TestWebhook_RetriesWithBackoff(t *testing.T) {
+	w, store, rc, hook := newTestWebhook(t, 500, 502)
+
+	d, err := w.Deliver(hook, "subscribed", &models.Deal{Id: "1"})
+	if err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if d.Attempt != 3 || !d.Succeeded() {
+		t.Errorf("last attempt = %+v, want a successful third attempt", d)
+	}
+
+	// Waits double: ~10ms then ~20ms
+	if gap1, gap2 := rc.times[1].Sub(rc.times[0]), rc.times[2].Sub(rc.times[1]); gap1 < 10*time.Millisecond || gap2 < 20*time.Millisecond {
+		t.Errorf("retry gaps %v, %v; want at least 10ms, 20ms", gap1, gap2)
+	}
+
+	// Every attempt is logged under the same delivery id
+	log, _ := store.ListWebhookDeliveries("h1", 10)
+	if len(log) != 3 || log[0].StatusCode != 500 || log[1].StatusCode != 502 || log[2].StatusCode != 200 {
+		t.Fatalf("unexpected delivery log %+v", log)
+	}
+	if log[0].DeliveryID != log[2].DeliveryID || rc.requests[0].Header.Get(HeaderDelivery) != log[0].DeliveryID {
+		t.Error("attempts do not share a delivery id")
+	}
+}
+
+func TestWebhook_GivesUp(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int
+	}{
+		{"client error is not retried", []int{404}, 1},
+		{"server errors until out of attempts", []int{500, 500, 500, 500, 500}, 5},
+		{"rate limit capped by max delay", []int{429, 429, 429, 429, 429}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, store, rc, hook := newTestWebhook(t, tt.statuses...)
+
+			start := time.Now()
+			d, err := w.Deliver(hook, "subscribed", &models.Deal{Id: "1"})
+			if err == nil {
+				t.Fatalf("Deliver() succeeded, want error (last attempt %+v)", d)
+			}
+			if len(rc.requests) != tt.wantAttempts || d.Attempt != tt.wantAttempts {
+				t.Errorf("made %d attempts, want %d", len(rc.requests), tt.wantAttempts)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("took %v; Retry-After should be capped by MaxDelay", elapsed)
+			}
+			if log, _ := store.ListWebhookDeliveries("h1", 10); len(log) != tt.wantAttempts {
+				t.Errorf("logged %d attempts, want %d", len(log), tt.wantAttempts)
+			}
+		})
+	}
+}
+
+// Failed deliveries stay queued until they are due again, so a restart picks
+// them up, and are dropped once they run out of attempts.
+func TestWebhook_QueuedRetries(t *testing.T) {
+	w, store, rc, _ := newTestWebhook(t, 500, 503, 500)
+	w.Workers, w.MaxAttempts = 1, 2
+	r := &Recipient{User: &models.UserData{ChatID: 1}, Account: &models.WebUser{ID: "u1"}}
+	for _, id := range []string{"1", "2"} {
+		if err := w.Notify(r, Event{Deal: &models.Deal{Id: id}, Kind: Subscribed}); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+	}
+
+	now := time.Now()
+	w.deliverQueued(now)
+	if len(rc.requests) != 2 || len(store.queue) != 2 {
+		t.Fatalf("first round: %d requests, %d queued; want 2, 2", len(rc.requests), len(store.queue))
+	}
+	w.deliverQueued(now) // nothing due yet
+	if len(rc.requests) != 2 {
+		t.Fatalf("retried %d deliveries before they were due", len(rc.requests)-2)
+	}
+
+	restarted := NewWebhook(store, zap.NewNop())
+	restarted.Client, restarted.Workers, restarted.MaxAttempts = w.Client, 1, 2
+	restarted.deliverQueued(now.Add(time.Minute))
+	if len(store.queue) != 0 {
+		t.Fatalf("%d deliveries still queued", len(store.queue))
+	}
+
+	// Deal 1 gave up after two attempts, deal 2 got through on its second
+	statuses := map[string][]int{}
+	log, _ := store.ListWebhookDeliveries("h1", 10)
+	for _, d := range log {
+		statuses[d.DealID] = append(statuses[d.DealID], d.StatusCode)
+	}
+	if got := fmt.Sprint(statuses["1"], statuses["2"]); got != "[500 500] [503 200]" {
+		t.Errorf("attempt statuses = %s, want [500 500] [503 200]", got)
+	}
+}
+
+func TestWebhook_NoAddress(t *testing.T) {
+	w, _, _, _ := newTestWebhook(t)
+	deal := &models.Deal{Id: "1"}
+
+	for _, r := range []*Recipient{
+		{User: &models.UserData{}},
+		{User: &models.UserData{}, Account: &models.WebUser{ID: "someone-else"}},
+	} {
+		if err := w.Notify(r, Event{Deal: deal}); err != ErrNoAddress {
+			t.Errorf("Notify() error = %v, want ErrNoAddress", err)
+		}
+	}
+}
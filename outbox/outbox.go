@@ -0,0 +1,318 @@
+// Package outbox queues Telegram messages in SQLite and sends them from a
+// worker pool that respects Telegram's rate limits.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/persist"
+	"github.com/intothevoid/kramerbot/util"
+	"go.uber.org/zap"
+)
+
+// ParseModeHTML formats a message as Telegram HTML.
+const ParseModeHTML = "HTML"
+
+const (
+	batchSize    = 100             // messages read from the store per round
+	pollInterval = time.Second     // how often the store is checked when nothing wakes the dispatcher
+	baseDelay    = 5 * time.Second // wait before the first retry, doubled for each further retry
+	maxDelay     = 10 * time.Minute
+)
+
+// Transport sends a single message to Telegram. It returns a *SendError to
+// say how a failure should be handled; any other error is retried.
+type Transport func(m models.OutboxMessage) error
+
+// SendError is a failed send that should not simply be retried.
+type SendError struct {
+	Err        error
+	Permanent  bool          // retrying will not help; dead-letter the message
+	RetryAfter time.Duration // rate limited; pause every send for this long
+}
+
+func (e *SendError) Error() string { return e.Err.Error() }
+func (e *SendError) Unwrap() error { return e.Err }
+
+// Outbox stores messages until Telegram accepts them. A single dispatcher
+// reads due messages and hands them to the workers, sending at most GlobalRate
+// messages per second overall and one message per ChatInterval to each chat.
+// When Telegram answers with retry_after every send is paused for that long.
+type Outbox struct {
+	Store        persist.OutboxDBIF
+	Send         Transport
+	Logger       *zap.Logger
+	Workers      int
+	ChatInterval time.Duration
+	MaxAttempts  int // failed attempts before a message is dead-lettered
+
+	// OnSent, if set, is called after Telegram accepts a message.
+	OnSent func(m models.OutboxMessage)
+	// OnDead, if set, is called after a message is dead-lettered with the
+	// error of its last attempt.
+	OnDead func(m models.OutboxMessage, err error)
+
+	global   *limiter
+	wake     chan struct{}
+	mu       sync.Mutex
+	inFlight map[int64]bool      // chats with a message being sent
+	nextSend map[int64]time.Time // earliest time each chat can be sent to again
+}
+
+// New returns an outbox configured from cfg. Call Run to start sending.
+func New(store persist.OutboxDBIF, send Transport, cfg util.OutboxConfig, logger *zap.Logger) *Outbox {
+	return &Outbox{
+		Store:        store,
+		Send:         send,
+		Logger:       logger,
+		Workers:      max(cfg.Workers, 1),
+		ChatInterval: time.Duration(cfg.ChatIntervalMs) * time.Millisecond,
+		MaxAttempts:  max(cfg.MaxAttempts, 1),
+		global:       &limiter{interval: time.Second / time.Duration(max(cfg.GlobalRate, 1))},
+		wake:         make(chan struct{}, 1),
+		inFlight:     make(map[int64]bool),
+		nextSend:     make(map[int64]time.Time),
+	}
+}
+
+// Enqueue stores a message for sending.
+func (o *Outbox) Enqueue(chatID int64, text, parseMode string) error {
+	return o.enqueue(&models.OutboxMessage{ChatID: chatID, Text: text, ParseMode: parseMode})
+}
+
+// SendHTML queues an HTML message with optional rows of inline buttons, so the
+// outbox can stand in for the bot as the Telegram channel's sender.
+func (o *Outbox) SendHTML(chatID int64, text string, buttons [][]models.InlineButton) error {
+	return o.enqueue(&models.OutboxMessage{ChatID: chatID, Text: text, ParseMode: ParseModeHTML, Buttons: buttons})
+}
+
+// EnqueueBatch stores a message that is part of a broadcast. The batch is
+// passed back to OnSent and OnDead so the broadcast's delivery can be tracked.
+func (o *Outbox) EnqueueBatch(batch string, chatID int64, text, parseMode string) error {
+	return o.enqueue(&models.OutboxMessage{ChatID: chatID, Text: text, ParseMode: parseMode, Batch: batch})
+}
+
+func (o *Outbox) enqueue(m *models.OutboxMessage) error {
+	if err := o.Store.EnqueueOutbox(m); err != nil {
+		return fmt.Errorf("failed to queue message: %w", err)
+	}
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Depth reports how many messages are waiting and how many were dead-lettered.
+func (o *Outbox) Depth() (models.OutboxStats, error) {
+	return o.Store.OutboxStats()
+}
+
+// Run sends queued messages until ctx is cancelled, then waits for the
+// messages already handed to workers.
+func (o *Outbox) Run(ctx context.Context) {
+	jobs := make(chan models.OutboxMessage)
+	var wg sync.WaitGroup
+	for i := 0; i < o.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range jobs {
+				o.deliver(m)
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		if o.dispatch(ctx, jobs) > 0 {
+			// More may be due right away; go round again unless stopping.
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// dispatch hands every due message whose chat is free to the workers and
+// returns how many it handed over.
+func (o *Outbox) dispatch(ctx context.Context, jobs chan<- models.OutboxMessage) int {
+	msgs, err := o.Store.DueOutbox(time.Now(), batchSize)
+	if err != nil {
+		o.Logger.Error("Failed to read outbox", zap.Error(err))
+		return 0
+	}
+
+	sent := 0
+	for _, m := range msgs {
+		if !o.reserve(m.ChatID) {
+			continue
+		}
+		if err := o.global.wait(ctx); err != nil {
+			o.release(m.ChatID, time.Time{})
+			return sent
+		}
+		select {
+		case jobs <- m:
+			sent++
+		case <-ctx.Done():
+			o.release(m.ChatID, time.Time{})
+			return sent
+		}
+	}
+	return sent
+}
+
+// deliver sends one message and records the outcome.
+func (o *Outbox) deliver(m models.OutboxMessage) {
+	err := o.Send(m)
+	now := time.Now()
+	next := now.Add(o.ChatInterval)
+	defer func() { o.release(m.ChatID, next) }()
+
+	if err == nil {
+		if err := o.Store.DeleteOutbox(m.ID); err != nil {
+			o.Logger.Error("Failed to remove sent message from outbox", zap.Int64("id", m.ID), zap.Error(err))
+		}
+		if o.OnSent != nil {
+			o.OnSent(m)
+		}
+		return
+	}
+
+	fields := []zap.Field{zap.Int64("id", m.ID), zap.Int64("chat_id", m.ChatID), zap.Error(err)}
+
+	// Rate limited: hold everything back, and retry without counting an attempt.
+	if wait, ok := retryAfter(err); ok {
+		o.Logger.Warn("Telegram rate limit hit, pausing sends", append(fields, zap.Duration("retry_after", wait))...)
+		o.global.pause(wait)
+		next = now.Add(wait)
+		o.reschedule(m, m.Attempts, next, err)
+		return
+	}
+
+	attempts := m.Attempts + 1
+	if permanent(err) || attempts >= o.MaxAttempts {
+		o.Logger.Error("Giving up on outbox message", append(fields, zap.Int("attempts", attempts))...)
+		if err := o.Store.DeadLetterOutbox(m.ID, attempts, err.Error()); err != nil {
+			o.Logger.Error("Failed to dead-letter outbox message", zap.Int64("id", m.ID), zap.Error(err))
+		}
+		if o.OnDead != nil {
+			m.Attempts = attempts
+			o.OnDead(m, err)
+		}
+		return
+	}
+
+	delay := min(baseDelay<<(attempts-1), maxDelay)
+	o.Logger.Warn("Telegram send failed, will retry", append(fields, zap.Duration("delay", delay))...)
+	o.reschedule(m, attempts, now.Add(delay), err)
+}
+
+func (o *Outbox) reschedule(m models.OutboxMessage, attempts int, at time.Time, sendErr error) {
+	if err := o.Store.RescheduleOutbox(m.ID, attempts, at, sendErr.Error()); err != nil {
+		o.Logger.Error("Failed to reschedule outbox message", zap.Int64("id", m.ID), zap.Error(err))
+	}
+}
+
+// reserve claims a chat for one send, reporting false if a message to it is
+// already in flight or it was sent to too recently.
+func (o *Outbox) reserve(chatID int64) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	if o.inFlight[chatID] || now.Before(o.nextSend[chatID]) {
+		return false
+	}
+	o.inFlight[chatID] = true
+	// Forget chats whose wait is over so the map does not grow without bound.
+	for id, t := range o.nextSend {
+		if !now.Before(t) {
+			delete(o.nextSend, id)
+		}
+	}
+	return true
+}
+
+// release frees a chat, which can next be sent to at next.
+func (o *Outbox) release(chatID int64, next time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.inFlight, chatID)
+	if next.After(time.Now()) {
+		o.nextSend[chatID] = next
+	}
+}
+
+// retryAfter returns the wait requested by a rate-limited send.
+func retryAfter(err error) (time.Duration, bool) {
+	var sendErr *SendError
+	if errors.As(err, &sendErr) && sendErr.RetryAfter > 0 {
+		return sendErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// permanent reports whether the transport said retrying will not help.
+func permanent(err error) bool {
+	var sendErr *SendError
+	return errors.As(err, &sendErr) && sendErr.Permanent
+}
+
+// limiter spaces events at least interval apart.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next slot or until ctx is cancelled.
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	d := time.Until(slot)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pause holds back every slot for at least d.
+func (l *limiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.next) {
+		l.next = until
+	}
+}
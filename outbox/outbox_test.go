@@ -0,0 +1,193 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/persist/sqlite"
+	"github.com/intothevoid/kramerbot/util"
+	"go.uber.org/zap"
+)
+
+type sent struct {
+	chatID int64
+	text   string
+	at     time.Time
+}
+
+// transport records sends and fails them with the queued errors first.
+type transport struct {
+	mu    sync.Mutex
+	sent  []sent
+	fails []error
+}
+
+func (tr *transport) send(m models.OutboxMessage) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if len(tr.fails) > 0 {
+		err := tr.fails[0]
+		tr.fails = tr.fails[1:]
+		return err
+	}
+	tr.sent = append(tr.sent, sent{m.ChatID, m.Text, time.Now()})
+	return nil
+}
+
+func (tr *transport) messages() []sent {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append([]sent(nil), tr.sent...)
+}
+
+func newTestOutbox(t *testing.T, tr *transport, cfg util.OutboxConfig) (*Outbox, *sqlite.SQLiteWrapper) {
+	t.Helper()
+	db, err := sqlite.NewSQLiteWrapper(filepath.Join(t.TempDir(), "outbox_test.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return New(db, tr.send, cfg, zap.NewNop()), db
+}
+
+func TestOutbox_SendsInOrderWithinRateLimits(t *testing.T) {
+	tr := &transport{}
+	o, db := newTestOutbox(t, tr, util.OutboxConfig{Workers: 4, GlobalRate: 50, ChatIntervalMs: 100, MaxAttempts: 3})
+	for i := 0; i < 3; i++ {
+		for _, chatID := range []int64{1, 2} {
+			if err := o.Enqueue(chatID, fmt.Sprintf("%d-%d", chatID, i), ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { o.Run(ctx); close(done) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(tr.messages()) < 6 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	msgs := tr.messages()
+	if len(msgs) != 6 {
+		t.Fatalf("sent %d messages, want 6", len(msgs))
+	}
+	last := map[int64]sent{}
+	next := map[int64]int{}
+	for i, m := range msgs {
+		if want := fmt.Sprintf("%d-%d", m.chatID, next[m.chatID]); m.text != want {
+			t.Errorf("chat %d got %q, want %q (out of order)", m.chatID, m.text, want)
+		}
+		next[m.chatID]++
+		if prev, ok := last[m.chatID]; ok && m.at.Sub(prev.at) < 100*time.Millisecond {
+			t.Errorf("chat %d sent twice within %v", m.chatID, m.at.Sub(prev.at))
+		}
+		if i > 0 && m.at.Sub(msgs[i-1].at) < 15*time.Millisecond {
+			t.Errorf("messages %d and %d sent %v apart, faster than the global rate", i-1, i, m.at.Sub(msgs[i-1].at))
+		}
+		last[m.chatID] = m
+	}
+
+	if stats, _ := db.OutboxStats(); stats.Pending != 0 || stats.Dead != 0 {
+		t.Errorf("OutboxStats() after sending = %+v, want empty", stats)
+	}
+}
+
+func TestOutbox_Failures(t *testing.T) {
+	rateLimited := &SendError{Err: errors.New("Too Many Requests: retry after 3"), RetryAfter: 3 * time.Second}
+	tests := []struct {
+		name         string
+		err          error
+		attempts     int // attempts already made
+		wantDead     bool
+		wantAttempts int
+		wantDelay    time.Duration
+	}{
+		{"transient", errors.New("connection reset"), 0, false, 1, baseDelay},
+		{"backoff doubles", errors.New("connection reset"), 2, false, 3, 4 * baseDelay},
+		{"rate limited", fmt.Errorf("failed to send message: %w", rateLimited), 1, false, 1, 3 * time.Second},
+		{"blocked", &SendError{Err: errors.New("Forbidden: bot was blocked by the user"), Permanent: true}, 0, true, 1, 0},
+		{"out of attempts", errors.New("connection reset"), 3, true, 4, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &transport{fails: []error{tt.err}}
+			o, db := newTestOutbox(t, tr, util.OutboxConfig{Workers: 1, GlobalRate: 30, MaxAttempts: 4})
+			var deadErr error
+			o.OnDead = func(m models.OutboxMessage, err error) { deadErr = err }
+			o.OnSent = func(m models.OutboxMessage) { t.Errorf("OnSent called for a failed send") }
+			if err := o.Enqueue(7, "deal", ParseModeHTML); err != nil {
+				t.Fatal(err)
+			}
+			due, _ := db.DueOutbox(time.Now(), 1)
+			m := due[0]
+			m.Attempts = tt.attempts
+
+			start := time.Now()
+			o.deliver(m)
+
+			if tt.wantDead {
+				dead, _ := db.ListDeadOutbox(1)
+				if len(dead) != 1 || dead[0].Attempts != tt.wantAttempts || dead[0].LastError == "" {
+					t.Errorf("dead letters = %+v", dead)
+				}
+				if !errors.Is(deadErr, tt.err) {
+					t.Errorf("OnDead error = %v, want %v", deadErr, tt.err)
+				}
+				return
+			}
+			if due, _ := db.DueOutbox(time.Now(), 1); len(due) != 0 {
+				t.Fatalf("message still due after a failed send: %+v", due)
+			}
+			if deadErr != nil {
+				t.Errorf("OnDead called for a retried message: %v", deadErr)
+			}
+			due, _ = db.DueOutbox(time.Now().Add(time.Hour), 1)
+			if len(due) != 1 || due[0].Attempts != tt.wantAttempts {
+				t.Fatalf("rescheduled message = %+v, want %d attempts", due, tt.wantAttempts)
+			}
+			if got := due[0].NextAttemptAt.Sub(start); got < tt.wantDelay-time.Second || got > tt.wantDelay+time.Second {
+				t.Errorf("next attempt in %v, want about %v", got, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestOutbox_OnSent(t *testing.T) {
+	o, _ := newTestOutbox(t, &transport{}, util.OutboxConfig{Workers: 1, GlobalRate: 30, MaxAttempts: 1})
+	var batches []string
+	o.OnSent = func(m models.OutboxMessage) { batches = append(batches, m.Batch) }
+	if err := o.EnqueueBatch("b1", 7, "news", ""); err != nil {
+		t.Fatal(err)
+	}
+	due, _ := o.Store.DueOutbox(time.Now(), 1)
+	o.deliver(due[0])
+	if len(batches) != 1 || batches[0] != "b1" {
+		t.Errorf("OnSent batches = %v, want [b1]", batches)
+	}
+}
+
+func TestOutbox_RetryAfterPausesAllChats(t *testing.T) {
+	tr := &transport{fails: []error{&SendError{Err: errors.New("Too Many Requests: retry after 1"), RetryAfter: time.Second}}}
+	o, _ := newTestOutbox(t, tr, util.OutboxConfig{Workers: 1, GlobalRate: 30, MaxAttempts: 3})
+	o.Enqueue(1, "a", "")
+	due, _ := o.Store.DueOutbox(time.Now(), 1)
+	o.deliver(due[0])
+
+	start := time.Now()
+	if err := o.global.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 900*time.Millisecond {
+		t.Errorf("next send after retry_after waited %v, want about 1s", waited)
+	}
+}
@@ -0,0 +1,485 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+// createDealsTableSQL stores one row per (source, deal_id). Scrapes update the row
+// in place so first_seen is kept and last_seen tracks the latest scrape.
+const createDealsTableSQL = `
+CREATE TABLE IF NOT EXISTS deals (
+	id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	source        TEXT NOT NULL,
+	deal_id       TEXT NOT NULL,
+	title         TEXT NOT NULL,
+	description   TEXT NOT NULL DEFAULT '',
+	url           TEXT NOT NULL DEFAULT '',
+	posted_on     TEXT NOT NULL DEFAULT '',
+	posted_at     DATETIME,
+	upvotes       TEXT NOT NULL DEFAULT '',
+	votes         INTEGER NOT NULL DEFAULT 0,
+	deal_age      TEXT NOT NULL DEFAULT '',
+	image         TEXT NOT NULL DEFAULT '',
+	price_drop    TEXT NOT NULL DEFAULT '',
+	drop_percent  INTEGER NOT NULL DEFAULT 0,
+	deal_type     INTEGER NOT NULL DEFAULT 0,
+	first_seen    DATETIME NOT NULL,
+	last_seen     DATETIME NOT NULL,
+	price         REAL,
+	store_domain  TEXT NOT NULL DEFAULT '',
+	coupon_code   TEXT NOT NULL DEFAULT '',
+	category      TEXT NOT NULL DEFAULT '',
+	tags          TEXT NOT NULL DEFAULT '',
+	expires_at    DATETIME,
+	poster        TEXT NOT NULL DEFAULT '',
+	expiry        TEXT NOT NULL DEFAULT '',
+	expired_at    DATETIME,
+	expiry_notified INTEGER NOT NULL DEFAULT 0,
+	last_expiry_check DATETIME,
+	UNIQUE (source, deal_id)
+)`
+
+// createDealVotesTableSQL keeps a snapshot every time a deal's vote count changes.
+const createDealVotesTableSQL = `
+CREATE TABLE IF NOT EXISTS deal_votes (
+	deal_row     INTEGER NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+	votes        INTEGER NOT NULL,
+	recorded_at  DATETIME NOT NULL
+)`
+
+// dealMigrateStmts adds columns or indexes that may be missing on an existing deals table.
+var dealMigrateStmts = []string{
+	// Columns added after the deals table was introduced.
+	`ALTER TABLE deals ADD COLUMN description TEXT NOT NULL DEFAULT ''`,
+	// Deal page details
+	`ALTER TABLE deals ADD COLUMN price REAL`,
+	`ALTER TABLE deals ADD COLUMN store_domain TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE deals ADD COLUMN coupon_code TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE deals ADD COLUMN category TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE deals ADD COLUMN tags TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE deals ADD COLUMN expires_at DATETIME`,
+	`ALTER TABLE deals ADD COLUMN poster TEXT NOT NULL DEFAULT ''`,
+	// Expiry tracking
+	`ALTER TABLE deals ADD COLUMN expiry TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE deals ADD COLUMN expired_at DATETIME`,
+	`ALTER TABLE deals ADD COLUMN expiry_notified INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE deals ADD COLUMN last_expiry_check DATETIME`,
+	// Indexes — created after columns to avoid "no such column" on old schemas.
+	`CREATE INDEX IF NOT EXISTS idx_deals_posted ON deals(COALESCE(posted_at, first_seen))`,
+	`CREATE INDEX IF NOT EXISTS idx_deals_source_type ON deals(source, deal_type)`,
+	`CREATE INDEX IF NOT EXISTS idx_deal_votes_deal ON deal_votes(deal_row, recorded_at)`,
+}
+
+// CreateDealsTables creates the deals and deal_votes tables, their indexes and
+// the full-text search index.
+func (udb *UserStoreDB) CreateDealsTables() error {
+	if _, err := udb.DB.Exec(createDealsTableSQL); err != nil {
+		return fmt.Errorf("failed to create deals table: %w", err)
+	}
+	if _, err := udb.DB.Exec(createDealVotesTableSQL); err != nil {
+		return fmt.Errorf("failed to create deal_votes table: %w", err)
+	}
+	// Best-effort, like the web_users migrations: duplicate columns and existing
+	// indexes return errors that are safe to ignore.
+	for _, stmt := range dealMigrateStmts {
+		udb.DB.Exec(stmt) //nolint:errcheck
+	}
+	return udb.createDealSearchIndex()
+}
+
+// dealColumns is the explicit column list used in all deal SELECT queries.
+const dealColumns = `
+	source, deal_id, title, description, url, posted_on, posted_at, upvotes, deal_age,
+	image, price_drop, drop_percent, deal_type, first_seen, last_seen,
+	price, store_domain, coupon_code, category, tags, expires_at, poster, expiry, expired_at`
+
+// UpsertDeals inserts new deals and refreshes existing ones, recording a vote
+// snapshot whenever a deal's vote count differs from the last snapshot.
+// Deal page details are kept when a scrape returns a deal without them, and
+// an expiry, once recorded, is kept.
+func (udb *UserStoreDB) UpsertDeals(deals []models.Deal, seenAt time.Time) error {
+	seenAt = dbTime(seenAt)
+
+	tx, err := udb.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Will be ignored if tx.Commit() is called
+
+	upsert, err := tx.Prepare(`
+		INSERT INTO deals
+			(source, deal_id, title, description, url, posted_on, posted_at, upvotes, votes, deal_age,
+			 image, price_drop, drop_percent, deal_type, first_seen, last_seen,
+			 price, store_domain, coupon_code, category, tags, expires_at, poster, expiry, expired_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (source, deal_id) DO UPDATE SET
+			title = excluded.title,
+			description = CASE WHEN excluded.description != '' THEN excluded.description ELSE deals.description END,
+			url = excluded.url,
+			posted_on = excluded.posted_on,
+			posted_at = COALESCE(excluded.posted_at, deals.posted_at),
+			upvotes = excluded.upvotes,
+			votes = excluded.votes,
+			deal_age = excluded.deal_age,
+			image = CASE WHEN excluded.image != '' THEN excluded.image ELSE deals.image END,
+			price_drop = excluded.price_drop,
+			drop_percent = excluded.drop_percent,
+			deal_type = excluded.deal_type,
+			last_seen = excluded.last_seen,
+			price = COALESCE(excluded.price, deals.price),
+			store_domain = CASE WHEN excluded.store_domain != '' THEN excluded.store_domain ELSE deals.store_domain END,
+			coupon_code = CASE WHEN excluded.coupon_code != '' THEN excluded.coupon_code ELSE deals.coupon_code END,
+			category = CASE WHEN excluded.category != '' THEN excluded.category ELSE deals.category END,
+			tags = CASE WHEN excluded.tags != '' THEN excluded.tags ELSE deals.tags END,
+			expires_at = COALESCE(excluded.expires_at, deals.expires_at),
+			poster = CASE WHEN excluded.poster != '' THEN excluded.poster ELSE deals.poster END,
+			expired_at = CASE WHEN deals.expiry = '' THEN excluded.expired_at ELSE deals.expired_at END,
+			expiry = CASE WHEN deals.expiry = '' THEN excluded.expiry ELSE deals.expiry END
+		RETURNING id`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare deal upsert: %w", err)
+	}
+	defer upsert.Close()
+
+	snapshot, err := tx.Prepare(`
+		INSERT INTO deal_votes (deal_row, votes, recorded_at)
+		SELECT ?1, ?2, ?3
+		WHERE COALESCE(
+			(SELECT votes FROM deal_votes WHERE deal_row = ?1 ORDER BY recorded_at DESC, rowid DESC LIMIT 1),
+			-1) != ?2`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare vote snapshot: %w", err)
+	}
+	defer snapshot.Close()
+
+	for i := range deals {
+		d := &deals[i]
+		tags := ""
+		if len(d.Tags) > 0 {
+			raw, err := json.Marshal(d.Tags)
+			if err != nil {
+				return fmt.Errorf("failed to marshal tags: %w", err)
+			}
+			tags = string(raw)
+		}
+		var expiredAt sql.NullTime
+		if d.Expiry != "" {
+			expiredAt = nullTime(seenAt)
+		}
+		var row int64
+		err := upsert.QueryRow(
+			d.Source, d.Id, d.Title, d.Description, d.Url, d.PostedOn, nullTime(d.PostedAt), d.Upvotes, d.Votes(), d.DealAge,
+			d.Image, d.PriceDrop, d.DropPercent, d.DealType, seenAt, seenAt,
+			d.Price, d.StoreDomain, d.CouponCode, d.Category, tags, nullTime(d.ExpiresAt), d.Poster, d.Expiry, expiredAt,
+		).Scan(&row)
+		if err != nil {
+			return fmt.Errorf("failed to upsert deal %s/%s: %w", d.Source, d.Id, err)
+		}
+
+		// Sources without community voting have nothing to snapshot
+		if d.Upvotes == "" {
+			continue
+		}
+		if _, err := snapshot.Exec(row, d.Votes(), seenAt); err != nil {
+			return fmt.Errorf("failed to record votes for deal %s/%s: %w", d.Source, d.Id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit deals: %w", err)
+	}
+	return nil
+}
+
+// QueryDeals returns stored deals matching q, newest first, along with the
+// total number of matches ignoring Limit and Offset.
+func (udb *UserStoreDB) QueryDeals(q models.DealQuery) ([]models.Deal, int, error) {
+	var where []string
+	var args []interface{}
+	if q.Source != "" {
+		where = append(where, "source = ?")
+		args = append(args, q.Source)
+	}
+	if q.DealType != 0 {
+		where = append(where, "deal_type = ?")
+		args = append(args, q.DealType)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "COALESCE(posted_at, first_seen) >= ?")
+		args = append(args, dbTime(q.Since))
+	}
+	if q.Active {
+		where = append(where, "expiry = '' AND (expires_at IS NULL OR expires_at > ?)")
+		args = append(args, dbTime(time.Now()))
+	}
+	filter := ""
+	if len(where) > 0 {
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := udb.DB.QueryRow(`SELECT COUNT(*) FROM deals`+filter, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count deals: %w", err)
+	}
+
+	query := `SELECT ` + dealColumns + `, id FROM deals` + filter +
+		` ORDER BY COALESCE(posted_at, first_seen) DESC, id DESC`
+	if q.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, q.Limit, q.Offset)
+	} else if q.Offset > 0 {
+		query += ` LIMIT -1 OFFSET ?`
+		args = append(args, q.Offset)
+	}
+
+	rows, err := udb.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query deals: %w", err)
+	}
+	defer rows.Close()
+
+	deals := []models.Deal{}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		d, err := scanDeal(rows, &id)
+		if err != nil {
+			return nil, 0, err
+		}
+		deals = append(deals, *d)
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read deals: %w", err)
+	}
+	if err := udb.setVelocities(deals, ids, time.Now()); err != nil {
+		return nil, 0, err
+	}
+	return deals, total, nil
+}
+
+// GetDeal retrieves a single stored deal, or nil if it has never been seen.
+func (udb *UserStoreDB) GetDeal(source, dealID string) (*models.Deal, error) {
+	var id int64
+	d, err := scanDeal(udb.DB.QueryRow(
+		`SELECT `+dealColumns+`, id FROM deals WHERE source = ? AND deal_id = ?`, source, dealID,
+	), &id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	deals := []models.Deal{*d}
+	if err := udb.setVelocities(deals, []int64{id}, time.Now()); err != nil {
+		return nil, err
+	}
+	return &deals[0], nil
+}
+
+// GetVoteHistory returns a deal's vote snapshots, oldest first.
+func (udb *UserStoreDB) GetVoteHistory(source, dealID string) ([]models.VoteSnapshot, error) {
+	rows, err := udb.DB.Query(`
+		SELECT v.votes, v.recorded_at
+		FROM deal_votes v JOIN deals d ON d.id = v.deal_row
+		WHERE d.source = ? AND d.deal_id = ?
+		ORDER BY v.recorded_at, v.rowid`, source, dealID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vote history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.VoteSnapshot{}
+	for rows.Next() {
+		var s models.VoteSnapshot
+		if err := rows.Scan(&s.Votes, &s.RecordedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan vote snapshot: %w", err)
+		}
+		history = append(history, s)
+	}
+	return history, rows.Err()
+}
+
+// SetDealExpiry records that a stored deal has expired or gone out of stock,
+// as found by a check at the given time. An expiry already recorded is kept.
+// It reports whether the deal was marked.
+func (udb *UserStoreDB) SetDealExpiry(source, dealID, expiry string, at time.Time) (bool, error) {
+	res, err := udb.DB.Exec(`
+		UPDATE deals SET expiry = ?, expired_at = ?
+		WHERE source = ? AND deal_id = ? AND expiry = ''`,
+		expiry, dbTime(at), source, dealID)
+	if err != nil {
+		return false, fmt.Errorf("failed to set deal expiry: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ExpiryNoticePending returns the deals sent over Telegram at or after since
+// that no expiry notice has gone out for yet. Deals never checked come first,
+// then the longest since their last check, so repeated checks rotate through
+// them all. Expired deals are included, as their notice is still due.
+func (udb *UserStoreDB) ExpiryNoticePending(since time.Time) ([]models.Deal, error) {
+	rows, err := udb.DB.Query(`
+		SELECT `+qualifiedDealColumns("d")+` FROM deals d
+		JOIN (
+			SELECT source, deal_id, MIN(sent_at) AS sent_at FROM sent_deals
+			WHERE channel = 'telegram' AND sent_at >= ?
+			GROUP BY source, deal_id
+		) s USING (source, deal_id)
+		WHERE d.expiry_notified = 0
+		ORDER BY d.last_expiry_check, s.sent_at, d.id`, dbTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sent deals: %w", err)
+	}
+	defer rows.Close()
+
+	deals := []models.Deal{}
+	for rows.Next() {
+		d, err := scanDeal(rows)
+		if err != nil {
+			return nil, err
+		}
+		deals = append(deals, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read sent deals: %w", err)
+	}
+	return deals, nil
+}
+
+// MarkExpiryChecked records when a deal was last checked for expiry.
+func (udb *UserStoreDB) MarkExpiryChecked(source, dealID string, at time.Time) error {
+	_, err := udb.DB.Exec(`UPDATE deals SET last_expiry_check = ? WHERE source = ? AND deal_id = ?`,
+		dbTime(at), source, dealID)
+	if err != nil {
+		return fmt.Errorf("failed to record expiry check: %w", err)
+	}
+	return nil
+}
+
+// MarkExpiryNoticeSent records that the expiry notices of a deal went out,
+// so ExpiryNoticePending no longer returns it.
+func (udb *UserStoreDB) MarkExpiryNoticeSent(source, dealID string) error {
+	_, err := udb.DB.Exec(`UPDATE deals SET expiry_notified = 1 WHERE source = ? AND deal_id = ?`, source, dealID)
+	if err != nil {
+		return fmt.Errorf("failed to record expiry notice: %w", err)
+	}
+	return nil
+}
+
+// Most deals whose vote history is loaded in one query, well under SQLite's
+// limit on query parameters
+const velocityBatch = 500
+
+// setVelocities sets the vote velocity of deals, whose deals table row IDs
+// are ids, from their vote history.
+func (udb *UserStoreDB) setVelocities(deals []models.Deal, ids []int64, now time.Time) error {
+	for start := 0; start < len(deals); start += velocityBatch {
+		end := min(start+velocityBatch, len(deals))
+
+		args := make([]interface{}, 0, end-start)
+		for i := start; i < end; i++ {
+			// Deals without votes have no history
+			if deals[i].Upvotes != "" {
+				args = append(args, ids[i])
+			}
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		rows, err := udb.DB.Query(`
+			SELECT deal_row, votes, recorded_at FROM deal_votes
+			WHERE deal_row IN (?`+strings.Repeat(", ?", len(args)-1)+`)
+			ORDER BY deal_row, recorded_at, rowid`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query vote history: %w", err)
+		}
+		history := make(map[int64][]models.VoteSnapshot)
+		for rows.Next() {
+			var row int64
+			var s models.VoteSnapshot
+			if err := rows.Scan(&row, &s.Votes, &s.RecordedAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan vote snapshot: %w", err)
+			}
+			history[row] = append(history[row], s)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read vote history: %w", err)
+		}
+
+		for i := start; i < end; i++ {
+			d := &deals[i]
+			if h := history[ids[i]]; len(h) > 0 {
+				d.Velocity = models.VoteVelocity(h, d.Votes(), d.Age(now), now)
+			}
+		}
+	}
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanDeal reads a dealColumns row into a Deal struct. Any extra columns
+// selected after dealColumns are scanned into extra.
+func scanDeal(row rowScanner, extra ...interface{}) (*models.Deal, error) {
+	d := &models.Deal{}
+	var postedAt, expiresAt, expiredAt sql.NullTime
+	var price sql.NullFloat64
+	var tags string
+	dest := []interface{}{
+		&d.Source, &d.Id, &d.Title, &d.Description, &d.Url, &d.PostedOn, &postedAt, &d.Upvotes, &d.DealAge,
+		&d.Image, &d.PriceDrop, &d.DropPercent, &d.DealType, &d.FirstSeen, &d.LastSeen,
+		&price, &d.StoreDomain, &d.CouponCode, &d.Category, &tags, &expiresAt, &d.Poster, &d.Expiry, &expiredAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan deal: %w", err)
+	}
+	if postedAt.Valid {
+		d.PostedAt = postedAt.Time
+	}
+	if expiresAt.Valid {
+		d.ExpiresAt = expiresAt.Time
+	}
+	if expiredAt.Valid {
+		d.ExpiredAt = expiredAt.Time
+	}
+	if price.Valid {
+		d.Price = &price.Float64
+	}
+	if tags != "" {
+		if err := json.Unmarshal([]byte(tags), &d.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// dbTime normalises times so they compare correctly as SQLite text.
+func dbTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
+
+// nullTime stores a zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: dbTime(t), Valid: true}
+}
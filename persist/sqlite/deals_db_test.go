@@ -0,0 +1,374 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/persist/sqlite"
+	"go.uber.org/zap"
+)
+
+func newDealsDB(t *testing.T) *sqlite.SQLiteWrapper {
+	t.Helper()
+	db, err := sqlite.NewSQLiteWrapper(filepath.Join(t.TempDir(), "deals_test.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Test that re-scraping a deal keeps first_seen, moves last_seen and refreshes fields
+func TestUpsertDeals_FirstAndLastSeen(t *testing.T) {
+	db := newDealsDB(t)
+	first := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	deal := models.Deal{Id: "1", Source: "ozbargain", Title: "Cheap TV", Upvotes: "5", DealType: 1}
+	if err := db.UpsertDeals([]models.Deal{deal}, first); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+
+	deal.Upvotes = "30"
+	deal.DealType = 2
+	if err := db.UpsertDeals([]models.Deal{deal}, second); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+
+	got, err := db.GetDeal("ozbargain", "1")
+	if err != nil || got == nil {
+		t.Fatalf("GetDeal() = %v, %v", got, err)
+	}
+	if !got.FirstSeen.Equal(first) {
+		t.Errorf("FirstSeen = %v, want %v", got.FirstSeen, first)
+	}
+	if !got.LastSeen.Equal(second) {
+		t.Errorf("LastSeen = %v, want %v", got.LastSeen, second)
+	}
+	if got.Upvotes != "30" || got.DealType != 2 {
+		t.Errorf("deal not refreshed: upvotes %q, type %d", got.Upvotes, got.DealType)
+	}
+}
+
+// Test that deal page details are stored and kept when a later scrape lacks them
+func TestUpsertDeals_Details(t *testing.T) {
+	db := newDealsDB(t)
+	seen := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	price := 199.0
+	expires := time.Date(2026, 3, 31, 23, 59, 0, 0, time.UTC)
+
+	deal := models.Deal{
+		Id: "1", Source: "ozbargain", Title: "Cheap TV", Upvotes: "5", Image: "https://files.example.com/1.jpg",
+		Price: &price, StoreDomain: "jbhifi.com.au", CouponCode: "TV20", Category: "Electrical & Electronics",
+		Tags: []string{"oled", "lg"}, ExpiresAt: expires, Poster: "kramer",
+	}
+	if err := db.UpsertDeals([]models.Deal{deal}, seen); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+	listing := models.Deal{Id: "1", Source: "ozbargain", Title: "Cheap TV", Upvotes: "9"}
+	if err := db.UpsertDeals([]models.Deal{listing}, seen.Add(time.Minute)); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+
+	got, err := db.GetDeal("ozbargain", "1")
+	if err != nil || got == nil {
+		t.Fatalf("GetDeal() = %v, %v", got, err)
+	}
+	if got.Price == nil || *got.Price != price {
+		t.Errorf("Price = %v, want %v", got.Price, price)
+	}
+	if got.StoreDomain != "jbhifi.com.au" || got.CouponCode != "TV20" || got.Category != "Electrical & Electronics" ||
+		got.Poster != "kramer" || got.Image != deal.Image {
+		t.Errorf("details not kept: %+v", got)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "oled" || got.Tags[1] != "lg" {
+		t.Errorf("Tags = %v, want [oled lg]", got.Tags)
+	}
+	if !got.ExpiresAt.Equal(expires) {
+		t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, expires)
+	}
+	if got.Upvotes != "9" {
+		t.Errorf("Upvotes = %q, want the latest scrape's", got.Upvotes)
+	}
+
+	// Deals without details have no price rather than a free one
+	amz := models.Deal{Id: "a1", Source: "amazon", Title: "Headphones"}
+	if err := db.UpsertDeals([]models.Deal{amz}, seen); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+	if got, err := db.GetDeal("amazon", "a1"); err != nil || got.Price != nil || got.Tags != nil {
+		t.Errorf("GetDeal() = %+v, %v, want no details", got, err)
+	}
+}
+
+// Test that vote snapshots are only recorded when the vote count changes
+func TestUpsertDeals_VoteSnapshots(t *testing.T) {
+	db := newDealsDB(t)
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	for i, votes := range []string{"5", "5", "12", "12", "20"} {
+		deal := models.Deal{Id: "1", Source: "ozbargain", Title: "Cheap TV", Upvotes: votes}
+		if err := db.UpsertDeals([]models.Deal{deal}, start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("UpsertDeals() error = %v", err)
+		}
+	}
+	// Sources without voting get no snapshots
+	amz := models.Deal{Id: "a1", Source: "amazon", Title: "Headphones"}
+	if err := db.UpsertDeals([]models.Deal{amz}, start); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+
+	history, err := db.GetVoteHistory("ozbargain", "1")
+	if err != nil {
+		t.Fatalf("GetVoteHistory() error = %v", err)
+	}
+	want := []int{5, 12, 20}
+	if len(history) != len(want) {
+		t.Fatalf("expected %d snapshots, got %d: %+v", len(want), len(history), history)
+	}
+	for i, s := range history {
+		if s.Votes != want[i] {
+			t.Errorf("snapshot %d: votes = %d, want %d", i, s.Votes, want[i])
+		}
+	}
+
+	history, err = db.GetVoteHistory("amazon", "a1")
+	if err != nil {
+		t.Fatalf("GetVoteHistory() error = %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("expected no snapshots for amazon deal, got %+v", history)
+	}
+}
+
+// Test that stored deals come with their vote velocity
+func TestQueryDeals_Velocity(t *testing.T) {
+	db := newDealsDB(t)
+	now := time.Now()
+	posted := now.Add(-5 * time.Hour)
+
+	for _, snap := range []struct {
+		votes string
+		ago   time.Duration
+	}{{"10", 90 * time.Minute}, {"16", 30 * time.Minute}, {"22", 0}} {
+		deal := models.Deal{Id: "1", Source: "ozbargain", Title: "Cheap TV", Upvotes: snap.votes, PostedAt: posted}
+		if err := db.UpsertDeals([]models.Deal{deal}, now.Add(-snap.ago)); err != nil {
+			t.Fatalf("UpsertDeals() error = %v", err)
+		}
+	}
+	amz := models.Deal{Id: "a1", Source: "amazon", Title: "Headphones"}
+	if err := db.UpsertDeals([]models.Deal{amz}, now); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+
+	deals, _, err := db.QueryDeals(models.DealQuery{})
+	if err != nil {
+		t.Fatalf("QueryDeals() error = %v", err)
+	}
+	velocity := map[string]float64{}
+	for _, d := range deals {
+		velocity[d.Id] = d.Velocity
+	}
+	// 12 votes since the snapshot an hour ago
+	if velocity["1"] != 12 || velocity["a1"] != 0 {
+		t.Errorf("velocities = %v, want 12 for deal 1 and 0 for a1", velocity)
+	}
+
+	got, err := db.GetDeal("ozbargain", "1")
+	if err != nil || got.Velocity != 12 {
+		t.Errorf("GetDeal() velocity = %v, %v; want 12", got.Velocity, err)
+	}
+}
+
+// Test filtering, ordering and pagination of stored deals
+func TestQueryDeals(t *testing.T) {
+	db := newDealsDB(t)
+	now := time.Now().UTC()
+
+	deals := []models.Deal{
+		{Id: "1", Source: "ozbargain", Title: "Old", DealType: 1, PostedAt: now.Add(-48 * time.Hour)},
+		{Id: "2", Source: "ozbargain", Title: "Newer", DealType: 2, PostedAt: now.Add(-2 * time.Hour)},
+		{Id: "3", Source: "ozbargain", Title: "Newest", DealType: 1, PostedAt: now.Add(-1 * time.Hour)},
+		{Id: "a1", Source: "amazon", Title: "Amazon", DealType: 4},
+	}
+	if err := db.UpsertDeals(deals, now); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		query     models.DealQuery
+		wantIDs   []string
+		wantTotal int
+	}{
+		{"all, newest first", models.DealQuery{}, []string{"a1", "3", "2", "1"}, 4},
+		{"by source", models.DealQuery{Source: "ozbargain"}, []string{"3", "2", "1"}, 3},
+		{"by deal type", models.DealQuery{Source: "ozbargain", DealType: 1}, []string{"3", "1"}, 2},
+		{"since", models.DealQuery{Source: "ozbargain", Since: now.Add(-24 * time.Hour)}, []string{"3", "2"}, 2},
+		{"paginated", models.DealQuery{Source: "ozbargain", Limit: 1, Offset: 1}, []string{"2"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := db.QueryDeals(tt.query)
+			if err != nil {
+				t.Fatalf("QueryDeals() error = %v", err)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("got %d deals, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, d := range got {
+				if d.Id != tt.wantIDs[i] {
+					t.Errorf("deal %d: id = %s, want %s", i, d.Id, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+// Test that expiry is stored, kept once recorded and left out of active queries
+func TestDeals_Expiry(t *testing.T) {
+	db := newDealsDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	first := now.Add(-time.Hour)
+
+	deals := []models.Deal{
+		{Id: "1", Source: "ozbargain", Title: "Available", PostedAt: now.Add(-3 * time.Hour)},
+		{Id: "2", Source: "ozbargain", Title: "Tagged expired", PostedAt: now.Add(-2 * time.Hour), Expiry: models.DealExpired},
+		{Id: "3", Source: "ozbargain", Title: "Past its date", PostedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)},
+	}
+	if err := db.UpsertDeals(deals, first); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+	// A later scrape that no longer shows the tag keeps the recorded expiry
+	deals[1].Expiry = ""
+	if err := db.UpsertDeals(deals, now); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+	got, err := db.GetDeal("ozbargain", "2")
+	if err != nil || got == nil {
+		t.Fatalf("GetDeal() = %v, %v", got, err)
+	}
+	if got.Expiry != models.DealExpired || !got.ExpiredAt.Equal(first) {
+		t.Errorf("deal 2 expiry = %q at %v, want expired at %v", got.Expiry, got.ExpiredAt, first)
+	}
+
+	active, total, err := db.QueryDeals(models.DealQuery{Source: "ozbargain", Active: true})
+	if err != nil {
+		t.Fatalf("QueryDeals() error = %v", err)
+	}
+	if total != 1 || len(active) != 1 || active[0].Id != "1" {
+		t.Errorf("active deals = %+v (total %d), want deal 1", active, total)
+	}
+
+	if marked, err := db.SetDealExpiry("ozbargain", "1", models.DealOutOfStock, now); err != nil || !marked {
+		t.Fatalf("SetDealExpiry() = %t, %v, want marked", marked, err)
+	}
+	if marked, err := db.SetDealExpiry("ozbargain", "1", models.DealExpired, now.Add(time.Hour)); err != nil || marked {
+		t.Errorf("SetDealExpiry() on an expired deal = %t, %v, want unchanged", marked, err)
+	}
+	if got, _ := db.GetDeal("ozbargain", "1"); got == nil || got.Expiry != models.DealOutOfStock || !got.ExpiredAt.Equal(now) {
+		t.Errorf("deal 1 = %+v, want out of stock at %v", got, now)
+	}
+}
+
+// Test that deals sent over Telegram are pending an expiry notice until it is
+// sent, least recently checked first
+func TestExpiryNoticePending(t *testing.T) {
+	db := newDealsDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	deals := []models.Deal{
+		{Id: "1", Source: "ozbargain", Title: "Sent recently"},
+		{Id: "2", Source: "ozbargain", Title: "Sent earlier", Expiry: models.DealExpired},
+		{Id: "3", Source: "ozbargain", Title: "Sent long ago"},
+		{Id: "4", Source: "ozbargain", Title: "Emailed only"},
+		{Id: "5", Source: "ozbargain", Title: "Never sent"},
+	}
+	if err := db.UpsertDeals(deals, now); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+	sent := []struct {
+		chatID  int64
+		id      string
+		channel string
+		at      time.Time
+	}{
+		{1, "1", "telegram", now.Add(-time.Hour)},
+		{2, "1", "telegram", now.Add(-30 * time.Minute)},
+		{2, "1", "email", now.Add(-30 * time.Minute)},
+		{1, "2", "telegram", now.Add(-2 * time.Hour)},
+		{1, "3", "telegram", now.Add(-72 * time.Hour)},
+		{1, "4", "email", now.Add(-time.Hour)},
+	}
+	for _, s := range sent {
+		if err := db.MarkDealSent(s.chatID, "ozbargain", s.id, s.channel, s.at); err != nil {
+			t.Fatalf("MarkDealSent() error = %v", err)
+		}
+	}
+
+	pendingIDs := func() []string {
+		t.Helper()
+		pending, err := db.ExpiryNoticePending(now.Add(-24 * time.Hour))
+		if err != nil {
+			t.Fatalf("ExpiryNoticePending() error = %v", err)
+		}
+		var ids []string
+		for _, d := range pending {
+			ids = append(ids, d.Id)
+		}
+		return ids
+	}
+	if got := pendingIDs(); !slices.Equal(got, []string{"2", "1"}) {
+		t.Errorf("pending = %v, want [2 1], oldest sent first", got)
+	}
+
+	chats, err := db.SentChats("ozbargain", "1", "telegram")
+	if err != nil || !slices.Equal(chats, []int64{1, 2}) {
+		t.Errorf("SentChats() = %v, %v, want [1 2]", chats, err)
+	}
+
+	// Checked deals go behind the ones never checked
+	if err := db.MarkExpiryChecked("ozbargain", "2", now); err != nil {
+		t.Fatalf("MarkExpiryChecked() error = %v", err)
+	}
+	if got := pendingIDs(); !slices.Equal(got, []string{"1", "2"}) {
+		t.Errorf("pending after checking deal 2 = %v, want [1 2]", got)
+	}
+
+	if err := db.MarkExpiryNoticeSent("ozbargain", "2"); err != nil {
+		t.Fatalf("MarkExpiryNoticeSent() error = %v", err)
+	}
+	if got := pendingIDs(); !slices.Equal(got, []string{"1"}) {
+		t.Errorf("pending after notice = %v, want [1]", got)
+	}
+}
+
+// Test that stored deals survive reopening the database
+func TestDeals_SurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deals_test.db")
+	db, err := sqlite.NewSQLiteWrapper(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	if err := db.UpsertDeals([]models.Deal{{Id: "1", Source: "ozbargain", Title: "Cheap TV"}}, time.Now()); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+	db.Close()
+
+	db, err = sqlite.NewSQLiteWrapper(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	got, err := db.GetDeal("ozbargain", "1")
+	if err != nil || got == nil || got.Title != "Cheap TV" {
+		t.Errorf("GetDeal() after restart = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,251 @@
+package sqlite
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	persist_if "github.com/intothevoid/kramerbot/persist"
+)
+
+// Default and maximum page sizes for SearchDeals
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// External-content FTS5 index over deal titles and descriptions, kept in sync
+// with the deals table by triggers.
+var createDealSearchStmts = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS deals_fts USING fts5(
+		title, description,
+		content='deals', content_rowid='id',
+		tokenize='porter unicode61'
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS deals_fts_ai AFTER INSERT ON deals BEGIN
+		INSERT INTO deals_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS deals_fts_ad AFTER DELETE ON deals BEGIN
+		INSERT INTO deals_fts(deals_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS deals_fts_au AFTER UPDATE OF title, description ON deals BEGIN
+		INSERT INTO deals_fts(deals_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+		INSERT INTO deals_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+}
+
+// createDealSearchIndex sets up the FTS5 index. SQLite builds without FTS5
+// (go-sqlite3 needs the sqlite_fts5 build tag) fall back to LIKE matching.
+func (udb *UserStoreDB) createDealSearchIndex() error {
+	var exists int
+	if err := udb.DB.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'deals_fts'`,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check deals_fts table: %w", err)
+	}
+
+	tx, err := udb.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Will be ignored if tx.Commit() is called
+
+	for _, stmt := range createDealSearchStmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			if strings.Contains(err.Error(), "no such module: fts5") {
+				udb.Logger.Warn("SQLite built without FTS5, deal search falls back to LIKE matching")
+				return nil
+			}
+			return fmt.Errorf("failed to create deal search index: %w", err)
+		}
+	}
+
+	// Index deals stored before the search index existed
+	if exists == 0 {
+		if _, err := tx.Exec(`INSERT INTO deals_fts(deals_fts) VALUES ('rebuild')`); err != nil {
+			return fmt.Errorf("failed to build deal search index: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit deal search index: %w", err)
+	}
+	udb.dealFTS = true
+	return nil
+}
+
+// SearchDeals runs a full-text search over stored deals. Results are ranked by
+// relevance (BM25, titles weighted above descriptions), most recently stored first on ties.
+// The returned cursor fetches the next page and is empty on the last page.
+func (udb *UserStoreDB) SearchDeals(s models.DealSearch) ([]models.Deal, string, error) {
+	terms := searchTerms(s.Query)
+	if len(terms) == 0 {
+		return []models.Deal{}, "", nil
+	}
+
+	limit := s.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	var from, score string
+	var where []string
+	var args []interface{}
+	if udb.dealFTS {
+		from = `deals_fts JOIN deals d ON d.id = deals_fts.rowid`
+		score = `bm25(deals_fts, 10.0, 1.0)`
+		where = append(where, `deals_fts MATCH ?`)
+		args = append(args, ftsQuery(terms))
+	} else {
+		from = `deals d`
+		score = `0.0`
+		for _, term := range terms {
+			where = append(where, `(d.title LIKE ? ESCAPE '\' OR d.description LIKE ? ESCAPE '\')`)
+			pattern := "%" + likeEscaper.Replace(strings.TrimSuffix(term, "*")) + "%"
+			args = append(args, pattern, pattern)
+		}
+	}
+	if s.Source != "" {
+		where = append(where, `d.source = ?`)
+		args = append(args, s.Source)
+	}
+	if !s.From.IsZero() {
+		where = append(where, `COALESCE(d.posted_at, d.first_seen) >= ?`)
+		args = append(args, dbTime(s.From))
+	}
+	if !s.To.IsZero() {
+		where = append(where, `COALESCE(d.posted_at, d.first_seen) <= ?`)
+		args = append(args, dbTime(s.To))
+	}
+
+	query := `SELECT ` + qualifiedDealColumns("d") + `, d.id, ` + score + ` AS score
+		FROM ` + from + `
+		WHERE ` + strings.Join(where, " AND ")
+
+	// Keyset pagination on (score ASC, id DESC)
+	if s.Cursor != "" {
+		afterScore, afterID, err := decodeSearchCursor(s.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query = `SELECT * FROM (` + query + `) WHERE score > ? OR (score = ? AND id < ?)`
+		args = append(args, afterScore, afterScore, afterID)
+	}
+	query += ` ORDER BY score, id DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := udb.DB.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to search deals: %w", err)
+	}
+	defer rows.Close()
+
+	deals := []models.Deal{}
+	var ids []int64
+	var next string
+	var lastScore float64
+	var lastID int64
+	for rows.Next() {
+		var id int64
+		var rowScore float64
+		d, err := scanDeal(rows, &id, &rowScore)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(deals) == limit {
+			// One extra row tells us there is another page
+			next = encodeSearchCursor(lastScore, lastID)
+			break
+		}
+		deals = append(deals, *d)
+		ids = append(ids, id)
+		lastScore, lastID = rowScore, id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read search results: %w", err)
+	}
+	if err := udb.setVelocities(deals, ids, time.Now()); err != nil {
+		return nil, "", err
+	}
+	return deals, next, nil
+}
+
+// searchTerms splits a user query into words, dropping FTS5 syntax characters.
+// A trailing * on a word is kept and means prefix match.
+func searchTerms(q string) []string {
+	var terms []string
+	for _, word := range strings.Fields(q) {
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`"*^:(){}[]+`, r) {
+				return -1
+			}
+			return r
+		}, word)
+		if word == "" {
+			continue
+		}
+		if prefix {
+			word += "*"
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// ftsQuery quotes every term so user input can never be parsed as FTS5 operators.
+// All terms must match.
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		if strings.HasSuffix(term, "*") {
+			quoted[i] = `"` + strings.TrimSuffix(term, "*") + `"*`
+		} else {
+			quoted[i] = `"` + term + `"`
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Cursors are opaque to API clients: base64 of "<score>:<row id>".
+func encodeSearchCursor(score float64, id int64) string {
+	raw := strconv.FormatFloat(score, 'g', -1, 64) + ":" + strconv.FormatInt(id, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSearchCursor(cursor string) (float64, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, persist_if.ErrInvalidCursor
+	}
+	scoreStr, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, 0, persist_if.ErrInvalidCursor
+	}
+	score, err := strconv.ParseFloat(scoreStr, 64)
+	if err != nil {
+		return 0, 0, persist_if.ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, 0, persist_if.ErrInvalidCursor
+	}
+	return score, id, nil
+}
+
+// qualifiedDealColumns prefixes dealColumns with a table alias.
+func qualifiedDealColumns(alias string) string {
+	cols := strings.Split(dealColumns, ",")
+	for i, col := range cols {
+		cols[i] = alias + "." + strings.TrimSpace(col)
+	}
+	return strings.Join(cols, ", ")
+}
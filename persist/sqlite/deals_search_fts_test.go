@@ -0,0 +1,32 @@
+//go:build sqlite_fts5
+
+package sqlite_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+// Test BM25 ranking: title matches outrank description matches
+func TestSearchDeals_Ranking(t *testing.T) {
+	db := newDealsDB(t)
+	now := time.Now().UTC()
+
+	deals := []models.Deal{
+		{Id: "desc", Source: "ozbargain", Title: "Bundle offer", Description: "Includes a free kettle"},
+		{Id: "title", Source: "ozbargain", Title: "Breville Kettle $49"},
+	}
+	if err := db.UpsertDeals(deals, now); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+
+	got, _, err := db.SearchDeals(models.DealSearch{Query: "kettle"})
+	if err != nil {
+		t.Fatalf("SearchDeals() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Id != "title" {
+		t.Errorf("expected title match first, got %+v", got)
+	}
+}
@@ -0,0 +1,141 @@
+package sqlite_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/persist"
+	"github.com/intothevoid/kramerbot/persist/sqlite"
+)
+
+// seedSearchDeals stores a small mixed history for the search tests.
+func seedSearchDeals(t *testing.T, db *sqlite.SQLiteWrapper, now time.Time) {
+	t.Helper()
+	deals := []models.Deal{
+		{Id: "1", Source: "ozbargain", Title: "Sony 65\" OLED TV $1999", PostedAt: now.Add(-40 * 24 * time.Hour)},
+		{Id: "2", Source: "ozbargain", Title: "LG OLED TV 55\" $1299", PostedAt: now.Add(-10 * 24 * time.Hour)},
+		{Id: "3", Source: "ozbargain", Title: "Nintendo Switch OLED $399", PostedAt: now.Add(-2 * 24 * time.Hour)},
+		{Id: "4", Source: "ozbargain", Title: "Dyson V15 Vacuum", Description: "Works great with any tv stand", PostedAt: now.Add(-1 * time.Hour)},
+		{Id: "a1", Source: "amazon", Title: "Samsung OLED TV - down 20.00% to $1599", PostedAt: now.Add(-3 * 24 * time.Hour)},
+	}
+	if err := db.UpsertDeals(deals, now); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+}
+
+func searchIDs(deals []models.Deal) map[string]bool {
+	ids := make(map[string]bool)
+	for _, d := range deals {
+		ids[d.Id] = true
+	}
+	return ids
+}
+
+// Test term matching and the source/date filters
+func TestSearchDeals_Filters(t *testing.T) {
+	db := newDealsDB(t)
+	now := time.Now().UTC()
+	seedSearchDeals(t, db, now)
+
+	tests := []struct {
+		name    string
+		search  models.DealSearch
+		wantIDs []string
+	}{
+		{"single term", models.DealSearch{Query: "oled"}, []string{"1", "2", "3", "a1"}},
+		{"all terms must match", models.DealSearch{Query: "oled tv"}, []string{"1", "2", "a1"}},
+		{"description match", models.DealSearch{Query: "stand"}, []string{"4"}},
+		{"source filter", models.DealSearch{Query: "oled tv", Source: "amazon"}, []string{"a1"}},
+		{"date range", models.DealSearch{Query: "oled", From: now.Add(-15 * 24 * time.Hour), To: now.Add(-5 * 24 * time.Hour)}, []string{"2"}},
+		{"prefix", models.DealSearch{Query: "nint*"}, []string{"3"}},
+		{"syntax characters ignored", models.DealSearch{Query: `"switch" (`}, []string{"3"}},
+		{"no match", models.DealSearch{Query: "playstation"}, nil},
+		{"empty query", models.DealSearch{Query: "  "}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, next, err := db.SearchDeals(tt.search)
+			if err != nil {
+				t.Fatalf("SearchDeals() error = %v", err)
+			}
+			if next != "" {
+				t.Errorf("expected no next cursor, got %q", next)
+			}
+			ids := searchIDs(got)
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("got %d deals %v, want %v", len(ids), ids, tt.wantIDs)
+			}
+			for _, id := range tt.wantIDs {
+				if !ids[id] {
+					t.Errorf("missing deal %s in %v", id, ids)
+				}
+			}
+		})
+	}
+}
+
+// Test that following cursors visits every match exactly once
+func TestSearchDeals_CursorPagination(t *testing.T) {
+	db := newDealsDB(t)
+	seedSearchDeals(t, db, time.Now().UTC())
+
+	seen := make(map[string]int)
+	search := models.DealSearch{Query: "oled", Limit: 1}
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("pagination did not terminate")
+		}
+		deals, next, err := db.SearchDeals(search)
+		if err != nil {
+			t.Fatalf("SearchDeals() error = %v", err)
+		}
+		for _, d := range deals {
+			seen[d.Id]++
+		}
+		if next == "" {
+			break
+		}
+		search.Cursor = next
+	}
+
+	if len(seen) != 4 {
+		t.Errorf("expected 4 distinct deals, got %v", seen)
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("deal %s returned %d times", id, n)
+		}
+	}
+}
+
+// Test that a malformed cursor is reported as persist.ErrInvalidCursor
+func TestSearchDeals_InvalidCursor(t *testing.T) {
+	db := newDealsDB(t)
+
+	_, _, err := db.SearchDeals(models.DealSearch{Query: "tv", Cursor: "not-a-cursor"})
+	if !errors.Is(err, persist.ErrInvalidCursor) {
+		t.Errorf("SearchDeals() error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+// Test that renamed deals are re-indexed
+func TestSearchDeals_FollowsUpdates(t *testing.T) {
+	db := newDealsDB(t)
+	now := time.Now().UTC()
+
+	if err := db.UpsertDeals([]models.Deal{{Id: "1", Source: "ozbargain", Title: "Mystery Deal"}}, now); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+	if err := db.UpsertDeals([]models.Deal{{Id: "1", Source: "ozbargain", Title: "Kindle Paperwhite"}}, now); err != nil {
+		t.Fatalf("UpsertDeals() error = %v", err)
+	}
+
+	if got, _, _ := db.SearchDeals(models.DealSearch{Query: "mystery"}); len(got) != 0 {
+		t.Errorf("old title still matches: %+v", got)
+	}
+	if got, _, _ := db.SearchDeals(models.DealSearch{Query: "kindle"}); len(got) != 1 {
+		t.Errorf("new title does not match: %+v", got)
+	}
+}
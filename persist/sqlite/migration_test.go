@@ -22,12 +22,10 @@ func TestMigrateUserStoreFromJsonToDatabase(t *testing.T) {
 		ChatID:    123456789,
 		Username:  "testuser",
 		Keywords:  []string{"test"},
-		OzbSent:   []string{},
 		OzbGood:   true,
 		OzbSuper:  true,
 		AmzDaily:  true,
 		AmzWeekly: true,
-		AmzSent:   []string{},
 	}
 
 	testStore := models.UserStore{
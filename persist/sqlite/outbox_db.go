@@ -0,0 +1,175 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+// createOutboxTableSQL queues Telegram messages until they are sent. Sent
+// messages are deleted; ones that keep failing are kept with status 'dead'.
+const createOutboxTableSQL = `
+CREATE TABLE IF NOT EXISTS outbox (
+	id               INTEGER PRIMARY KEY AUTOINCREMENT,
+	chat_id          INTEGER NOT NULL,
+	text             TEXT NOT NULL,
+	parse_mode       TEXT NOT NULL DEFAULT '',
+	buttons          TEXT NOT NULL DEFAULT '',
+	batch            TEXT NOT NULL DEFAULT '',
+	status           TEXT NOT NULL DEFAULT 'pending',
+	attempts         INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at  DATETIME NOT NULL,
+	last_error       TEXT NOT NULL DEFAULT '',
+	created_at       DATETIME NOT NULL
+)`
+
+var outboxMigrateStmts = []string{
+	`ALTER TABLE outbox ADD COLUMN buttons TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE outbox ADD COLUMN batch TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX IF NOT EXISTS idx_outbox_status_chat ON outbox(status, chat_id, id)`,
+}
+
+const outboxColumns = `id, chat_id, text, parse_mode, buttons, batch, status, attempts, next_attempt_at, last_error, created_at`
+
+// CreateOutboxTable creates the outbox table.
+func (udb *UserStoreDB) CreateOutboxTable() error {
+	if _, err := udb.DB.Exec(createOutboxTableSQL); err != nil {
+		return fmt.Errorf("failed to create outbox table: %w", err)
+	}
+	for _, stmt := range outboxMigrateStmts {
+		udb.DB.Exec(stmt) //nolint:errcheck
+	}
+	return nil
+}
+
+// EnqueueOutbox adds a message to the outbox, ready to send immediately.
+func (udb *UserStoreDB) EnqueueOutbox(m *models.OutboxMessage) error {
+	m.Status = models.OutboxPending
+	m.CreatedAt = dbTime(time.Now())
+	m.NextAttemptAt = m.CreatedAt
+	var buttons []byte
+	if len(m.Buttons) > 0 {
+		var err error
+		if buttons, err = json.Marshal(m.Buttons); err != nil {
+			return fmt.Errorf("failed to marshal buttons: %w", err)
+		}
+	}
+	res, err := udb.DB.Exec(`
+		INSERT INTO outbox (chat_id, text, parse_mode, buttons, batch, status, attempts, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?)`,
+		m.ChatID, m.Text, m.ParseMode, string(buttons), m.Batch, m.Status, m.NextAttemptAt, m.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue outbox message: %w", err)
+	}
+	m.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// DueOutbox returns the oldest pending message of each chat whose next attempt
+// is due, oldest first. A chat whose head message is waiting to be retried
+// returns nothing, so messages to one chat are sent in order.
+func (udb *UserStoreDB) DueOutbox(now time.Time, limit int) ([]models.OutboxMessage, error) {
+	rows, err := udb.DB.Query(`
+		SELECT `+outboxColumns+` FROM outbox
+		WHERE id IN (SELECT MIN(id) FROM outbox WHERE status = 'pending' GROUP BY chat_id)
+		  AND next_attempt_at <= ?
+		ORDER BY id LIMIT ?`, dbTime(now), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+	return scanOutboxRows(rows)
+}
+
+// DeleteOutbox removes a sent message.
+func (udb *UserStoreDB) DeleteOutbox(id int64) error {
+	if _, err := udb.DB.Exec(`DELETE FROM outbox WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete outbox message: %w", err)
+	}
+	return nil
+}
+
+// RescheduleOutbox records a failed attempt and sets when to try again.
+func (udb *UserStoreDB) RescheduleOutbox(id int64, attempts int, next time.Time, lastErr string) error {
+	_, err := udb.DB.Exec(`
+		UPDATE outbox SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?`,
+		attempts, dbTime(next), lastErr, id)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule outbox message: %w", err)
+	}
+	return nil
+}
+
+// DeadLetterOutbox gives up on a message, keeping it for inspection.
+func (udb *UserStoreDB) DeadLetterOutbox(id int64, attempts int, lastErr string) error {
+	_, err := udb.DB.Exec(`
+		UPDATE outbox SET status = 'dead', attempts = ?, last_error = ? WHERE id = ?`,
+		attempts, lastErr, id)
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter outbox message: %w", err)
+	}
+	return nil
+}
+
+// ListDeadOutbox returns the most recently dead-lettered messages, newest first.
+func (udb *UserStoreDB) ListDeadOutbox(limit int) ([]models.OutboxMessage, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := udb.DB.Query(`
+		SELECT `+outboxColumns+` FROM outbox WHERE status = 'dead' ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead outbox messages: %w", err)
+	}
+	defer rows.Close()
+	return scanOutboxRows(rows)
+}
+
+// OutboxStats counts pending and dead messages.
+func (udb *UserStoreDB) OutboxStats() (models.OutboxStats, error) {
+	var s models.OutboxStats
+	err := udb.DB.QueryRow(`
+		SELECT
+			COALESCE(SUM(status = 'pending'), 0),
+			COALESCE(SUM(status = 'pending' AND next_attempt_at <= ?), 0),
+			COALESCE(SUM(status = 'dead'), 0)
+		FROM outbox`, dbTime(time.Now())).Scan(&s.Pending, &s.Due, &s.Dead)
+	if err != nil {
+		return s, fmt.Errorf("failed to count outbox messages: %w", err)
+	}
+
+	var oldest time.Time
+	err = udb.DB.QueryRow(`SELECT created_at FROM outbox WHERE status = 'pending' ORDER BY id LIMIT 1`).Scan(&oldest)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return s, fmt.Errorf("failed to query oldest outbox message: %w", err)
+	default:
+		s.Oldest = &oldest
+	}
+	return s, nil
+}
+
+func scanOutboxRows(rows *sql.Rows) ([]models.OutboxMessage, error) {
+	msgs := []models.OutboxMessage{}
+	for rows.Next() {
+		var m models.OutboxMessage
+		var buttons string
+		if err := rows.Scan(&m.ID, &m.ChatID, &m.Text, &m.ParseMode, &buttons, &m.Batch, &m.Status, &m.Attempts,
+			&m.NextAttemptAt, &m.LastError, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		if buttons != "" {
+			if err := json.Unmarshal([]byte(buttons), &m.Buttons); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal buttons: %w", err)
+			}
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
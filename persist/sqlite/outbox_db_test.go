@@ -0,0 +1,92 @@
+package sqlite_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+func TestOutbox_DueOneHeadPerChat(t *testing.T) {
+	db := newDealsDB(t)
+	var ids []int64
+	for _, chatID := range []int64{1, 1, 2} {
+		m := &models.OutboxMessage{ChatID: chatID, Text: "hi"}
+		if err := db.EnqueueOutbox(m); err != nil {
+			t.Fatalf("EnqueueOutbox() error = %v", err)
+		}
+		ids = append(ids, m.ID)
+	}
+
+	due, err := db.DueOutbox(time.Now(), 10)
+	if err != nil || len(due) != 2 || due[0].ID != ids[0] || due[1].ID != ids[2] {
+		t.Fatalf("DueOutbox() = %+v, %v; want the first message of each chat", due, err)
+	}
+
+	// A head waiting for a retry holds back the rest of its chat
+	if err := db.RescheduleOutbox(ids[0], 1, time.Now().Add(time.Hour), "timeout"); err != nil {
+		t.Fatal(err)
+	}
+	due, _ = db.DueOutbox(time.Now(), 10)
+	if len(due) != 1 || due[0].ChatID != 2 {
+		t.Errorf("DueOutbox() after reschedule = %+v, want only chat 2", due)
+	}
+
+	if err := db.DeleteOutbox(ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeadLetterOutbox(ids[0], 2, "Forbidden: bot was blocked by the user"); err != nil {
+		t.Fatal(err)
+	}
+	due, _ = db.DueOutbox(time.Now(), 10)
+	if len(due) != 1 || due[0].ID != ids[1] {
+		t.Errorf("DueOutbox() after dead-letter = %+v, want the second chat 1 message", due)
+	}
+
+	stats, err := db.OutboxStats()
+	if err != nil {
+		t.Fatalf("OutboxStats() error = %v", err)
+	}
+	if stats.Pending != 1 || stats.Due != 1 || stats.Dead != 1 || stats.Oldest == nil {
+		t.Errorf("OutboxStats() = %+v", stats)
+	}
+
+	dead, err := db.ListDeadOutbox(10)
+	if err != nil || len(dead) != 1 || dead[0].Attempts != 2 || dead[0].Status != models.OutboxDead {
+		t.Errorf("ListDeadOutbox() = %+v, %v", dead, err)
+	}
+}
+
+func TestOutbox_Buttons(t *testing.T) {
+	db := newDealsDB(t)
+	buttons := [][]models.InlineButton{{{Text: "Save", Data: "save:ozbargain:1"}}, {{Text: "Mute", Data: "mute:ab"}}}
+	for _, m := range []*models.OutboxMessage{{ChatID: 1, Text: "deal", Buttons: buttons}, {ChatID: 2, Text: "plain"}} {
+		if err := db.EnqueueOutbox(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	due, err := db.DueOutbox(time.Now(), 10)
+	if err != nil || len(due) != 2 {
+		t.Fatalf("DueOutbox() = %+v, %v", due, err)
+	}
+	if got := due[0].Buttons; len(got) != 2 || got[0][0] != buttons[0][0] || got[1][0] != buttons[1][0] {
+		t.Errorf("buttons = %+v, want %+v", got, buttons)
+	}
+	if due[1].Buttons != nil {
+		t.Errorf("plain message buttons = %+v, want none", due[1].Buttons)
+	}
+}
+
+func TestOutbox_Batch(t *testing.T) {
+	db := newDealsDB(t)
+	for _, m := range []*models.OutboxMessage{{ChatID: 1, Text: "news", Batch: "b1"}, {ChatID: 2, Text: "deal"}} {
+		if err := db.EnqueueOutbox(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	due, err := db.DueOutbox(time.Now(), 10)
+	if err != nil || len(due) != 2 || due[0].Batch != "b1" || due[1].Batch != "" {
+		t.Errorf("DueOutbox() = %+v, %v; want the first message in batch b1", due, err)
+	}
+}
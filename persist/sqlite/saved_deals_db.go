@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+// createSavedDealsTableSQL holds the deals users saved from a notification.
+const createSavedDealsTableSQL = `
+CREATE TABLE IF NOT EXISTS saved_deals (
+	chat_id   INTEGER NOT NULL,
+	source    TEXT NOT NULL,
+	deal_id   TEXT NOT NULL,
+	saved_at  DATETIME NOT NULL,
+	PRIMARY KEY (chat_id, source, deal_id)
+)`
+
+// CreateSavedDealsTable creates the saved_deals table.
+func (udb *UserStoreDB) CreateSavedDealsTable() error {
+	if _, err := udb.DB.Exec(createSavedDealsTableSQL); err != nil {
+		return fmt.Errorf("failed to create saved_deals table: %w", err)
+	}
+	return nil
+}
+
+// SaveDeal adds a deal to a chat's saved deals, reporting false if it was
+// already saved.
+func (udb *UserStoreDB) SaveDeal(chatID int64, source, dealID string) (bool, error) {
+	res, err := udb.DB.Exec(`
+		INSERT OR IGNORE INTO saved_deals (chat_id, source, deal_id, saved_at) VALUES (?, ?, ?, ?)`,
+		chatID, source, dealID, dbTime(time.Now()))
+	if err != nil {
+		return false, fmt.Errorf("failed to save deal: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// SavedDeals returns a chat's saved deals, most recently saved first. Saved
+// deals missing from the deal history are left out.
+func (udb *UserStoreDB) SavedDeals(chatID int64, limit int) ([]models.Deal, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := udb.DB.Query(`
+		SELECT `+dealColumns+` FROM saved_deals s
+		JOIN deals USING (source, deal_id)
+		WHERE s.chat_id = ?
+		ORDER BY s.saved_at DESC, s.rowid DESC LIMIT ?`, chatID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved deals: %w", err)
+	}
+	defer rows.Close()
+
+	deals := []models.Deal{}
+	for rows.Next() {
+		d, err := scanDeal(rows)
+		if err != nil {
+			return nil, err
+		}
+		deals = append(deals, *d)
+	}
+	return deals, rows.Err()
+}
@@ -0,0 +1,48 @@
+package sqlite_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+func TestSavedDeals(t *testing.T) {
+	db := newDealsDB(t)
+	deals := []models.Deal{
+		{Source: "ozbargain", Id: "1", Title: "First"},
+		{Source: "ozbargain", Id: "2", Title: "Second"},
+	}
+	if err := db.UpsertDeals(deals, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		chatID    int64
+		dealID    string
+		wantSaved bool
+	}{
+		{1, "1", true},
+		{1, "2", true},
+		{1, "1", false}, // already saved
+		{1, "gone", true},
+		{2, "1", true},
+	} {
+		saved, err := db.SaveDeal(tt.chatID, "ozbargain", tt.dealID)
+		if err != nil || saved != tt.wantSaved {
+			t.Errorf("SaveDeal(%d, %s) = %v, %v, want %v", tt.chatID, tt.dealID, saved, err, tt.wantSaved)
+		}
+	}
+
+	got, err := db.SavedDeals(1, 10)
+	if err != nil {
+		t.Fatalf("SavedDeals() error = %v", err)
+	}
+	// Newest first; the deal missing from the history is left out
+	if len(got) != 2 || got[0].Id != "2" || got[1].Id != "1" || got[1].Title != "First" {
+		t.Errorf("SavedDeals(1) = %+v", got)
+	}
+	if got, _ := db.SavedDeals(3, 10); len(got) != 0 {
+		t.Errorf("SavedDeals(3) = %+v, want none", got)
+	}
+}
@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Deal sources as recorded in the old per-user sent lists.
+const (
+	legacyOzbSource = "ozbargain"
+	legacyAmzSource = "amazon"
+)
+
+// createSentDealsTableSQL records every deal sent to a chat, one row per
+// delivery channel. The primary key doubles as the index for sent lookups.
+const createSentDealsTableSQL = `
+CREATE TABLE IF NOT EXISTS sent_deals (
+	chat_id  INTEGER NOT NULL,
+	source   TEXT NOT NULL,
+	deal_id  TEXT NOT NULL,
+	channel  TEXT NOT NULL,
+	sent_at  DATETIME NOT NULL,
+	PRIMARY KEY (chat_id, source, deal_id, channel)
+)`
+
+var sentDealsMigrateStmts = []string{
+	`CREATE INDEX IF NOT EXISTS idx_sent_deals_sent_at ON sent_deals(sent_at)`,
+}
+
+// CreateSentDealsTable creates the sent_deals table and moves any sent lists
+// still stored on users rows into it. It must run after CreateTable.
+func (udb *UserStoreDB) CreateSentDealsTable() error {
+	if _, err := udb.DB.Exec(createSentDealsTableSQL); err != nil {
+		return fmt.Errorf("failed to create sent_deals table: %w", err)
+	}
+	for _, stmt := range sentDealsMigrateStmts {
+		udb.DB.Exec(stmt) //nolint:errcheck
+	}
+	return udb.migrateSentLists()
+}
+
+// migrateSentLists copies the ozb_sent and amz_sent JSON lists of the users
+// table into sent_deals, then drops those columns. Migrated deals are recorded
+// as sent over Telegram now, so they age out after one retention period.
+func (udb *UserStoreDB) migrateSentLists() error {
+	var n int
+	err := udb.DB.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('users') WHERE name = 'ozb_sent'`).Scan(&n)
+	if err != nil {
+		return fmt.Errorf("failed to inspect users table: %w", err)
+	}
+	if n == 0 {
+		return nil
+	}
+
+	tx, err := udb.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Will be ignored if tx.Commit() is called
+
+	rows, err := tx.Query(`SELECT chat_id, ozb_sent, amz_sent FROM users`)
+	if err != nil {
+		return fmt.Errorf("failed to read sent lists: %w", err)
+	}
+	type sentRow struct {
+		source, dealID string
+	}
+	pending := make(map[int64][]sentRow)
+	for rows.Next() {
+		var chatID int64
+		var ozbSent, amzSent []byte
+		if err := rows.Scan(&chatID, &ozbSent, &amzSent); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan sent lists: %w", err)
+		}
+		var ozb, amz []string
+		if len(ozbSent) > 0 {
+			json.Unmarshal(ozbSent, &ozb) //nolint:errcheck // unreadable lists are dropped
+		}
+		if len(amzSent) > 0 {
+			json.Unmarshal(amzSent, &amz) //nolint:errcheck
+		}
+		for _, key := range ozb {
+			// Sources without their own list were stored as "source:id"
+			if source, id, ok := strings.Cut(key, ":"); ok {
+				pending[chatID] = append(pending[chatID], sentRow{source, id})
+			} else {
+				pending[chatID] = append(pending[chatID], sentRow{legacyOzbSource, key})
+			}
+		}
+		for _, id := range amz {
+			pending[chatID] = append(pending[chatID], sentRow{legacyAmzSource, id})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read sent lists: %w", err)
+	}
+
+	now := dbTime(time.Now())
+	migrated := 0
+	for chatID, sent := range pending {
+		for _, s := range sent {
+			if _, err := tx.Exec(`
+				INSERT OR IGNORE INTO sent_deals (chat_id, source, deal_id, channel, sent_at)
+				VALUES (?, ?, ?, 'telegram', ?)`, chatID, s.source, s.dealID, now); err != nil {
+				return fmt.Errorf("failed to migrate sent deal: %w", err)
+			}
+			migrated++
+		}
+	}
+	for _, col := range []string{"ozb_sent", "amz_sent"} {
+		if _, err := tx.Exec(`ALTER TABLE users DROP COLUMN ` + col); err != nil {
+			return fmt.Errorf("failed to drop users.%s: %w", col, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	udb.Logger.Info("Moved sent deal lists to sent_deals", zap.Int("deals", migrated))
+	return nil
+}
+
+// DealSent reports whether a deal was sent to a chat over any channel.
+func (udb *UserStoreDB) DealSent(chatID int64, source, dealID string) (bool, error) {
+	var n int
+	err := udb.DB.QueryRow(`
+		SELECT COUNT(*) FROM sent_deals WHERE chat_id = ? AND source = ? AND deal_id = ?`,
+		chatID, source, dealID).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to query sent deals: %w", err)
+	}
+	return n > 0, nil
+}
+
+// MarkDealSent records that a deal was sent to a chat over a channel.
+func (udb *UserStoreDB) MarkDealSent(chatID int64, source, dealID, channel string, sentAt time.Time) error {
+	_, err := udb.DB.Exec(`
+		INSERT INTO sent_deals (chat_id, source, deal_id, channel, sent_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (chat_id, source, deal_id, channel) DO UPDATE SET sent_at = excluded.sent_at`,
+		chatID, source, dealID, channel, dbTime(sentAt))
+	if err != nil {
+		return fmt.Errorf("failed to record sent deal: %w", err)
+	}
+	return nil
+}
+
+// SentChats returns the chats a deal was sent to over a channel.
+func (udb *UserStoreDB) SentChats(source, dealID, channel string) ([]int64, error) {
+	rows, err := udb.DB.Query(`
+		SELECT chat_id FROM sent_deals WHERE source = ? AND deal_id = ? AND channel = ?
+		ORDER BY chat_id`, source, dealID, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sent deals: %w", err)
+	}
+	defer rows.Close()
+
+	var chats []int64
+	for rows.Next() {
+		var chatID int64
+		if err := rows.Scan(&chatID); err != nil {
+			return nil, fmt.Errorf("failed to scan sent deal: %w", err)
+		}
+		chats = append(chats, chatID)
+	}
+	return chats, rows.Err()
+}
+
+// PruneSentDeals deletes sent records older than before and returns how many
+// were removed.
+func (udb *UserStoreDB) PruneSentDeals(before time.Time) (int64, error) {
+	res, err := udb.DB.Exec(`DELETE FROM sent_deals WHERE sent_at < ?`, dbTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune sent deals: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,95 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/persist/sqlite"
+	"go.uber.org/zap"
+)
+
+func TestSentDeals_MarkAndPrune(t *testing.T) {
+	db := newDealsDB(t)
+	now := time.Now()
+	if err := db.MarkDealSent(1, "ozbargain", "100", "telegram", now.Add(-40*24*time.Hour)); err != nil {
+		t.Fatalf("MarkDealSent() error = %v", err)
+	}
+	if err := db.MarkDealSent(1, "ozbargain", "200", "telegram", now); err != nil {
+		t.Fatal(err)
+	}
+	// A second channel for the same deal is recorded separately
+	if err := db.MarkDealSent(1, "ozbargain", "200", "email", now); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		chatID         int64
+		source, dealID string
+		want           bool
+	}{
+		{1, "ozbargain", "100", true},
+		{1, "ozbargain", "200", true},
+		{1, "amazon", "200", false},
+		{2, "ozbargain", "200", false},
+	} {
+		if got, err := db.DealSent(tt.chatID, tt.source, tt.dealID); got != tt.want || err != nil {
+			t.Errorf("DealSent(%d, %s, %s) = %v, %v; want %v", tt.chatID, tt.source, tt.dealID, got, err, tt.want)
+		}
+	}
+
+	n, err := db.PruneSentDeals(now.Add(-30 * 24 * time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("PruneSentDeals() = %d, %v; want 1", n, err)
+	}
+	if sent, _ := db.DealSent(1, "ozbargain", "100"); sent {
+		t.Error("expired deal still recorded as sent")
+	}
+	if sent, _ := db.DealSent(1, "ozbargain", "200"); !sent {
+		t.Error("recent deal pruned")
+	}
+}
+
+// Sent lists stored as JSON on the users table are moved to sent_deals
+func TestCreateSentDealsTable_MigratesSentLists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sqlite.CreateDatabaseConnection(path, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE users (chat_id INTEGER PRIMARY KEY, username TEXT, ozb_good INTEGER, ozb_super INTEGER,
+			keywords BLOB, ozb_sent BLOB, amz_daily INTEGER, amz_weekly INTEGER, amz_sent BLOB)`,
+		`INSERT INTO users VALUES (1, 'kramer', 1, 0, '["ssd"]', '["100","ebay:7"]', 0, 1, '["B0"]')`,
+		`INSERT INTO users VALUES (2, 'newman', 0, 0, '[]', NULL, 0, 0, NULL)`,
+	} {
+		if _, err := legacy.DB.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy.Close()
+
+	db, err := sqlite.NewSQLiteWrapper(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewSQLiteWrapper() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, tt := range []struct{ source, dealID string }{
+		{"ozbargain", "100"}, {"ebay", "7"}, {"amazon", "B0"},
+	} {
+		if sent, err := db.DealSent(1, tt.source, tt.dealID); !sent || err != nil {
+			t.Errorf("DealSent(1, %s, %s) = %v, %v; want migrated", tt.source, tt.dealID, sent, err)
+		}
+	}
+
+	var cols int
+	db.DB.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('users') WHERE name IN ('ozb_sent', 'amz_sent')`).Scan(&cols)
+	if cols != 0 {
+		t.Errorf("users still has %d sent list columns", cols)
+	}
+	user, err := db.GetUser(1)
+	if err != nil || user.Username != "kramer" || !user.AmzWeekly || len(user.Keywords) != 1 {
+		t.Errorf("GetUser() after migration = %+v, %v", user, err)
+	}
+}
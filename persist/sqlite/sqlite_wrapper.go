@@ -21,6 +21,21 @@ type SQLiteWrapper struct {
 // Ensure SQLiteWrapper implements DatabaseIF at compile time.
 var _ persist_if.DatabaseIF = (*SQLiteWrapper)(nil)
 
+// Ensure SQLiteWrapper implements DealDBIF at compile time.
+var _ persist_if.DealDBIF = (*SQLiteWrapper)(nil)
+
+// Ensure SQLiteWrapper implements WebhookDBIF at compile time.
+var _ persist_if.WebhookDBIF = (*SQLiteWrapper)(nil)
+
+// Ensure SQLiteWrapper implements OutboxDBIF at compile time.
+var _ persist_if.OutboxDBIF = (*SQLiteWrapper)(nil)
+
+// Ensure SQLiteWrapper implements SentDealDBIF at compile time.
+var _ persist_if.SentDealDBIF = (*SQLiteWrapper)(nil)
+
+// Ensure SQLiteWrapper implements SavedDealDBIF at compile time.
+var _ persist_if.SavedDealDBIF = (*SQLiteWrapper)(nil)
+
 // NewSQLiteWrapper creates a new SQLiteWrapper, initializes the database, and creates the table if needed.
 func NewSQLiteWrapper(dbPath string, logger *zap.Logger) (*SQLiteWrapper, error) {
 	// Default path if not provided
@@ -59,11 +74,36 @@ func NewSQLiteWrapper(dbPath string, logger *zap.Logger) (*SQLiteWrapper, error)
 		db.Close()
 		return nil, fmt.Errorf("failed to create table in database '%s': %w", dbPath, err)
 	}
+	if err := db.CreateSentDealsTable(); err != nil {
+		logger.Error("Failed to create sent_deals table", zap.String("path", dbPath), zap.Error(err))
+		db.Close()
+		return nil, fmt.Errorf("failed to create sent_deals table in database '%s': %w", dbPath, err)
+	}
 	if err := db.CreateWebUsersTable(); err != nil {
 		logger.Error("Failed to create web_users table", zap.String("path", dbPath), zap.Error(err))
 		db.Close()
 		return nil, fmt.Errorf("failed to create web_users table in database '%s': %w", dbPath, err)
 	}
+	if err := db.CreateWebhooksTables(); err != nil {
+		logger.Error("Failed to create webhooks tables", zap.String("path", dbPath), zap.Error(err))
+		db.Close()
+		return nil, fmt.Errorf("failed to create webhooks tables in database '%s': %w", dbPath, err)
+	}
+	if err := db.CreateOutboxTable(); err != nil {
+		logger.Error("Failed to create outbox table", zap.String("path", dbPath), zap.Error(err))
+		db.Close()
+		return nil, fmt.Errorf("failed to create outbox table in database '%s': %w", dbPath, err)
+	}
+	if err := db.CreateDealsTables(); err != nil {
+		logger.Error("Failed to create deals tables", zap.String("path", dbPath), zap.Error(err))
+		db.Close()
+		return nil, fmt.Errorf("failed to create deals tables in database '%s': %w", dbPath, err)
+	}
+	if err := db.CreateSavedDealsTable(); err != nil {
+		logger.Error("Failed to create saved_deals table", zap.String("path", dbPath), zap.Error(err))
+		db.Close()
+		return nil, fmt.Errorf("failed to create saved_deals table in database '%s': %w", dbPath, err)
+	}
 
 	// Ensure SQLiteWrapper implements WebUserDBIF at compile time (checked via persist package).
 	logger.Info("SQLite database initialized successfully", zap.String("path", dbPath))
@@ -13,10 +13,28 @@ import (
 	"go.uber.org/zap"
 )
 
+// userColumns lists the users columns read into UserData, in scan order.
+const userColumns = `chat_id, username, ozb_good, ozb_super, keywords, amz_daily, amz_weekly, inactive, muted_stores, role, banned,
+	categories, top_deal, ozb_trending, expiry_notices`
+
+// userMigrateStmts add users columns that may be missing on an existing database.
+var userMigrateStmts = []string{
+	`ALTER TABLE users ADD COLUMN inactive INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE users ADD COLUMN muted_stores TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE users ADD COLUMN banned INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE users ADD COLUMN categories TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE users ADD COLUMN top_deal TEXT NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE users ADD COLUMN ozb_trending INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE users ADD COLUMN expiry_notices INTEGER NOT NULL DEFAULT 0`,
+}
+
 type UserStoreDB struct {
 	DB     *sql.DB
 	Name   string
 	Logger *zap.Logger
+
+	dealFTS bool // deals_fts search index available (sqlite built with FTS5)
 }
 
 // Connect to the database with connection pooling
@@ -86,10 +104,16 @@ func (udb *UserStoreDB) CreateTable() error {
 			ozb_good INTEGER,
 			ozb_super INTEGER,
 			keywords BLOB,
-			ozb_sent BLOB,
 			amz_daily INTEGER,
 			amz_weekly INTEGER,
-			amz_sent BLOB
+			inactive INTEGER NOT NULL DEFAULT 0,
+			muted_stores TEXT NOT NULL DEFAULT '[]',
+			role TEXT NOT NULL DEFAULT '',
+			banned INTEGER NOT NULL DEFAULT 0,
+			categories TEXT NOT NULL DEFAULT '[]',
+			top_deal TEXT NOT NULL DEFAULT '{}',
+			ozb_trending INTEGER NOT NULL DEFAULT 0,
+			expiry_notices INTEGER NOT NULL DEFAULT 0
 		);
 	`); err != nil {
 		return fmt.Errorf("failed to create table: %w", err)
@@ -100,6 +124,11 @@ func (udb *UserStoreDB) CreateTable() error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	// Columns added after the table was first created; errors mean they exist
+	for _, stmt := range userMigrateStmts {
+		udb.DB.Exec(stmt) //nolint:errcheck
+	}
+
 	return nil
 }
 
@@ -138,23 +167,17 @@ func (udb *UserStoreDB) addUserWithTransaction(user *models.UserData) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal keywords: %w", err)
 	}
-
-	ozbSent, err := json.Marshal(user.OzbSent)
-	if err != nil {
-		return fmt.Errorf("failed to marshal OZB deals sent: %w", err)
-	}
-
-	amzSent, err := json.Marshal(user.AmzSent)
-	if err != nil {
-		return fmt.Errorf("failed to marshal AMZ deals sent: %w", err)
-	}
+	mutedStores := jsonStringList(user.MutedStores)
 
 	// Insert the user
 	_, err = tx.Exec(`
 		INSERT INTO users (
-			chat_id, username, ozb_good, ozb_super, keywords, ozb_sent, amz_daily, amz_weekly, amz_sent
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		user.ChatID, user.Username, user.OzbGood, user.OzbSuper, keywords, ozbSent, user.AmzDaily, user.AmzWeekly, amzSent,
+			chat_id, username, ozb_good, ozb_super, keywords, amz_daily, amz_weekly, inactive, muted_stores, role, banned,
+			categories, top_deal, ozb_trending, expiry_notices
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.ChatID, user.Username, user.OzbGood, user.OzbSuper, keywords, user.AmzDaily, user.AmzWeekly, user.Inactive, mutedStores,
+		user.Role, user.Banned, jsonCategoryList(user.Categories), jsonTopDealRule(user.TopDeal), user.OzbTrending,
+		user.ExpiryNotices,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert user: %w", err)
@@ -203,23 +226,18 @@ func (udb *UserStoreDB) updateUserWithTransaction(user *models.UserData) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal keywords: %w", err)
 	}
-
-	ozbSent, err := json.Marshal(user.OzbSent)
-	if err != nil {
-		return fmt.Errorf("failed to marshal OZB deals sent: %w", err)
-	}
-
-	amzSent, err := json.Marshal(user.AmzSent)
-	if err != nil {
-		return fmt.Errorf("failed to marshal AMZ deals sent: %w", err)
-	}
+	mutedStores := jsonStringList(user.MutedStores)
 
 	// Update the user
 	result, err := tx.Exec(`
 		UPDATE users SET
-			username = ?, ozb_good = ?, ozb_super = ?, keywords = ?, ozb_sent = ?, amz_daily = ?, amz_weekly = ?, amz_sent = ?
+			username = ?, ozb_good = ?, ozb_super = ?, keywords = ?, amz_daily = ?, amz_weekly = ?, inactive = ?,
+			muted_stores = ?, role = ?, banned = ?, categories = ?, top_deal = ?, ozb_trending = ?,
+			expiry_notices = ?
 		WHERE chat_id = ?`,
-		user.Username, user.OzbGood, user.OzbSuper, keywords, ozbSent, user.AmzDaily, user.AmzWeekly, amzSent, user.ChatID,
+		user.Username, user.OzbGood, user.OzbSuper, keywords, user.AmzDaily, user.AmzWeekly, user.Inactive,
+		mutedStores, user.Role, user.Banned, jsonCategoryList(user.Categories), jsonTopDealRule(user.TopDeal), user.OzbTrending,
+		user.ExpiryNotices, user.ChatID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
@@ -306,12 +324,12 @@ func (udb *UserStoreDB) GetUser(chatID int64) (*models.UserData, error) {
 
 	user := &models.UserData{}
 	keywords := []byte{}
-	ozbSent := []byte{}
-	amzSent := []byte{}
+	var mutedStores, categories, topDeal string
 
 	// Get the user
-	err = tx.QueryRow(`SELECT * FROM users WHERE chat_id = ?`, chatID).Scan(
-		&user.ChatID, &user.Username, &user.OzbGood, &user.OzbSuper, &keywords, &ozbSent, &user.AmzDaily, &user.AmzWeekly, &amzSent,
+	err = tx.QueryRow(`SELECT `+userColumns+` FROM users WHERE chat_id = ?`, chatID).Scan(
+		&user.ChatID, &user.Username, &user.OzbGood, &user.OzbSuper, &keywords, &user.AmzDaily, &user.AmzWeekly, &user.Inactive,
+		&mutedStores, &user.Role, &user.Banned, &categories, &topDeal, &user.OzbTrending, &user.ExpiryNotices,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -324,12 +342,9 @@ func (udb *UserStoreDB) GetUser(chatID int64) (*models.UserData, error) {
 	if err := json.Unmarshal(keywords, &user.Keywords); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal keywords: %w", err)
 	}
-	if err := json.Unmarshal(ozbSent, &user.OzbSent); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal OZB deals sent: %w", err)
-	}
-	if err := json.Unmarshal(amzSent, &user.AmzSent); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal AMZ deals sent: %w", err)
-	}
+	user.MutedStores = jsonStrings(mutedStores)
+	user.Categories = jsonCategories(categories)
+	user.TopDeal = topDealRule(topDeal)
 
 	// Commit the transaction
 	if err := tx.Commit(); err != nil {
@@ -341,7 +356,7 @@ func (udb *UserStoreDB) GetUser(chatID int64) (*models.UserData, error) {
 
 // Read all users from the database
 func (udb *UserStoreDB) ReadUserStore() (*models.UserStore, error) {
-	rows, err := udb.DB.Query(`Select * from users`)
+	rows, err := udb.DB.Query(`SELECT ` + userColumns + ` FROM users`)
 	if err != nil {
 		udb.Logger.Error("Error getting all users", zap.Error(err))
 		return nil, err
@@ -354,11 +369,11 @@ func (udb *UserStoreDB) ReadUserStore() (*models.UserStore, error) {
 	for rows.Next() {
 		user := &models.UserData{}
 		keywords := []byte{}
-		ozbSent := []byte{}
-		amzSent := []byte{}
+		var mutedStores, categories, topDeal string
 
 		err = rows.Scan(
-			&user.ChatID, &user.Username, &user.OzbGood, &user.OzbSuper, &keywords, &ozbSent, &user.AmzDaily, &user.AmzWeekly, &amzSent,
+			&user.ChatID, &user.Username, &user.OzbGood, &user.OzbSuper, &keywords, &user.AmzDaily, &user.AmzWeekly, &user.Inactive,
+			&mutedStores, &user.Role, &user.Banned, &categories, &topDeal, &user.OzbTrending, &user.ExpiryNotices,
 		)
 		if err != nil {
 			udb.Logger.Error("Error getting user", zap.Error(err))
@@ -369,16 +384,9 @@ func (udb *UserStoreDB) ReadUserStore() (*models.UserStore, error) {
 		if err := json.Unmarshal([]byte(keywords), &user.Keywords); err != nil {
 			udb.Logger.Error("Error unmarshalling user keywords", zap.Error(err))
 		}
-
-		// Bytes to string array - OZB deals sent
-		if err := json.Unmarshal([]byte(ozbSent), &user.OzbSent); err != nil {
-			udb.Logger.Error("Error unmarshalling OZB deals sent", zap.Error(err))
-		}
-
-		// Bytes to string array - AMZ deals sent
-		if err := json.Unmarshal([]byte(amzSent), &user.AmzSent); err != nil {
-			udb.Logger.Error("Error unmarshalling AMZ deals sent", zap.Error(err))
-		}
+		user.MutedStores = jsonStrings(mutedStores)
+		user.Categories = jsonCategories(categories)
+		user.TopDeal = topDealRule(topDeal)
 
 		userStore.Users[user.ChatID] = user
 
@@ -398,25 +406,22 @@ func (udb *UserStoreDB) WriteUserStore(userStore *models.UserStore) error {
 			udb.Logger.Error("Error marshalling user keywords", zap.Error(err))
 		}
 
-		ozbSent, err := json.Marshal(user.OzbSent)
-		if err != nil {
-			udb.Logger.Error("Error marshalling OZB deals sent", zap.Error(err))
-		}
-
-		amzSent, err := json.Marshal(user.AmzSent)
-		if err != nil {
-			udb.Logger.Error("Error marshalling AMZ deals sent", zap.Error(err))
-		}
-
 		_, err = udb.DB.Exec(`
 			INSERT INTO users (
-				chat_id, username, ozb_good, ozb_super, keywords, ozb_sent, amz_daily, amz_weekly, amz_sent
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+				chat_id, username, ozb_good, ozb_super, keywords, amz_daily, amz_weekly, inactive, muted_stores, role, banned,
+				categories, top_deal, ozb_trending, expiry_notices
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			ON CONFLICT(chat_id) DO UPDATE SET
-				username = ?, ozb_good = ?, ozb_super = ?, keywords = ?, ozb_sent = ?, amz_daily =?, amz_weekly =?, amz_sent =?
+				username = ?, ozb_good = ?, ozb_super = ?, keywords = ?, amz_daily =?, amz_weekly =?, inactive =?,
+				muted_stores = ?, role = ?, banned = ?, categories = ?, top_deal = ?, ozb_trending = ?,
+				expiry_notices = ?
 			`,
-			user.ChatID, user.Username, user.OzbGood, user.OzbSuper, keywords, ozbSent, user.AmzDaily, user.AmzWeekly, amzSent,
-			user.Username, user.OzbGood, user.OzbSuper, keywords, ozbSent, user.AmzDaily, user.AmzWeekly, amzSent,
+			user.ChatID, user.Username, user.OzbGood, user.OzbSuper, keywords, user.AmzDaily, user.AmzWeekly, user.Inactive,
+			jsonStringList(user.MutedStores), user.Role, user.Banned, jsonCategoryList(user.Categories), jsonTopDealRule(user.TopDeal),
+			user.OzbTrending, user.ExpiryNotices,
+			user.Username, user.OzbGood, user.OzbSuper, keywords, user.AmzDaily, user.AmzWeekly, user.Inactive,
+			jsonStringList(user.MutedStores), user.Role, user.Banned, jsonCategoryList(user.Categories), jsonTopDealRule(user.TopDeal),
+			user.OzbTrending, user.ExpiryNotices,
 		)
 
 		if err != nil {
@@ -75,10 +75,8 @@ func TestAddUser(t *testing.T) {
 		OzbGood:   false,
 		OzbSuper:  false,
 		Keywords:  []string{"test", "test2"},
-		OzbSent:   []string{"120", "122"},
 		AmzDaily:  false,
 		AmzWeekly: false,
-		AmzSent:   []string{"222", "333"},
 	}
 
 	err = udb.AddUser(user)
@@ -122,10 +120,8 @@ func TestGetUser(t *testing.T) {
 		OzbGood:   true,
 		OzbSuper:  false,
 		Keywords:  []string{"james", "bond"},
-		OzbSent:   []string{"007", "mi5"},
 		AmzDaily:  false,
 		AmzWeekly: true,
-		AmzSent:   []string{"222", "333", "444"},
 	}
 
 	err = udb.AddUser(user2)
@@ -161,18 +157,12 @@ func TestGetUser(t *testing.T) {
 	if len(user.Keywords) != 2 {
 		t.Errorf("Expected user keywords to have length 2, got %d", len(user.Keywords))
 	}
-	if len(user.OzbSent) != 2 {
-		t.Errorf("Expected user ozb_sent to have length 2, got %d", len(user.OzbSent))
-	}
 	if user.AmzDaily != false {
 		t.Errorf("Expected user amz_daily to be %t, got %t", true, user.OzbGood)
 	}
 	if user.AmzWeekly != true {
 		t.Errorf("Expected user amz_weekly to be %t, got %t", false, user.OzbSuper)
 	}
-	if len(user.AmzSent) != 3 {
-		t.Errorf("Expected user amz_sent to have length 3, got %d", len(user.OzbSent))
-	}
 }
 
 // Test update user in database
@@ -198,10 +188,8 @@ func TestUpdateUser(t *testing.T) {
 		OzbGood:   false,
 		OzbSuper:  false,
 		Keywords:  []string{"test", "test2"},
-		OzbSent:   []string{"120", "122"},
 		AmzDaily:  false,
 		AmzWeekly: true,
-		AmzSent:   []string{"222", "333", "444"},
 	}
 
 	err = udb.AddUser(user)
@@ -219,11 +207,9 @@ func TestUpdateUser(t *testing.T) {
 	user.OzbGood = true
 	user.OzbSuper = true
 	user.Keywords = []string{"test", "test2", "test3"}
-	user.OzbSent = []string{"120", "122", "123"}
 	user.Username = "test_user_updated"
 	user.AmzDaily = true
 	user.AmzWeekly = false
-	user.OzbSent = []string{"555", "666", "777"}
 
 	err = udb.UpdateUser(user)
 	if err != nil {
@@ -237,6 +223,93 @@ func TestUpdateUser(t *testing.T) {
 	}
 }
 
+// Test that roles and bans are stored, and read back by GetUser and ReadUserStore
+func TestUserRoleAndBan(t *testing.T) {
+	db := newDealsDB(t)
+	if err := db.AddUser(&models.UserData{ChatID: 1, Role: models.RoleAdmin}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddUser(&models.UserData{ChatID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateUser(&models.UserData{ChatID: 2, Banned: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	admin, err := db.GetUser(1)
+	if err != nil || admin.Role != models.RoleAdmin || admin.Banned {
+		t.Errorf("GetUser(1) = %+v, %v; want an admin", admin, err)
+	}
+	store, err := db.ReadUserStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := store.GetUser(2); u == nil || !u.Banned || u.Role != "" {
+		t.Errorf("ReadUserStore user 2 = %+v, want banned", u)
+	}
+}
+
+// Test that category subscriptions are stored, and read back by GetUser and ReadUserStore
+func TestUserCategories(t *testing.T) {
+	db := newDealsDB(t)
+	subs := []models.CategorySub{{Category: "Gaming", MinVotes: 50}, {Category: "lego"}}
+	if err := db.AddUser(&models.UserData{ChatID: 1, Categories: subs}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddUser(&models.UserData{ChatID: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := db.GetUser(1)
+	if err != nil || fmt.Sprint(user.Categories) != fmt.Sprint(subs) {
+		t.Errorf("GetUser(1) categories = %v, %v; want %v", user.Categories, err, subs)
+	}
+	user.Categories = subs[:1]
+	if err := db.UpdateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	store, err := db.ReadUserStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := store.GetUser(1).Categories; len(got) != 1 || got[0] != subs[0] {
+		t.Errorf("ReadUserStore user 1 categories = %v, want %v", got, subs[:1])
+	}
+	if got := store.GetUser(2).Categories; got == nil || len(got) != 0 {
+		t.Errorf("ReadUserStore user 2 categories = %#v, want empty", got)
+	}
+}
+
+func TestUserTopDeal(t *testing.T) {
+	db := newDealsDB(t)
+	rule := models.TopDealRule{MinVotes: 10, MaxAgeHours: 12, MinVotesPerHour: 1.5}
+	if err := db.AddUser(&models.UserData{ChatID: 1, TopDeal: rule}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddUser(&models.UserData{ChatID: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := db.GetUser(1)
+	if err != nil || user.TopDeal != rule {
+		t.Errorf("GetUser(1) top deal = %+v, %v; want %+v", user.TopDeal, err, rule)
+	}
+	user.TopDeal = models.TopDealRule{MinVotes: 40}
+	if err := db.UpdateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	store, err := db.ReadUserStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := store.GetUser(1).TopDeal; got != (models.TopDealRule{MinVotes: 40}) {
+		t.Errorf("ReadUserStore user 1 top deal = %+v, want min votes 40", got)
+	}
+	if got := store.GetUser(2).TopDeal; got != (models.TopDealRule{}) {
+		t.Errorf("ReadUserStore user 2 top deal = %+v, want the default", got)
+	}
+}
+
 // Delete database file
 func DeleteDBFile(dbName string) {
 	err := os.Remove(dbName)
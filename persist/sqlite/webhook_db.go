@@ -0,0 +1,232 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+// createWebhooksTableSQL stores each web user's webhook endpoints.
+const createWebhooksTableSQL = `
+CREATE TABLE IF NOT EXISTS webhooks (
+	id           TEXT PRIMARY KEY,
+	web_user_id  TEXT NOT NULL REFERENCES web_users(id) ON DELETE CASCADE,
+	url          TEXT NOT NULL,
+	secret       TEXT NOT NULL,
+	created_at   DATETIME NOT NULL
+)`
+
+// createWebhookDeliveriesTableSQL logs every delivery attempt.
+const createWebhookDeliveriesTableSQL = `
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhook_id   TEXT NOT NULL,
+	delivery_id  TEXT NOT NULL,
+	event        TEXT NOT NULL,
+	source       TEXT NOT NULL DEFAULT '',
+	deal_id      TEXT NOT NULL DEFAULT '',
+	attempt      INTEGER NOT NULL,
+	status_code  INTEGER NOT NULL DEFAULT 0,
+	error        TEXT NOT NULL DEFAULT '',
+	duration_ms  INTEGER NOT NULL DEFAULT 0,
+	created_at   DATETIME NOT NULL
+)`
+
+// createWebhookQueueTableSQL holds payloads until their webhook accepts them
+// or they run out of attempts.
+const createWebhookQueueTableSQL = `
+CREATE TABLE IF NOT EXISTS webhook_queue (
+	id               INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhook_id       TEXT NOT NULL,
+	payload          TEXT NOT NULL,
+	attempts         INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at  DATETIME NOT NULL,
+	created_at       DATETIME NOT NULL
+)`
+
+// webhookIndexStmts adds indexes to the webhook tables.
+var webhookIndexStmts = []string{
+	`CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(web_user_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id)`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_queue_next ON webhook_queue(next_attempt_at)`,
+}
+
+// CreateWebhooksTables creates the webhooks, webhook_deliveries and
+// webhook_queue tables.
+func (udb *UserStoreDB) CreateWebhooksTables() error {
+	if _, err := udb.DB.Exec(createWebhooksTableSQL); err != nil {
+		return fmt.Errorf("failed to create webhooks table: %w", err)
+	}
+	if _, err := udb.DB.Exec(createWebhookDeliveriesTableSQL); err != nil {
+		return fmt.Errorf("failed to create webhook_deliveries table: %w", err)
+	}
+	if _, err := udb.DB.Exec(createWebhookQueueTableSQL); err != nil {
+		return fmt.Errorf("failed to create webhook_queue table: %w", err)
+	}
+	for _, stmt := range webhookIndexStmts {
+		if _, err := udb.DB.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create webhook index: %w", err)
+		}
+	}
+	return nil
+}
+
+// CreateWebhook inserts a new webhook endpoint.
+func (udb *UserStoreDB) CreateWebhook(hook *models.Webhook) error {
+	hook.CreatedAt = dbTime(time.Now())
+	_, err := udb.DB.Exec(`
+		INSERT INTO webhooks (id, web_user_id, url, secret, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		hook.ID, hook.UserID, hook.URL, hook.Secret, hook.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+// ListWebhooks returns a web user's webhooks, oldest first.
+func (udb *UserStoreDB) ListWebhooks(userID string) ([]*models.Webhook, error) {
+	rows, err := udb.DB.Query(`
+		SELECT id, web_user_id, url, secret, created_at
+		FROM webhooks WHERE web_user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []*models.Webhook{}
+	for rows.Next() {
+		h := &models.Webhook{}
+		if err := rows.Scan(&h.ID, &h.UserID, &h.URL, &h.Secret, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks, rows.Err()
+}
+
+// DeleteWebhook removes one of a web user's webhooks along with its delivery
+// log and queued deliveries. It returns false if the user has no such webhook.
+func (udb *UserStoreDB) DeleteWebhook(userID, id string) (bool, error) {
+	res, err := udb.DB.Exec(`DELETE FROM webhooks WHERE id = ? AND web_user_id = ?`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return false, nil
+	}
+	if _, err := udb.DB.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return true, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	if _, err := udb.DB.Exec(`DELETE FROM webhook_queue WHERE webhook_id = ?`, id); err != nil {
+		return true, fmt.Errorf("failed to delete queued webhook deliveries: %w", err)
+	}
+	return true, nil
+}
+
+// LogWebhookDelivery records a delivery attempt.
+func (udb *UserStoreDB) LogWebhookDelivery(d *models.WebhookDelivery) error {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	d.CreatedAt = dbTime(d.CreatedAt)
+	res, err := udb.DB.Exec(`
+		INSERT INTO webhook_deliveries
+			(webhook_id, delivery_id, event, source, deal_id, attempt, status_code, error, duration_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.WebhookID, d.DeliveryID, d.Event, d.Source, d.DealID, d.Attempt, d.StatusCode, d.Error, d.Duration, d.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to log webhook delivery: %w", err)
+	}
+	d.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// ListWebhookDeliveries returns a webhook's most recent delivery attempts, newest first.
+func (udb *UserStoreDB) ListWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := udb.DB.Query(`
+		SELECT id, webhook_id, delivery_id, event, source, deal_id, attempt, status_code, error, duration_ms, created_at
+		FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?`, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.DeliveryID, &d.Event, &d.Source, &d.DealID,
+			&d.Attempt, &d.StatusCode, &d.Error, &d.Duration, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// EnqueueWebhook queues a payload for a webhook, ready to send immediately.
+func (udb *UserStoreDB) EnqueueWebhook(q *models.QueuedWebhook) error {
+	q.CreatedAt = dbTime(time.Now())
+	q.NextAttemptAt = q.CreatedAt
+	res, err := udb.DB.Exec(`
+		INSERT INTO webhook_queue (webhook_id, payload, attempts, next_attempt_at, created_at)
+		VALUES (?, ?, 0, ?, ?)`,
+		q.WebhookID, q.Payload, q.NextAttemptAt, q.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+	}
+	q.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// DueWebhooks returns the queued deliveries whose next attempt is due, oldest
+// first, with their webhook's URL and secret.
+func (udb *UserStoreDB) DueWebhooks(now time.Time, limit int) ([]models.QueuedWebhook, error) {
+	rows, err := udb.DB.Query(`
+		SELECT q.id, q.webhook_id, w.url, w.secret, q.payload, q.attempts, q.next_attempt_at, q.created_at
+		FROM webhook_queue q JOIN webhooks w ON w.id = q.webhook_id
+		WHERE q.next_attempt_at <= ?
+		ORDER BY q.id LIMIT ?`, dbTime(now), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook queue: %w", err)
+	}
+	defer rows.Close()
+
+	queued := []models.QueuedWebhook{}
+	for rows.Next() {
+		var q models.QueuedWebhook
+		if err := rows.Scan(&q.ID, &q.WebhookID, &q.URL, &q.Secret, &q.Payload,
+			&q.Attempts, &q.NextAttemptAt, &q.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan queued webhook delivery: %w", err)
+		}
+		queued = append(queued, q)
+	}
+	return queued, rows.Err()
+}
+
+// DeleteQueuedWebhook removes a delivery that succeeded or was given up on.
+func (udb *UserStoreDB) DeleteQueuedWebhook(id int64) error {
+	if _, err := udb.DB.Exec(`DELETE FROM webhook_queue WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete queued webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// RescheduleWebhook records a failed attempt and sets when to try again.
+func (udb *UserStoreDB) RescheduleWebhook(id int64, attempts int, next time.Time) error {
+	_, err := udb.DB.Exec(`
+		UPDATE webhook_queue SET attempts = ?, next_attempt_at = ? WHERE id = ?`,
+		attempts, dbTime(next), id)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule webhook delivery: %w", err)
+	}
+	return nil
+}
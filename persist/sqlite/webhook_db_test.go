@@ -0,0 +1,87 @@
+package sqlite_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+func TestWebhooks_CreateListDelete(t *testing.T) {
+	db := newDealsDB(t)
+	hook := &models.Webhook{ID: "h1", UserID: "u1", URL: "https://example.com/hook", Secret: "whsec_x"}
+	if err := db.CreateWebhook(hook); err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	for attempt, status := range []int{500, 200} {
+		d := &models.WebhookDelivery{WebhookID: "h1", DeliveryID: "d1", Event: "watched", Attempt: attempt + 1, StatusCode: status}
+		if err := db.LogWebhookDelivery(d); err != nil {
+			t.Fatalf("LogWebhookDelivery() error = %v", err)
+		}
+	}
+
+	hooks, err := db.ListWebhooks("u1")
+	if err != nil || len(hooks) != 1 || hooks[0].Secret != "whsec_x" || hooks[0].CreatedAt.IsZero() {
+		t.Fatalf("ListWebhooks() = %+v, %v", hooks, err)
+	}
+	if others, _ := db.ListWebhooks("u2"); len(others) != 0 {
+		t.Errorf("ListWebhooks(u2) = %+v, want none", others)
+	}
+
+	log, err := db.ListWebhookDeliveries("h1", 10)
+	if err != nil || len(log) != 2 || log[0].StatusCode != 200 || log[1].Attempt != 1 {
+		t.Fatalf("ListWebhookDeliveries() = %+v, %v (want newest first)", log, err)
+	}
+
+	if found, err := db.DeleteWebhook("u2", "h1"); found || err != nil {
+		t.Errorf("DeleteWebhook() by another user = %v, %v", found, err)
+	}
+	if found, err := db.DeleteWebhook("u1", "h1"); !found || err != nil {
+		t.Errorf("DeleteWebhook() = %v, %v", found, err)
+	}
+	if log, _ := db.ListWebhookDeliveries("h1", 10); len(log) != 0 {
+		t.Errorf("delivery log kept after delete: %+v", log)
+	}
+}
+
+func TestWebhookQueue(t *testing.T) {
+	db := newDealsDB(t)
+	if err := db.CreateWebhook(&models.Webhook{ID: "h1", UserID: "u1", URL: "https://example.com/hook", Secret: "whsec_x"}); err != nil {
+		t.Fatal(err)
+	}
+	first := &models.QueuedWebhook{WebhookID: "h1", Payload: `{"id":"a"}`}
+	second := &models.QueuedWebhook{WebhookID: "h1", Payload: `{"id":"b"}`}
+	for _, q := range []*models.QueuedWebhook{first, second} {
+		if err := db.EnqueueWebhook(q); err != nil {
+			t.Fatalf("EnqueueWebhook() error = %v", err)
+		}
+	}
+
+	now := time.Now()
+	due, err := db.DueWebhooks(now, 10)
+	if err != nil || len(due) != 2 || due[0].Payload != `{"id":"a"}` || due[0].URL != "https://example.com/hook" || due[0].Secret != "whsec_x" {
+		t.Fatalf("DueWebhooks() = %+v, %v", due, err)
+	}
+
+	if err := db.RescheduleWebhook(first.ID, 1, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteQueuedWebhook(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if due, _ := db.DueWebhooks(now, 10); len(due) != 0 {
+		t.Errorf("DueWebhooks() before the retry = %+v, want none", due)
+	}
+	if due, _ := db.DueWebhooks(now.Add(2*time.Minute), 10); len(due) != 1 || due[0].Attempts != 1 {
+		t.Errorf("DueWebhooks() after the retry = %+v", due)
+	}
+
+	// Deleting the webhook drops its queued deliveries
+	if _, err := db.DeleteWebhook("u1", "h1"); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.DB.QueryRow(`SELECT COUNT(*) FROM webhook_queue`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("%d deliveries left queued after delete, %v", n, err)
+	}
+}
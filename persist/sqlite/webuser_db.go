@@ -31,6 +31,11 @@ CREATE TABLE IF NOT EXISTS web_users (
 	amz_weekly            INTEGER NOT NULL DEFAULT 0,
 	email_summary         INTEGER NOT NULL DEFAULT 0,
 	keywords              TEXT NOT NULL DEFAULT '[]',
+	channels              TEXT NOT NULL DEFAULT '[]',
+	categories            TEXT NOT NULL DEFAULT '[]',
+	top_deal              TEXT NOT NULL DEFAULT '{}',
+	ozb_trending          INTEGER NOT NULL DEFAULT 0,
+	expiry_notices        INTEGER NOT NULL DEFAULT 0,
 	created_at            DATETIME DEFAULT CURRENT_TIMESTAMP,
 	updated_at            DATETIME DEFAULT CURRENT_TIMESTAMP
 )`
@@ -53,11 +58,22 @@ var migrateStmts = []string{
 	`ALTER TABLE web_users ADD COLUMN verify_token_expires DATETIME`,
 	// Daily email summary preference.
 	`ALTER TABLE web_users ADD COLUMN email_summary INTEGER NOT NULL DEFAULT 0`,
+	// Deal delivery channels.
+	`ALTER TABLE web_users ADD COLUMN channels TEXT NOT NULL DEFAULT '[]'`,
+	// Category subscriptions.
+	`ALTER TABLE web_users ADD COLUMN categories TEXT NOT NULL DEFAULT '[]'`,
+	// Top deal rule.
+	`ALTER TABLE web_users ADD COLUMN top_deal TEXT NOT NULL DEFAULT '{}'`,
+	// Trending deals subscription.
+	`ALTER TABLE web_users ADD COLUMN ozb_trending INTEGER NOT NULL DEFAULT 0`,
+	// Expiry notices.
+	`ALTER TABLE web_users ADD COLUMN expiry_notices INTEGER NOT NULL DEFAULT 0`,
 	// Indexes — created after columns to avoid "no such column" on old schemas.
 	`CREATE INDEX IF NOT EXISTS idx_web_users_email ON web_users(email)`,
 	`CREATE INDEX IF NOT EXISTS idx_web_users_link_token ON web_users(link_token)`,
 	`CREATE INDEX IF NOT EXISTS idx_web_users_reset_token ON web_users(reset_token)`,
 	`CREATE INDEX IF NOT EXISTS idx_web_users_verify_token ON web_users(verify_token)`,
+	`CREATE INDEX IF NOT EXISTS idx_web_users_telegram_chat_id ON web_users(telegram_chat_id)`,
 }
 
 // CreateWebUsersTable creates the web_users table and migrates any missing columns/indexes.
@@ -81,6 +97,7 @@ const webUserColumns = `
 	link_token, link_token_expires,
 	reset_token, reset_token_expires,
 	ozb_good, ozb_super, amz_daily, amz_weekly, email_summary, keywords,
+	channels, categories, top_deal, ozb_trending, expiry_notices,
 	created_at, updated_at`
 
 // CreateWebUser inserts a new web user record.
@@ -130,6 +147,13 @@ func (udb *UserStoreDB) GetWebUserByLinkToken(token string) (*models.WebUser, er
 	))
 }
 
+// GetWebUserByTelegramChatID retrieves the web user linked to a Telegram chat.
+func (udb *UserStoreDB) GetWebUserByTelegramChatID(chatID int64) (*models.WebUser, error) {
+	return udb.scanWebUser(udb.DB.QueryRow(
+		`SELECT `+webUserColumns+` FROM web_users WHERE telegram_chat_id = ?`, chatID,
+	))
+}
+
 // GetWebUserByVerifyToken retrieves a web user by a non-expired email verification token.
 func (udb *UserStoreDB) GetWebUserByVerifyToken(token string) (*models.WebUser, error) {
 	return udb.scanWebUser(udb.DB.QueryRow(
@@ -152,7 +176,11 @@ func (udb *UserStoreDB) UpdateWebUser(user *models.WebUser) error {
 	if user.Keywords == nil {
 		user.Keywords = []string{}
 	}
+	if user.Channels == nil {
+		user.Channels = []string{}
+	}
 	kw, _ := json.Marshal(user.Keywords)
+	ch, _ := json.Marshal(user.Channels)
 	_, err := udb.DB.Exec(`
 		UPDATE web_users SET
 			email = ?,
@@ -173,6 +201,11 @@ func (udb *UserStoreDB) UpdateWebUser(user *models.WebUser) error {
 			amz_weekly = ?,
 			email_summary = ?,
 			keywords = ?,
+			channels = ?,
+			categories = ?,
+			top_deal = ?,
+			ozb_trending = ?,
+			expiry_notices = ?,
 			updated_at = ?
 		WHERE id = ?`,
 		user.Email, user.PasswordHash, user.DisplayName,
@@ -181,7 +214,8 @@ func (udb *UserStoreDB) UpdateWebUser(user *models.WebUser) error {
 		user.LinkToken, user.LinkTokenExpires,
 		user.ResetToken, user.ResetTokenExpires,
 		user.OzbGood, user.OzbSuper, user.AmzDaily, user.AmzWeekly, user.EmailSummary, string(kw),
-		user.UpdatedAt, user.ID,
+		string(ch), jsonCategoryList(user.Categories), jsonTopDealRule(user.TopDeal), user.OzbTrending,
+		user.ExpiryNotices, user.UpdatedAt, user.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update web user: %w", err)
@@ -208,23 +242,9 @@ func (udb *UserStoreDB) GetAllVerifiedWebUsers() ([]*models.WebUser, error) {
 
 	var users []*models.WebUser
 	for rows.Next() {
-		u := &models.WebUser{}
-		var kwJSON string
-		if err := rows.Scan(
-			&u.ID, &u.Email, &u.PasswordHash, &u.DisplayName,
-			&u.EmailVerified, &u.VerifyToken, &u.VerifyTokenExpires,
-			&u.TelegramChatID, &u.TelegramUsername,
-			&u.LinkToken, &u.LinkTokenExpires,
-			&u.ResetToken, &u.ResetTokenExpires,
-			&u.OzbGood, &u.OzbSuper, &u.AmzDaily, &u.AmzWeekly, &u.EmailSummary, &kwJSON,
-			&u.CreatedAt, &u.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan web user: %w", err)
-		}
-		if kwJSON == "" || kwJSON == "null" {
-			u.Keywords = []string{}
-		} else {
-			json.Unmarshal([]byte(kwJSON), &u.Keywords) //nolint:errcheck
+		u, err := scanWebUserRow(rows)
+		if err != nil {
+			return nil, err
 		}
 		users = append(users, u)
 	}
@@ -233,8 +253,17 @@ func (udb *UserStoreDB) GetAllVerifiedWebUsers() ([]*models.WebUser, error) {
 
 // scanWebUser reads an explicit-column row into a WebUser struct.
 func (udb *UserStoreDB) scanWebUser(row *sql.Row) (*models.WebUser, error) {
+	u, err := scanWebUserRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return u, err
+}
+
+// scanWebUserRow scans one row selected with webUserColumns.
+func scanWebUserRow(row rowScanner) (*models.WebUser, error) {
 	u := &models.WebUser{}
-	var kwJSON string
+	var kwJSON, chJSON, catJSON, topJSON string
 	err := row.Scan(
 		&u.ID, &u.Email, &u.PasswordHash, &u.DisplayName,
 		&u.EmailVerified, &u.VerifyToken, &u.VerifyTokenExpires,
@@ -242,18 +271,91 @@ func (udb *UserStoreDB) scanWebUser(row *sql.Row) (*models.WebUser, error) {
 		&u.LinkToken, &u.LinkTokenExpires,
 		&u.ResetToken, &u.ResetTokenExpires,
 		&u.OzbGood, &u.OzbSuper, &u.AmzDaily, &u.AmzWeekly, &u.EmailSummary, &kwJSON,
+		&chJSON, &catJSON, &topJSON, &u.OzbTrending, &u.ExpiryNotices,
 		&u.CreatedAt, &u.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
-		return nil, nil
+		return nil, err
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan web user: %w", err)
 	}
-	if kwJSON == "" || kwJSON == "null" {
-		u.Keywords = []string{}
-	} else {
-		json.Unmarshal([]byte(kwJSON), &u.Keywords) //nolint:errcheck
-	}
+	u.Keywords = jsonStrings(kwJSON)
+	u.Channels = jsonStrings(chJSON)
+	u.Categories = jsonCategories(catJSON)
+	u.TopDeal = topDealRule(topJSON)
 	return u, nil
 }
+
+// jsonStrings decodes a JSON string array column, returning an empty slice for
+// empty or invalid values.
+func jsonStrings(s string) []string {
+	out := []string{}
+	if s != "" && s != "null" {
+		json.Unmarshal([]byte(s), &out) //nolint:errcheck
+	}
+	return out
+}
+
+// jsonStringList encodes a string slice for a JSON array column, storing nil
+// as an empty array.
+func jsonStringList(list []string) string {
+	if list == nil {
+		list = []string{}
+	}
+	b, _ := json.Marshal(list)
+	return string(b)
+}
+
+// jsonCategories decodes a JSON category subscriptions column, returning an
+// empty slice for empty or invalid values.
+func jsonCategories(s string) []models.CategorySub {
+	out := []models.CategorySub{}
+	if s != "" && s != "null" {
+		json.Unmarshal([]byte(s), &out) //nolint:errcheck
+	}
+	return out
+}
+
+// jsonCategoryList encodes category subscriptions for a JSON array column,
+// storing nil as an empty array.
+func jsonCategoryList(list []models.CategorySub) string {
+	if list == nil {
+		list = []models.CategorySub{}
+	}
+	b, _ := json.Marshal(list)
+	return string(b)
+}
+
+// topDealRule decodes a JSON top deal rule column. Empty or invalid values
+// give the zero rule, i.e. the default.
+func topDealRule(s string) models.TopDealRule {
+	var r models.TopDealRule
+	json.Unmarshal([]byte(s), &r) //nolint:errcheck
+	return r
+}
+
+// jsonTopDealRule encodes a top deal rule for a JSON column.
+func jsonTopDealRule(r models.TopDealRule) string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+// GetLinkedWebUsers returns all web users with a linked Telegram account.
+func (udb *UserStoreDB) GetLinkedWebUsers() ([]*models.WebUser, error) {
+	rows, err := udb.DB.Query(`SELECT ` + webUserColumns + ` FROM web_users WHERE telegram_chat_id IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query linked web users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.WebUser
+	for rows.Next() {
+		u, err := scanWebUserRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
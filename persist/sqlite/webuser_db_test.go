@@ -0,0 +1,125 @@
+package sqlite_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+// Test that delivery channels survive a round trip
+func TestUpdateWebUser_Channels(t *testing.T) {
+	db := newDealsDB(t)
+	user := &models.WebUser{ID: "u1", Email: "kramer@example.com", PasswordHash: "x"}
+	if err := db.CreateWebUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.GetWebUserByID("u1")
+	if err != nil || got == nil {
+		t.Fatalf("GetWebUserByID() = %v, %v", got, err)
+	}
+	if got.Channels == nil || len(got.Channels) != 0 {
+		t.Errorf("new user Channels = %#v, want empty", got.Channels)
+	}
+
+	got.Channels = []string{"telegram", "webhook"}
+	if err := db.UpdateWebUser(got); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ = db.GetWebUserByID("u1")
+	if fmt.Sprint(got.Channels) != "[telegram webhook]" {
+		t.Errorf("got Channels %v", got.Channels)
+	}
+}
+
+// Test that category subscriptions survive a round trip
+func TestUpdateWebUser_Categories(t *testing.T) {
+	db := newDealsDB(t)
+	if err := db.CreateWebUser(&models.WebUser{ID: "u1", Email: "kramer@example.com", PasswordHash: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := db.GetWebUserByID("u1")
+	if got.Categories == nil || len(got.Categories) != 0 {
+		t.Errorf("new user Categories = %#v, want empty", got.Categories)
+	}
+
+	got.Categories = []models.CategorySub{{Category: "Travel", MinVotes: 20}}
+	if err := db.UpdateWebUser(got); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = db.GetWebUserByID("u1")
+	if len(got.Categories) != 1 || got.Categories[0] != (models.CategorySub{Category: "Travel", MinVotes: 20}) {
+		t.Errorf("got Categories %v", got.Categories)
+	}
+}
+
+func TestUpdateWebUser_TopDeal(t *testing.T) {
+	db := newDealsDB(t)
+	if err := db.CreateWebUser(&models.WebUser{ID: "u1", Email: "kramer@example.com", PasswordHash: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := db.GetWebUserByID("u1")
+	if got.TopDeal != (models.TopDealRule{}) {
+		t.Errorf("new user TopDeal = %+v, want the default", got.TopDeal)
+	}
+
+	rule := models.TopDealRule{MinVotes: 15, MaxAgeHours: 48, MinVotesPerHour: 3}
+	got.TopDeal = rule
+	if err := db.UpdateWebUser(got); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = db.GetWebUserByID("u1")
+	if got.TopDeal != rule {
+		t.Errorf("got TopDeal %+v, want %+v", got.TopDeal, rule)
+	}
+}
+
+func TestGetLinkedWebUsers(t *testing.T) {
+	db := newDealsDB(t)
+	chatID := int64(42)
+	for _, u := range []*models.WebUser{
+		{ID: "linked", Email: "a@example.com", PasswordHash: "x"},
+		{ID: "unlinked", Email: "b@example.com", PasswordHash: "x"},
+	} {
+		if err := db.CreateWebUser(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	linked, _ := db.GetWebUserByID("linked")
+	linked.TelegramChatID = &chatID
+	if err := db.UpdateWebUser(linked); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := db.GetLinkedWebUsers()
+	if err != nil {
+		t.Fatalf("GetLinkedWebUsers() error = %v", err)
+	}
+	if len(users) != 1 || users[0].ID != "linked" || *users[0].TelegramChatID != chatID {
+		t.Errorf("GetLinkedWebUsers() = %+v", users)
+	}
+}
+
+func TestGetWebUserByTelegramChatID(t *testing.T) {
+	db := newDealsDB(t)
+	chatID := int64(42)
+	user := &models.WebUser{ID: "u1", Email: "a@example.com", PasswordHash: "x"}
+	if err := db.CreateWebUser(user); err != nil {
+		t.Fatal(err)
+	}
+	user.TelegramChatID = &chatID
+	if err := db.UpdateWebUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.GetWebUserByTelegramChatID(42)
+	if err != nil || got == nil || got.ID != "u1" {
+		t.Errorf("GetWebUserByTelegramChatID(42) = %+v, %v", got, err)
+	}
+	got, err = db.GetWebUserByTelegramChatID(7)
+	if err != nil || got != nil {
+		t.Errorf("GetWebUserByTelegramChatID(7) = %+v, %v, want nil", got, err)
+	}
+}
@@ -1,6 +1,14 @@
 package persist
 
-import "github.com/intothevoid/kramerbot/models"
+import (
+	"errors"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
 
 type UserStore interface {
 	WriteUserStore(userStore *models.UserStore) error
@@ -24,9 +32,63 @@ type WebUserDBIF interface {
 	GetWebUserByEmail(email string) (*models.WebUser, error)
 	GetWebUserByID(id string) (*models.WebUser, error)
 	GetWebUserByLinkToken(token string) (*models.WebUser, error)
+	GetWebUserByTelegramChatID(chatID int64) (*models.WebUser, error)
 	GetWebUserByVerifyToken(token string) (*models.WebUser, error)
 	GetWebUserByResetToken(token string) (*models.WebUser, error)
 	UpdateWebUser(user *models.WebUser) error
 	DeleteWebUser(id string) error
 	GetAllVerifiedWebUsers() ([]*models.WebUser, error)
+	GetLinkedWebUsers() ([]*models.WebUser, error)
+}
+
+// DealDBIF stores every scraped deal along with its first/last seen times and vote history.
+type DealDBIF interface {
+	UpsertDeals(deals []models.Deal, seenAt time.Time) error
+	QueryDeals(q models.DealQuery) ([]models.Deal, int, error)
+	GetDeal(source, dealID string) (*models.Deal, error)
+	GetVoteHistory(source, dealID string) ([]models.VoteSnapshot, error)
+	SearchDeals(s models.DealSearch) ([]models.Deal, string, error)
+	SetDealExpiry(source, dealID, expiry string, at time.Time) (bool, error)
+	ExpiryNoticePending(since time.Time) ([]models.Deal, error)
+	MarkExpiryChecked(source, dealID string, at time.Time) error
+	MarkExpiryNoticeSent(source, dealID string) error
+}
+
+// WebhookDBIF stores web users' webhook endpoints, their delivery log and the
+// queue of deliveries waiting to be sent.
+type WebhookDBIF interface {
+	CreateWebhook(hook *models.Webhook) error
+	ListWebhooks(userID string) ([]*models.Webhook, error)
+	DeleteWebhook(userID, id string) (bool, error)
+	LogWebhookDelivery(d *models.WebhookDelivery) error
+	ListWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error)
+	EnqueueWebhook(q *models.QueuedWebhook) error
+	DueWebhooks(now time.Time, limit int) ([]models.QueuedWebhook, error)
+	DeleteQueuedWebhook(id int64) error
+	RescheduleWebhook(id int64, attempts int, next time.Time) error
+}
+
+// OutboxDBIF is the persistent queue of Telegram messages waiting to be sent.
+type OutboxDBIF interface {
+	EnqueueOutbox(m *models.OutboxMessage) error
+	DueOutbox(now time.Time, limit int) ([]models.OutboxMessage, error)
+	DeleteOutbox(id int64) error
+	RescheduleOutbox(id int64, attempts int, next time.Time, lastErr string) error
+	DeadLetterOutbox(id int64, attempts int, lastErr string) error
+	ListDeadOutbox(limit int) ([]models.OutboxMessage, error)
+	OutboxStats() (models.OutboxStats, error)
+}
+
+// SentDealDBIF records which deals were sent to each chat, and over which channel.
+type SentDealDBIF interface {
+	DealSent(chatID int64, source, dealID string) (bool, error)
+	MarkDealSent(chatID int64, source, dealID, channel string, sentAt time.Time) error
+	SentChats(source, dealID, channel string) ([]int64, error)
+	PruneSentDeals(before time.Time) (int64, error)
+}
+
+// SavedDealDBIF stores the deals users saved from Telegram notifications.
+type SavedDealDBIF interface {
+	SaveDeal(chatID int64, source, dealID string) (bool, error)
+	SavedDeals(chatID int64, limit int) ([]models.Deal, error)
 }
@@ -15,6 +15,9 @@ import (
 
 var SID_CCC_AMAZON ScraperID = 1
 
+// gofeed sets Published as RFC1123Z or RFC1123
+var cccTimeLayouts = []string{time.RFC1123Z, time.RFC1123}
+
 // Camel Camel Camel - Amazon scraper
 type CamCamCamScraper struct {
 	BaseUrl         []string               // Urls to scrape
@@ -25,6 +28,21 @@ type CamCamCamScraper struct {
 	Deals           []models.CamCamCamDeal // List of deals
 }
 
+// Ensure CamCamCamScraper can be registered as a deal source at compile time.
+var _ Source = (*CamCamCamScraper)(nil)
+
+// Create a Camel Camel Camel scraper from its configuration
+func NewCamCamCamScraper(cfg util.AmazonConfig, logger *zap.Logger) *CamCamCamScraper {
+	return &CamCamCamScraper{
+		SID:             SID_CCC_AMAZON,
+		Logger:          logger,
+		BaseUrl:         cfg.URLs,
+		Deals:           []models.CamCamCamDeal{},
+		ScrapeInterval:  cfg.ScrapeInterval,
+		MaxDealsToStore: cfg.MaxStoredDeals,
+	}
+}
+
 // Check initialisation
 func (s *CamCamCamScraper) CheckInit() bool {
 	if s.ScrapeInterval == 0 || s.MaxDealsToStore == 0 || len(s.BaseUrl) <= 0 || s.Logger == nil {
@@ -161,17 +179,71 @@ func (s *CamCamCamScraper) GetData() []models.CamCamCamDeal {
 	return s.Deals
 }
 
+// Name returns the registry name of the source
+func (s *CamCamCamScraper) Name() string {
+	return SOURCE_AMAZON
+}
+
+// DisplayName returns the human readable name of the source
+func (s *CamCamCamScraper) DisplayName() string {
+	return "Amazon"
+}
+
+// Interval returns the time between scrapes
+func (s *CamCamCamScraper) Interval() time.Duration {
+	return time.Minute * time.Duration(s.ScrapeInterval)
+}
+
+// GetDeals returns the cached deals in the common deal model
+func (s *CamCamCamScraper) GetDeals() []models.Deal {
+	deals := make([]models.Deal, 0, len(s.Deals))
+	for i := range s.Deals {
+		d := &s.Deals[i]
+		drop, _ := dropPercent(d.Title)
+		deal := models.Deal{
+			Id:          d.Id,
+			Source:      SOURCE_AMAZON,
+			Title:       d.Title,
+			Url:         d.Url,
+			PostedOn:    d.Published,
+			Image:       d.Image,
+			PriceDrop:   s.GetDealDropString(d),
+			DropPercent: drop,
+			DealType:    d.DealType,
+		}
+		for _, layout := range cccTimeLayouts {
+			if t, err := time.Parse(layout, d.Published); err == nil {
+				deal.PostedAt = t
+				break
+			}
+		}
+		deals = append(deals, deal)
+	}
+	return deals
+}
+
 // Check deal drop percent i.e. check if the price drop is greater than 'target' percent
 func (s *CamCamCamScraper) IsTargetDropGreater(deal *models.CamCamCamDeal, target int) bool {
-	re, err := regexp.Compile(`down\s*(?P<gdrop>(\d+))\.(.*%)`)
-	if err != nil {
-		s.Logger.Error("Error checking target drop percentage", zap.Error(err))
+	percentageDrop, ok := dropPercent(deal.Title)
+	if !ok {
 		return false
 	}
 
+	// Check if drop is greater than target
+	return percentageDrop >= target
+}
+
+// dropPercent finds the whole percentage drop in a deal title such as
+// "... - down 27.85% ($584.52) to $1,514.48 from $2,099.00"
+func dropPercent(title string) (int, bool) {
+	re := regexp.MustCompile(`down\s*(?P<gdrop>(\d+))\.(.*%)`)
+
 	// Find drop percentage in deal title
 	var drop string
-	match := re.FindStringSubmatch(deal.Title)
+	match := re.FindStringSubmatch(title)
+	if match == nil {
+		return 0, false
+	}
 	for i, name := range re.SubexpNames() {
 		if i != 0 && name == "gdrop" {
 			drop = match[i]
@@ -181,15 +253,9 @@ func (s *CamCamCamScraper) IsTargetDropGreater(deal *models.CamCamCamDeal, targe
 	// Convert drop from string to int
 	percentageDrop, err := strconv.Atoi(drop)
 	if err != nil {
-		return false
+		return 0, false
 	}
-
-	// Check if drop is greater than target
-	if percentageDrop >= target {
-		return true
-	}
-
-	return false
+	return percentageDrop, true
 }
 
 // Get deal drop string
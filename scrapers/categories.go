@@ -0,0 +1,25 @@
+package scrapers
+
+import (
+	"slices"
+	"strings"
+)
+
+// OzBargain deal categories, as shown on the site
+var OzbCategories = []string{
+	"Alcohol", "Automotive", "Books & Magazines", "Computing", "Dining & Takeaway",
+	"Education", "Electrical & Electronics", "Entertainment", "Fashion & Apparel",
+	"Financial", "Gaming", "Groceries", "Health & Beauty", "Home & Garden",
+	"Internet", "Mobile", "Pets", "Sports & Outdoors", "Toys & Kids", "Travel", "Other",
+}
+
+// CanonicalCategory returns a known OzBargain category spelled as the site
+// does, or name trimmed if it is not a category, e.g. a tag.
+func CanonicalCategory(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	i := slices.IndexFunc(OzbCategories, func(c string) bool { return strings.EqualFold(c, name) })
+	if i < 0 {
+		return name
+	}
+	return OzbCategories[i]
+}
@@ -5,3 +5,9 @@ const NUM_DEALS_TO_SEND = 5
 
 // Ozbargain base url
 const URL_OZBARGAIN = "https://www.ozbargain.com.au/"
+
+// Source registry names
+const (
+	SOURCE_OZBARGAIN = "ozbargain"
+	SOURCE_AMAZON    = "amazon"
+)
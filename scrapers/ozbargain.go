@@ -9,11 +9,15 @@ import (
 
 	"github.com/gocolly/colly"
 	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/util"
 	"go.uber.org/zap"
 )
 
 var SID_OZBARGAIN ScraperID = 0
 
+// time format as scraped from ozbargain
+const ozbTimeLayout = "02/01/2006 - 15:04"
+
 // Ozbargain scraper
 type OzBargainScraper struct {
 	BaseUrl         string
@@ -22,6 +26,31 @@ type OzBargainScraper struct {
 	SID             ScraperID // Scraper ID
 	ScrapeInterval  int       // Scrape interval
 	MaxDealsToStore int       // Max. no. of deals to have in memory
+
+	// Enrich fetches each deal's page for the details the listing lacks.
+	// Details are cached per deal while the deal is held.
+	Enrich      bool
+	DetailDelay time.Duration // pause between deal page fetches
+	details     map[string]models.OzBargainDetails
+
+	votes map[string][]models.VoteSnapshot // recent vote counts per deal ID
+}
+
+// Ensure OzBargainScraper can be registered as a deal source at compile time.
+var _ Source = (*OzBargainScraper)(nil)
+
+// Create an OzBargain scraper from its configuration
+func NewOzBargainScraper(cfg util.OzBargainConfig, logger *zap.Logger) *OzBargainScraper {
+	return &OzBargainScraper{
+		SID:             SID_OZBARGAIN,
+		Logger:          logger,
+		BaseUrl:         URL_OZBARGAIN,
+		Deals:           []models.OzBargainDeal{},
+		ScrapeInterval:  cfg.ScrapeInterval,
+		MaxDealsToStore: cfg.MaxStoredDeals,
+		Enrich:          cfg.Enrich,
+		DetailDelay:     detailFetchDelay,
+	}
 }
 
 // Check initialisation
@@ -77,6 +106,9 @@ func (s *OzBargainScraper) Scrape() error {
 		// get the deal upvotes
 		upVotes := e.ChildText(".n-left .n-vote.n-deal.inact .nvb.voteup")
 
+		// get the deal category
+		category := strings.TrimSpace(e.ChildText(`.n-right .taxonomy a[href^="/cat/"]`))
+
 		// populate the deal
 		deal := models.OzBargainDeal{
 			Id:       dealID,
@@ -85,6 +117,8 @@ func (s *OzBargainScraper) Scrape() error {
 			PostedOn: postedOn,
 			Upvotes:  upVotes,
 			DealAge:  s.GetDealAge(postedOn).String(),
+			Category: category,
+			Expiry:   parseOzbExpiryTag(e, ".n-right h2.title"),
 		}
 		// Classify now so the API can filter by DealType.
 		deal.DealType = s.GetDealType(deal)
@@ -109,6 +143,12 @@ func (s *OzBargainScraper) Scrape() error {
 		s.Deals = s.Deals[len(s.Deals)-s.MaxDealsToStore:]
 	}
 
+	s.recordVotes(s.Deals, time.Now())
+
+	if s.Enrich {
+		s.enrich(s.Deals)
+	}
+
 	return nil
 }
 
@@ -119,15 +159,12 @@ func (s *OzBargainScraper) GetDealAge(postedOn string) time.Duration {
 	re := regexp.MustCompile(`[\d\/]+\s*\-\s*[\d:]+`)
 	dealTimestamp := re.FindString(postedOn)
 
-	// time format as scraped from ozbargain
-	const layout = "02/01/2006 - 15:04"
-
-	tmts, err := time.Parse(layout, dealTimestamp)
+	tmts, err := time.Parse(ozbTimeLayout, dealTimestamp)
 	if err != nil {
 		s.Logger.Error("Error parsing time", zap.Error(err))
 	}
 
-	tmnow, err := time.Parse(layout, time.Now().Format(layout))
+	tmnow, err := time.Parse(ozbTimeLayout, time.Now().Format(ozbTimeLayout))
 	if err != nil {
 		s.Logger.Error("Error parsing time", zap.Error(err))
 	}
@@ -136,7 +173,8 @@ func (s *OzBargainScraper) GetDealAge(postedOn string) time.Duration {
 	return tmnow.Sub(tmts)
 }
 
-// GetDealType classifies a deal as a top deal or a regular deal.
+// GetDealType classifies a deal as a top deal or a regular deal, using the
+// site-wide rule. Users' top deal subscriptions apply their own rules.
 // Top deal: 25+ upvotes within 24 hours (OZB_SUPER).
 // Regular:  everything else (OZB_REG).
 func (s *OzBargainScraper) GetDealType(deal models.OzBargainDeal) int {
@@ -156,7 +194,7 @@ func (s *OzBargainScraper) GetDealType(deal models.OzBargainDeal) int {
 	}
 
 	// 25+ upvotes within 24 hours → top deal
-	if duration.Hours() < 24.0 && upvotesInt >= 25 {
+	if models.DefaultTopDealRule.IsTop(upvotesInt, duration) {
 		return int(OZB_SUPER)
 	}
 
@@ -209,3 +247,67 @@ func (s *OzBargainScraper) AutoScrape() {
 func (s *OzBargainScraper) GetData() []models.OzBargainDeal {
 	return s.Deals
 }
+
+// Name returns the registry name of the source
+func (s *OzBargainScraper) Name() string {
+	return SOURCE_OZBARGAIN
+}
+
+// DisplayName returns the human readable name of the source
+func (s *OzBargainScraper) DisplayName() string {
+	return "OzBargain"
+}
+
+// Interval returns the time between scrapes
+func (s *OzBargainScraper) Interval() time.Duration {
+	return time.Minute * time.Duration(s.ScrapeInterval)
+}
+
+// GetDeals returns the cached deals in the common deal model
+func (s *OzBargainScraper) GetDeals() []models.Deal {
+	deals := make([]models.Deal, 0, len(s.Deals))
+	for _, d := range s.Deals {
+		deal := models.Deal{
+			Id:       d.Id,
+			Source:   SOURCE_OZBARGAIN,
+			Title:    d.Title,
+			Url:      d.Url,
+			PostedOn: d.PostedOn,
+			PostedAt: parsePostedOn(d.PostedOn),
+			Upvotes:  d.Upvotes,
+			DealAge:  d.DealAge,
+			DealType: d.DealType,
+			Category: d.Category,
+			Velocity: d.Velocity,
+			Expiry:   d.Expiry,
+		}
+		if det := d.Details; det != nil {
+			deal.Price = det.Price
+			deal.StoreDomain = det.StoreDomain
+			deal.CouponCode = det.CouponCode
+			if det.Category != "" {
+				deal.Category = det.Category
+			}
+			deal.Tags = det.Tags
+			deal.ExpiresAt = det.ExpiresAt
+			deal.Poster = det.Poster
+			deal.Image = det.Thumbnail
+			if deal.Expiry == "" {
+				deal.Expiry = det.Expiry
+			}
+		}
+		deals = append(deals, deal)
+	}
+	return deals
+}
+
+// parsePostedOn extracts the posting time from the scraped 'submitted' text,
+// e.g. "Neoika on 15/05/2022 - 14:38 kogan.com". Returns the zero time if absent.
+func parsePostedOn(postedOn string) time.Time {
+	re := regexp.MustCompile(`[\d\/]+\s*\-\s*[\d:]+`)
+	t, err := time.ParseInLocation(ozbTimeLayout, re.FindString(postedOn), time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,190 @@
+package scrapers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly"
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+// Most deal pages fetched per scrape. Deals left over are enriched on the
+// next scrape, so a cold start doesn't hit the site hundreds of times at once.
+const maxDetailFetches = 20
+
+// Pause between deal page fetches
+const detailFetchDelay = time.Second
+
+// expiry formats shown on OzBargain deal pages
+var ozbExpiryLayouts = []string{"2 Jan 2006 3:04pm", "2 Jan 2006 15:04", ozbTimeLayout}
+
+// expiry formats without a time, for deals that last until the end of the day
+var ozbExpiryDateLayouts = []string{"2 Jan 2006", "02/01/2006"}
+
+var (
+	ozbExpiryRe = regexp.MustCompile(`\d{1,2} [A-Za-z]{3} \d{4}(?: \d{1,2}:\d{2}(?:[ap]m)?)?|\d{2}/\d{2}/\d{4}(?: - \d{2}:\d{2})?`)
+	ozbPriceRe  = regexp.MustCompile(`\d[\d,]*(?:\.\d+)?`)
+)
+
+// enrich adds deal page details to the deals, fetching the pages of deals
+// not in the cache yet. Cached details of deals no longer held are dropped.
+func (s *OzBargainScraper) enrich(deals []models.OzBargainDeal) {
+	if s.details == nil {
+		s.details = make(map[string]models.OzBargainDetails)
+	}
+
+	fetched := 0
+	for i := range deals {
+		id := deals[i].Id
+		d, ok := s.details[id]
+		if !ok {
+			if fetched == maxDetailFetches {
+				continue
+			}
+			if fetched > 0 {
+				time.Sleep(s.DetailDelay)
+			}
+			fetched++
+
+			var err error
+			d, err = s.FetchDetails(s.nodeURL(id))
+			if err != nil {
+				// Not cached, so the page is tried again on the next scrape
+				s.Logger.Warn("Failed to fetch deal page", zap.String("id", id), zap.Error(err))
+				continue
+			}
+			s.details[id] = d
+		}
+		deals[i].Details = &d
+	}
+
+	held := make(map[string]bool, len(deals))
+	for _, d := range deals {
+		held[d.Id] = true
+	}
+	for id := range s.details {
+		if !held[id] {
+			delete(s.details, id)
+		}
+	}
+}
+
+// nodeURL is the deal page of a deal ID.
+func (s *OzBargainScraper) nodeURL(id string) string {
+	return strings.TrimSuffix(s.BaseUrl, "/") + "/node/" + id
+}
+
+// FetchDetails scrapes an OzBargain deal page.
+func (s *OzBargainScraper) FetchDetails(url string) (models.OzBargainDetails, error) {
+	var d models.OzBargainDetails
+	found := false
+
+	c := colly.NewCollector()
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		if e.DOM.Find(".node-ozbdeal").Length() == 0 {
+			return
+		}
+		found = true
+		d = parseOzbDetails(e)
+	})
+	if err := c.Visit(url); err != nil {
+		return d, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	if !found {
+		return d, fmt.Errorf("no deal found at %s", url)
+	}
+	d.FetchedAt = time.Now()
+	return d, nil
+}
+
+// parseOzbDetails reads the details from a deal page.
+func parseOzbDetails(e *colly.HTMLElement) models.OzBargainDetails {
+	node := e.DOM.Find(".node-ozbdeal").First()
+	submitted := node.Find(".submitted").First()
+
+	d := models.OzBargainDetails{
+		StoreDomain: strings.TrimSpace(submitted.Find(".via a").First().Text()),
+		CouponCode:  strings.TrimSpace(node.Find(".couponcode strong").First().Text()),
+		Poster:      strings.TrimSpace(submitted.Find(`a[href^="/user/"]`).First().Text()),
+	}
+	if price, ok := parseOzbPrice(node.Find("h1.title em.dealprice").First().Text()); ok {
+		d.Price = &price
+	}
+	d.Category = strings.TrimSpace(node.Find(`.taxonomy a[href^="/cat/"]`).First().Text())
+	e.ForEach(`.node-ozbdeal .taxonomy a[href^="/tag/"]`, func(_ int, tag *colly.HTMLElement) {
+		if t := strings.TrimSpace(tag.Text); t != "" {
+			d.Tags = append(d.Tags, t)
+		}
+	})
+	d.ExpiresAt = parseOzbExpiry(node.Find(".nodeexpiry").First().Text())
+	d.Expiry = parseOzbExpiryTag(e, ".node-ozbdeal h1.title")
+
+	d.Thumbnail = e.ChildAttr(`meta[property="og:image"]`, "content")
+	if d.Thumbnail == "" {
+		d.Thumbnail, _ = node.Find(".foxshot-container img").First().Attr("src")
+	}
+	return d
+}
+
+// parseOzbExpiryTag returns DealExpired or DealOutOfStock if the deal title
+// matched by title carries OzBargain's "expired" or "out of stock" tag.
+func parseOzbExpiryTag(e *colly.HTMLElement, title string) string {
+	switch {
+	case e.DOM.Find(title+" .tagger.expired").Length() > 0:
+		return models.DealExpired
+	case e.DOM.Find(title+" .tagger.outofstock").Length() > 0:
+		return models.DealOutOfStock
+	}
+	return ""
+}
+
+// CheckExpiry fetches a deal's page and returns its expiry state, "" if the
+// deal is still available.
+func (s *OzBargainScraper) CheckExpiry(deal models.Deal) (string, error) {
+	d, err := s.FetchDetails(s.nodeURL(deal.Id))
+	if err != nil {
+		return "", err
+	}
+	if d.Expiry == "" && !d.ExpiresAt.IsZero() && !d.ExpiresAt.After(time.Now()) {
+		return models.DealExpired, nil
+	}
+	return d.Expiry, nil
+}
+
+// parseOzbPrice parses a price such as "$1,299.95". "Free" is a price of 0.
+func parseOzbPrice(text string) (float64, bool) {
+	if strings.EqualFold(strings.TrimSpace(text), "free") {
+		return 0, true
+	}
+	price, err := strconv.ParseFloat(strings.ReplaceAll(ozbPriceRe.FindString(text), ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+	return price, true
+}
+
+// parseOzbExpiry finds the expiry time in text such as "15/05/2022 - 31 May
+// 2022 11:59pm", the last date shown. A date without a time expires at the end
+// of that day. Returns the zero time if there is none.
+func parseOzbExpiry(text string) time.Time {
+	matches := ozbExpiryRe.FindAllString(text, -1)
+	if len(matches) == 0 {
+		return time.Time{}
+	}
+	match := matches[len(matches)-1]
+	for _, layout := range ozbExpiryLayouts {
+		if t, err := time.ParseInLocation(layout, match, time.Local); err == nil {
+			return t
+		}
+	}
+	for _, layout := range ozbExpiryDateLayouts {
+		if t, err := time.ParseInLocation(layout, match, time.Local); err == nil {
+			return t.AddDate(0, 0, 1).Add(-time.Second)
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,180 @@
+package scrapers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/scrapers"
+	"go.uber.org/zap"
+)
+
+// ozbFixtures serves the saved OzBargain pages in testdata/ozbargain and
+// counts the deal page requests.
+type ozbFixtures struct {
+	mu    sync.Mutex
+	nodes map[string]int
+}
+
+func (f *ozbFixtures) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := path.Clean(r.URL.Path)
+	name := "deals.html"
+	if id, ok := strings.CutPrefix(p, "/node/"); ok {
+		f.mu.Lock()
+		f.nodes[id]++
+		f.mu.Unlock()
+		name = "node_" + id + ".html"
+	} else if p != "/deals" {
+		http.NotFound(w, r)
+		return
+	}
+	page, err := os.ReadFile(filepath.Join("testdata", "ozbargain", name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+func (f *ozbFixtures) fetches(id string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.nodes[id]
+}
+
+func newFixtureScraper(t *testing.T) (*scrapers.OzBargainScraper, *ozbFixtures) {
+	t.Helper()
+	fixtures := &ozbFixtures{nodes: make(map[string]int)}
+	srv := httptest.NewServer(fixtures)
+	t.Cleanup(srv.Close)
+	return &scrapers.OzBargainScraper{
+		BaseUrl:         srv.URL + "/",
+		Logger:          zap.NewNop(),
+		ScrapeInterval:  5,
+		MaxDealsToStore: 100,
+		Enrich:          true,
+	}, fixtures
+}
+
+func dealByID(deals []models.Deal, id string) *models.Deal {
+	for i := range deals {
+		if deals[i].Id == id {
+			return &deals[i]
+		}
+	}
+	return nil
+}
+
+func TestOzBargainScraper_Enrich(t *testing.T) {
+	s, fixtures := newFixtureScraper(t)
+	if err := s.Scrape(); err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+	deals := s.GetDeals()
+	if len(deals) != 3 {
+		t.Fatalf("got %d deals, want 3", len(deals))
+	}
+
+	tv := dealByID(deals, "101")
+	if tv == nil || tv.Price == nil || *tv.Price != 1299.95 {
+		t.Fatalf("deal 101 price = %+v, want 1299.95", tv)
+	}
+	if tv.StoreDomain != "jbhifi.com.au" || tv.CouponCode != "OLED100" || tv.Poster != "kramer" {
+		t.Errorf("deal 101 store %q, coupon %q, poster %q", tv.StoreDomain, tv.CouponCode, tv.Poster)
+	}
+	if tv.Category != "Electrical & Electronics" || strings.Join(tv.Tags, ",") != "OLED,LG,TV" {
+		t.Errorf("deal 101 category %q, tags %v", tv.Category, tv.Tags)
+	}
+	if want := time.Date(2022, 5, 31, 23, 59, 0, 0, time.Local); !tv.ExpiresAt.Equal(want) {
+		t.Errorf("deal 101 expires %v, want %v", tv.ExpiresAt, want)
+	}
+	if tv.Image != "https://files.ozbargain.com.au/n/01/101.jpg" {
+		t.Errorf("deal 101 image %q, want the og:image", tv.Image)
+	}
+
+	game := dealByID(deals, "102")
+	if game == nil || game.Price == nil || *game.Price != 0 {
+		t.Fatalf("deal 102 = %+v, want a free deal", game)
+	}
+	if game.CouponCode != "" || game.Category != "Gaming" || len(game.Tags) != 0 {
+		t.Errorf("deal 102 coupon %q, category %q, tags %v", game.CouponCode, game.Category, game.Tags)
+	}
+	if want := time.Date(2022, 5, 23, 23, 59, 59, 0, time.Local); !game.ExpiresAt.Equal(want) {
+		t.Errorf("deal 102 expires %v, want the end of the day", game.ExpiresAt)
+	}
+	if game.Image != "https://files.ozbargain.com.au/n/02/102.thumb.jpg" {
+		t.Errorf("deal 102 image %q, want the deal thumbnail", game.Image)
+	}
+
+	// A deal whose page can't be fetched keeps its listing fields
+	missing := dealByID(deals, "103")
+	if missing == nil || missing.Title != "Deal whose page went missing" || missing.Category != "Computing" ||
+		missing.Price != nil || missing.Poster != "" {
+		t.Errorf("deal 103 = %+v, want listing fields only", missing)
+	}
+
+	// Expiry tags come from the listing, or the deal page if the listing has none
+	if tv.Expiry != "" || game.Expiry != models.DealExpired || missing.Expiry != models.DealOutOfStock {
+		t.Errorf("expiry = %q, %q, %q, want \"\", expired, out of stock", tv.Expiry, game.Expiry, missing.Expiry)
+	}
+
+	// Cached deal pages are not fetched again; failed ones are retried
+	if err := s.Scrape(); err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+	if n := fixtures.fetches("101"); n != 1 {
+		t.Errorf("deal 101 page fetched %d times, want 1", n)
+	}
+	if n := fixtures.fetches("103"); n != 2 {
+		t.Errorf("deal 103 page fetched %d times, want 2", n)
+	}
+	if tv := dealByID(s.GetDeals(), "101"); tv == nil || tv.CouponCode != "OLED100" {
+		t.Errorf("deal 101 lost its details on the second scrape: %+v", tv)
+	}
+}
+
+func TestOzBargainScraper_NoEnrich(t *testing.T) {
+	s, fixtures := newFixtureScraper(t)
+	s.Enrich = false
+	if err := s.Scrape(); err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+	if n := fixtures.fetches("101"); n != 0 {
+		t.Errorf("deal page fetched %d times with enrichment off", n)
+	}
+	if tv := dealByID(s.GetDeals(), "101"); tv == nil || tv.Price != nil || tv.Poster != "" ||
+		tv.Category != "Electrical & Electronics" {
+		t.Errorf("deal 101 = %+v, want listing fields only", tv)
+	}
+}
+
+func TestOzBargainScraper_CheckExpiry(t *testing.T) {
+	s, fixtures := newFixtureScraper(t)
+
+	tests := []struct {
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{id: "101", want: models.DealExpired}, // its expiry date has passed
+		{id: "102", want: models.DealExpired}, // tagged expired
+		{id: "103", wantErr: true},            // no deal page
+	}
+	for _, tt := range tests {
+		got, err := s.CheckExpiry(models.Deal{Id: tt.id, Source: scrapers.SOURCE_OZBARGAIN})
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("CheckExpiry(%s) = %q, %v, want %q, error %t", tt.id, got, err, tt.want, tt.wantErr)
+		}
+		if n := fixtures.fetches(tt.id); n != 1 {
+			t.Errorf("deal %s page fetched %d times, want 1", tt.id, n)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package scrapers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
+// recordVotes adds a vote snapshot for each deal whose vote count changed
+// since the last scrape, and sets the deals' vote velocity. Snapshots older
+// than the velocity window needs, and those of deals no longer held, are
+// dropped.
+func (s *OzBargainScraper) recordVotes(deals []models.OzBargainDeal, now time.Time) {
+	if s.votes == nil {
+		s.votes = make(map[string][]models.VoteSnapshot)
+	}
+
+	held := make(map[string]bool, len(deals))
+	for i := range deals {
+		d := &deals[i]
+		held[d.Id] = true
+		votes, err := strconv.Atoi(d.Upvotes)
+		if err != nil {
+			continue
+		}
+
+		history := s.votes[d.Id]
+		if n := len(history); n == 0 || history[n-1].Votes != votes {
+			history = append(history, models.VoteSnapshot{Votes: votes, RecordedAt: now})
+		}
+		history = trimVotes(history, now.Add(-models.VelocityWindow))
+		s.votes[d.Id] = history
+
+		age, _ := time.ParseDuration(d.DealAge)
+		if posted := parsePostedOn(d.PostedOn); !posted.IsZero() {
+			age = now.Sub(posted)
+		}
+		d.Velocity = models.VoteVelocity(history, votes, age, now)
+	}
+
+	for id := range s.votes {
+		if !held[id] {
+			delete(s.votes, id)
+		}
+	}
+}
+
+// trimVotes drops the snapshots before start, except the last one, which
+// holds the vote count at start.
+func trimVotes(history []models.VoteSnapshot, start time.Time) []models.VoteSnapshot {
+	i := 0
+	for i+1 < len(history) && !history[i+1].RecordedAt.After(start) {
+		i++
+	}
+	return history[i:]
+}
@@ -0,0 +1,61 @@
+package scrapers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"go.uber.org/zap"
+)
+
+func TestOzBargainScraper_recordVotes(t *testing.T) {
+	s := &OzBargainScraper{Logger: zap.NewNop()}
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	posted := start.Add(-3 * time.Hour).Format(ozbTimeLayout)
+
+	// An old deal picking up votes, and a deal posted 30 minutes ago
+	scrape := func(at time.Duration, old, young string) []models.OzBargainDeal {
+		deals := []models.OzBargainDeal{
+			{Id: "1", Upvotes: old, PostedOn: "kramer on " + posted},
+			{Id: "2", Upvotes: young, DealAge: (30*time.Minute + at).String()},
+		}
+		s.recordVotes(deals, start.Add(at))
+		return deals
+	}
+
+	deals := scrape(0, "40", "6")
+	if deals[0].Velocity != 0 {
+		t.Errorf("first scrape velocity = %v, want 0 without history", deals[0].Velocity)
+	}
+	if deals[1].Velocity != 12 {
+		t.Errorf("young deal velocity = %v, want 12 (6 votes in 30 minutes)", deals[1].Velocity)
+	}
+
+	scrape(20*time.Minute, "45", "9")
+	deals = scrape(40*time.Minute, "50", "15")
+	if deals[0].Velocity != 15 {
+		t.Errorf("velocity = %v, want 15 (10 votes in 40 minutes)", deals[0].Velocity)
+	}
+	// Now older than the window, so measured from the first scrape
+	if deals[1].Velocity != 13.5 {
+		t.Errorf("deal 2 velocity = %v, want 13.5 (9 votes in 40 minutes)", deals[1].Velocity)
+	}
+
+	// Two hours on, only the count an hour ago is needed
+	deals = scrape(2*time.Hour, "62", "15")
+	if deals[0].Velocity != 12 {
+		t.Errorf("velocity = %v, want 12 (12 votes in the last hour)", deals[0].Velocity)
+	}
+	if deals[1].Velocity != 0 {
+		t.Errorf("stalled deal velocity = %v, want 0", deals[1].Velocity)
+	}
+	if n := len(s.votes["1"]); n != 2 {
+		t.Errorf("kept %d snapshots, want 2: %+v", n, s.votes["1"])
+	}
+
+	// Deals dropped from the listing lose their history
+	s.recordVotes([]models.OzBargainDeal{{Id: "2", Upvotes: "15"}}, start.Add(3*time.Hour))
+	if _, ok := s.votes["1"]; ok {
+		t.Error("history kept for a deal no longer held")
+	}
+}
@@ -0,0 +1,95 @@
+package scrapers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+	"github.com/intothevoid/kramerbot/util"
+	"go.uber.org/zap"
+)
+
+// Registry holds every deal source the bot scrapes, in registration order.
+type Registry struct {
+	sources []Source
+	byName  map[string]Source
+}
+
+// Create an empty source registry
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]Source)}
+}
+
+// Create a registry with every source enabled in the configuration.
+// New deal sites are added here (and to util.ScrapersConfig) only.
+func NewRegistryFromConfig(cfg *util.Config, logger *zap.Logger) (*Registry, error) {
+	r := NewRegistry()
+
+	if cfg.Scrapers.OzBargain.Enabled {
+		if err := r.Register(NewOzBargainScraper(cfg.Scrapers.OzBargain, logger)); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Scrapers.Amazon.Enabled {
+		if err := r.Register(NewCamCamCamScraper(cfg.Scrapers.Amazon, logger)); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Register adds a source. Source names must be unique.
+func (r *Registry) Register(s Source) error {
+	if _, exists := r.byName[s.Name()]; exists {
+		return fmt.Errorf("source %q is already registered", s.Name())
+	}
+	r.sources = append(r.sources, s)
+	r.byName[s.Name()] = s
+	return nil
+}
+
+// Get returns the source registered under name, or nil.
+func (r *Registry) Get(name string) Source {
+	if r == nil {
+		return nil
+	}
+	return r.byName[name]
+}
+
+// Sources returns all registered sources in registration order.
+func (r *Registry) Sources() []Source {
+	if r == nil {
+		return nil
+	}
+	return r.sources
+}
+
+// AllDeals returns the cached deals of every registered source.
+func (r *Registry) AllDeals() []models.Deal {
+	var deals []models.Deal
+	for _, s := range r.Sources() {
+		deals = append(deals, s.GetDeals()...)
+	}
+	return deals
+}
+
+// TopDeals returns the top deals (see DealType.IsTop) posted within maxAge,
+// sorted by upvotes descending. Deals without a posting time, and expired or
+// out of stock deals, are skipped.
+func TopDeals(deals []models.Deal, maxAge time.Duration) []models.Deal {
+	now := time.Now()
+	cutoff := now.Add(-maxAge)
+	var top []models.Deal
+	for _, d := range deals {
+		if DealType(d.DealType).IsTop() && !d.PostedAt.IsZero() && d.PostedAt.After(cutoff) && !d.IsExpired(now) {
+			top = append(top, d)
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool {
+		return top[i].Votes() > top[j].Votes()
+	})
+	return top
+}
@@ -1,5 +1,11 @@
 package scrapers
 
+import (
+	"time"
+
+	"github.com/intothevoid/kramerbot/models"
+)
+
 // Deal types
 type DealType int
 
@@ -12,11 +18,45 @@ const (
 	AMZ_WEEKLY
 )
 
+// Short names accepted by the API ?type= filter
+var dealTypeNames = map[string]DealType{
+	"reg":    OZB_REG,
+	"super":  OZB_SUPER,
+	"good":   OZB_GOOD,
+	"daily":  AMZ_DAILY,
+	"weekly": AMZ_WEEKLY,
+}
+
+// DealTypeFromName returns the deal type for a short name such as "super" or "daily".
+func DealTypeFromName(name string) (DealType, bool) {
+	dt, ok := dealTypeNames[name]
+	return dt, ok
+}
+
+// IsTop reports whether the deal type marks a site's best deals, i.e. the ones
+// featured in the daily summary.
+func (dt DealType) IsTop() bool {
+	return dt == OZB_SUPER || dt == AMZ_DAILY
+}
+
 // Scraper type
 type ScraperID int
 
-type Scraper interface {
-	Scrape()
-	AutoScrape()
-	GetData() interface{}
+// Source is a deal site that can be registered with the bot. The bot loop,
+// API and daily summary only talk to sources through this interface.
+type Source interface {
+	Name() string            // unique registry key, e.g. "ozbargain"
+	DisplayName() string     // human readable name, e.g. "OzBargain"
+	Scrape() error           // refresh the in-memory deal cache
+	AutoScrape()             // scrape now and then every Interval()
+	Interval() time.Duration // time between scrapes
+	GetDeals() []models.Deal // cached deals in the common model
+}
+
+// ExpiryChecker is implemented by sources that can tell whether a deal has
+// expired since it was scraped, so deals already sent can be followed up.
+type ExpiryChecker interface {
+	// CheckExpiry returns models.DealExpired or models.DealOutOfStock, or ""
+	// if the deal is still available.
+	CheckExpiry(deal models.Deal) (string, error)
 }
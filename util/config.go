@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -19,6 +20,43 @@ type Config struct {
 	Pipup     PipupConfig
 	API       APIConfig
 	SMTP      SMTPConfig
+	Outbox    OutboxConfig
+	Telegram  TelegramConfig
+
+	// JSONL file test mode appends the notifications it would have sent to
+	TestModeFile string `mapstructure:"test_mode_file"`
+}
+
+// TelegramConfig holds Telegram options beyond the bot token.
+type TelegramConfig struct {
+	// Channel OzBargain top deals are broadcast to, as @username or chat ID.
+	// The bot must be an admin of the channel. Empty disables broadcasting.
+	Channel string `mapstructure:"channel"`
+	// Chat IDs of the bot's admins, who can use /stats, /broadcast and the
+	// other admin commands. Users can also be given the admin role in the
+	// users table.
+	Admins []int64 `mapstructure:"admins"`
+	// Receive updates through a webhook on the API server instead of polling.
+	Webhook WebhookConfig `mapstructure:"webhook"`
+}
+
+// WebhookConfig holds the Telegram webhook options. Telegram posts updates to
+// URL, which must reach Path on the API server, with Secret in the
+// X-Telegram-Bot-Api-Secret-Token header.
+type WebhookConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	URL     string `mapstructure:"url"`  // public URL; defaults to api.web_url + path
+	Path    string `mapstructure:"path"` // route on the API server
+	Secret  string `mapstructure:"secret"`
+}
+
+// OutboxConfig tunes the persistent Telegram send queue. Telegram allows
+// about 30 messages per second overall and one per second in a single chat.
+type OutboxConfig struct {
+	Workers        int `mapstructure:"workers"`
+	GlobalRate     int `mapstructure:"global_rate"`      // messages per second across all chats
+	ChatIntervalMs int `mapstructure:"chat_interval_ms"` // minimum gap between messages to one chat
+	MaxAttempts    int `mapstructure:"max_attempts"`     // failed sends before a message is dead-lettered
 }
 
 // SMTPConfig holds outbound email (STARTTLS) configuration.
@@ -38,27 +76,54 @@ type APIConfig struct {
 	CORSOrigins     []string `mapstructure:"cors_origins"`
 	JWTExpiryHours  int      `mapstructure:"jwt_expiry_hours"`
 	SummaryTimezone string   `mapstructure:"summary_timezone"`
+	// Let users' webhooks reach localhost and private network addresses,
+	// for receivers on the bot's own network.
+	AllowPrivateWebhooks bool `mapstructure:"allow_private_webhooks"`
 }
 
 // SQLiteConfig holds SQLite database configuration
 type SQLiteConfig struct {
-	DBPath string `mapstructure:"db_path"`
+	DBPath           string `mapstructure:"db_path"`
+	SentDealsTTLDays int    `mapstructure:"sent_deals_ttl_days"` // how long sent deals are remembered
 }
 
-// ScrapersConfig holds configuration for all scrapers
+// ScrapersConfig holds configuration for all scrapers.
+// Every enabled scraper is registered as a deal source at startup.
 type ScrapersConfig struct {
 	OzBargain OzBargainConfig `mapstructure:"ozbargain"`
 	Amazon    AmazonConfig    `mapstructure:"amazon"`
+
+	ExpiryCheck ExpiryCheckConfig `mapstructure:"expiry_check"`
+}
+
+// ExpiryCheckConfig controls how deals already sent are re-checked for expiry
+// on sources that support it.
+type ExpiryCheckConfig struct {
+	Interval int `mapstructure:"interval"` // minutes between checks; 0 turns checking off
+	Days     int `mapstructure:"days"`     // how long after being sent a deal is checked
 }
 
 // OzBargainConfig holds OzBargain scraper configuration
 type OzBargainConfig struct {
-	ScrapeInterval int `mapstructure:"scrape_interval"`
-	MaxStoredDeals int `mapstructure:"max_stored_deals"`
+	Enabled        bool `mapstructure:"enabled"`
+	ScrapeInterval int  `mapstructure:"scrape_interval"`
+	MaxStoredDeals int  `mapstructure:"max_stored_deals"`
+	Enrich         bool `mapstructure:"enrich"` // fetch each deal's page for price, store, coupon, etc.
+
+	Trending TrendingConfig `mapstructure:"trending"`
+}
+
+// TrendingConfig decides which OzBargain deals are trending: young deals
+// gaining votes quickly, before they become top deals.
+type TrendingConfig struct {
+	MinVelocity float64 `mapstructure:"min_velocity"`  // votes per hour; 0 turns trending alerts off
+	MinVotes    int     `mapstructure:"min_votes"`     // so a deal's first couple of votes don't count
+	MaxAgeHours int     `mapstructure:"max_age_hours"` // older deals are no longer "early"
 }
 
 // AmazonConfig holds Amazon scraper configuration
 type AmazonConfig struct {
+	Enabled         bool     `mapstructure:"enabled"`
 	ScrapeInterval  int      `mapstructure:"scrape_interval"`
 	MaxStoredDeals  int      `mapstructure:"max_stored_deals"`
 	URLs            []string `mapstructure:"urls"`
@@ -89,14 +154,22 @@ func DefaultConfig() *Config {
 		LogToFile: true,
 		TestMode:  false,
 		SQLite: SQLiteConfig{
-			DBPath: "data/users.db",
+			DBPath:           "data/users.db",
+			SentDealsTTLDays: 30,
 		},
 		Scrapers: ScrapersConfig{
 			OzBargain: OzBargainConfig{
+				Enabled:        true,
 				ScrapeInterval: 5,
 				MaxStoredDeals: 250,
+				Trending: TrendingConfig{
+					MinVelocity: 10,
+					MinVotes:    5,
+					MaxAgeHours: 6,
+				},
 			},
 			Amazon: AmazonConfig{
+				Enabled:        true,
 				ScrapeInterval: 30,
 				MaxStoredDeals: 250,
 				URLs: []string{
@@ -105,6 +178,10 @@ func DefaultConfig() *Config {
 				},
 				TargetPriceDrop: 20,
 			},
+			ExpiryCheck: ExpiryCheckConfig{
+				Interval: 60,
+				Days:     3,
+			},
 		},
 		Pipup: PipupConfig{
 			Enabled:         false,
@@ -132,6 +209,19 @@ func DefaultConfig() *Config {
 			Port: 587,
 			From: "KramerBot <noreply@yourdomain.com>",
 		},
+		Outbox: OutboxConfig{
+			Workers:        4,
+			GlobalRate:     30,
+			ChatIntervalMs: 1000,
+			MaxAttempts:    5,
+		},
+		Telegram: TelegramConfig{
+			Webhook: WebhookConfig{
+				Path: "/telegram/updates",
+			},
+		},
+
+		TestModeFile: "data/test_notifications.jsonl",
 	}
 }
 
@@ -146,27 +236,41 @@ func ValidateConfig(config *Config) error {
 	if config.SQLite.DBPath == "" {
 		return fmt.Errorf("sqlite.db_path cannot be empty")
 	}
-
-	// Validate OzBargain config
-	if config.Scrapers.OzBargain.ScrapeInterval < 1 {
-		return fmt.Errorf("ozbargain.scrape_interval must be at least 1 minute")
-	}
-	if config.Scrapers.OzBargain.MaxStoredDeals < 1 {
-		return fmt.Errorf("ozbargain.max_stored_deals must be at least 1")
+	if config.SQLite.SentDealsTTLDays < 1 {
+		return fmt.Errorf("sqlite.sent_deals_ttl_days must be at least 1")
 	}
 
-	// Validate Amazon config
-	if config.Scrapers.Amazon.ScrapeInterval < 1 {
-		return fmt.Errorf("amazon.scrape_interval must be at least 1 minute")
-	}
-	if config.Scrapers.Amazon.MaxStoredDeals < 1 {
-		return fmt.Errorf("amazon.max_stored_deals must be at least 1")
+	// Validate OzBargain config if enabled
+	if config.Scrapers.OzBargain.Enabled {
+		if config.Scrapers.OzBargain.ScrapeInterval < 1 {
+			return fmt.Errorf("ozbargain.scrape_interval must be at least 1 minute")
+		}
+		if config.Scrapers.OzBargain.MaxStoredDeals < 1 {
+			return fmt.Errorf("ozbargain.max_stored_deals must be at least 1")
+		}
+		if t := config.Scrapers.OzBargain.Trending; t.MinVelocity < 0 || t.MinVotes < 0 || t.MaxAgeHours < 0 {
+			return fmt.Errorf("ozbargain.trending values cannot be negative")
+		}
 	}
-	if len(config.Scrapers.Amazon.URLs) == 0 {
-		return fmt.Errorf("amazon.urls cannot be empty")
+
+	if e := config.Scrapers.ExpiryCheck; e.Interval < 0 || e.Days < 0 {
+		return fmt.Errorf("scrapers.expiry_check values cannot be negative")
 	}
-	if config.Scrapers.Amazon.TargetPriceDrop < 0 {
-		return fmt.Errorf("amazon.target_price_drop cannot be negative")
+
+	// Validate Amazon config if enabled
+	if config.Scrapers.Amazon.Enabled {
+		if config.Scrapers.Amazon.ScrapeInterval < 1 {
+			return fmt.Errorf("amazon.scrape_interval must be at least 1 minute")
+		}
+		if config.Scrapers.Amazon.MaxStoredDeals < 1 {
+			return fmt.Errorf("amazon.max_stored_deals must be at least 1")
+		}
+		if len(config.Scrapers.Amazon.URLs) == 0 {
+			return fmt.Errorf("amazon.urls cannot be empty")
+		}
+		if config.Scrapers.Amazon.TargetPriceDrop < 0 {
+			return fmt.Errorf("amazon.target_price_drop cannot be negative")
+		}
 	}
 
 	// Validate Pipup config if enabled
@@ -188,9 +292,49 @@ func ValidateConfig(config *Config) error {
 		}
 	}
 
+	// Validate outbox config
+	if config.Outbox.Workers < 1 {
+		return fmt.Errorf("outbox.workers must be at least 1")
+	}
+	if config.Outbox.GlobalRate < 1 {
+		return fmt.Errorf("outbox.global_rate must be at least 1")
+	}
+	if config.Outbox.ChatIntervalMs < 0 {
+		return fmt.Errorf("outbox.chat_interval_ms cannot be negative")
+	}
+	if config.Outbox.MaxAttempts < 1 {
+		return fmt.Errorf("outbox.max_attempts must be at least 1")
+	}
+
+	// Validate Telegram webhook config if enabled
+	if config.Telegram.Webhook.Enabled {
+		if !config.API.Enabled {
+			return fmt.Errorf("telegram.webhook requires the API server (api.enabled)")
+		}
+		if !strings.HasPrefix(config.Telegram.Webhook.Path, "/") {
+			return fmt.Errorf("telegram.webhook.path must start with /")
+		}
+		if !isValidWebhookSecret(config.Telegram.Webhook.Secret) {
+			return fmt.Errorf("telegram.webhook.secret must be 1-256 characters of A-Z, a-z, 0-9, _ and -")
+		}
+	}
+
 	return nil
 }
 
+// isValidWebhookSecret checks a secret token against Telegram's rules
+func isValidWebhookSecret(secret string) bool {
+	if len(secret) < 1 || len(secret) > 256 {
+		return false
+	}
+	for _, c := range secret {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
 // isValidMediaType checks if the media type is valid
 func isValidMediaType(mediaType string) bool {
 	switch mediaType {
@@ -219,13 +363,23 @@ func SetupConfig(confPath string, logger *zap.Logger) (*Config, error) {
 	v.SetDefault("log_level", config.LogLevel)
 	v.SetDefault("log_to_file", config.LogToFile)
 	v.SetDefault("test_mode", config.TestMode)
+	v.SetDefault("test_mode_file", config.TestModeFile)
 	v.SetDefault("sqlite.db_path", config.SQLite.DBPath)
+	v.SetDefault("sqlite.sent_deals_ttl_days", config.SQLite.SentDealsTTLDays)
+	v.SetDefault("scrapers.ozbargain.enabled", config.Scrapers.OzBargain.Enabled)
 	v.SetDefault("scrapers.ozbargain.scrape_interval", config.Scrapers.OzBargain.ScrapeInterval)
 	v.SetDefault("scrapers.ozbargain.max_stored_deals", config.Scrapers.OzBargain.MaxStoredDeals)
+	v.SetDefault("scrapers.ozbargain.enrich", config.Scrapers.OzBargain.Enrich)
+	v.SetDefault("scrapers.ozbargain.trending.min_velocity", config.Scrapers.OzBargain.Trending.MinVelocity)
+	v.SetDefault("scrapers.ozbargain.trending.min_votes", config.Scrapers.OzBargain.Trending.MinVotes)
+	v.SetDefault("scrapers.ozbargain.trending.max_age_hours", config.Scrapers.OzBargain.Trending.MaxAgeHours)
+	v.SetDefault("scrapers.amazon.enabled", config.Scrapers.Amazon.Enabled)
 	v.SetDefault("scrapers.amazon.scrape_interval", config.Scrapers.Amazon.ScrapeInterval)
 	v.SetDefault("scrapers.amazon.max_stored_deals", config.Scrapers.Amazon.MaxStoredDeals)
 	v.SetDefault("scrapers.amazon.urls", config.Scrapers.Amazon.URLs)
 	v.SetDefault("scrapers.amazon.target_price_drop", config.Scrapers.Amazon.TargetPriceDrop)
+	v.SetDefault("scrapers.expiry_check.interval", config.Scrapers.ExpiryCheck.Interval)
+	v.SetDefault("scrapers.expiry_check.days", config.Scrapers.ExpiryCheck.Days)
 	v.SetDefault("pipup.enabled", config.Pipup.Enabled)
 	v.SetDefault("pipup.username", config.Pipup.Username)
 	v.SetDefault("pipup.base_url", config.Pipup.BaseURL)
@@ -245,11 +399,22 @@ func SetupConfig(confPath string, logger *zap.Logger) (*Config, error) {
 	v.SetDefault("api.cors_origins", config.API.CORSOrigins)
 	v.SetDefault("api.jwt_expiry_hours", config.API.JWTExpiryHours)
 	v.SetDefault("api.summary_timezone", "Australia/Adelaide")
+	v.SetDefault("api.allow_private_webhooks", config.API.AllowPrivateWebhooks)
 	v.SetDefault("smtp.host", config.SMTP.Host)
 	v.SetDefault("smtp.port", config.SMTP.Port)
 	v.SetDefault("smtp.username", config.SMTP.Username)
 	v.SetDefault("smtp.password", config.SMTP.Password)
 	v.SetDefault("smtp.from", config.SMTP.From)
+	v.SetDefault("outbox.workers", config.Outbox.Workers)
+	v.SetDefault("outbox.global_rate", config.Outbox.GlobalRate)
+	v.SetDefault("outbox.chat_interval_ms", config.Outbox.ChatIntervalMs)
+	v.SetDefault("outbox.max_attempts", config.Outbox.MaxAttempts)
+	v.SetDefault("telegram.channel", config.Telegram.Channel)
+	v.SetDefault("telegram.admins", config.Telegram.Admins)
+	v.SetDefault("telegram.webhook.enabled", config.Telegram.Webhook.Enabled)
+	v.SetDefault("telegram.webhook.url", config.Telegram.Webhook.URL)
+	v.SetDefault("telegram.webhook.path", config.Telegram.Webhook.Path)
+	v.SetDefault("telegram.webhook.secret", config.Telegram.Webhook.Secret)
 
 	// Check if config file exists
 	if confPath != "" {
@@ -271,6 +436,11 @@ func SetupConfig(confPath string, logger *zap.Logger) (*Config, error) {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
+	// The webhook secret is read before validation, which checks it
+	if v := os.Getenv("TELEGRAM_WEBHOOK_SECRET"); v != "" {
+		config.Telegram.Webhook.Secret = v
+	}
+
 	// Validate config
 	if err := ValidateConfig(config); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
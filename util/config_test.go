@@ -142,3 +142,29 @@ pipup:
 		}
 	})
 }
+
+func TestValidateConfig_Webhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		apiOn   bool
+		path    string
+		secret  string
+		wantErr bool
+	}{
+		{"valid", true, "/telegram/updates", "abc_DEF-123", false},
+		{"api disabled", false, "/telegram/updates", "abc", true},
+		{"relative path", true, "telegram/updates", "abc", true},
+		{"no secret", true, "/telegram/updates", "", true},
+		{"invalid secret", true, "/telegram/updates", "not allowed!", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.API.Enabled = tt.apiOn
+			config.Telegram.Webhook = WebhookConfig{Enabled: true, Path: tt.path, Secret: tt.secret}
+			if err := ValidateConfig(config); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
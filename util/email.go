@@ -131,50 +131,57 @@ func (s *EmailService) SendPasswordResetEmail(to, resetLink string) error {
 	return s.Send(to, subject, body)
 }
 
+// DigestSection is one titled block of deals in the daily summary email,
+// typically the top deals of a single source.
+type DigestSection struct {
+	Title string
+	Deals []models.Deal
+}
+
 // SendDailySummary sends the nightly deal digest email.
-// ozbDeals should be pre-filtered to OZB_SUPER type, sorted by votes descending.
-// amzDeals should be pre-filtered to AMZ_DAILY type.
-func (s *EmailService) SendDailySummary(to string, ozbDeals []models.OzBargainDeal, amzDeals []models.CamCamCamDeal) error {
+// Each section's deals should be pre-filtered to top deals, best first.
+func (s *EmailService) SendDailySummary(to string, sections []DigestSection) error {
 	subject := "KramerBot Daily Deal Summary 🔥"
 
 	const limit = 10
-	var ozbRows strings.Builder
-	for i, d := range ozbDeals {
-		if i >= limit {
-			break
-		}
-		ozbRows.WriteString(fmt.Sprintf(`
+	var blocks strings.Builder
+	for n, section := range sections {
+		var rows strings.Builder
+		for i, d := range section.Deals {
+			if i >= limit {
+				break
+			}
+
+			// Votes for community sites, price drop for price trackers
+			badge := fmt.Sprintf(`<span style="background:#e8f4f8;color:#1a1a1a;border-radius:4px;padding:2px 7px;font-size:12px">📦 %s</span>`,
+				html.EscapeString(d.PriceDrop))
+			if d.Upvotes != "" {
+				badge = fmt.Sprintf(`<span style="background:#F5C518;color:#1a1a1a;border-radius:4px;padding:2px 7px;font-size:12px;font-weight:bold">🔺 %s votes</span>
+            <span style="color:#aaa;font-size:12px;margin-left:8px">%s</span>`,
+					html.EscapeString(d.Upvotes), html.EscapeString(d.PostedOn))
+			}
+
+			rows.WriteString(fmt.Sprintf(`
       <tr>
         <td style="padding:10px 0;border-bottom:1px solid #f0ede4">
           <a href="%s" style="color:#c0392b;font-weight:bold;text-decoration:none;font-size:14px">%s</a>
           <div style="margin-top:4px">
-            <span style="background:#F5C518;color:#1a1a1a;border-radius:4px;padding:2px 7px;font-size:12px;font-weight:bold">🔺 %s votes</span>
-            <span style="color:#aaa;font-size:12px;margin-left:8px">%s</span>
+            %s
           </div>
         </td>
-      </tr>`, html.EscapeString(d.Url), html.EscapeString(d.Title), html.EscapeString(d.Upvotes), html.EscapeString(d.PostedOn)))
-	}
-	if ozbRows.Len() == 0 {
-		ozbRows.WriteString(`<tr><td style="padding:10px 0;color:#aaa;font-size:13px">No top deals today.</td></tr>`)
-	}
+      </tr>`, html.EscapeString(d.Url), html.EscapeString(d.Title), badge))
+		}
+		if rows.Len() == 0 {
+			rows.WriteString(`<tr><td style="padding:10px 0;color:#aaa;font-size:13px">No top deals today.</td></tr>`)
+		}
 
-	var amzRows strings.Builder
-	for i, d := range amzDeals {
-		if i >= limit {
-			break
+		marginTop := "28px"
+		if n == 0 {
+			marginTop = "0"
 		}
-		amzRows.WriteString(fmt.Sprintf(`
-      <tr>
-        <td style="padding:10px 0;border-bottom:1px solid #f0ede4">
-          <a href="%s" style="color:#c0392b;font-weight:bold;text-decoration:none;font-size:14px">%s</a>
-          <div style="margin-top:4px">
-            <span style="background:#e8f4f8;color:#1a1a1a;border-radius:4px;padding:2px 7px;font-size:12px">📦 Amazon Daily</span>
-          </div>
-        </td>
-      </tr>`, html.EscapeString(d.Url), html.EscapeString(d.Title)))
-	}
-	if amzRows.Len() == 0 {
-		amzRows.WriteString(`<tr><td style="padding:10px 0;color:#aaa;font-size:13px">No Amazon daily deals today.</td></tr>`)
+		blocks.WriteString(fmt.Sprintf(`
+    <h2 style="color:#c0392b;margin-top:%s;font-size:17px">🔥 %s</h2>
+    <table style="width:100%%;border-collapse:collapse">%s</table>`, marginTop, html.EscapeString(section.Title), rows.String()))
 	}
 
 	body := fmt.Sprintf(`<!DOCTYPE html>
@@ -183,18 +190,14 @@ func (s *EmailService) SendDailySummary(to string, ozbDeals []models.OzBargainDe
   <div style="background:#c0392b;border-radius:12px 12px 0 0;padding:24px;text-align:center">
     <span style="color:#fff;font-size:22px;font-weight:bold">KramerBot — Daily Summary</span>
   </div>
-  <div style="background:#fff;border-radius:0 0 12px 12px;padding:32px;border:1px solid #e5e7eb;border-top:none">
-    <h2 style="color:#c0392b;margin-top:0;font-size:17px">🔥 Top OzBargain Deals</h2>
-    <table style="width:100%%;border-collapse:collapse">%s</table>
-    <h2 style="color:#c0392b;margin-top:28px;font-size:17px">📦 Amazon Daily Deals</h2>
-    <table style="width:100%%;border-collapse:collapse">%s</table>
+  <div style="background:#fff;border-radius:0 0 12px 12px;padding:32px;border:1px solid #e5e7eb;border-top:none">%s
     <hr style="border:none;border-top:1px solid #e5e7eb;margin:28px 0">
     <p style="color:#aaa;font-size:12px;text-align:center">
       KramerBot Daily Summary · Manage your preferences in the Dashboard
     </p>
   </div>
 </body>
-</html>`, ozbRows.String(), amzRows.String())
+</html>`, blocks.String())
 
 	return s.Send(to, subject, body)
 }
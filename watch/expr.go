@@ -0,0 +1,148 @@
+// Package watch parses and evaluates keyword watch expressions such as
+// `"samsung" AND ssd -refurbished` or `(ps5 OR "playstation 5")`.
+package watch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed watch expression.
+type Expr interface {
+	// Match reports whether the text satisfies the expression.
+	Match(t *Text) bool
+	// String returns the canonical form of the expression.
+	String() string
+}
+
+// Text is a deal title (or any other text) split into lowercase words, so one
+// deal can be matched against many expressions without re-tokenising.
+type Text struct {
+	words []string
+}
+
+// NewText tokenises s for matching.
+func NewText(s string) *Text {
+	return &Text{words: words(s)}
+}
+
+// words splits s into lowercase runs of letters and digits.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Term matches one or more consecutive whole words. A term written with a
+// trailing * matches any word starting with its last word.
+type Term struct {
+	Words  []string
+	Prefix bool
+}
+
+func (e *Term) Match(t *Text) bool {
+	n := len(e.Words)
+	if n == 0 {
+		return false
+	}
+	for i := 0; i+n <= len(t.words); i++ {
+		if e.matchAt(t.words, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Term) matchAt(words []string, i int) bool {
+	last := len(e.Words) - 1
+	for j, w := range e.Words {
+		if j == last && e.Prefix {
+			if !strings.HasPrefix(words[i+j], w) {
+				return false
+			}
+		} else if words[i+j] != w {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Term) String() string {
+	s := strings.Join(e.Words, " ")
+	if len(e.Words) > 1 {
+		s = `"` + s + `"`
+	}
+	if e.Prefix {
+		s += "*"
+	}
+	return s
+}
+
+// And matches when every operand matches.
+type And struct {
+	Exprs []Expr
+}
+
+func (e *And) Match(t *Text) bool {
+	for _, x := range e.Exprs {
+		if !x.Match(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *And) String() string {
+	return join(e.Exprs, " AND ", func(x Expr) bool {
+		_, isOr := x.(*Or)
+		return isOr
+	})
+}
+
+// Or matches when any operand matches.
+type Or struct {
+	Exprs []Expr
+}
+
+func (e *Or) Match(t *Text) bool {
+	for _, x := range e.Exprs {
+		if x.Match(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Or) String() string {
+	return join(e.Exprs, " OR ", func(Expr) bool { return false })
+}
+
+// Not matches when its operand does not.
+type Not struct {
+	Expr Expr
+}
+
+func (e *Not) Match(t *Text) bool {
+	return !e.Expr.Match(t)
+}
+
+func (e *Not) String() string {
+	switch e.Expr.(type) {
+	case *And, *Or:
+		return "NOT (" + e.Expr.String() + ")"
+	}
+	return "NOT " + e.Expr.String()
+}
+
+// join renders operands with sep, parenthesising those that need it.
+func join(exprs []Expr, sep string, needsParens func(Expr) bool) string {
+	parts := make([]string, len(exprs))
+	for i, x := range exprs {
+		if needsParens(x) {
+			parts[i] = "(" + x.String() + ")"
+		} else {
+			parts[i] = x.String()
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,40 @@
+package watch
+
+import "testing"
+
+func TestExpr_Match(t *testing.T) {
+	tests := []struct {
+		expr  string
+		title string
+		want  bool
+	}{
+		{"ssd", "Samsung 990 Pro SSD 2TB $199", true},
+		{"ssd", "Samsung T7 SSDs on sale", false},
+		{"ssd*", "Samsung T7 SSDs on sale", true},
+		{"tv", "Sony TV-stand", true},
+		{"tv", "Hisense 65\" ULED TVs", false},
+		{`"samsung" AND ssd -refurbished`, "Samsung 870 EVO SSD 1TB", true},
+		{`"samsung" AND ssd -refurbished`, "Samsung 870 EVO SSD 1TB (Refurbished)", false},
+		{`"samsung" AND ssd -refurbished`, "Crucial MX500 SSD", false},
+		{`(ps5 OR "playstation 5")`, "PlayStation 5 Slim Console $599", true},
+		{`(ps5 OR "playstation 5")`, "PS5 DualSense Controller", true},
+		{`(ps5 OR "playstation 5")`, "PlayStation 4 Pro", false},
+		{`"playstation 5"`, "5 PlayStation games", false},
+		{"usb-c cable", "Anker USB C Cable 2-Pack", true},
+		{"NOT (xbox OR switch) console", "PS5 Console", true},
+		{"NOT (xbox OR switch) console", "Xbox Series X Console", false},
+		{"4k", "LG 4K OLED TV", true},
+		{"café", "Café Racer Helmet", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr+" / "+tt.title, func(t *testing.T) {
+			expr, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			if got := expr.Match(NewText(tt.title)); got != tt.want {
+				t.Errorf("%s matching %q = %v, want %v", tt.expr, tt.title, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package watch
+
+// Matcher finds which watchers' expressions match a text. Expressions are
+// indexed by words they require (an inverted index), so only expressions
+// sharing a word with the text are evaluated.
+type Matcher struct {
+	entries   []entry
+	words     map[string][]int // exact word -> entries requiring it
+	prefixes  map[string][]int // word prefix -> entries requiring it
+	maxPrefix int              // longest prefix in the index, in bytes
+	always    []int            // entries that cannot be indexed, checked for every text
+}
+
+type entry struct {
+	id   int64
+	expr Expr
+}
+
+// NewMatcher returns an empty matcher.
+func NewMatcher() *Matcher {
+	return &Matcher{
+		words:    make(map[string][]int),
+		prefixes: make(map[string][]int),
+	}
+}
+
+// Add registers an expression for the watcher id (e.g. a Telegram chat ID).
+// A watcher may have many expressions.
+func (m *Matcher) Add(id int64, expr Expr) {
+	i := len(m.entries)
+	m.entries = append(m.entries, entry{id: id, expr: expr})
+
+	words, prefixes, ok := triggers(expr)
+	if !ok {
+		m.always = append(m.always, i)
+		return
+	}
+	for _, w := range words {
+		m.words[w] = append(m.words[w], i)
+	}
+	for _, p := range prefixes {
+		m.prefixes[p] = append(m.prefixes[p], i)
+		if len(p) > m.maxPrefix {
+			m.maxPrefix = len(p)
+		}
+	}
+}
+
+// Len returns the number of registered expressions.
+func (m *Matcher) Len() int {
+	return len(m.entries)
+}
+
+// Match returns the distinct ids with at least one expression matching t.
+func (m *Matcher) Match(t *Text) []int64 {
+	if len(m.entries) == 0 {
+		return nil
+	}
+
+	checked := make(map[int]bool)
+	matched := make(map[int64]bool)
+	var ids []int64
+	check := func(candidates []int) {
+		for _, i := range candidates {
+			e := m.entries[i]
+			if checked[i] || matched[e.id] {
+				continue
+			}
+			checked[i] = true
+			if e.expr.Match(t) {
+				matched[e.id] = true
+				ids = append(ids, e.id)
+			}
+		}
+	}
+
+	for _, w := range t.words {
+		check(m.words[w])
+		for n := 1; n <= len(w) && n <= m.maxPrefix; n++ {
+			check(m.prefixes[w[:n]])
+		}
+	}
+	check(m.always)
+	return ids
+}
+
+// triggers returns exact words and word prefixes, at least one of which must
+// appear in any text the expression matches. ok is false when no such set
+// exists (e.g. for a negation), in which case the expression must always be
+// evaluated.
+func triggers(e Expr) (words, prefixes []string, ok bool) {
+	switch x := e.(type) {
+	case *Term:
+		switch {
+		case len(x.Words) == 0:
+			return nil, nil, true // matches nothing
+		case x.Prefix && len(x.Words) == 1:
+			return nil, x.Words, true
+		default:
+			return x.Words[:1], nil, true
+		}
+
+	case *And:
+		// Any operand's triggers will do; pick the most selective
+		found := false
+		for _, child := range x.Exprs {
+			w, p, childOK := triggers(child)
+			if childOK && (!found || len(w)+len(p) < len(words)+len(prefixes)) {
+				words, prefixes, found = w, p, true
+			}
+		}
+		return words, prefixes, found
+
+	case *Or:
+		for _, child := range x.Exprs {
+			w, p, childOK := triggers(child)
+			if !childOK {
+				return nil, nil, false
+			}
+			words = append(words, w...)
+			prefixes = append(prefixes, p...)
+		}
+		return words, prefixes, true
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,196 @@
+package watch
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	watches := map[int64][]string{
+		1: {"ssd"},
+		2: {`"samsung" AND ssd -refurbished`},
+		3: {`(ps5 OR "playstation 5")`},
+		4: {"nintendo*", "lego"},
+		5: {"NOT (xbox OR switch) console"},
+		6: {"(ssd OR -hdd) samsung"},
+	}
+	m := NewMatcher()
+	for id, kws := range watches {
+		for _, kw := range kws {
+			expr, err := Parse(kw)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", kw, err)
+			}
+			m.Add(id, expr)
+		}
+	}
+
+	tests := []struct {
+		title string
+		want  []int64
+	}{
+		{"Samsung 990 Pro SSD 2TB", []int64{1, 2, 6}},
+		{"Samsung 990 Pro SSD 2TB (Refurbished)", []int64{1, 6}},
+		{"PlayStation 5 Slim Console", []int64{3, 5}},
+		{"Xbox Series X Console", nil},
+		{"Nintendo Switch Lite + LEGO Set", []int64{4}},
+		{"Samsung Galaxy Watch", []int64{6}},
+		{"Nothing to see here", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			got := m.Match(NewText(tt.title))
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Match(%q) = %v, want %v", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+// The matcher must agree with evaluating every expression directly.
+func TestMatcher_AgreesWithScan(t *testing.T) {
+	users, titles := syntheticWatches(500, 3), syntheticTitles(200)
+	m := NewMatcher()
+	for id, exprs := range users {
+		for _, e := range exprs {
+			m.Add(id, e)
+		}
+	}
+
+	for _, title := range titles {
+		text := NewText(title)
+		want := scanMatch(users, text)
+		got := m.Match(text)
+		sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("Match(%q) = %v, want %v", title, got, want)
+		}
+	}
+}
+
+// Benchmarks compare the matcher against evaluating every user's keywords for
+// every deal, the approach the deal loop used before the matcher existed.
+func BenchmarkKeywordMatch(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		users, titles := syntheticWatches(n, 3), syntheticTitles(100)
+		texts := make([]*Text, len(titles))
+		for i, title := range titles {
+			texts[i] = NewText(title)
+		}
+
+		b.Run(fmt.Sprintf("scan/users=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, text := range texts {
+					scanMatch(users, text)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("matcher/users=%d", n), func(b *testing.B) {
+			m := NewMatcher()
+			for id, exprs := range users {
+				for _, e := range exprs {
+					m.Add(id, e)
+				}
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, text := range texts {
+					m.Match(text)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMatcherBuild(b *testing.B) {
+	users := syntheticWatches(10000, 3)
+	for i := 0; i < b.N; i++ {
+		m := NewMatcher()
+		for id, exprs := range users {
+			for _, e := range exprs {
+				m.Add(id, e)
+			}
+		}
+	}
+}
+
+// scanMatch evaluates every expression of every user, returning sorted ids.
+func scanMatch(users map[int64][]Expr, text *Text) []int64 {
+	var ids []int64
+	for id, exprs := range users {
+		for _, e := range exprs {
+			if e.Match(text) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+var vocabulary = []string{
+	"samsung", "ssd", "nvme", "tv", "oled", "qled", "lg", "sony", "ps5", "xbox", "switch",
+	"nintendo", "lego", "dyson", "vacuum", "iphone", "ipad", "macbook", "kindle", "airpods",
+	"headphones", "monitor", "laptop", "coffee", "kettle", "blender", "drill", "bosch",
+	"makita", "ryobi", "refurbished", "bundle", "controller", "console", "usb", "charger",
+	"router", "mesh", "camera", "lens", "tent", "bike", "shoes", "jacket", "vpn", "ram",
+	"gpu", "rtx", "ryzen", "intel", "keyboard", "mouse", "chair", "desk", "speaker",
+}
+
+var templates = []string{
+	"%s",
+	"%s*",
+	"%s %s",
+	`"%s %s"`,
+	"%s OR %s",
+	"%s -%s",
+	"(%s OR %s) %s",
+	"%s AND NOT (%s OR %s)",
+}
+
+// keywordWord picks a watch word: mostly from a long tail of product names,
+// sometimes one of the popular vocabulary words.
+func keywordWord(r *rand.Rand) string {
+	if r.Intn(4) == 0 {
+		return vocabulary[r.Intn(len(vocabulary))]
+	}
+	return fmt.Sprintf("model%d", r.Intn(5000))
+}
+
+// syntheticWatches gives n users perUser random expressions each.
+func syntheticWatches(n, perUser int) map[int64][]Expr {
+	r := rand.New(rand.NewSource(1))
+	users := make(map[int64][]Expr, n)
+	for id := int64(1); id <= int64(n); id++ {
+		for j := 0; j < perUser; j++ {
+			tmpl := templates[r.Intn(len(templates))]
+			args := make([]interface{}, strings.Count(tmpl, "%s"))
+			for k := range args {
+				args[k] = keywordWord(r)
+			}
+			users[id] = append(users[id], ParseOrLiteral(fmt.Sprintf(tmpl, args...)))
+		}
+	}
+	return users
+}
+
+// syntheticTitles builds deal titles of 6-10 random words.
+func syntheticTitles(n int) []string {
+	r := rand.New(rand.NewSource(2))
+	titles := make([]string, n)
+	for i := range titles {
+		words := 6 + r.Intn(5)
+		title := ""
+		for j := 0; j < words; j++ {
+			title += keywordWord(r) + " "
+		}
+		titles[i] = title + fmt.Sprintf("$%d", 10+r.Intn(990))
+	}
+	return titles
+}
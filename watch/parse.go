@@ -0,0 +1,299 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError describes an invalid watch expression. Pos is the 1-based
+// character position of the problem.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// ErrMatchesEverything is returned for expressions made only of exclusions,
+// which would match (and notify about) nearly every deal.
+var ErrMatchesEverything = errors.New("expression matches every deal, add at least one word to look for")
+
+// Parse compiles a watch expression.
+//
+// Words match whole words case-insensitively; a trailing * makes the last word
+// a prefix (ssd* matches "SSDs"). "Quoted phrases" match consecutive words.
+// Terms next to each other must all match. AND, OR and NOT (upper case) combine
+// terms, NOT binds tightest and AND binds tighter than OR. A leading - excludes
+// a term, and parentheses group.
+func Parse(s string) (Expr, error) {
+	p := &parser{lex: lexer{src: []rune(s)}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokEOF {
+		return nil, &SyntaxError{Pos: 1, Msg: "expression is empty"}
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.unexpected()
+	}
+
+	if expr.Match(NewText("")) {
+		return nil, ErrMatchesEverything
+	}
+	return expr, nil
+}
+
+// ParseOrLiteral compiles s, falling back to matching s as a plain phrase if
+// it is not a valid expression (e.g. keywords saved before expressions existed).
+func ParseOrLiteral(s string) Expr {
+	if expr, err := Parse(s); err == nil {
+		return expr
+	}
+	return &Term{Words: words(s)}
+}
+
+// Canonical returns the canonical form of s, used to compare saved keywords.
+// Invalid expressions compare by their trimmed, lower-cased text.
+func Canonical(s string) string {
+	if expr, err := Parse(s); err == nil {
+		return expr.String()
+	}
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	prefix bool // word or phrase followed by *
+	pos    int
+}
+
+type lexer struct {
+	src []rune
+	i   int
+}
+
+// next returns the next token in the source.
+func (l *lexer) next() (token, error) {
+	for l.i < len(l.src) && unicode.IsSpace(l.src[l.i]) {
+		l.i++
+	}
+	pos := l.i + 1
+	if l.i >= len(l.src) {
+		return token{kind: tokEOF, pos: pos}, nil
+	}
+
+	switch r := l.src[l.i]; r {
+	case '(':
+		l.i++
+		return token{kind: tokLParen, text: "(", pos: pos}, nil
+	case ')':
+		l.i++
+		return token{kind: tokRParen, text: ")", pos: pos}, nil
+	case '-':
+		l.i++
+		return token{kind: tokNot, text: "-", pos: pos}, nil
+	case '"':
+		end := l.i + 1
+		for end < len(l.src) && l.src[end] != '"' {
+			end++
+		}
+		if end >= len(l.src) {
+			return token{}, &SyntaxError{Pos: pos, Msg: "unterminated quote"}
+		}
+		text := string(l.src[l.i+1 : end])
+		l.i = end + 1
+		prefix := l.i < len(l.src) && l.src[l.i] == '*'
+		if prefix {
+			l.i++
+		}
+		return token{kind: tokPhrase, text: text, prefix: prefix, pos: pos}, nil
+	}
+
+	start := l.i
+	for l.i < len(l.src) && !unicode.IsSpace(l.src[l.i]) && !strings.ContainsRune(`()"`, l.src[l.i]) {
+		l.i++
+	}
+	text := string(l.src[start:l.i])
+	switch text {
+	case "AND":
+		return token{kind: tokAnd, text: text, pos: pos}, nil
+	case "OR":
+		return token{kind: tokOr, text: text, pos: pos}, nil
+	case "NOT":
+		return token{kind: tokNot, text: text, pos: pos}, nil
+	}
+	return token{kind: tokWord, text: text, prefix: strings.HasSuffix(text, "*"), pos: pos}, nil
+}
+
+// parser is a recursive descent parser over:
+//
+//	or      = and { "OR" and }
+//	and     = unary { ["AND"] unary }
+//	unary   = ("NOT" | "-") unary | primary
+//	primary = "(" or ")" | word | phrase
+type parser struct {
+	lex lexer
+	tok token
+}
+
+func (p *parser) next() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{first}
+	for p.tok.kind == tokOr {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, x)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return &Or{Exprs: flatten(exprs, func(x Expr) []Expr {
+		if or, ok := x.(*Or); ok {
+			return or.Exprs
+		}
+		return nil
+	})}, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{first}
+	for {
+		switch p.tok.kind {
+		case tokAnd:
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		case tokWord, tokPhrase, tokNot, tokLParen:
+			// Implicit AND between adjacent terms
+		default:
+			if len(exprs) == 1 {
+				return first, nil
+			}
+			return &And{Exprs: flatten(exprs, func(x Expr) []Expr {
+				if and, ok := x.(*And); ok {
+					return and.Exprs
+				}
+				return nil
+			})}, nil
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, x)
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.tok.kind != tokNot {
+		return p.parsePrimary()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &Not{Expr: x}, nil
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokLParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokRParen {
+			return nil, &SyntaxError{Pos: p.tok.pos, Msg: "empty parentheses"}
+		}
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: "unclosed parenthesis"}
+		}
+		return x, p.next()
+
+	case tokWord, tokPhrase:
+		w := words(tok.text)
+		if len(w) == 0 {
+			if tok.kind == tokPhrase {
+				return nil, &SyntaxError{Pos: tok.pos, Msg: "empty phrase"}
+			}
+			return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("%q has no letters or digits", tok.text)}
+		}
+		return &Term{Words: w, Prefix: tok.prefix}, p.next()
+	}
+	return nil, p.unexpected()
+}
+
+// unexpected reports the current token as out of place.
+func (p *parser) unexpected() error {
+	switch p.tok.kind {
+	case tokEOF:
+		return &SyntaxError{Pos: p.tok.pos, Msg: "unexpected end of expression, expected a word"}
+	case tokRParen:
+		return &SyntaxError{Pos: p.tok.pos, Msg: "unmatched closing parenthesis"}
+	}
+	return &SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf("unexpected %s, expected a word", p.tok.text)}
+}
+
+// flatten merges nested operands of the same operator, e.g. (a AND b) AND c.
+func flatten(exprs []Expr, children func(Expr) []Expr) []Expr {
+	var out []Expr
+	for _, x := range exprs {
+		if c := children(x); c != nil {
+			out = append(out, c...)
+		} else {
+			out = append(out, x)
+		}
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package watch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse_Canonical(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"ssd", "ssd"},
+		{"SSD", "ssd"},
+		{`"samsung" AND ssd -refurbished`, `samsung AND ssd AND NOT refurbished`},
+		{"samsung ssd", "samsung AND ssd"},
+		{`(ps5 OR "playstation 5")`, `ps5 OR "playstation 5"`},
+		{"(ps5 OR playstation) console", "(ps5 OR playstation) AND console"},
+		{"a OR b c", "a OR b AND c"},
+		{"NOT (a OR b) c", "NOT (a OR b) AND c"},
+		{"usb-c cable", `"usb c" AND cable`},
+		{"ssd*", "ssd*"},
+		{`"nintendo sw"*`, `"nintendo sw"*`},
+		{"fish and chips", "fish AND and AND chips"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			expr, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.in, err)
+			}
+			if got := expr.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+			// The canonical form must parse back to itself
+			again, err := Parse(expr.String())
+			if err != nil || again.String() != tt.want {
+				t.Errorf("canonical form %q does not round-trip: %v, %v", tt.want, again, err)
+			}
+		})
+	}
+}
+
+func TestParse_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantPos int
+	}{
+		{"", 1},
+		{"   ", 1},
+		{`"samsung ssd`, 1},
+		{"(ps5 OR playstation", 1},
+		{"ps5)", 4},
+		{"ps5 OR", 7},
+		{"AND ps5", 1},
+		{"ps5 AND AND xbox", 9},
+		{"()", 2},
+		{`""`, 1},
+		{"ssd -", 6},
+		{"$$$", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			_, err := Parse(tt.in)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse(%q) error = %v, want *SyntaxError", tt.in, err)
+			}
+			if syntaxErr.Pos != tt.wantPos {
+				t.Errorf("Parse(%q) error at %d, want %d (%v)", tt.in, syntaxErr.Pos, tt.wantPos, err)
+			}
+		})
+	}
+}
+
+func TestParse_MatchesEverything(t *testing.T) {
+	for _, in := range []string{"-refurbished", "NOT ssd", "ssd OR -hdd", "NOT (a b)"} {
+		if _, err := Parse(in); !errors.Is(err, ErrMatchesEverything) {
+			t.Errorf("Parse(%q) error = %v, want ErrMatchesEverything", in, err)
+		}
+	}
+}
+
+func TestParseOrLiteral(t *testing.T) {
+	expr := ParseOrLiteral("(ps5 bundle")
+	if !expr.Match(NewText("PS5 Bundle with 2 controllers")) {
+		t.Error("invalid expression should fall back to a phrase match")
+	}
+	if expr.Match(NewText("PS5 disc edition")) {
+		t.Error("phrase fallback should require the whole phrase")
+	}
+	if ParseOrLiteral("$$$").Match(NewText("Any deal at all")) {
+		t.Error("fallback without words should match nothing")
+	}
+}